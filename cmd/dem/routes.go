@@ -11,22 +11,40 @@ import (
 	getadmincoef "vue-golang/http-server/admin/get"
 	saveadmincoef "vue-golang/http-server/admin/save"
 	upadmincoef "vue-golang/http-server/admin/update"
+	"vue-golang/http-server/coefficient"
 	generate_excel "vue-golang/http-server/generate-report/generate-excel"
 	getmaterials "vue-golang/http-server/materials/get"
 	getorder "vue-golang/http-server/order-dem/get"
+	"vue-golang/http-server/order-norm/duplicates"
 	"vue-golang/http-server/order-norm/get"
+	"vue-golang/http-server/order-norm/history"
+	"vue-golang/http-server/order-norm/normmoney"
 	"vue-golang/http-server/order-norm/save"
+	"vue-golang/http-server/order-norm/status"
 	"vue-golang/http-server/order-norm/update"
+	"vue-golang/http-server/payroll"
 	recalculate_norm "vue-golang/http-server/recalculate-norm"
+	bundletemplate "vue-golang/http-server/template/bundle"
+	casestemplate "vue-golang/http-server/template/cases"
 	gettemplate "vue-golang/http-server/template/get"
+	renormtemplate "vue-golang/http-server/template/renorm"
 	savetemplate "vue-golang/http-server/template/save"
 	uptemplate "vue-golang/http-server/template/update"
+	versiontemplate "vue-golang/http-server/template/version"
 	getWorkers "vue-golang/http-server/workers/get"
 	saveWorkers "vue-golang/http-server/workers/save"
+	workerstatement "vue-golang/http-server/workers/statement"
 	"vue-golang/internal/config"
 	"vue-golang/internal/middleware/auth"
+	"vue-golang/internal/service/assignments"
+	"vue-golang/internal/service/audit"
+	"vue-golang/internal/service/batch"
 	generate_excel2 "vue-golang/internal/service/generate-excel"
+	"vue-golang/internal/service/lifecycle"
 	"vue-golang/internal/service/recalculate"
+	"vue-golang/internal/service/renorm"
+	"vue-golang/internal/service/statement"
+	"vue-golang/internal/service/templates"
 	"vue-golang/internal/storage/mysql"
 )
 
@@ -44,7 +62,8 @@ func routes(cfg config.Config, log *slog.Logger, storage *mysql.Storage, service
 	corsHandler := cors.New(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:8081", "http://localhost:5173"}, // Разрешаем запросы с фронтенда
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-User", "If-Match", "Idempotency-Key"},
+		ExposedHeaders:   []string{"ETag"},
 		AllowCredentials: true,
 	})
 
@@ -65,13 +84,24 @@ func routes(cfg config.Config, log *slog.Logger, storage *mysql.Storage, service
 
 	//TODO получение шаблонов
 	router.Get("/api/template", gettemplate.GetTemplatesByCode(log, storage))
+	router.Get("/api/template/match", gettemplate.MatchTemplates(log, storage))
 	router.Get("/api/all_templates", gettemplate.GetAllTemplates(log, storage))
 
 	//TODO сохранение нормированных нарядов
 	router.Post("/api/orders/order-norm/template", save.SaveNormOrderOperation(log, storage))
+	router.Get("/api/orders/order-norm/existing", duplicates.GetExistingNorms(log, storage))
 
 	//TODO обновление статуса нормировки(отмена)
-	router.Post("/api/orders/cancel", update.UpdateCancelStatus(log, storage))
+	statusService := lifecycle.NewService(storage)
+	router.Post("/api/orders/cancel", update.UpdateCancelStatus(log, statusService))
+	router.Post("/api/orders/status", status.ChangeStatus(log, statusService))
+	router.Post("/api/orders/reopen", status.ReopenOrder(log, statusService))
+	router.Get("/api/orders/order/norm/{id}/status-history", status.GetStatusHistory(log, statusService))
+
+	// История правок нормировки и назначений
+	auditService := audit.NewService(storage)
+	router.Get("/api/orders/order/norm/{id}/history", history.GetProductTimeline(log, auditService))
+	router.Get("/api/orders/order/norm/{id}/history/diff", history.DiffProductRevisions(log, auditService))
 
 	//TODO get получение нормированного наряда
 	router.Get("/api/orders/order/norm/{id}", get.GetNormOrder(log, storage))
@@ -83,29 +113,45 @@ func routes(cfg config.Config, log *slog.Logger, storage *mysql.Storage, service
 	router.Get("/api/orders/order/norm/all", get.GetNormOrders(log, storage))
 
 	//TODO update обновление нормированного наряда
-	router.Put("/api/orders/order/norm/update/{id}", update.UpdateNormOrderOperation(log, storage))
+	router.Put("/api/orders/order/norm/update/{id}", update.UpdateNormOrderOperation(log, storage, statusService))
 
 	//TODO назначение сотрудников
-	router.Post("/api/workers", saveWorkers.SaveWorkersOperation(log, storage))
+	router.Post("/api/workers", saveWorkers.SaveWorkersOperation(log, storage, statusService, assignments.NewValidator(storage, cfg.AssignmentTolerance)))
 	//TODO получение всех сотрудников
 	router.Get("/api/workers/all", getWorkers.GetWorkers(log, storage))
+	// Ведомость выработки и заработка сотрудника за период
+	statementService := statement.NewService(storage)
+	router.Get("/api/workers/{id}/statement", workerstatement.GetEmployeeStatement(log, statementService))
+	router.Get("/api/workers/{id}/statement/excel", workerstatement.ExportEmployeeStatement(log, statementService))
+
+	// Расчётные периоды: закрытый месяц бригады замораживает её изделия; переоткрыть — только в админке
+	router.Get("/api/payroll/periods", payroll.GetPayrollPeriods(log, storage))
+	router.Get("/api/payroll/periods/{id}", payroll.GetPayrollPeriod(log, storage))
+	router.Post("/api/payroll/periods/close", payroll.ClosePayrollPeriod(log, storage))
 
 	//TODO финальные маршруты для всех готовых заказов и возможность провалиться в них
 	router.Get("/api/allians/{order_num}", get.FinalReportNormOrder(log, storage))
 	router.Get("/api/all_final_order", get.FinalReportNormOrders(log, storage))
 
 	//TODO финальное обновление
-	router.Put("/api/final/update/{id}", update.UpdateFinalOrder(log, storage))
+	router.Put("/api/final/update/{id}", update.UpdateFinalOrder(log, storage, statusService))
 
 	//Материалы к заказу
 	router.Get("/api/materials", getmaterials.GetMaterials(log, storage))
 	router.Post("/api/materials/calculation", recalculate_norm.CalculateNormOperations(log, service))
+	router.Post("/api/materials/calculation/order", recalculate_norm.CalculateOrderNorm(log, batch.NewService(storage, service)))
+
+	// Коэффициент ПЭО, действующий на дату готовности
+	router.Get("/api/coefficient", coefficient.ResolveCoefficient(log, storage))
 
 	// TODO генерация excel
 	router.Get("/api/report/excel", generate_excel.GenerateReportExcel(log, genSevice))
 
 	//TODO adminPanel
 
+	bundleService := templates.NewBundleService(storage)
+	renormService := renorm.NewService(storage, service)
+
 	adminRouter := chi.NewRouter()
 	adminRouter.Use(auth.BasicAuth(cfg.AdminLogin, cfg.AdminPass))
 
@@ -113,11 +159,31 @@ func routes(cfg config.Config, log *slog.Logger, storage *mysql.Storage, service
 	adminRouter.Get("/template", gettemplate.GetTemplatesByCodeAdmin(log, storage))
 	adminRouter.Put("/template/update/{id}", uptemplate.UpdateTemplateAdmin(log, storage))
 	adminRouter.Post("/template/new", savetemplate.SaveTemplateAdmin(log, storage))
+	adminRouter.Get("/template/{id}/versions", versiontemplate.ListTemplateVersionsAdmin(log, storage))
+	adminRouter.Get("/template/{id}/versions/compare", versiontemplate.CompareTemplateVersionsAdmin(log, storage))
+	adminRouter.Post("/template/{id}/rollback", versiontemplate.RollbackTemplateAdmin(log, storage))
+	adminRouter.Get("/template/{id}/cases", casestemplate.ListTemplateCasesAdmin(log, storage))
+	adminRouter.Post("/template/{id}/cases", casestemplate.SaveTemplateCaseAdmin(log, storage))
+	adminRouter.Post("/template/{id}/cases/run", casestemplate.RunTemplateCasesAdmin(log, storage))
+	adminRouter.Put("/template/{id}/cases/{caseId}", casestemplate.UpdateTemplateCaseAdmin(log, storage))
+	adminRouter.Delete("/template/{id}/cases/{caseId}", casestemplate.DeleteTemplateCaseAdmin(log, storage))
+	adminRouter.Get("/template/renorm/preview", renormtemplate.PreviewRenormAdmin(log, renormService))
+	adminRouter.Post("/template/renorm/apply", renormtemplate.ApplyRenormAdmin(log, renormService))
+	adminRouter.Get("/templates/export", bundletemplate.ExportTemplatesAdmin(log, bundleService))
+	adminRouter.Post("/templates/import", bundletemplate.ImportTemplatesAdmin(log, bundleService))
+	adminRouter.Get("/norm/duplicates", duplicates.GetDuplicateNormsAdmin(log, storage))
+	adminRouter.Get("/norm-money/mismatches", normmoney.GetNormMoneyMismatchesAdmin(log, storage))
+	adminRouter.Post("/norm-money/recompute", normmoney.RecomputeNormMoneyAdmin(log, storage))
+	adminRouter.Post("/payroll/periods/reopen", payroll.ReopenPayrollPeriodAdmin(log, storage))
 	adminRouter.Get("/coefficient", getadmincoef.GetCoefficientAdmin(log, storage))
 	adminRouter.Put("/coefficient/update", upadmincoef.UpdateCoefficientAdmin(log, storage))
+	adminRouter.Get("/coefficient/history", coefficient.GetCoefficientHistoryAdmin(log, storage))
 	adminRouter.Get("/employees", getadmincoef.GetAllEmployeesAdmin(log, storage))
 	adminRouter.Put("/employees/update", upadmincoef.UpdateEmployeesAdmin(log, storage))
 	adminRouter.Post("/employees/save", saveadmincoef.SaveEmployerAdmin(log, storage))
+	adminRouter.Get("/features", getadmincoef.GetContextFeaturesAdmin(log, storage))
+	adminRouter.Post("/features/save", saveadmincoef.SaveContextFeatureAdmin(log, storage))
+	adminRouter.Put("/features/update/{id}", upadmincoef.UpdateContextFeatureAdmin(log, storage))
 	//
 	router.Mount("/api/admin", adminRouter)
 	//
@@ -0,0 +1,144 @@
+// Команда templates выгружает и загружает шаблоны нормирования файлом-выгрузкой.
+//
+//	templates export [-code KP45Door,vitrage] [-out templates.json]
+//	templates import -in templates.json [-dry-run] [-comment "перенос с dev"]
+//
+// Конфиг читается так же, как у сервера: ./config/local.yaml
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"vue-golang/internal/config"
+	"vue-golang/internal/service/templates"
+	"vue-golang/internal/storage/mysql"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "export":
+		err = runExport(os.Args[2:])
+	case "import":
+		err = runImport(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ошибка:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "использование:")
+	fmt.Fprintln(os.Stderr, "  templates export [-code a,b] [-out file.json]")
+	fmt.Fprintln(os.Stderr, "  templates import -in file.json [-dry-run] [-comment текст]")
+}
+
+func newService() (*templates.BundleService, error) {
+	cfg := config.MustConfig()
+
+	storage, err := mysql.New(*cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open db: %w", err)
+	}
+
+	return templates.NewBundleService(storage), nil
+}
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	codes := fs.String("code", "", "коды шаблонов через запятую, пусто — все")
+	out := fs.String("out", "", "файл выгрузки, пусто — stdout")
+	_ = fs.Parse(args)
+
+	service, err := newService()
+	if err != nil {
+		return err
+	}
+
+	var list []string
+	for _, code := range strings.Split(*codes, ",") {
+		if code = strings.TrimSpace(code); code != "" {
+			list = append(list, code)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	bundle, err := service.Export(ctx, list)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(bundle)
+}
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	in := fs.String("in", "", "файл выгрузки")
+	dryRun := fs.Bool("dry-run", false, "только проверить и показать изменения")
+	comment := fs.String("comment", "импорт", "комментарий к новым версиям шаблонов")
+	_ = fs.Parse(args)
+
+	if *in == "" {
+		return errors.New("не указан -in")
+	}
+
+	data, err := os.ReadFile(*in)
+	if err != nil {
+		return err
+	}
+
+	var bundle templates.Bundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return fmt.Errorf("неверный JSON в %s: %w", *in, err)
+	}
+
+	service, err := newService()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	report, importErr := service.Import(ctx, &bundle, *dryRun, *comment)
+	if report != nil {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	}
+
+	return importErr
+}
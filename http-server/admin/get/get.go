@@ -53,3 +53,25 @@ func GetAllEmployeesAdmin(log *slog.Logger, emp AdminCoefProvider) http.HandlerF
 		w.WriteHeader(http.StatusOK)
 	}
 }
+
+type ContextFeatureProvider interface {
+	GetAllContextFeaturesAdmin(ctx context.Context) ([]storage.ContextFeature, error)
+}
+
+func GetContextFeaturesAdmin(log *slog.Logger, features ContextFeatureProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.GetContextFeaturesAdmin"
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		res, err := features.GetAllContextFeaturesAdmin(ctx)
+		if err != nil {
+			log.With(slog.String("op", op), slog.String("error", err.Error())).Error("ошибка получения реестра признаков")
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, res)
+	}
+}
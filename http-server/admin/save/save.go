@@ -3,9 +3,11 @@ package save
 import (
 	"context"
 	"encoding/json"
+	"github.com/go-chi/render"
 	"log/slog"
 	"net/http"
 	"time"
+	"vue-golang/internal/service/recalculate"
 	"vue-golang/internal/storage"
 )
 
@@ -42,3 +44,37 @@ func SaveEmployerAdmin(log *slog.Logger, emp EmployeesProvider) http.HandlerFunc
 		w.WriteHeader(http.StatusOK)
 	}
 }
+
+type ContextFeatureCreator interface {
+	CreateContextFeatureAdmin(ctx context.Context, feature storage.ContextFeature) (int64, error)
+}
+
+func SaveContextFeatureAdmin(log *slog.Logger, features ContextFeatureCreator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.SaveContextFeatureAdmin"
+
+		// Не переданный is_active — признак включён, как по умолчанию в таблице
+		feature := storage.ContextFeature{IsActive: true}
+		if err := json.NewDecoder(r.Body).Decode(&feature); err != nil {
+			http.Error(w, "Неверный JSON", http.StatusBadRequest)
+			return
+		}
+
+		if err := recalculate.ValidateFeature(feature); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		id, err := features.CreateContextFeatureAdmin(ctx, feature)
+		if err != nil {
+			log.Error("Ошибка добавления признака в реестр", "op", op, "error", err)
+			http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, map[string]interface{}{"status": "created", "id": id})
+	}
+}
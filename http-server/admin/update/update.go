@@ -3,14 +3,19 @@ package update
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
+	"vue-golang/internal/middleware/auth"
+	"vue-golang/internal/service/recalculate"
 	"vue-golang/internal/storage"
 )
 
 type UpdateCoefProvider interface {
-	UpdateCoefficientPEOAdmin(ctx context.Context, coeffs []storage.CoefficientPEOAdmin) error
+	UpdateCoefficientPEOAdmin(ctx context.Context, coeffs []storage.CoefficientPEOAdmin, changedBy string) error
 	UpdateAllEmployeesAdmin(ctx context.Context, emps []storage.EmployeesAdmin) error
 }
 
@@ -28,11 +33,21 @@ func UpdateCoefficientAdmin(log *slog.Logger, update UpdateCoefProvider) http.Ha
 			http.Error(w, "Неверный JSON", http.StatusBadRequest)
 			return
 		}
+		for _, coef := range coeffs {
+			if coef.EffectiveFrom == "" {
+				continue
+			}
+			if _, err := time.Parse("2006-01-02", coef.EffectiveFrom); err != nil {
+				http.Error(w, "Неверная дата effective_from (YYYY-MM-DD) для типа "+coef.Type, http.StatusBadRequest)
+				return
+			}
+		}
 
-		ctx, cancel := context.WithTimeout(r.Context(), 50*time.Millisecond)
+		// Новые значения пишутся в историю, поэтому запрос дольше простого UPDATE
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		defer cancel()
 
-		err := update.UpdateCoefficientPEOAdmin(ctx, coeffs)
+		err := update.UpdateCoefficientPEOAdmin(ctx, coeffs, auth.Actor(r))
 		if err != nil {
 			log.Error("Ошибка обновления коэффициентов", "error", err)
 			http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
@@ -71,3 +86,46 @@ func UpdateEmployeesAdmin(log *slog.Logger, update UpdateCoefProvider) http.Hand
 		w.WriteHeader(http.StatusOK)
 	}
 }
+
+type ContextFeatureUpdater interface {
+	UpdateContextFeatureAdmin(ctx context.Context, id int64, update storage.ContextFeatureUpdate) error
+}
+
+func UpdateContextFeatureAdmin(log *slog.Logger, features ContextFeatureUpdater) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.UpdateContextFeatureAdmin"
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "неверный ID признака", http.StatusBadRequest)
+			return
+		}
+
+		var update storage.ContextFeatureUpdate
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			http.Error(w, "Неверный JSON", http.StatusBadRequest)
+			return
+		}
+
+		if err := recalculate.ValidateFeature(update.ContextFeature); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		err = features.UpdateContextFeatureAdmin(ctx, id, update)
+		if errors.Is(err, storage.ErrFeatureNotFound) {
+			http.Error(w, "признак не найден", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Error("Ошибка обновления признака реестра", "op", op, "error", err)
+			http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
@@ -0,0 +1,57 @@
+package update
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"vue-golang/internal/storage"
+)
+
+type fakeFeatureUpdater struct {
+	got storage.ContextFeatureUpdate
+	err error
+}
+
+func (f *fakeFeatureUpdater) UpdateContextFeatureAdmin(_ context.Context, _ int64, update storage.ContextFeatureUpdate) error {
+	f.got = update
+	return f.err
+}
+
+func updateFeature(updater *fakeFeatureUpdater, body string) *httptest.ResponseRecorder {
+	r := chi.NewRouter()
+	r.Put("/features/update/{id}", UpdateContextFeatureAdmin(slog.Default(), updater))
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/features/update/5", strings.NewReader(body)))
+	return rr
+}
+
+// Тест: не переданный is_active не выключает признак, пустая агрегация допустима
+func TestUpdateContextFeatureAdmin_Defaults(t *testing.T) {
+	updater := &fakeFeatureUpdater{}
+	rr := updateFeature(updater, `{"feature_key": "A", "match_names": ["x"]}`)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Nil(t, updater.got.IsActive)
+	assert.Equal(t, "A", updater.got.Key)
+
+	rr = updateFeature(updater, `{"feature_key": "A", "match_names": ["x"], "is_active": false}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	if assert.NotNil(t, updater.got.IsActive) {
+		assert.False(t, *updater.got.IsActive)
+	}
+}
+
+// Тест: нет признака с таким id — 404
+func TestUpdateContextFeatureAdmin_NotFound(t *testing.T) {
+	updater := &fakeFeatureUpdater{err: fmt.Errorf("op: %w", storage.ErrFeatureNotFound)}
+	rr := updateFeature(updater, `{"feature_key": "A", "match_names": ["x"]}`)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,73 @@
+package coefficient
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"time"
+	"vue-golang/internal/storage"
+)
+
+type CoefficientHistory interface {
+	GetCoefficientHistory(ctx context.Context, productType string) ([]storage.CoefficientHistory, error)
+	ResolveCoefficient(ctx context.Context, productType string, date time.Time) (storage.CoefficientHistory, error)
+}
+
+// ResolveCoefficient — коэффициент типа изделия на дату готовности: ?type=&date= (2006-01-02, по умолчанию сегодня)
+func ResolveCoefficient(log *slog.Logger, coefs CoefficientHistory) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.coefficient.ResolveCoefficient"
+
+		productType := r.URL.Query().Get("type")
+		if productType == "" {
+			http.Error(w, "type is required", http.StatusBadRequest)
+			return
+		}
+
+		date := time.Now()
+		if s := r.URL.Query().Get("date"); s != "" {
+			var err error
+			if date, err = time.Parse("2006-01-02", s); err != nil {
+				http.Error(w, "invalid date", http.StatusBadRequest)
+				return
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		coef, err := coefs.ResolveCoefficient(ctx, productType, date)
+		if err != nil {
+			if errors.Is(err, storage.ErrCoefficientNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			log.Error("Ошибка получения коэффициента", slog.String("op", op), slog.String("error", err.Error()))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, coef)
+	}
+}
+
+// GetCoefficientHistoryAdmin — история коэффициентов: ?type= (пусто — все типы)
+func GetCoefficientHistoryAdmin(log *slog.Logger, coefs CoefficientHistory) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.GetCoefficientHistory"
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		history, err := coefs.GetCoefficientHistory(ctx, r.URL.Query().Get("type"))
+		if err != nil {
+			log.Error("Ошибка получения истории коэффициентов", slog.String("op", op), slog.String("error", err.Error()))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, history)
+	}
+}
@@ -1,11 +1,13 @@
 package generate_excel
 
 import (
+	"errors"
 	"fmt"
 	"golang.org/x/net/context"
 	"log/slog"
 	"net/http"
 	"time"
+	report "vue-golang/internal/service/generate-excel"
 	"vue-golang/internal/storage/mysql"
 )
 
@@ -54,13 +56,17 @@ func GenerateReportExcel(log *slog.Logger, gen GenerateExcelHandler) http.Handle
 		defer cancel()
 
 		excelBytes, err := gen.GenerateExcel(ctx, filter)
+		if errors.Is(err, report.ErrMixedReportTypes) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			log.Error("failed to generate excel", "op", op, "err", err)
 			http.Error(w, "Internal error", http.StatusInternalServerError)
 			return
 		}
 
-		fileName := fmt.Sprintf("test_%s.xlsx", time.Now().Format("2006-01-02_150405"))
+		fileName := fmt.Sprintf("peo_report_%s.xlsx", time.Now().Format("2006-01-02_150405"))
 
 		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
 		w.Header().Set("Content-Disposition", "attachment; filename="+fileName)
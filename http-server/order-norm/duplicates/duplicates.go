@@ -0,0 +1,63 @@
+package duplicates
+
+import (
+	"context"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"vue-golang/internal/storage"
+)
+
+type NormDuplicates interface {
+	GetExistingNorms(ctx context.Context, orderNum string, position int, templateCode string) ([]storage.ExistingNorm, error)
+	GetDuplicateNorms(ctx context.Context) ([]storage.DuplicateNormGroup, error)
+}
+
+// GetExistingNorms — предупреждение перед нормировкой: ?order_num=&position=&template_code= (шаблон необязателен).
+// Пустой список — позицию можно нормировать; иначе сохранять только с renorm.
+func GetExistingNorms(log *slog.Logger, norms NormDuplicates) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.norm.GetExistingNorms"
+
+		q := r.URL.Query()
+		orderNum := q.Get("order_num")
+		position, err := strconv.Atoi(q.Get("position"))
+		if orderNum == "" || err != nil {
+			http.Error(w, "order_num and position are required", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		existing, err := norms.GetExistingNorms(ctx, orderNum, position, q.Get("template_code"))
+		if err != nil {
+			log.Error("Ошибка получения нормировок позиции", slog.String("op", op), slog.String("error", err.Error()))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, existing)
+	}
+}
+
+// GetDuplicateNormsAdmin — отчёт о текущих дублях для ручной чистки
+func GetDuplicateNormsAdmin(log *slog.Logger, norms NormDuplicates) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.GetDuplicateNorms"
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		groups, err := norms.GetDuplicateNorms(ctx)
+		if err != nil {
+			log.Error("Ошибка получения дублей нормировок", slog.String("op", op), slog.String("error", err.Error()))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, groups)
+	}
+}
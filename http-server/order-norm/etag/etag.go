@@ -0,0 +1,106 @@
+package etag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"vue-golang/internal/storage"
+)
+
+var (
+	ErrInvalidIfMatch       = errors.New("некорректный заголовок If-Match")
+	ErrPreconditionRequired = errors.New("не передана версия нормировки: нужен заголовок If-Match или row_version")
+)
+
+// CurrentState читает актуальную нормировку, которую отдаём клиенту при конфликте версий
+type CurrentState interface {
+	GetNormOrder(ctx context.Context, id int64) (*storage.GetOrderDetails, error)
+}
+
+// Format — ETag для версии строки изделия
+func Format(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// Set выставляет ETag ответа
+func Set(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", Format(version))
+}
+
+// IfMatch возвращает ожидаемую клиентом версию: заголовок If-Match важнее row_version из тела.
+// Нет ни того, ни другого (или If-Match: *) — nil, запись без проверки.
+func IfMatch(r *http.Request, fromBody *int) (*int, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		return fromBody, nil
+	}
+	if header == "*" {
+		return nil, nil
+	}
+
+	value := strings.TrimPrefix(header, "W/")
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	}
+
+	version, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidIfMatch, header)
+	}
+
+	return &version, nil
+}
+
+// Require — то же, что IfMatch, но без версии запись не выполняется: ErrPreconditionRequired
+func Require(r *http.Request, fromBody *int) (*int, error) {
+	if strings.TrimSpace(r.Header.Get("If-Match")) == "" && fromBody == nil {
+		return nil, ErrPreconditionRequired
+	}
+
+	return IfMatch(r, fromBody)
+}
+
+// WriteError отвечает на ошибку IfMatch/Require: 428 без версии, 400 на некорректный If-Match
+func WriteError(w http.ResponseWriter, err error) {
+	code := http.StatusBadRequest
+	if errors.Is(err, ErrPreconditionRequired) {
+		code = http.StatusPreconditionRequired
+	}
+	http.Error(w, err.Error(), code)
+}
+
+// WriteConflict отвечает 409 с текущим состоянием изделия, если err — конфликт версий; иначе возвращает false
+func WriteConflict(w http.ResponseWriter, r *http.Request, log *slog.Logger, op string, current CurrentState, err error) bool {
+	var conflict *storage.VersionConflictError
+	if !errors.As(err, &conflict) {
+		return false
+	}
+
+	log.Warn("Конфликт версий нормировки", slog.String("op", op), slog.Int64("id", conflict.ProductID),
+		slog.Int("expected", conflict.Expected), slog.Int("current", conflict.Current))
+
+	version := conflict.Current
+	resp := map[string]interface{}{
+		"error": "нормировка изменена другим пользователем, обновите страницу",
+	}
+
+	// Состояние читаем уже после отката транзакции; если не вышло — клиенту хватит версии
+	state, stateErr := current.GetNormOrder(r.Context(), conflict.ProductID)
+	if stateErr != nil {
+		log.Error("Ошибка чтения текущей нормировки", slog.String("op", op), slog.String("error", stateErr.Error()))
+	} else {
+		resp["current"] = state
+		version = state.RowVersion
+	}
+	resp["current_version"] = version
+
+	Set(w, version)
+	render.Status(r, http.StatusConflict)
+	render.JSON(w, r, resp)
+	return true
+}
@@ -0,0 +1,107 @@
+package etag
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"vue-golang/internal/storage"
+)
+
+type MockCurrentState struct {
+	mock.Mock
+}
+
+func (m *MockCurrentState) GetNormOrder(ctx context.Context, id int64) (*storage.GetOrderDetails, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*storage.GetOrderDetails), args.Error(1)
+}
+
+// Тест: If-Match важнее тела, кавычки и W/ допускаются, * — без проверки
+func TestIfMatch(t *testing.T) {
+	body := 2
+
+	req := httptest.NewRequest(http.MethodPut, "/", nil)
+	got, err := IfMatch(req, &body)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, *got)
+
+	for _, header := range []string{`"5"`, `W/"5"`, `5`} {
+		req.Header.Set("If-Match", header)
+		got, err = IfMatch(req, &body)
+		assert.NoError(t, err, header)
+		assert.Equal(t, 5, *got, header)
+	}
+
+	req.Header.Set("If-Match", "*")
+	got, err = IfMatch(req, &body)
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+
+	req.Header.Set("If-Match", `"abc"`)
+	_, err = IfMatch(req, &body)
+	assert.ErrorIs(t, err, ErrInvalidIfMatch)
+}
+
+// Тест: без If-Match и row_version — 428, с любым из них — версия как у IfMatch
+func TestRequire(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/", nil)
+	_, err := Require(req, nil)
+	assert.ErrorIs(t, err, ErrPreconditionRequired)
+
+	rec := httptest.NewRecorder()
+	WriteError(rec, err)
+	assert.Equal(t, http.StatusPreconditionRequired, rec.Code)
+
+	body := 2
+	got, err := Require(req, &body)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, *got)
+
+	req.Header.Set("If-Match", `"7"`)
+	got, err = Require(req, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, *got)
+
+	req.Header.Set("If-Match", "abc")
+	_, err = Require(req, nil)
+	rec = httptest.NewRecorder()
+	WriteError(rec, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+// Тест: конфликт версий — 409, актуальная нормировка в теле и ETag с текущей версией
+func TestWriteConflict(t *testing.T) {
+	current := new(MockCurrentState)
+	current.On("GetNormOrder", mock.Anything, int64(10)).Return(&storage.GetOrderDetails{ID: 10, Name: "Окно", RowVersion: 4}, nil)
+
+	err := fmt.Errorf("storage.mysql.UpdateNormOrder: %w", &storage.VersionConflictError{ProductID: 10, Expected: 3, Current: 4})
+
+	rr := httptest.NewRecorder()
+	ok := WriteConflict(rr, httptest.NewRequest(http.MethodPut, "/", nil), slog.Default(), "test", current, err)
+
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, `"4"`, rr.Header().Get("ETag"))
+
+	var resp struct {
+		CurrentVersion int                     `json:"current_version"`
+		Current        storage.GetOrderDetails `json:"current"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, 4, resp.CurrentVersion)
+	assert.Equal(t, "Окно", resp.Current.Name)
+
+	rr = httptest.NewRecorder()
+	assert.False(t, WriteConflict(rr, httptest.NewRequest(http.MethodPut, "/", nil), slog.Default(), "test", current, errors.New("boom")))
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
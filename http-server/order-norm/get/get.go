@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"vue-golang/http-server/order-norm/etag"
 	"vue-golang/internal/storage"
 	"vue-golang/internal/storage/mysql"
 )
@@ -52,7 +53,8 @@ func GetNormOrder(log *slog.Logger, result ResultGetNorm) http.HandlerFunc {
 			return
 		}
 
-		// Успешный ответ
+		// Успешный ответ; ETag — версия для If-Match при сохранении
+		etag.Set(w, norm.RowVersion)
 		render.JSON(w, r, norm)
 	}
 }
@@ -0,0 +1,90 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"vue-golang/internal/service/audit"
+)
+
+type ProductHistory interface {
+	Timeline(ctx context.Context, productID int64) ([]audit.Event, error)
+	Diff(ctx context.Context, productID int64, from, to int) (audit.Diff, error)
+}
+
+// GetProductTimeline — хронология правок нормировки, назначений и статусов изделия
+func GetProductTimeline(log *slog.Logger, history ProductHistory) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.norm.GetProductTimeline"
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid ID", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		events, err := history.Timeline(ctx, id)
+		if err != nil {
+			log.Error("Ошибка получения истории изделия", slog.String("op", op), slog.String("error", err.Error()))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, events)
+	}
+}
+
+// DiffProductRevisions сравнивает две ревизии изделия: ?from=&to= (по умолчанию последняя и предыдущая)
+func DiffProductRevisions(log *slog.Logger, history ProductHistory) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.norm.DiffProductRevisions"
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid ID", http.StatusBadRequest)
+			return
+		}
+
+		from, errFrom := optionalInt(r.URL.Query().Get("from"))
+		to, errTo := optionalInt(r.URL.Query().Get("to"))
+		if errFrom != nil || errTo != nil {
+			http.Error(w, "Invalid query parameter 'from' or 'to'", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		diff, err := history.Diff(ctx, id, from, to)
+		if err != nil {
+			if errors.Is(err, audit.ErrRevisionNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			log.Error("Ошибка сравнения ревизий", slog.String("op", op), slog.String("error", err.Error()))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, diff)
+	}
+}
+
+func optionalInt(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 {
+		return 0, errors.New("invalid")
+	}
+	return v, nil
+}
@@ -0,0 +1,83 @@
+package normmoney
+
+import (
+	"context"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"vue-golang/internal/storage"
+)
+
+type NormMoneyChecker interface {
+	GetNormMoneyMismatches(ctx context.Context, tolerance float64) ([]storage.NormMoneyMismatch, error)
+	RecomputeNormMoney(ctx context.Context, ids []int64) ([]int64, error)
+}
+
+type RecomputeRequest struct {
+	IDs []int64 `json:"ids"`
+}
+
+type RecomputeResponse struct {
+	// Пересчитанные изделия; остальные из запроса заморожены закрытым расчётным периодом
+	Recomputed []int64 `json:"recomputed"`
+}
+
+// GetNormMoneyMismatchesAdmin — отчёт о согласованности Н/руб: ?tolerance= (руб., по умолчанию storage.NormMoneyTolerance)
+func GetNormMoneyMismatchesAdmin(log *slog.Logger, checker NormMoneyChecker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.GetNormMoneyMismatches"
+
+		tolerance := storage.NormMoneyTolerance
+		if s := r.URL.Query().Get("tolerance"); s != "" {
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil || v < 0 {
+				http.Error(w, "invalid tolerance", http.StatusBadRequest)
+				return
+			}
+			tolerance = v
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		mismatches, err := checker.GetNormMoneyMismatches(ctx, tolerance)
+		if err != nil {
+			log.Error("Ошибка проверки Н/руб", slog.String("op", op), slog.String("error", err.Error()))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, mismatches)
+	}
+}
+
+// RecomputeNormMoneyAdmin — пересчёт Н/руб выбранных из отчёта изделий
+func RecomputeNormMoneyAdmin(log *slog.Logger, checker NormMoneyChecker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.RecomputeNormMoney"
+
+		var req RecomputeRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil || len(req.IDs) == 0 {
+			http.Error(w, "ids are required", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		recomputed, err := checker.RecomputeNormMoney(ctx, req.IDs)
+		if err != nil {
+			log.Error("Ошибка пересчёта Н/руб", slog.String("op", op), slog.String("error", err.Error()))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if recomputed == nil {
+			recomputed = []int64{}
+		}
+
+		log.Info("Н/руб пересчитана", slog.String("op", op), slog.Int("requested", len(req.IDs)), slog.Int("recomputed", len(recomputed)))
+		render.JSON(w, r, RecomputeResponse{Recomputed: recomputed})
+	}
+}
@@ -0,0 +1,69 @@
+package normmoney
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"vue-golang/internal/storage"
+)
+
+type MockNormMoneyChecker struct {
+	mock.Mock
+}
+
+func (m *MockNormMoneyChecker) GetNormMoneyMismatches(ctx context.Context, tolerance float64) ([]storage.NormMoneyMismatch, error) {
+	args := m.Called(ctx, tolerance)
+	return args.Get(0).([]storage.NormMoneyMismatch), args.Error(1)
+}
+
+func (m *MockNormMoneyChecker) RecomputeNormMoney(ctx context.Context, ids []int64) ([]int64, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+// Тест: без tolerance берётся допуск по умолчанию, неверный tolerance — 400
+func TestGetNormMoneyMismatches(t *testing.T) {
+	checker := new(MockNormMoneyChecker)
+	checker.On("GetNormMoneyMismatches", mock.Anything, storage.NormMoneyTolerance).
+		Return([]storage.NormMoneyMismatch{{ID: 7, Stored: 120, Computed: 118.5, Diff: 1.5}}, nil)
+
+	rr := httptest.NewRecorder()
+	GetNormMoneyMismatchesAdmin(slog.Default(), checker).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/admin/norm-money/mismatches", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp []storage.NormMoneyMismatch
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Len(t, resp, 1)
+	assert.Equal(t, int64(7), resp[0].ID)
+
+	rr = httptest.NewRecorder()
+	GetNormMoneyMismatchesAdmin(slog.Default(), checker).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/admin/norm-money/mismatches?tolerance=-1", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	checker.AssertNumberOfCalls(t, "GetNormMoneyMismatches", 1)
+}
+
+// Тест: в ответе только пересчитанные изделия, замороженные не попадают
+func TestRecomputeNormMoney(t *testing.T) {
+	checker := new(MockNormMoneyChecker)
+	checker.On("RecomputeNormMoney", mock.Anything, []int64{1, 2}).Return([]int64{2}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/norm-money/recompute", strings.NewReader(`{"ids": [1, 2]}`))
+	rr := httptest.NewRecorder()
+	RecomputeNormMoneyAdmin(slog.Default(), checker).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp RecomputeResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, []int64{2}, resp.Recomputed)
+
+	rr = httptest.NewRecorder()
+	RecomputeNormMoneyAdmin(slog.Default(), checker).ServeHTTP(rr,
+		httptest.NewRequest(http.MethodPost, "/api/admin/norm-money/recompute", strings.NewReader(`{"ids": []}`)))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
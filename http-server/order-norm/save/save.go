@@ -3,23 +3,33 @@ package save
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"github.com/go-chi/render"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
+	"vue-golang/http-server/order-norm/status"
+	"vue-golang/internal/middleware/auth"
+	"vue-golang/internal/service/lifecycle"
 	"vue-golang/internal/storage"
 )
 
 type ResultNorm interface {
-	SaveNormOrder(ctx context.Context, result storage.OrderNormDetails) (int64, error)
-	SaveNormOperation(ctx context.Context, OrderID int64, operations []storage.NormOperation) error
+	SaveNormOrder(ctx context.Context, order storage.OrderNormDetails) (storage.SavedNormOrder, error)
 }
 
+// idempotencyKeyMaxLen — длина ключа в dem_norm_idempotency_al
+const idempotencyKeyMaxLen = 100
+
 type Response struct {
-	OrderID int64  `json:"order_id"`
-	Status  string `json:"status"`
-	Error   string `json:"error"`
+	OrderID    int64   `json:"order_id"`
+	SubIDs     []int64 `json:"sub_ids,omitempty"`
+	Replayed   bool    `json:"replayed,omitempty"`
+	Superseded []int64 `json:"superseded,omitempty"`
+	Status     string  `json:"status"`
+	Error      string  `json:"error"`
 }
 
 func SaveNormOrderOperation(log *slog.Logger, res ResultNorm) http.HandlerFunc {
@@ -35,30 +45,92 @@ func SaveNormOrderOperation(log *slog.Logger, res ResultNorm) http.HandlerFunc {
 			return
 		}
 
-		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
-		defer cancel()
+		if key := strings.TrimSpace(r.Header.Get("Idempotency-Key")); key != "" {
+			req.IdempotencyKey = key
+		}
+		if len(req.IdempotencyKey) > idempotencyKeyMaxLen {
+			http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+			return
+		}
 
-		orderID, err := res.SaveNormOrder(ctx, req)
+		// Новая нормировка начинается с черновика или сразу нормированной
+		req.Status, err = lifecycle.Initial(req.Status)
 		if err != nil {
-			log.Error("Ошибка при сохранения нормированного наряда", slog.String("op", op), slog.String("error", err.Error()))
-			render.JSON(w, r, Response{Error: "не удалось сохранить нормировку"})
+			status.WriteError(w, r, log, op, err)
 			return
 		}
+		for i := range req.SubProducts {
+			if req.SubProducts[i].Status == "" {
+				continue
+			}
+			req.SubProducts[i].Status, err = lifecycle.Initial(req.SubProducts[i].Status)
+			if err != nil {
+				status.WriteError(w, r, log, op, err)
+				return
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
 
-		// Сохраняем операции
-		err = res.SaveNormOperation(ctx, orderID, req.Operations)
+		// Изделие, sub-изделия и операции сохраняются одной транзакцией
+		req.ChangedBy = auth.Actor(r)
+		saved, err := res.SaveNormOrder(ctx, req)
 		if err != nil {
-			log.Error("Ошибка при сохранении операции нормированного наряда", slog.String("op", op), slog.String("error", err.Error()))
+			var duplicate *storage.DuplicateNormError
+			if errors.As(err, &duplicate) {
+				// Позиция уже нормирована — клиент показывает прежние нормировки и может повторить с renorm
+				log.Warn("Повторная нормировка позиции", slog.String("order_num", req.OrderNum), slog.Int("position", req.Position))
+				render.Status(r, http.StatusConflict)
+				render.JSON(w, r, map[string]interface{}{
+					"error":    err.Error(),
+					"existing": duplicate.Existing,
+				})
+				return
+			}
+			// Перенормировка не заменяет назначенные и финальные наряды — клиент показывает, какие мешают
+			var locked *storage.SupersedeLockedError
+			if errors.As(err, &locked) {
+				log.Warn("Перенормировка назначенного наряда", slog.String("order_num", req.OrderNum), slog.Int("position", req.Position))
+				render.Status(r, http.StatusConflict)
+				render.JSON(w, r, map[string]interface{}{
+					"error":  err.Error(),
+					"locked": locked.Locked,
+				})
+				return
+			}
+			// Тот же ключ уже сохранил другую нормировку — прежний результат не отдаём
+			if errors.Is(err, storage.ErrIdempotencyKeyReused) {
+				log.Warn("Повтор ключа идемпотентности с другим запросом", slog.String("idempotency_key", req.IdempotencyKey))
+				render.Status(r, http.StatusConflict)
+				render.JSON(w, r, Response{Error: storage.ErrIdempotencyKeyReused.Error()})
+				return
+			}
+			// Заменяемая нормировка в закрытом расчётном периоде
+			if errors.Is(err, storage.ErrPeriodClosed) {
+				status.WriteError(w, r, log, op, err)
+				return
+			}
+
+			log.Error("Ошибка при сохранения нормированного наряда", slog.String("op", op), slog.String("error", err.Error()))
 			render.JSON(w, r, Response{Error: "не удалось сохранить нормировку"})
 			return
 		}
 
-		//log.Info("message added", slog.Int64("id", orderID))
+		if len(saved.Superseded) > 0 {
+			log.Info("Перенормировка позиции", slog.Int64("id", saved.OrderID), slog.Any("superseded", saved.Superseded))
+		}
+		if saved.Replayed {
+			log.Info("Повтор сохранения нормировки", slog.String("idempotency_key", req.IdempotencyKey), slog.Int64("id", saved.OrderID))
+		}
 
 		render.JSON(w, r, Response{
-			OrderID: orderID,
-			Status:  strconv.Itoa(http.StatusOK),
-			Error:   "",
+			OrderID:    saved.OrderID,
+			SubIDs:     saved.SubIDs,
+			Replayed:   saved.Replayed,
+			Superseded: saved.Superseded,
+			Status:     strconv.Itoa(http.StatusOK),
+			Error:      "",
 		})
 	}
 }
@@ -0,0 +1,120 @@
+package save
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"vue-golang/internal/storage"
+)
+
+type MockResultNorm struct {
+	mock.Mock
+}
+
+func (m *MockResultNorm) SaveNormOrder(ctx context.Context, order storage.OrderNormDetails) (storage.SavedNormOrder, error) {
+	args := m.Called(ctx, order)
+	return args.Get(0).(storage.SavedNormOrder), args.Error(1)
+}
+
+// Тест: корень и sub-изделия уходят в хранилище одним вызовом, ключ берётся из заголовка Idempotency-Key
+func TestSaveNormOrderOperation_Atomic(t *testing.T) {
+	res := new(MockResultNorm)
+	res.On("SaveNormOrder", mock.Anything, mock.MatchedBy(func(o storage.OrderNormDetails) bool {
+		return o.IdempotencyKey == "req-1" && o.Status == "draft" && len(o.SubProducts) == 2 && len(o.Operations) == 1
+	})).Return(storage.SavedNormOrder{OrderID: 10, SubIDs: []int64{11, 12}, Replayed: true}, nil)
+
+	body := `{"name": "Окно", "operations": [{"operation_name": "cut"}], "sub_products": [{"name": "Створка"}, {"name": "Импост"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/orders/order-norm/template", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", "req-1")
+	rr := httptest.NewRecorder()
+	SaveNormOrderOperation(slog.Default(), res).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	res.AssertExpectations(t)
+
+	var resp Response
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, int64(10), resp.OrderID)
+	assert.Equal(t, []int64{11, 12}, resp.SubIDs)
+	assert.True(t, resp.Replayed)
+}
+
+// Тест: недопустимый начальный статус sub-изделия отклоняется до записи
+func TestSaveNormOrderOperation_SubStatus(t *testing.T) {
+	res := new(MockResultNorm)
+
+	body := `{"name": "Окно", "sub_products": [{"name": "Створка", "status": "final"}]}`
+	rr := httptest.NewRecorder()
+	SaveNormOrderOperation(slog.Default(), res).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	res.AssertNotCalled(t, "SaveNormOrder", mock.Anything, mock.Anything)
+}
+
+// Тест: позиция уже нормирована — 409 со списком действующих нормировок
+func TestSaveNormOrderOperation_Duplicate(t *testing.T) {
+	res := new(MockResultNorm)
+	res.On("SaveNormOrder", mock.Anything, mock.Anything).Return(storage.SavedNormOrder{},
+		fmt.Errorf("storage.mysql.SaveNormOrder: %w", &storage.DuplicateNormError{Existing: []storage.ExistingNorm{{ID: 7, OrderNum: "Q6-1", Position: 2}}}))
+
+	body := `{"order_num": "Q6-1", "position": 2, "template_code": "window"}`
+	rr := httptest.NewRecorder()
+	SaveNormOrderOperation(slog.Default(), res).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	var resp struct {
+		Existing []storage.ExistingNorm `json:"existing"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Len(t, resp.Existing, 1)
+	assert.Equal(t, int64(7), resp.Existing[0].ID)
+}
+
+// Тест: ключ идемпотентности повторён с другим телом — 409, прежний результат не возвращается
+func TestSaveNormOrderOperation_IdempotencyKeyReused(t *testing.T) {
+	res := new(MockResultNorm)
+	res.On("SaveNormOrder", mock.Anything, mock.Anything).Return(storage.SavedNormOrder{},
+		fmt.Errorf("storage.mysql.SaveNormOrder: ключ идемпотентности %q: %w", "req-1", storage.ErrIdempotencyKeyReused))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"order_num": "Q6-1", "position": 3, "template_code": "window"}`))
+	req.Header.Set("Idempotency-Key", "req-1")
+	rr := httptest.NewRecorder()
+	SaveNormOrderOperation(slog.Default(), res).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	var resp Response
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Zero(t, resp.OrderID)
+	assert.Equal(t, storage.ErrIdempotencyKeyReused.Error(), resp.Error)
+}
+
+// Тест: перенормировка упёрлась в назначенный или финальный наряд — 409 со списком этих нарядов
+func TestSaveNormOrderOperation_SupersedeLocked(t *testing.T) {
+	res := new(MockResultNorm)
+	res.On("SaveNormOrder", mock.Anything, mock.MatchedBy(func(o storage.OrderNormDetails) bool { return o.Renorm })).
+		Return(storage.SavedNormOrder{}, fmt.Errorf("storage.mysql.SaveNormOrder: %w",
+			&storage.SupersedeLockedError{Locked: []storage.ExistingNorm{{ID: 7, OrderNum: "Q6-1", Position: 2, Status: "final"}}}))
+
+	body := `{"order_num": "Q6-1", "position": 2, "template_code": "window", "renorm": true}`
+	rr := httptest.NewRecorder()
+	SaveNormOrderOperation(slog.Default(), res).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	var resp struct {
+		Locked []storage.ExistingNorm `json:"locked"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	if assert.Len(t, resp.Locked, 1) {
+		assert.Equal(t, "final", resp.Locked[0].Status)
+	}
+}
@@ -0,0 +1,139 @@
+package status
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"vue-golang/internal/middleware/auth"
+	"vue-golang/internal/service/lifecycle"
+	"vue-golang/internal/storage"
+)
+
+type StatusChanger interface {
+	Transition(ctx context.Context, rootProductID int64, to, actor, reason string) (storage.StatusChange, error)
+	Reopen(ctx context.Context, rootProductID int64, actor, reason string) (storage.StatusChange, error)
+	History(ctx context.Context, productID int64) ([]storage.StatusChange, error)
+}
+
+type Request struct {
+	RootProductID int64  `json:"root_product_id"`
+	Status        string `json:"status"`
+	Reason        string `json:"reason"`
+}
+
+// ChangeStatus переводит наряд в новый статус по жизненному циклу; недопустимый переход — 409
+func ChangeStatus(log *slog.Logger, changer StatusChanger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.norm.ChangeStatus"
+
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RootProductID == 0 || req.Status == "" {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		change, err := changer.Transition(ctx, req.RootProductID, req.Status, auth.Actor(r), req.Reason)
+		if err != nil {
+			WriteError(w, r, log, op, err)
+			return
+		}
+
+		log.Info("Статус нормировки изменён", "root_product_id", req.RootProductID, "from", change.From, "to", change.To)
+		render.JSON(w, r, change)
+	}
+}
+
+// ReopenOrder возвращает финальный или отменённый наряд в работу, причина обязательна
+func ReopenOrder(log *slog.Logger, changer StatusChanger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.norm.ReopenOrder"
+
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RootProductID == 0 {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		change, err := changer.Reopen(ctx, req.RootProductID, auth.Actor(r), req.Reason)
+		if err != nil {
+			WriteError(w, r, log, op, err)
+			return
+		}
+
+		log.Info("Наряд переоткрыт", "root_product_id", req.RootProductID, "from", change.From, "to", change.To)
+		render.JSON(w, r, change)
+	}
+}
+
+// GetStatusHistory — кто и когда менял статус наряда
+func GetStatusHistory(log *slog.Logger, changer StatusChanger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.norm.GetStatusHistory"
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid ID", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		history, err := changer.History(ctx, id)
+		if err != nil {
+			WriteError(w, r, log, op, err)
+			return
+		}
+
+		render.JSON(w, r, history)
+	}
+}
+
+// WriteError отвечает на ошибки смены статуса: запрещённый переход, гонка и закрытый расчётный период — 409,
+// неверный статус — 400
+func WriteError(w http.ResponseWriter, r *http.Request, log *slog.Logger, op string, err error) {
+	var transitionErr *lifecycle.TransitionError
+	var periodErr *storage.PeriodClosedError
+
+	switch {
+	case errors.As(err, &transitionErr):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, map[string]interface{}{
+			"error":   err.Error(),
+			"from":    transitionErr.From,
+			"to":      transitionErr.To,
+			"allowed": transitionErr.Allowed,
+		})
+	case errors.As(err, &periodErr):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, map[string]interface{}{
+			"error":      storage.ErrPeriodClosed.Error(),
+			"product_id": periodErr.ProductID,
+			"team":       periodErr.Team,
+			"period":     periodErr.Period,
+		})
+	case errors.Is(err, storage.ErrStatusConflict):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, map[string]string{"error": "статус нормировки изменён другим пользователем, обновите страницу"})
+	case errors.Is(err, lifecycle.ErrUnknownStatus), errors.Is(err, lifecycle.ErrReasonRequired):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, "Нормировка не найдена", http.StatusNotFound)
+	default:
+		log.Error("Ошибка смены статуса нормировки", slog.String("op", op), slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
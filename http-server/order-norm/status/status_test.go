@@ -0,0 +1,99 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"vue-golang/internal/service/lifecycle"
+	"vue-golang/internal/storage"
+)
+
+type MockStatusChanger struct {
+	mock.Mock
+}
+
+func (m *MockStatusChanger) Transition(ctx context.Context, rootProductID int64, to, actor, reason string) (storage.StatusChange, error) {
+	args := m.Called(ctx, rootProductID, to, actor, reason)
+	return args.Get(0).(storage.StatusChange), args.Error(1)
+}
+
+func (m *MockStatusChanger) Reopen(ctx context.Context, rootProductID int64, actor, reason string) (storage.StatusChange, error) {
+	args := m.Called(ctx, rootProductID, actor, reason)
+	return args.Get(0).(storage.StatusChange), args.Error(1)
+}
+
+func (m *MockStatusChanger) History(ctx context.Context, productID int64) ([]storage.StatusChange, error) {
+	args := m.Called(ctx, productID)
+	return args.Get(0).([]storage.StatusChange), args.Error(1)
+}
+
+// Тест: запрещённый переход — 409 с текущим статусом и допустимыми переходами
+func TestChangeStatus_Illegal(t *testing.T) {
+	changer := new(MockStatusChanger)
+	changer.On("Transition", mock.Anything, int64(10), "final", "ivanov", "").Return(storage.StatusChange{},
+		&lifecycle.TransitionError{From: lifecycle.Normed, To: lifecycle.Final, Allowed: []string{lifecycle.Assigned, lifecycle.Cancel}})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/orders/status", strings.NewReader(`{"root_product_id": 10, "status": "final"}`))
+	req.Header.Set("X-User", "ivanov")
+	rr := httptest.NewRecorder()
+	ChangeStatus(slog.Default(), changer).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	var resp struct {
+		From    string   `json:"from"`
+		Allowed []string `json:"allowed"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, "in_production", resp.From)
+	assert.Equal(t, []string{"assigned", "cancel"}, resp.Allowed)
+}
+
+// Тест: переоткрытие без причины — 400, с причиной — новый статус в ответе
+func TestReopenOrder(t *testing.T) {
+	changer := new(MockStatusChanger)
+	changer.On("Reopen", mock.Anything, int64(10), mock.Anything, "").Return(storage.StatusChange{}, lifecycle.ErrReasonRequired)
+	changer.On("Reopen", mock.Anything, int64(10), mock.Anything, "пересчёт").
+		Return(storage.StatusChange{ProductID: 10, From: lifecycle.Final, To: lifecycle.Assigned, Reason: "пересчёт"}, nil)
+
+	handler := ReopenOrder(slog.Default(), changer)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/orders/reopen", strings.NewReader(`{"root_product_id": 10}`)))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/orders/reopen", strings.NewReader(`{"root_product_id": 10, "reason": "пересчёт"}`)))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var change storage.StatusChange
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &change))
+	assert.Equal(t, "assigned", change.To)
+}
+
+// Тест: изделие из закрытого расчётного периода — 409 с бригадой и месяцем
+func TestReopenOrder_PeriodClosed(t *testing.T) {
+	changer := new(MockStatusChanger)
+	changer.On("Reopen", mock.Anything, int64(10), mock.Anything, "пересчёт").Return(storage.StatusChange{},
+		fmt.Errorf("storage.mysql.ChangeStatusTx: %w", &storage.PeriodClosedError{ProductID: 10, Team: "windows", Period: "2026-09"}))
+
+	rr := httptest.NewRecorder()
+	ReopenOrder(slog.Default(), changer).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/orders/reopen",
+		strings.NewReader(`{"root_product_id": 10, "reason": "пересчёт"}`)))
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	var resp struct {
+		Team   string `json:"team"`
+		Period string `json:"period"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, "windows", resp.Team)
+	assert.Equal(t, "2026-09", resp.Period)
+}
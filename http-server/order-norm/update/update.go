@@ -3,22 +3,33 @@ package update
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 	"log/slog"
 	"net/http"
 	"strconv"
 	"time"
+	"vue-golang/http-server/order-norm/etag"
+	"vue-golang/http-server/order-norm/status"
+	"vue-golang/internal/middleware/auth"
+	"vue-golang/internal/service/lifecycle"
 	"vue-golang/internal/storage"
 )
 
 type ResultUpdateNorm interface {
-	UpdateNormOrder(ctx context.Context, ID int64, update storage.UpdateOrderDetails) error
-	UpdateFinalOrder(ctx context.Context, ID int64, update storage.UpdateFinalOrderDetails) error
-	UpdateStatus(ctx context.Context, rootProductID int64, status string) error
+	UpdateNormOrder(ctx context.Context, ID int64, update storage.UpdateOrderDetails) (int, error)
+	UpdateFinalOrder(ctx context.Context, ID int64, update storage.UpdateFinalOrderDetails) (int, error)
+	GetNormOrder(ctx context.Context, id int64) (*storage.GetOrderDetails, error)
 }
 
-func UpdateNormOrderOperation(log *slog.Logger, update ResultUpdateNorm) http.HandlerFunc {
+// StatusLifecycle проверяет переходы статуса нормировки
+type StatusLifecycle interface {
+	Prepare(ctx context.Context, productID int64, to, actor, reason string) (storage.StatusChange, error)
+	Transition(ctx context.Context, rootProductID int64, to, actor, reason string) (storage.StatusChange, error)
+}
+
+func UpdateNormOrderOperation(log *slog.Logger, update ResultUpdateNorm, statuses StatusLifecycle) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.norm.UpdateNormHandler"
 
@@ -36,13 +47,36 @@ func UpdateNormOrderOperation(log *slog.Logger, update ResultUpdateNorm) http.Ha
 			return
 		}
 
+		req.RowVersion, err = etag.Require(r, req.RowVersion)
+		if err != nil {
+			etag.WriteError(w, err)
+			return
+		}
+
 		log.Info("Обновление нормировки", slog.Int64("id", id))
 
 		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		defer cancel()
 
-		err = update.UpdateNormOrder(ctx, id, req)
+		req.ChangedBy = auth.Actor(r)
+		if req.Status != nil {
+			change, err := statuses.Prepare(ctx, id, *req.Status, req.ChangedBy, "")
+			if err != nil {
+				status.WriteError(w, r, log, op, err)
+				return
+			}
+			req.StatusChange = &change
+		}
+
+		version, err := update.UpdateNormOrder(ctx, id, req)
 		if err != nil {
+			if etag.WriteConflict(w, r, log, op, update, err) {
+				return
+			}
+			if errors.Is(err, storage.ErrStatusConflict) || errors.Is(err, storage.ErrPeriodClosed) {
+				status.WriteError(w, r, log, op, err)
+				return
+			}
 			log.Error("Ошибка обновления", slog.String("op", op), slog.String("error", err.Error()))
 			http.Error(w, "Ошибка обновления", http.StatusInternalServerError)
 			return
@@ -50,14 +84,16 @@ func UpdateNormOrderOperation(log *slog.Logger, update ResultUpdateNorm) http.Ha
 
 		log.Info("Нормировка обновлена", slog.Int64("id", id))
 
+		etag.Set(w, version)
 		render.JSON(w, r, map[string]interface{}{
-			"status":  strconv.Itoa(http.StatusOK),
-			"norm_id": id,
+			"status":      strconv.Itoa(http.StatusOK),
+			"norm_id":     id,
+			"row_version": version,
 		})
 	}
 }
 
-func UpdateFinalOrder(log *slog.Logger, update ResultUpdateNorm) http.HandlerFunc {
+func UpdateFinalOrder(log *slog.Logger, update ResultUpdateNorm, statuses StatusLifecycle) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.norm.UpdateFinalOrder"
 
@@ -75,25 +111,49 @@ func UpdateFinalOrder(log *slog.Logger, update ResultUpdateNorm) http.HandlerFun
 			return
 		}
 
+		req.RowVersion, err = etag.Require(r, req.RowVersion)
+		if err != nil {
+			etag.WriteError(w, err)
+			return
+		}
+
 		log.Info("Обновление финальной нормировки", slog.Int64("id", id))
 
 		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		defer cancel()
 
-		err = update.UpdateFinalOrder(ctx, id, req)
+		req.ChangedBy = auth.Actor(r)
+		// Финальное обновление переводит наряд в final; повторная правка финального наряда — без смены статуса
+		change, err := statuses.Prepare(ctx, id, lifecycle.Final, req.ChangedBy, "")
+		if err != nil {
+			status.WriteError(w, r, log, op, err)
+			return
+		}
+		req.StatusChange = &change
+
+		version, err := update.UpdateFinalOrder(ctx, id, req)
 		if err != nil {
+			if etag.WriteConflict(w, r, log, op, update, err) {
+				return
+			}
+			if errors.Is(err, storage.ErrStatusConflict) || errors.Is(err, storage.ErrPeriodClosed) {
+				status.WriteError(w, r, log, op, err)
+				return
+			}
 			log.Error("Ошибка обновления", slog.String("op", op), slog.String("error", err.Error()))
 			http.Error(w, "Ошибка обновления", http.StatusInternalServerError)
 			return
 		}
 
+		etag.Set(w, version)
 		render.JSON(w, r, map[string]interface{}{
-			"status": "success",
+			"status":      "success",
+			"row_version": version,
 		})
 	}
 }
 
-func UpdateCancelStatus(log *slog.Logger, update ResultUpdateNorm) http.HandlerFunc {
+func UpdateCancelStatus(log *slog.Logger, statuses StatusLifecycle) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.norm.UpdateCancelStatus"
 
@@ -116,13 +176,14 @@ func UpdateCancelStatus(log *slog.Logger, update ResultUpdateNorm) http.HandlerF
 			return
 		}
 
-		err := update.UpdateStatus(ctx, req.RootProductID, "cancel")
+		change, err := statuses.Transition(ctx, req.RootProductID, lifecycle.Cancel, auth.Actor(r), "")
 		if err != nil {
-			log.Error("Failed to update status to 'cancelled'", "error", err, "root_product_id", req.RootProductID)
-			http.Error(w, "Failed to cancel order", http.StatusInternalServerError)
+			status.WriteError(w, r, log, op, err)
 			return
 		}
 
 		log.Info("Order successfully cancelled", "root_product_id", req.RootProductID)
+
+		render.JSON(w, r, change)
 	}
 }
@@ -0,0 +1,162 @@
+package payroll
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"vue-golang/internal/middleware/auth"
+	"vue-golang/internal/storage"
+)
+
+type PayrollPeriods interface {
+	GetPayrollPeriods(ctx context.Context) ([]storage.PayrollPeriod, error)
+	GetPayrollPeriod(ctx context.Context, id int64) (storage.PayrollPeriod, error)
+	ClosePayrollPeriod(ctx context.Context, team string, month time.Time, actor string) (storage.PayrollPeriod, error)
+	ReopenPayrollPeriod(ctx context.Context, team string, month time.Time, actor, reason string) (storage.PayrollPeriod, error)
+}
+
+// PeriodRequest — бригада (slug из dem_teams_al) и месяц в формате 2006-01
+type PeriodRequest struct {
+	Team   string `json:"team"`
+	Period string `json:"period"`
+	Reason string `json:"reason"`
+}
+
+// GetPayrollPeriods — список расчётных периодов со статусами
+func GetPayrollPeriods(log *slog.Logger, periods PayrollPeriods) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.payroll.GetPayrollPeriods"
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		list, err := periods.GetPayrollPeriods(ctx)
+		if err != nil {
+			writeError(w, r, log, op, err)
+			return
+		}
+
+		render.JSON(w, r, list)
+	}
+}
+
+// GetPayrollPeriod — период с историей закрытий и итогами по сотрудникам на момент последнего закрытия
+func GetPayrollPeriod(log *slog.Logger, periods PayrollPeriods) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.payroll.GetPayrollPeriod"
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid ID", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		period, err := periods.GetPayrollPeriod(ctx, id)
+		if err != nil {
+			writeError(w, r, log, op, err)
+			return
+		}
+
+		render.JSON(w, r, period)
+	}
+}
+
+// ClosePayrollPeriod закрывает месяц бригады: изделия с ready_date в нём больше нельзя править
+func ClosePayrollPeriod(log *slog.Logger, periods PayrollPeriods) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.payroll.ClosePayrollPeriod"
+
+		req, month, ok := decodeRequest(w, r, log, op)
+		if !ok {
+			return
+		}
+		if month.After(time.Now()) {
+			http.Error(w, "нельзя закрыть будущий период", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		defer cancel()
+
+		period, err := periods.ClosePayrollPeriod(ctx, req.Team, month, auth.Actor(r))
+		if err != nil {
+			writeError(w, r, log, op, err)
+			return
+		}
+
+		log.Info("Расчётный период закрыт", slog.String("team", req.Team), slog.String("period", req.Period))
+
+		render.JSON(w, r, period)
+	}
+}
+
+// ReopenPayrollPeriodAdmin переоткрывает период; только из админки и только с причиной
+func ReopenPayrollPeriodAdmin(log *slog.Logger, periods PayrollPeriods) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.admin.ReopenPayrollPeriod"
+
+		req, month, ok := decodeRequest(w, r, log, op)
+		if !ok {
+			return
+		}
+		req.Reason = strings.TrimSpace(req.Reason)
+		if req.Reason == "" {
+			http.Error(w, "reason is required", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		period, err := periods.ReopenPayrollPeriod(ctx, req.Team, month, auth.Actor(r), req.Reason)
+		if err != nil {
+			writeError(w, r, log, op, err)
+			return
+		}
+
+		log.Warn("Расчётный период переоткрыт", slog.String("team", req.Team), slog.String("period", req.Period),
+			slog.String("reason", req.Reason))
+
+		render.JSON(w, r, period)
+	}
+}
+
+func decodeRequest(w http.ResponseWriter, r *http.Request, log *slog.Logger, op string) (PeriodRequest, time.Time, bool) {
+	var req PeriodRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("Invalid JSON", slog.String("op", op), slog.String("error", err.Error()))
+		http.Error(w, "Invalid data", http.StatusBadRequest)
+		return req, time.Time{}, false
+	}
+
+	month, err := time.Parse("2006-01", req.Period)
+	if req.Team == "" || err != nil {
+		http.Error(w, "team and period (YYYY-MM) are required", http.StatusBadRequest)
+		return req, time.Time{}, false
+	}
+
+	return req, month, true
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, log *slog.Logger, op string, err error) {
+	switch {
+	case errors.Is(err, storage.ErrTeamNotFound), errors.Is(err, storage.ErrPeriodMissing):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, storage.ErrPeriodState):
+		render.Status(r, http.StatusConflict)
+		render.JSON(w, r, map[string]string{"error": err.Error()})
+	default:
+		log.Error("Ошибка расчётного периода", slog.String("op", op), slog.String("error", err.Error()))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
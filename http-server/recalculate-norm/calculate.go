@@ -13,12 +13,17 @@ import (
 
 type NormCalculator interface {
 	//CalculateNorm(ctx context.Context, orderNum string, pos int, typeIzd string, templateCode string, itemCount int) ([]storage.Operation, recalculate.Context, error)
-	CalculateNorm(ctx context.Context, orderNum string, pos int, typeIzd string, templateCode string, itemCount int, permisDopMaterial bool) ([]storage.Operation, recalculate.Context, error)
+	CalculateNorm(ctx context.Context, orderNum string, pos int, typeIzd string, templateCode string, itemCount int, permisDopMaterial bool, attrs recalculate.OrderAttributes) ([]storage.Operation, recalculate.Context, error)
+	CalculateNormExplain(ctx context.Context, orderNum string, pos int, typeIzd string, templateCode string, itemCount int, permisDopMaterial bool, attrs recalculate.OrderAttributes) ([]storage.Operation, recalculate.Context, []recalculate.OperationTrace, error)
 }
 
 type Resp struct {
-	Operation []storage.Operation `json:"operation"`
-	Context   recalculate.Context `json:"context"`
+	Operation []storage.Operation          `json:"operation"`
+	Context   recalculate.Context          `json:"context"`
+	Explain   []recalculate.OperationTrace `json:"explain,omitempty"`
+
+	// Версия шаблона — передаётся обратно при сохранении нормировки
+	TemplateVersion int `json:"template_version"`
 }
 
 func CalculateNormOperations(log *slog.Logger, calc NormCalculator) http.HandlerFunc {
@@ -32,6 +37,14 @@ func CalculateNormOperations(log *slog.Logger, calc NormCalculator) http.Handler
 			TemplateCode      string `json:"template"`
 			ItemCount         int    `json:"count"`
 			PermisDopMaterial bool   `json:"permis_dop_material"`
+			Explain           bool   `json:"explain"`
+
+			// атрибуты позиции для условий правил по системе/профилю; тип изделия — поле type
+			Systema string `json:"systema"`
+			Profile string `json:"profile"`
+
+			// версия шаблона сохранённой нормировки, 0 — действующая
+			TemplateVersion int `json:"template_version"`
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -39,6 +52,11 @@ func CalculateNormOperations(log *slog.Logger, calc NormCalculator) http.Handler
 			return
 		}
 
+		// разбор правил можно запросить и через ?explain=true
+		if r.URL.Query().Get("explain") == "true" {
+			req.Explain = true
+		}
+
 		if req.TypeIzd == "door" {
 			req.PermisDopMaterial = true
 		}
@@ -49,7 +67,23 @@ func CalculateNormOperations(log *slog.Logger, calc NormCalculator) http.Handler
 		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		defer cancel()
 
-		norm, ctxData, err := calc.CalculateNorm(ctx, req.OrderNum, req.Position, req.TypeIzd, req.TemplateCode, req.ItemCount, req.PermisDopMaterial)
+		attrs := recalculate.OrderAttributes{
+			Systema:         req.Systema,
+			Profile:         req.Profile,
+			TemplateVersion: req.TemplateVersion,
+		}
+
+		var (
+			norm    []storage.Operation
+			ctxData recalculate.Context
+			trace   []recalculate.OperationTrace
+			err     error
+		)
+		if req.Explain {
+			norm, ctxData, trace, err = calc.CalculateNormExplain(ctx, req.OrderNum, req.Position, req.TypeIzd, req.TemplateCode, req.ItemCount, req.PermisDopMaterial, attrs)
+		} else {
+			norm, ctxData, err = calc.CalculateNorm(ctx, req.OrderNum, req.Position, req.TypeIzd, req.TemplateCode, req.ItemCount, req.PermisDopMaterial, attrs)
+		}
 		if err != nil {
 			log.Error("Failed to recalculate norm", slog.String("error", err.Error()))
 			http.Error(w, "Internal error", http.StatusInternalServerError)
@@ -59,6 +93,9 @@ func CalculateNormOperations(log *slog.Logger, calc NormCalculator) http.Handler
 		render.JSON(w, r, Resp{
 			Operation: norm,
 			Context:   ctxData,
+			Explain:   trace,
+
+			TemplateVersion: ctxData.TemplateVersion,
 		})
 	}
 }
@@ -0,0 +1,48 @@
+package recalculate_norm
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"time"
+	"vue-golang/internal/service/batch"
+)
+
+type OrderCalculator interface {
+	CalculateOrder(ctx context.Context, req batch.Request) (*batch.OrderDraft, error)
+}
+
+// CalculateOrderNorm считает черновик нормировки по всем позициям заказа одним запросом
+func CalculateOrderNorm(log *slog.Logger, calc OrderCalculator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handler.norm.CalculateOrderNorm"
+
+		var req batch.Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Некорректный JSON", http.StatusBadRequest)
+			return
+		}
+
+		if req.OrderNum == "" {
+			http.Error(w, "не указан order_num", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		defer cancel()
+
+		draft, err := calc.CalculateOrder(ctx, req)
+		if err != nil {
+			log.Error("Failed to calculate order norm", "op", op, "order", req.OrderNum, slog.String("error", err.Error()))
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+
+		log.Info("Calculated order norm", "op", op, "order", req.OrderNum,
+			"positions", len(draft.Positions), "failed", draft.Failed)
+
+		render.JSON(w, r, draft)
+	}
+}
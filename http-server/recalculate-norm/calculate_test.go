@@ -18,8 +18,8 @@ type MockNormCalculation struct {
 	mock.Mock
 }
 
-func (m *MockNormCalculation) CalculateNorm(ctx context.Context, orderNum string, pos int, typeIzd string, templateCode string, itemCount int) ([]storage.Operation, recalculate.Context, error) {
-	args := m.Called(ctx, orderNum, pos, typeIzd, templateCode, itemCount)
+func (m *MockNormCalculation) CalculateNorm(ctx context.Context, orderNum string, pos int, typeIzd string, templateCode string, itemCount int, permisDopMaterial bool, attrs recalculate.OrderAttributes) ([]storage.Operation, recalculate.Context, error) {
+	args := m.Called(ctx, orderNum, pos, typeIzd, templateCode, itemCount, permisDopMaterial, attrs)
 
 	ops := []storage.Operation{}
 	if args.Get(0) != nil {
@@ -34,6 +34,27 @@ func (m *MockNormCalculation) CalculateNorm(ctx context.Context, orderNum string
 	return ops, ctxData, args.Error(2)
 }
 
+func (m *MockNormCalculation) CalculateNormExplain(ctx context.Context, orderNum string, pos int, typeIzd string, templateCode string, itemCount int, permisDopMaterial bool, attrs recalculate.OrderAttributes) ([]storage.Operation, recalculate.Context, []recalculate.OperationTrace, error) {
+	args := m.Called(ctx, orderNum, pos, typeIzd, templateCode, itemCount, permisDopMaterial, attrs)
+
+	ops := []storage.Operation{}
+	if args.Get(0) != nil {
+		ops = args.Get(0).([]storage.Operation)
+	}
+
+	ctxData := recalculate.Context{}
+	if args.Get(1) != nil {
+		ctxData = args.Get(1).(recalculate.Context)
+	}
+
+	var trace []recalculate.OperationTrace
+	if args.Get(2) != nil {
+		trace = args.Get(2).([]recalculate.OperationTrace)
+	}
+
+	return ops, ctxData, trace, args.Error(3)
+}
+
 func TestCalculateNormOperations_Success(t *testing.T) {
 	// 1. Создаём мок калькулятора
 	mockCalc := new(MockNormCalculation)
@@ -56,6 +77,8 @@ func TestCalculateNormOperations_Success(t *testing.T) {
 		"door",        // typeIzd
 		"56",          // templateCode
 		2,             // itemCount
+		true,          // permisDopMaterial (для дверей всегда true)
+		recalculate.OrderAttributes{},
 	).Return(operations, ctxData, nil)
 
 	// 3. Создаём фейковый логгер
@@ -133,7 +156,7 @@ func TestCalculateNormOperations_ServiceError(t *testing.T) {
 
 	// Настраиваем мок на возврат ошибки
 	mockCalc.On("CalculateNorm",
-		mock.Anything, "ORD-123", 1, "door", "TEST", 1,
+		mock.Anything, "ORD-123", 1, "door", "TEST", 1, true, recalculate.OrderAttributes{},
 	).Return([]storage.Operation{}, recalculate.Context{}, assert.AnError)
 
 	logger := slog.Default()
@@ -163,7 +186,7 @@ func TestCalculateNormOperations_ContextCanceled(t *testing.T) {
 	mockCalc := new(MockNormCalculation)
 
 	// Мок "ждёт", пока контекст не будет отменён
-	mockCalc.On("CalculateNorm", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+	mockCalc.On("CalculateNorm", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 		Run(func(args mock.Arguments) {
 			ctx := args.Get(0).(context.Context)
 			<-ctx.Done() // ждём отмены контекста
@@ -190,3 +213,52 @@ func TestCalculateNormOperations_ContextCanceled(t *testing.T) {
 	assert.Equal(t, http.StatusInternalServerError, rr.Code)
 	mockCalc.AssertExpectations(t)
 }
+
+func TestCalculateNormOperations_Explain(t *testing.T) {
+	mockCalc := new(MockNormCalculation)
+
+	operations := []storage.Operation{
+		{Name: "сборка", Minutes: 70.0, Value: 17.0, Count: 1.0},
+	}
+	trace := []recalculate.OperationTrace{
+		{
+			Operation:   "сборка",
+			BaseValue:   15.0,
+			BaseMinutes: 60.0,
+			Rules: []recalculate.RuleTrace{
+				{Index: 0, Mode: "additive", Matched: true, Applied: true, ValueBefore: 15.0, ValueAfter: 17.0, MinutesBefore: 60.0, MinutesAfter: 70.0},
+			},
+			Value:   17.0,
+			Minutes: 70.0,
+			Count:   1.0,
+		},
+	}
+
+	mockCalc.On("CalculateNormExplain", mock.Anything, "ORD-789", 1, "window", "56", 1, false,
+		recalculate.OrderAttributes{Systema: "KBE", Profile: "58"}).
+		Return(operations, recalculate.Context{Type: "window"}, trace, nil)
+
+	handler := CalculateNormOperations(slog.Default(), mockCalc)
+
+	reqBody := `{"order_num": "ORD-789", "position": 1, "type": "window", "template": "56", "count": 1, "systema": "KBE", "profile": "58"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/norm/calculate?explain=true", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var resp Resp
+	err := render.DecodeJSON(strings.NewReader(rr.Body.String()), &resp)
+	assert.NoError(t, err)
+
+	assert.Len(t, resp.Explain, 1)
+	assert.Equal(t, 15.0, resp.Explain[0].BaseValue)
+	assert.Len(t, resp.Explain[0].Rules, 1)
+	assert.True(t, resp.Explain[0].Rules[0].Matched)
+	assert.Equal(t, 17.0, resp.Explain[0].Rules[0].ValueAfter)
+
+	mockCalc.AssertNotCalled(t, "CalculateNorm")
+	mockCalc.AssertExpectations(t)
+}
@@ -0,0 +1,100 @@
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"vue-golang/internal/service/templates"
+)
+
+type TemplateBundler interface {
+	Export(ctx context.Context, codes []string) (*templates.Bundle, error)
+	Import(ctx context.Context, bundle *templates.Bundle, dryRun bool, comment string) (*templates.ImportReport, error)
+}
+
+// ExportTemplatesAdmin выгружает шаблоны одним файлом: ?code=KP45Door&code=vitrage или ?code=a,b; без code — все
+func ExportTemplatesAdmin(log *slog.Logger, bundler TemplateBundler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.template.ExportTemplatesAdmin"
+
+		var codes []string
+		for _, param := range r.URL.Query()["code"] {
+			for _, code := range strings.Split(param, ",") {
+				if code = strings.TrimSpace(code); code != "" {
+					codes = append(codes, code)
+				}
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		bundle, err := bundler.Export(ctx, codes)
+		if err != nil {
+			log.Error("Ошибка выгрузки шаблонов", "op", op, "codes", codes, "error", err)
+			http.Error(w, "Ошибка выгрузки шаблонов: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		filename := "templates.json"
+		if len(codes) == 1 {
+			filename = codes[0] + ".json"
+		}
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+		render.JSON(w, r, bundle)
+	}
+}
+
+// ImportTemplatesAdmin загружает выгрузку шаблонов. ?dry_run=true — только проверка и diff без сохранения
+func ImportTemplatesAdmin(log *slog.Logger, bundler TemplateBundler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.template.ImportTemplatesAdmin"
+
+		dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+		var bundle templates.Bundle
+		if err := json.NewDecoder(r.Body).Decode(&bundle); err != nil {
+			log.Error("Неверный JSON выгрузки шаблонов", "op", op, "error", err)
+			http.Error(w, "Неверный JSON", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		defer cancel()
+
+		comment := r.URL.Query().Get("comment")
+		if comment == "" {
+			comment = "импорт"
+		}
+
+		report, err := bundler.Import(ctx, &bundle, dryRun, comment)
+		if err != nil {
+			if errors.Is(err, templates.ErrInvalidBundle) {
+				if report == nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				render.Status(r, http.StatusUnprocessableEntity)
+				render.JSON(w, r, report)
+				return
+			}
+
+			log.Error("Ошибка импорта шаблонов", "op", op, "error", err)
+			http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+
+		log.Info("Импорт шаблонов", "op", op, "dry_run", dryRun,
+			"created", report.Created, "updated", report.Updated, "unchanged", report.Unchanged)
+
+		render.JSON(w, r, report)
+	}
+}
@@ -0,0 +1,255 @@
+package cases
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"vue-golang/internal/service/recalculate"
+	"vue-golang/internal/storage"
+)
+
+// errCaseInput — пример нельзя посчитать по шаблону (например, неизвестный тип изделия)
+var errCaseInput = errors.New("пример не считается по шаблону")
+
+type TemplateCaseProvider interface {
+	GetTemplateByCodeAdmin(ctx context.Context, id int64) (*storage.Template, error)
+	GetAllContextFeaturesAdmin(ctx context.Context) ([]storage.ContextFeature, error)
+	GetTemplateTestCasesAdmin(ctx context.Context, templateID int64) ([]storage.TemplateTestCase, error)
+	CreateTemplateTestCaseAdmin(ctx context.Context, tc storage.TemplateTestCase) (int64, error)
+	UpdateTemplateTestCaseAdmin(ctx context.Context, templateID, id int64, tc storage.TemplateTestCase) error
+	DeleteTemplateTestCaseAdmin(ctx context.Context, templateID, id int64) error
+}
+
+// ListTemplateCasesAdmin — регрессионные примеры шаблона
+func ListTemplateCasesAdmin(log *slog.Logger, provider TemplateCaseProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.template.ListTemplateCasesAdmin"
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "неверный ID шаблона", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		cases, err := provider.GetTemplateTestCasesAdmin(ctx, id)
+		if err != nil {
+			log.Error("Ошибка получения примеров шаблона", "op", op, "id", id, "error", err)
+			http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, cases)
+	}
+}
+
+// SaveTemplateCaseAdmin добавляет пример. Если expected не передан — замораживается текущий результат шаблона
+func SaveTemplateCaseAdmin(log *slog.Logger, provider TemplateCaseProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.template.SaveTemplateCaseAdmin"
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "неверный ID шаблона", http.StatusBadRequest)
+			return
+		}
+
+		tc, ok := decodeCase(w, r)
+		if !ok {
+			return
+		}
+		tc.TemplateID = id
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		if len(tc.Expected) == 0 {
+			if err := freezeExpected(ctx, provider, &tc); err != nil {
+				writeCaseError(w, log, op, err)
+				return
+			}
+		}
+
+		caseID, err := provider.CreateTemplateTestCaseAdmin(ctx, tc)
+		if err != nil {
+			writeCaseError(w, log, op, err)
+			return
+		}
+
+		tc.ID = caseID
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, tc)
+	}
+}
+
+// UpdateTemplateCaseAdmin заменяет пример целиком. Пустой expected — заморозить текущий результат
+func UpdateTemplateCaseAdmin(log *slog.Logger, provider TemplateCaseProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.template.UpdateTemplateCaseAdmin"
+
+		id, errID := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		caseID, errCase := strconv.ParseInt(chi.URLParam(r, "caseId"), 10, 64)
+		if errID != nil || errCase != nil {
+			http.Error(w, "неверный ID шаблона или примера", http.StatusBadRequest)
+			return
+		}
+
+		tc, ok := decodeCase(w, r)
+		if !ok {
+			return
+		}
+		tc.ID = caseID
+		tc.TemplateID = id
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		if len(tc.Expected) == 0 {
+			if err := freezeExpected(ctx, provider, &tc); err != nil {
+				writeCaseError(w, log, op, err)
+				return
+			}
+		}
+
+		if err := provider.UpdateTemplateTestCaseAdmin(ctx, id, caseID, tc); err != nil {
+			writeCaseError(w, log, op, err)
+			return
+		}
+
+		render.JSON(w, r, tc)
+	}
+}
+
+func DeleteTemplateCaseAdmin(log *slog.Logger, provider TemplateCaseProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.template.DeleteTemplateCaseAdmin"
+
+		id, errID := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		caseID, errCase := strconv.ParseInt(chi.URLParam(r, "caseId"), 10, 64)
+		if errID != nil || errCase != nil {
+			http.Error(w, "неверный ID шаблона или примера", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		if err := provider.DeleteTemplateTestCaseAdmin(ctx, id, caseID); err != nil {
+			writeCaseError(w, log, op, err)
+			return
+		}
+
+		render.JSON(w, r, map[string]string{"status": "ok"})
+	}
+}
+
+// RunTemplateCasesAdmin прогоняет все примеры через сохранённый шаблон и возвращает расхождения
+func RunTemplateCasesAdmin(log *slog.Logger, provider TemplateCaseProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.template.RunTemplateCasesAdmin"
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "неверный ID шаблона", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		template, err := provider.GetTemplateByCodeAdmin(ctx, id)
+		if err != nil {
+			writeCaseError(w, log, op, err)
+			return
+		}
+
+		cases, err := provider.GetTemplateTestCasesAdmin(ctx, id)
+		if err != nil {
+			writeCaseError(w, log, op, err)
+			return
+		}
+
+		features, err := provider.GetAllContextFeaturesAdmin(ctx)
+		if err != nil {
+			writeCaseError(w, log, op, err)
+			return
+		}
+
+		render.JSON(w, r, recalculate.RunTemplateCases(template, cases, recalculate.ActiveFeatures(features)))
+	}
+}
+
+func decodeCase(w http.ResponseWriter, r *http.Request) (storage.TemplateTestCase, bool) {
+	var tc storage.TemplateTestCase
+	if err := json.NewDecoder(r.Body).Decode(&tc); err != nil {
+		http.Error(w, "Неверный JSON", http.StatusBadRequest)
+		return tc, false
+	}
+
+	if tc.ItemCount == 0 {
+		tc.ItemCount = 1
+	}
+
+	switch {
+	case strings.TrimSpace(tc.Name) == "":
+		http.Error(w, "не указано название примера", http.StatusBadRequest)
+		return tc, false
+	case tc.TypeIzd == "":
+		http.Error(w, "не указан тип изделия type_izd", http.StatusBadRequest)
+		return tc, false
+	case tc.ItemCount < 0:
+		http.Error(w, "item_count не может быть отрицательным", http.StatusBadRequest)
+		return tc, false
+	case len(tc.Materials) == 0:
+		http.Error(w, "не указаны материалы примера", http.StatusBadRequest)
+		return tc, false
+	}
+
+	return tc, true
+}
+
+// freezeExpected считает пример по текущему шаблону и сохраняет результат как ожидаемый
+func freezeExpected(ctx context.Context, provider TemplateCaseProvider, tc *storage.TemplateTestCase) error {
+	template, err := provider.GetTemplateByCodeAdmin(ctx, tc.TemplateID)
+	if err != nil {
+		return err
+	}
+
+	features, err := provider.GetAllContextFeaturesAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	operations, err := recalculate.RunCase(template, *tc, recalculate.ActiveFeatures(features))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errCaseInput, err)
+	}
+
+	tc.Expected = recalculate.ExpectedFromOperations(operations)
+	return nil
+}
+
+func writeCaseError(w http.ResponseWriter, log *slog.Logger, op string, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "шаблон или пример не найден", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, errCaseInput) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Error("Ошибка работы с примерами шаблона", "op", op, "error", err)
+	http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+}
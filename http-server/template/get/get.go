@@ -17,6 +17,7 @@ import (
 
 type TemplateJSON interface {
 	GetTemplateByCode(ctx context.Context, code string) (*storage.Template, error)
+	GetTemplateVersion(ctx context.Context, code string, version int) (*storage.Template, error)
 	GetAllTemplates(ctx context.Context) ([]*storage.Template, error)
 
 	GetTemplateByCodeAdmin(ctx context.Context, id int64) (*storage.Template, error)
@@ -32,6 +33,7 @@ type ResponseForm struct {
 	TypeIzd    *string             `json:"type_izd"`
 	Profile    *string             `json:"profile"`
 	Operations []storage.Operation `json:"operations"`
+	Version    int                 `json:"version"`
 }
 
 func GetTemplatesByCode(log *slog.Logger, template TemplateJSON) http.HandlerFunc {
@@ -53,8 +55,27 @@ func GetTemplatesByCode(log *slog.Logger, template TemplateJSON) http.HandlerFun
 		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		defer cancel()
 
+		// Для открытия сохранённой нормировки передают версию шаблона, с которой её считали
+		version := 0
+		if versionStr := r.URL.Query().Get("version"); versionStr != "" {
+			v, err := strconv.Atoi(versionStr)
+			if err != nil || v < 0 {
+				http.Error(w, "Invalid query parameter 'version'", http.StatusBadRequest)
+				return
+			}
+			version = v
+		}
+
 		// Получаем шаблон из хранилища
-		template, err := template.GetTemplateByCode(ctx, code)
+		var (
+			found *storage.Template
+			err   error
+		)
+		if version > 0 {
+			found, err = template.GetTemplateVersion(ctx, code, version)
+		} else {
+			found, err = template.GetTemplateByCode(ctx, code)
+		}
 		if err != nil {
 			if strings.Contains(err.Error(), "не найден") || errors.Is(err, sql.ErrNoRows) {
 				log.With(slog.String("op", op), slog.String("code", code)).Warn("Form not found")
@@ -71,6 +92,8 @@ func GetTemplatesByCode(log *slog.Logger, template TemplateJSON) http.HandlerFun
 			return
 		}
 
+		template := found
+
 		// Формируем ответ
 		response := ResponseForm{
 			ID:         template.ID,
@@ -81,6 +104,7 @@ func GetTemplatesByCode(log *slog.Logger, template TemplateJSON) http.HandlerFun
 			TypeIzd:    template.TypeIzd,
 			Profile:    template.Profile,
 			Operations: template.Operations,
+			Version:    template.Version,
 		}
 
 		//log.With(slog.String("code", code)).Info("Successfully fetched form")
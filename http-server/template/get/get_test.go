@@ -37,8 +37,16 @@ func (m *MockTemplateJSON) GetAllTemplates(ctx context.Context) ([]*storage.Temp
 	return args.Get(0).([]*storage.Template), args.Error(1)
 }
 
-func (m *MockTemplateJSON) GetTemplateByCodeAdmin(ctx context.Context, code string) (*storage.Template, error) {
-	args := m.Called(ctx, code)
+func (m *MockTemplateJSON) GetTemplateVersion(ctx context.Context, code string, version int) (*storage.Template, error) {
+	args := m.Called(ctx, code, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*storage.Template), args.Error(1)
+}
+
+func (m *MockTemplateJSON) GetTemplateByCodeAdmin(ctx context.Context, id int64) (*storage.Template, error) {
+	args := m.Called(ctx, id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -234,3 +242,33 @@ func TestGetAllTemplates_DBError(t *testing.T) {
 func strPtr(s string) *string {
 	return &s
 }
+
+// Тест: открытие сохранённой нормировки по версии шаблона
+func TestGetTemplatesByCode_Version(t *testing.T) {
+	mockStorage := new(MockTemplateJSON)
+
+	mockStorage.On("GetTemplateVersion", mock.Anything, "56", 2).
+		Return(&storage.Template{ID: 56, Code: "56", Name: "Дверь", Version: 2}, nil)
+
+	handler := GetTemplatesByCode(slog.Default(), mockStorage)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/template?code=56&version=2", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var resp ResponseForm
+	err := render.DecodeJSON(strings.NewReader(rr.Body.String()), &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, resp.Version)
+
+	mockStorage.AssertNotCalled(t, "GetTemplateByCode", mock.Anything, mock.Anything)
+	mockStorage.AssertExpectations(t)
+
+	// Некорректная версия
+	req = httptest.NewRequest(http.MethodGet, "/api/template?code=56&version=abc", nil)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
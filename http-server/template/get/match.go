@@ -0,0 +1,132 @@
+package get
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"vue-golang/internal/service/recalculate"
+	"vue-golang/internal/service/templates"
+	"vue-golang/internal/storage"
+)
+
+type TemplateMatchProvider interface {
+	GetAllTemplates(ctx context.Context) ([]*storage.Template, error)
+	GetOrderDetails(ctx context.Context, orderNum string) ([]*storage.ResultOrderDetails, error)
+	GetOrderMaterials(ctx context.Context, orderNum string, pos int) ([]*storage.KlaesMaterials, error)
+	GetAllContextFeaturesAdmin(ctx context.Context) ([]storage.ContextFeature, error)
+}
+
+type ResponseMatch struct {
+	OrderNum     string                 `json:"order_num"`
+	Position     int                    `json:"position"`
+	NamePosition string                 `json:"name_position"`
+	Suggestions  []templates.Suggestion `json:"suggestions"`
+}
+
+// MatchTemplates подсказывает шаблоны для позиции заказа Dem:
+// ?order_num=&pos=&systema=&profile=&type_izd=&limit=
+func MatchTemplates(log *slog.Logger, provider TemplateMatchProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.template.MatchTemplates"
+
+		query := r.URL.Query()
+
+		orderNum := query.Get("order_num")
+		if orderNum == "" {
+			http.Error(w, "Missing required query parameter 'order_num'", http.StatusBadRequest)
+			return
+		}
+
+		pos, err := strconv.Atoi(query.Get("pos"))
+		if err != nil || pos <= 0 {
+			http.Error(w, "Invalid query parameter 'pos'", http.StatusBadRequest)
+			return
+		}
+
+		limit := templates.DefaultMatchLimit
+		if limitStr := query.Get("limit"); limitStr != "" {
+			limit, err = strconv.Atoi(limitStr)
+			if err != nil || limit <= 0 {
+				http.Error(w, "Invalid query parameter 'limit'", http.StatusBadRequest)
+				return
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		details, err := provider.GetOrderDetails(ctx, orderNum)
+		if err != nil {
+			log.With(slog.String("op", op), slog.String("error", err.Error())).Error("Failed to fetch order details")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		input := templates.MatchInput{
+			Attributes: templates.Attributes{
+				Systema: query.Get("systema"),
+				TypeIzd: query.Get("type_izd"),
+				Profile: query.Get("profile"),
+			},
+		}
+
+		found := false
+		for _, d := range details {
+			if d.Position == strconv.Itoa(pos) {
+				input.NamePosition = d.NamePosition
+				found = true
+				break
+			}
+		}
+		if !found {
+			http.Error(w, "Position not found", http.StatusNotFound)
+			return
+		}
+
+		input.Materials, err = provider.GetOrderMaterials(ctx, orderNum, pos)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, "Order not found", http.StatusNotFound)
+				return
+			}
+			log.With(slog.String("op", op), slog.String("error", err.Error())).Error("Failed to fetch order materials")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		features, err := provider.GetAllContextFeaturesAdmin(ctx)
+		if err != nil {
+			log.With(slog.String("op", op), slog.String("error", err.Error())).Error("Failed to fetch context features")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		input.Features = recalculate.ActiveFeatures(features)
+
+		all, err := provider.GetAllTemplates(ctx)
+		if err != nil {
+			log.With(slog.String("op", op), slog.String("error", err.Error())).Error("Failed to fetch templates")
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		suggestions := templates.Match(all, input)
+		if len(suggestions) > limit {
+			suggestions = suggestions[:limit]
+		}
+		if suggestions == nil {
+			suggestions = []templates.Suggestion{}
+		}
+
+		render.JSON(w, r, ResponseMatch{
+			OrderNum:     orderNum,
+			Position:     pos,
+			NamePosition: input.NamePosition,
+			Suggestions:  suggestions,
+		})
+	}
+}
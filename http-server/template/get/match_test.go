@@ -0,0 +1,97 @@
+package get
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"vue-golang/internal/storage"
+)
+
+type MockTemplateMatchProvider struct {
+	mock.Mock
+}
+
+func (m *MockTemplateMatchProvider) GetAllTemplates(ctx context.Context) ([]*storage.Template, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*storage.Template), args.Error(1)
+}
+
+func (m *MockTemplateMatchProvider) GetOrderDetails(ctx context.Context, orderNum string) ([]*storage.ResultOrderDetails, error) {
+	args := m.Called(ctx, orderNum)
+	return args.Get(0).([]*storage.ResultOrderDetails), args.Error(1)
+}
+
+func (m *MockTemplateMatchProvider) GetOrderMaterials(ctx context.Context, orderNum string, pos int) ([]*storage.KlaesMaterials, error) {
+	args := m.Called(ctx, orderNum, pos)
+	return args.Get(0).([]*storage.KlaesMaterials), args.Error(1)
+}
+
+func (m *MockTemplateMatchProvider) GetAllContextFeaturesAdmin(ctx context.Context) ([]storage.ContextFeature, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]storage.ContextFeature), args.Error(1)
+}
+
+// Тест: подсказки ранжируются по названию позиции и материалам, limit обрезает список
+func TestMatchTemplates_Success(t *testing.T) {
+	mockProvider := new(MockTemplateMatchProvider)
+	mockProvider.On("GetOrderDetails", mock.Anything, "Q6-1").Return([]*storage.ResultOrderDetails{
+		{Position: "1", NamePosition: "Окно"},
+		{Position: "2", NamePosition: "Дверь"},
+	}, nil)
+	mockProvider.On("GetOrderMaterials", mock.Anything, "Q6-1", 2).Return([]*storage.KlaesMaterials{
+		{NameMat: "Многозапорный замок Stublina с управлением от ручки", Count: 1},
+	}, nil)
+	mockProvider.On("GetAllContextFeaturesAdmin", mock.Anything).Return([]storage.ContextFeature{}, nil)
+	mockProvider.On("GetAllTemplates", mock.Anything).Return([]*storage.Template{
+		{ID: 23, Code: "23", Name: "окна КП45", Category: "window", Systema: strPtr("х"), Profile: strPtr("сх")},
+		{ID: 56, Code: "56", Name: "Двери 1П КП45", Category: "door", Systema: strPtr("х"), TypeIzd: strPtr("1П"), Profile: strPtr("сх")},
+		{ID: 57, Code: "57", Name: "Двери 2П КП45", Category: "door", Systema: strPtr("х"), TypeIzd: strPtr("2П"), Profile: strPtr("сх")},
+		{ID: 90, Code: "90", Name: "Двери КПТ74", Category: "door", Systema: strPtr("т"), Profile: strPtr("ст")},
+	}, nil)
+
+	handler := MatchTemplates(slog.Default(), mockProvider)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/template/match?order_num=Q6-1&pos=2&systema=х&profile=сх&limit=2", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var resp ResponseMatch
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, "Дверь", resp.NamePosition)
+	if assert.Len(t, resp.Suggestions, 2) {
+		assert.Equal(t, "56", resp.Suggestions[0].Code)
+		assert.Equal(t, "57", resp.Suggestions[1].Code)
+		assert.Contains(t, resp.Suggestions[0].Reasons, "в материалах есть «Многозапорный замок Stublina с управлением от ручки»")
+	}
+}
+
+func TestMatchTemplates_Errors(t *testing.T) {
+	mockProvider := new(MockTemplateMatchProvider)
+	mockProvider.On("GetOrderDetails", mock.Anything, "Q6-1").Return([]*storage.ResultOrderDetails{{Position: "1", NamePosition: "Окно"}}, nil)
+	mockProvider.On("GetOrderDetails", mock.Anything, "Q6-2").Return([]*storage.ResultOrderDetails{{Position: "1", NamePosition: "Окно"}}, nil)
+	mockProvider.On("GetOrderMaterials", mock.Anything, "Q6-2", 1).Return([]*storage.KlaesMaterials(nil), fmt.Errorf("op: %w", sql.ErrNoRows))
+
+	handler := MatchTemplates(slog.Default(), mockProvider)
+
+	for url, code := range map[string]int{
+		"/api/template/match?pos=1":                        http.StatusBadRequest,
+		"/api/template/match?order_num=Q6-1&pos=x":         http.StatusBadRequest,
+		"/api/template/match?order_num=Q6-1&pos=1&limit=0": http.StatusBadRequest,
+		"/api/template/match?order_num=Q6-1&pos=5":         http.StatusNotFound,
+		"/api/template/match?order_num=Q6-2&pos=1":         http.StatusNotFound,
+	} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.Equal(t, code, rr.Code, url)
+	}
+}
@@ -0,0 +1,81 @@
+package renorm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"vue-golang/internal/middleware/auth"
+	"vue-golang/internal/service/renorm"
+)
+
+type Renormer interface {
+	Preview(ctx context.Context, templateCode string) (*renorm.Preview, error)
+	Apply(ctx context.Context, templateCode string, productIDs []int64, actor string) (*renorm.Applied, error)
+}
+
+type ApplyRequest struct {
+	TemplateCode string  `json:"template_code"`
+	ProductIDs   []int64 `json:"product_ids"`
+}
+
+// PreviewRenormAdmin — ?code=KP45Door: разница между сохранёнными нормировками шаблона и пересчётом по действующему
+func PreviewRenormAdmin(log *slog.Logger, renormer Renormer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.template.PreviewRenormAdmin"
+
+		code := strings.TrimSpace(r.URL.Query().Get("code"))
+		if code == "" {
+			http.Error(w, "не указан код шаблона", http.StatusBadRequest)
+			return
+		}
+
+		// пересчёт идёт по каждому изделию, поэтому времени больше, чем на обычный запрос
+		ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+		defer cancel()
+
+		preview, err := renormer.Preview(ctx, code)
+		if err != nil {
+			log.Error("Ошибка предпросмотра перенормировки", "op", op, "code", code, "error", err)
+			http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, preview)
+	}
+}
+
+// ApplyRenormAdmin перенормирует выбранные изделия; назначения остаются у операций, которые сохранились
+func ApplyRenormAdmin(log *slog.Logger, renormer Renormer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.template.ApplyRenormAdmin"
+
+		var req ApplyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.TemplateCode) == "" {
+			http.Error(w, "Неверный JSON", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+		defer cancel()
+
+		applied, err := renormer.Apply(ctx, req.TemplateCode, req.ProductIDs, auth.Actor(r))
+		if err != nil {
+			if errors.Is(err, renorm.ErrNoProducts) {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			log.Error("Ошибка перенормировки", "op", op, "code", req.TemplateCode, "error", err)
+			http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+
+		log.Info("Перенормировка по шаблону", "op", op, "code", req.TemplateCode, "applied", applied.Applied, "failed", applied.Failed)
+
+		render.JSON(w, r, applied)
+	}
+}
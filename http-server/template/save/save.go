@@ -6,11 +6,15 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"time"
+	"vue-golang/http-server/template/validate"
+	"vue-golang/internal/service/recalculate"
 	"vue-golang/internal/storage"
 )
 
 type TemplateCreateProvider interface {
 	CreateTemplateAdmin(ctx context.Context, res storage.TemplateAdmin) error
+	GetAllContextFeaturesAdmin(ctx context.Context) ([]storage.ContextFeature, error)
 }
 
 func SaveTemplateAdmin(log *slog.Logger, temp TemplateCreateProvider) http.HandlerFunc {
@@ -28,6 +32,10 @@ func SaveTemplateAdmin(log *slog.Logger, temp TemplateCreateProvider) http.Handl
 			Operations []storage.Operation `json:"operations"`
 			Rules      []storage.Rule      `json:"rules"`
 			HeadName   string              `json:"head_name"`
+
+			// С какой даты действует новая версия (пусто — сразу) и комментарий к ней
+			EffectiveFrom *time.Time `json:"effective_from"`
+			Comment       string     `json:"comment"`
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -35,6 +43,24 @@ func SaveTemplateAdmin(log *slog.Logger, temp TemplateCreateProvider) http.Handl
 			return
 		}
 
+		features, err := temp.GetAllContextFeaturesAdmin(r.Context())
+		if err != nil {
+			log.Error(fmt.Sprintf("%s: ошибка получения признаков: %v", op, err))
+			http.Error(w, "ошибка проверки шаблона", http.StatusInternalServerError)
+			return
+		}
+
+		err = recalculate.ValidateTemplate(storage.Template{
+			Code:       req.Code,
+			Name:       req.Name,
+			Operations: req.Operations,
+			Rules:      req.Rules,
+		}, recalculate.FeatureKeys(features))
+		if err != nil {
+			validate.WriteError(w, r, err)
+			return
+		}
+
 		opsJSON, err := json.Marshal(req.Operations)
 		if err != nil {
 			log.Error(fmt.Sprintf("%s: ошибка сериализации operations: %v", op, err))
@@ -55,6 +81,7 @@ func SaveTemplateAdmin(log *slog.Logger, temp TemplateCreateProvider) http.Handl
 		}
 
 		//log.Info("FFFFF", rulesJSON)
+		rulesStr := string(rulesJSON)
 
 		err = temp.CreateTemplateAdmin(r.Context(), storage.TemplateAdmin{
 			Code:      req.Code,
@@ -65,8 +92,11 @@ func SaveTemplateAdmin(log *slog.Logger, temp TemplateCreateProvider) http.Handl
 			Systema:   req.Systema,
 			TypeIzd:   req.TypeIzd,
 			Operation: string(opsJSON),
-			Rules:     string(rulesJSON),
+			Rules:     &rulesStr,
 			HeadName:  req.HeadName,
+
+			EffectiveFrom: req.EffectiveFrom,
+			Comment:       req.Comment,
 		})
 		if err != nil {
 			log.Error(fmt.Sprintf("%s: %v", op, err))
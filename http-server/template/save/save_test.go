@@ -14,6 +14,7 @@ import (
 	"github.com/stretchr/testify/mock"
 	"log/slog"
 
+	"vue-golang/internal/service/recalculate"
 	"vue-golang/internal/storage"
 )
 
@@ -27,9 +28,21 @@ func (m *MockTemplateCreateProvider) CreateTemplateAdmin(ctx context.Context, re
 	return args.Error(0)
 }
 
+func (m *MockTemplateCreateProvider) GetAllContextFeaturesAdmin(ctx context.Context) ([]storage.ContextFeature, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]storage.ContextFeature), args.Error(1)
+}
+
+// newMockTemplateCreateProvider — мок с реестром признаков из одного ключа ArkaCount
+func newMockTemplateCreateProvider() *MockTemplateCreateProvider {
+	m := new(MockTemplateCreateProvider)
+	m.On("GetAllContextFeaturesAdmin", mock.Anything).Return([]storage.ContextFeature{{Key: "ArkaCount"}}, nil).Maybe()
+	return m
+}
+
 // Тест: успешное создание шаблона
 func TestSaveTemplateAdmin_Success(t *testing.T) {
-	mockProvider := new(MockTemplateCreateProvider)
+	mockProvider := newMockTemplateCreateProvider()
 
 	// Ожидаем вызов с конкретными данными
 	mockProvider.On("CreateTemplateAdmin", mock.Anything, mock.MatchedBy(func(res storage.TemplateAdmin) bool {
@@ -65,7 +78,7 @@ func TestSaveTemplateAdmin_Success(t *testing.T) {
 				"operation": "адаптер ПДП",
 				"condition": {"HasPetliRDRH": true},
 				"mode": "additivePlusMultiplied",
-				"unit_field": "ItemCountForRDRH",
+				"unitField": "ItemCountForRDRH",
 				"minutes_per_unit": 4.5
 			}
 		]
@@ -92,7 +105,7 @@ func TestSaveTemplateAdmin_Success(t *testing.T) {
 
 // Тест: невалидный JSON (синтаксическая ошибка)
 func TestSaveTemplateAdmin_InvalidJSON(t *testing.T) {
-	mockProvider := new(MockTemplateCreateProvider)
+	mockProvider := newMockTemplateCreateProvider()
 	logger := slog.Default()
 	handler := SaveTemplateAdmin(logger, mockProvider)
 
@@ -113,7 +126,7 @@ func TestSaveTemplateAdmin_InvalidJSON(t *testing.T) {
 
 // Тест: ошибка сериализации операций
 func TestSaveTemplateAdmin_OperationsMarshalError(t *testing.T) {
-	//mockProvider := new(MockTemplateCreateProvider)
+	//mockProvider := newMockTemplateCreateProvider()
 	//logger := slog.Default()
 	//handler := SaveTemplateAdmin(logger, mockProvider)
 
@@ -139,12 +152,15 @@ func TestSaveTemplateAdmin_OperationsMarshalError(t *testing.T) {
 
 // Тест: пустые правила (должны сериализоваться как пустой массив)
 func TestSaveTemplateAdmin_EmptyRules(t *testing.T) {
-	mockProvider := new(MockTemplateCreateProvider)
+	mockProvider := newMockTemplateCreateProvider()
 
 	// Ожидаем, что правила будут сериализованы как "[]"
 	mockProvider.On("CreateTemplateAdmin", mock.Anything, mock.MatchedBy(func(res storage.TemplateAdmin) bool {
+		if res.Rules == nil {
+			return false
+		}
 		var rules []storage.Rule
-		err := json.Unmarshal([]byte(res.Rules), &rules)
+		err := json.Unmarshal([]byte(*res.Rules), &rules)
 		return err == nil && len(rules) == 0
 	})).Return(nil)
 
@@ -164,7 +180,6 @@ func TestSaveTemplateAdmin_EmptyRules(t *testing.T) {
 		"operations": [
 			{"name": "сборка", "minutes": 30.0}
 		]
-		// Поле "rules" отсутствует — должно стать []
 	}`
 
 	req := httptest.NewRequest(http.MethodPost, "/api/templates/admin", strings.NewReader(reqBody))
@@ -179,7 +194,7 @@ func TestSaveTemplateAdmin_EmptyRules(t *testing.T) {
 
 // Тест: ошибка создания в провайдере (БД)
 func TestSaveTemplateAdmin_ProviderError(t *testing.T) {
-	mockProvider := new(MockTemplateCreateProvider)
+	mockProvider := newMockTemplateCreateProvider()
 
 	// Возвращаем ошибку при создании
 	mockProvider.On("CreateTemplateAdmin", mock.Anything, mock.Anything).
@@ -213,13 +228,13 @@ func TestSaveTemplateAdmin_ProviderError(t *testing.T) {
 	mockProvider.AssertExpectations(t)
 }
 
-// Тест: обязательные поля отсутствуют (валидация на уровне бизнес-логики)
+// Тест: обязательные поля отсутствуют — 400 с ошибками по полям, до базы запрос не доходит
 func TestSaveTemplateAdmin_MissingRequiredFields(t *testing.T) {
-	mockProvider := new(MockTemplateCreateProvider)
+	mockProvider := newMockTemplateCreateProvider()
 	logger := slog.Default()
 	handler := SaveTemplateAdmin(logger, mockProvider)
 
-	// Отсутствует обязательное поле "code"
+	// Отсутствует обязательное поле "code" и нет операций
 	reqBody := `{
 		"category": "door",
 		"is_active": true,
@@ -233,23 +248,89 @@ func TestSaveTemplateAdmin_MissingRequiredFields(t *testing.T) {
 	rr := httptest.NewRecorder()
 	handler.ServeHTTP(rr, req)
 
-	// В текущей реализации нет валидации обязательных полей — они будут пустыми
-	// Но провайдер вернёт ошибку при сохранении пустого кода
-	// Поэтому ожидаем 500 от провайдера, а не 400 от хендлера
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
 
-	// Для улучшения: добавить валидацию в хендлер:
-	// if req.Code == "" { http.Error(w, "поле code обязательно", http.StatusBadRequest); return }
+	var resp struct {
+		Errors []recalculate.FieldError `json:"errors"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, []recalculate.FieldError{
+		{Field: "code", Message: "обязательное поле"},
+		{Field: "operations", Message: "нужна хотя бы одна операция"},
+	}, resp.Errors)
 
-	// Пока проверим, что запрос доходит до провайдера с пустым кодом
-	// (реально будет ошибка БД из-за уникального индекса или NOT NULL)
-	mockProvider.On("CreateTemplateAdmin", mock.Anything, mock.MatchedBy(func(res storage.TemplateAdmin) bool {
-		return res.Code == ""
-	})).Return(errors.New("code cannot be empty"))
+	mockProvider.AssertNotCalled(t, "CreateTemplateAdmin", mock.Anything, mock.Anything)
+}
 
-	// Повторный вызов с тем же запросом уже с настроенным моком
-	rr2 := httptest.NewRecorder()
-	handler.ServeHTTP(rr2, req)
+// Тест: правило ссылается на несуществующую операцию, неизвестный режим, unitField и ключ условия
+func TestSaveTemplateAdmin_UnknownRuleReferences(t *testing.T) {
+	mockProvider := newMockTemplateCreateProvider()
+	logger := slog.Default()
+	handler := SaveTemplateAdmin(logger, mockProvider)
 
-	assert.Equal(t, http.StatusInternalServerError, rr2.Code)
-	mockProvider.AssertExpectations(t)
+	reqBody := `{
+		"code": "DOOR-BAD",
+		"name": "Дверь с ошибками",
+		"operations": [{"name": "сборка", "minutes": 30.0}],
+		"rules": [
+			{"operation": "покраска", "mode": "set", "condition": {"HasImpost": true}},
+			{"operation": "сборка", "mode": "replace", "condition": {"ArkaCount": {"min": 1}}},
+			{"operation": "сборка", "mode": "multiplied", "unitField": "StvorkiWith3Petli", "condition": {"StvorkiWith3Petli": 2}},
+			{"operation": "сборка", "mode": "additive", "condition": {"HasImpost": 1}}
+		]
+	}`
+
+	req := httptest.NewRequest(http.MethodPost, "/api/templates/admin", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	var resp struct {
+		Errors []recalculate.FieldError `json:"errors"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+	fields := make([]string, 0, len(resp.Errors))
+	for _, fe := range resp.Errors {
+		fields = append(fields, fe.Field)
+	}
+	assert.Equal(t, []string{
+		"rules[0].operation",
+		"rules[1].mode",
+		"rules[2].condition.StvorkiWith3Petli",
+		"rules[2].unitField",
+		"rules[3].condition.HasImpost",
+	}, fields)
+
+	mockProvider.AssertNotCalled(t, "CreateTemplateAdmin", mock.Anything, mock.Anything)
+}
+
+// Тест: правило с синтаксической ошибкой в выражении не сохраняется
+func TestSaveTemplateAdmin_InvalidRuleExpression(t *testing.T) {
+	mockProvider := newMockTemplateCreateProvider()
+	logger := slog.Default()
+	handler := SaveTemplateAdmin(logger, mockProvider)
+
+	reqBody := `{
+		"code": "WIN-EXPR",
+		"category": "window",
+		"name": "Окно с выражением",
+		"operations": [{"name": "сборка", "minutes": 30.0}],
+		"rules": [
+			{"operation": "сборка", "mode": "additive", "condition": {"$expr": "PetliStand >"}}
+		]
+	}`
+
+	req := httptest.NewRequest(http.MethodPost, "/api/templates/admin", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"field":"rules[0].condition.$expr"`)
+	mockProvider.AssertNotCalled(t, "CreateTemplateAdmin", mock.Anything, mock.Anything)
 }
@@ -2,17 +2,26 @@ package update
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
+	"vue-golang/http-server/template/validate"
+	"vue-golang/internal/service/recalculate"
 	"vue-golang/internal/storage"
 )
 
 type TemplateUpdateProvider interface {
 	UpdateTemplateAdmin(ctx context.Context, id int, update storage.TemplateAdmin) error
+	GetAllContextFeaturesAdmin(ctx context.Context) ([]storage.ContextFeature, error)
+	GetTemplateByCodeAdmin(ctx context.Context, id int64) (*storage.Template, error)
+	GetTemplateTestCasesAdmin(ctx context.Context, templateID int64) ([]storage.TemplateTestCase, error)
 }
 
 func UpdateTemplateAdmin(log *slog.Logger, temp TemplateUpdateProvider) http.HandlerFunc {
@@ -37,6 +46,10 @@ func UpdateTemplateAdmin(log *slog.Logger, temp TemplateUpdateProvider) http.Han
 			Operations []storage.Operation `json:"operations"`
 			Rules      []storage.Rule      `json:"rules"`
 			HeadName   string              `json:"head_name"`
+
+			// С какой даты действует новая версия (пусто — сразу) и комментарий к ней
+			EffectiveFrom *time.Time `json:"effective_from"`
+			Comment       string     `json:"comment"`
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -44,6 +57,60 @@ func UpdateTemplateAdmin(log *slog.Logger, temp TemplateUpdateProvider) http.Han
 			return
 		}
 
+		features, err := temp.GetAllContextFeaturesAdmin(r.Context())
+		if err != nil {
+			log.Error(fmt.Sprintf("%s: ошибка получения признаков: %v", op, err))
+			http.Error(w, "ошибка проверки шаблона", http.StatusInternalServerError)
+			return
+		}
+
+		// Правила не передали — проверяем новые операции вместе с сохранёнными правилами
+		rules := req.Rules
+		if rules == nil {
+			stored, err := temp.GetTemplateByCodeAdmin(r.Context(), int64(id))
+			if errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, "шаблон не найден", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				log.Error(fmt.Sprintf("%s: ошибка получения шаблона: %v", op, err))
+				http.Error(w, "ошибка проверки шаблона", http.StatusInternalServerError)
+				return
+			}
+			rules = stored.Rules
+		}
+
+		updated := storage.Template{
+			Code:       req.Code,
+			Name:       req.Name,
+			Operations: req.Operations,
+			Rules:      rules,
+		}
+
+		if err := recalculate.ValidateTemplate(updated, recalculate.FeatureKeys(features)); err != nil {
+			validate.WriteError(w, r, err)
+			return
+		}
+
+		// Регрессионные примеры шаблона должны проходить и после правки
+		cases, err := temp.GetTemplateTestCasesAdmin(r.Context(), int64(id))
+		if err != nil {
+			log.Error(fmt.Sprintf("%s: ошибка получения примеров шаблона: %v", op, err))
+			http.Error(w, "ошибка проверки шаблона", http.StatusInternalServerError)
+			return
+		}
+		if len(cases) > 0 {
+			report := recalculate.RunTemplateCases(&updated, cases, recalculate.ActiveFeatures(features))
+			if report.Failed > 0 {
+				render.Status(r, http.StatusUnprocessableEntity)
+				render.JSON(w, r, map[string]interface{}{
+					"error": "шаблон не проходит тестовые примеры",
+					"cases": report,
+				})
+				return
+			}
+		}
+
 		// 3. Сериализовать operations в JSON
 		opsJSON, err := json.Marshal(req.Operations)
 		if err != nil {
@@ -52,6 +119,19 @@ func UpdateTemplateAdmin(log *slog.Logger, temp TemplateUpdateProvider) http.Han
 			return
 		}
 
+		// Правила не передали — в базе останутся прежние; пустой список очищает их
+		var rulesJSON *string
+		if req.Rules != nil {
+			data, err := json.Marshal(req.Rules)
+			if err != nil {
+				log.Error(fmt.Sprintf("%s: ошибка сериализации правил: %v", op, err))
+				http.Error(w, "ошибка обработки правил шаблона", http.StatusInternalServerError)
+				return
+			}
+			rulesJSON = new(string)
+			*rulesJSON = string(data)
+		}
+
 		err = temp.UpdateTemplateAdmin(r.Context(), id, storage.TemplateAdmin{
 			Code:      req.Code,
 			Category:  req.Category,
@@ -61,8 +141,16 @@ func UpdateTemplateAdmin(log *slog.Logger, temp TemplateUpdateProvider) http.Han
 			Systema:   req.Systema,
 			TypeIzd:   req.TypeIzd,
 			Operation: string(opsJSON),
+			Rules:     rulesJSON,
 			HeadName:  req.HeadName,
+
+			EffectiveFrom: req.EffectiveFrom,
+			Comment:       req.Comment,
 		})
+		if errors.Is(err, storage.ErrTemplateNotFound) {
+			http.Error(w, "шаблон не найден", http.StatusNotFound)
+			return
+		}
 		if err != nil {
 			log.Error(fmt.Sprintf("%s: %v", op, err))
 			http.Error(w, "ошибка обновления шаблона", http.StatusInternalServerError)
@@ -0,0 +1,190 @@
+package update
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"log/slog"
+
+	"vue-golang/internal/service/recalculate"
+	"vue-golang/internal/storage"
+)
+
+type MockTemplateUpdateProvider struct {
+	mock.Mock
+}
+
+func (m *MockTemplateUpdateProvider) UpdateTemplateAdmin(ctx context.Context, id int, update storage.TemplateAdmin) error {
+	args := m.Called(ctx, id, update)
+	return args.Error(0)
+}
+
+func (m *MockTemplateUpdateProvider) GetAllContextFeaturesAdmin(ctx context.Context) ([]storage.ContextFeature, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]storage.ContextFeature), args.Error(1)
+}
+
+func (m *MockTemplateUpdateProvider) GetTemplateByCodeAdmin(ctx context.Context, id int64) (*storage.Template, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*storage.Template), args.Error(1)
+}
+
+func (m *MockTemplateUpdateProvider) GetTemplateTestCasesAdmin(ctx context.Context, templateID int64) ([]storage.TemplateTestCase, error) {
+	args := m.Called(ctx, templateID)
+	return args.Get(0).([]storage.TemplateTestCase), args.Error(1)
+}
+
+// Пример витража: 3 стойки, операция «стойки» по 3 минуты на стойку
+func vitrageCase() storage.TemplateTestCase {
+	return storage.TemplateTestCase{
+		ID: 7, TemplateID: 5, Name: "три стойки", TypeIzd: "vitrage", ItemCount: 1,
+		Materials: []*storage.KlaesMaterials{{NameMat: "Стойка", Count: 3}},
+		Expected:  []storage.ExpectedOperation{{Name: "стойки", Value: 0.15, Minutes: 9}},
+	}
+}
+
+func newUpdateRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPut, "/template/update/5", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "5")
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+// Тест: правка меняет результат примера — шаблон не сохраняется, в ответе расхождения
+func TestUpdateTemplateAdmin_CasesFail(t *testing.T) {
+	mockProvider := new(MockTemplateUpdateProvider)
+	mockProvider.On("GetAllContextFeaturesAdmin", mock.Anything).Return([]storage.ContextFeature{}, nil)
+	mockProvider.On("GetTemplateTestCasesAdmin", mock.Anything, int64(5)).Return([]storage.TemplateTestCase{vitrageCase()}, nil)
+
+	handler := UpdateTemplateAdmin(slog.Default(), mockProvider)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, newUpdateRequest(`{
+		"code": "vitrage",
+		"name": "Витраж",
+		"operations": [{"name": "стойки"}],
+		"rules": [{"operation": "стойки", "mode": "multiplied", "unitField": "MullionCount", "value_per_unit": 0.05, "minutes_per_unit": 4}]
+	}`))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	var resp struct {
+		Cases recalculate.CasesReport `json:"cases"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, 1, resp.Cases.Failed)
+	assert.Equal(t, []recalculate.CaseDiff{{Operation: "стойки", Field: "minutes", Expected: 9, Actual: 12}}, resp.Cases.Cases[0].Diffs)
+
+	mockProvider.AssertNotCalled(t, "UpdateTemplateAdmin", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// Тест: правила не переданы — примеры прогоняются с сохранёнными правилами и проходят
+func TestUpdateTemplateAdmin_CasesPassWithStoredRules(t *testing.T) {
+	mockProvider := new(MockTemplateUpdateProvider)
+	mockProvider.On("GetAllContextFeaturesAdmin", mock.Anything).Return([]storage.ContextFeature{}, nil)
+	mockProvider.On("GetTemplateTestCasesAdmin", mock.Anything, int64(5)).Return([]storage.TemplateTestCase{vitrageCase()}, nil)
+	mockProvider.On("GetTemplateByCodeAdmin", mock.Anything, int64(5)).Return(&storage.Template{
+		Rules: []storage.Rule{{Operation: "стойки", Mode: "multiplied", UnitField: "MullionCount", ValuePerUnit: 0.05, MinutesPerUnit: 3}},
+	}, nil)
+	mockProvider.On("UpdateTemplateAdmin", mock.Anything, 5, mock.MatchedBy(func(u storage.TemplateAdmin) bool {
+		return u.Code == "vitrage" && u.Rules == nil
+	})).Return(nil)
+
+	handler := UpdateTemplateAdmin(slog.Default(), mockProvider)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, newUpdateRequest(`{
+		"code": "vitrage",
+		"name": "Витраж",
+		"comment": "переименование",
+		"operations": [{"name": "стойки"}]
+	}`))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockProvider.AssertExpectations(t)
+}
+
+// Тест: пустой список правил очищает сохранённые правила, а не оставляет их
+func TestUpdateTemplateAdmin_EmptyRulesClear(t *testing.T) {
+	mockProvider := new(MockTemplateUpdateProvider)
+	mockProvider.On("GetAllContextFeaturesAdmin", mock.Anything).Return([]storage.ContextFeature{}, nil)
+	mockProvider.On("GetTemplateTestCasesAdmin", mock.Anything, int64(5)).Return([]storage.TemplateTestCase{}, nil)
+	mockProvider.On("UpdateTemplateAdmin", mock.Anything, 5, mock.MatchedBy(func(u storage.TemplateAdmin) bool {
+		return u.Rules != nil && *u.Rules == "[]"
+	})).Return(nil)
+
+	handler := UpdateTemplateAdmin(slog.Default(), mockProvider)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, newUpdateRequest(`{
+		"code": "vitrage",
+		"name": "Витраж",
+		"operations": [{"name": "стойки"}],
+		"rules": []
+	}`))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockProvider.AssertNotCalled(t, "GetTemplateByCodeAdmin", mock.Anything, mock.Anything)
+	mockProvider.AssertExpectations(t)
+}
+
+// Тест: правила не переданы, а сохранённый шаблон не читается — 404 только если его нет, иначе 500
+func TestUpdateTemplateAdmin_StoredTemplateErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{"нет шаблона", fmt.Errorf("storage: шаблон не найден: %w", sql.ErrNoRows), http.StatusNotFound},
+		{"ошибка базы", errors.New("connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockProvider := new(MockTemplateUpdateProvider)
+			mockProvider.On("GetAllContextFeaturesAdmin", mock.Anything).Return([]storage.ContextFeature{}, nil)
+			mockProvider.On("GetTemplateByCodeAdmin", mock.Anything, int64(5)).Return((*storage.Template)(nil), tt.err)
+
+			rr := httptest.NewRecorder()
+			UpdateTemplateAdmin(slog.Default(), mockProvider).ServeHTTP(rr, newUpdateRequest(`{
+				"code": "vitrage",
+				"name": "Витраж",
+				"operations": [{"name": "стойки"}]
+			}`))
+
+			assert.Equal(t, tt.code, rr.Code)
+			mockProvider.AssertNotCalled(t, "UpdateTemplateAdmin", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+// Тест: шаблона с таким id нет — 404, а не 500
+func TestUpdateTemplateAdmin_NotFound(t *testing.T) {
+	mockProvider := new(MockTemplateUpdateProvider)
+	mockProvider.On("GetAllContextFeaturesAdmin", mock.Anything).Return([]storage.ContextFeature{}, nil)
+	mockProvider.On("GetTemplateTestCasesAdmin", mock.Anything, int64(5)).Return([]storage.TemplateTestCase{}, nil)
+	mockProvider.On("UpdateTemplateAdmin", mock.Anything, 5, mock.Anything).
+		Return(fmt.Errorf("storage.mysql.TemplateAdmin: id=5: %w", storage.ErrTemplateNotFound))
+
+	rr := httptest.NewRecorder()
+	UpdateTemplateAdmin(slog.Default(), mockProvider).ServeHTTP(rr, newUpdateRequest(`{
+		"code": "vitrage",
+		"name": "Витраж",
+		"operations": [{"name": "стойки"}],
+		"rules": []
+	}`))
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
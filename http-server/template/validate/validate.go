@@ -0,0 +1,24 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-chi/render"
+	"net/http"
+	"vue-golang/internal/service/recalculate"
+)
+
+// WriteError отдаёт ошибки проверки шаблона по полям, чтобы форма могла подсветить их
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	var verr *recalculate.ValidationError
+	if !errors.As(err, &verr) {
+		http.Error(w, fmt.Sprintf("ошибка в шаблоне: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	render.Status(r, http.StatusBadRequest)
+	render.JSON(w, r, map[string]interface{}{
+		"error":  "ошибка в шаблоне",
+		"errors": verr.Errors,
+	})
+}
@@ -0,0 +1,127 @@
+package version
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"vue-golang/internal/service/templates"
+	"vue-golang/internal/storage"
+)
+
+type TemplateVersionProvider interface {
+	GetTemplateVersionsAdmin(ctx context.Context, templateID int64) ([]storage.TemplateVersion, error)
+	GetTemplateVersionAdmin(ctx context.Context, templateID int64, version int) (*storage.Template, error)
+	RollbackTemplateAdmin(ctx context.Context, templateID int64, version int, effectiveFrom *time.Time) (int, error)
+}
+
+// ListTemplateVersionsAdmin — история версий шаблона, новые сверху
+func ListTemplateVersionsAdmin(log *slog.Logger, provider TemplateVersionProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.template.ListTemplateVersionsAdmin"
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "неверный ID шаблона", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		versions, err := provider.GetTemplateVersionsAdmin(ctx, id)
+		if err != nil {
+			log.Error("Ошибка получения версий шаблона", "op", op, "id", id, "error", err)
+			http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+			return
+		}
+
+		render.JSON(w, r, versions)
+	}
+}
+
+// CompareTemplateVersionsAdmin сравнивает две версии шаблона: ?from=1&to=3
+func CompareTemplateVersionsAdmin(log *slog.Logger, provider TemplateVersionProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.template.CompareTemplateVersionsAdmin"
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "неверный ID шаблона", http.StatusBadRequest)
+			return
+		}
+
+		from, errFrom := strconv.Atoi(r.URL.Query().Get("from"))
+		to, errTo := strconv.Atoi(r.URL.Query().Get("to"))
+		if errFrom != nil || errTo != nil {
+			http.Error(w, "параметры from и to обязательны", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		fromTemplate, err := provider.GetTemplateVersionAdmin(ctx, id, from)
+		if err != nil {
+			writeVersionError(w, log, op, err)
+			return
+		}
+
+		toTemplate, err := provider.GetTemplateVersionAdmin(ctx, id, to)
+		if err != nil {
+			writeVersionError(w, log, op, err)
+			return
+		}
+
+		render.JSON(w, r, templates.Compare(fromTemplate, toTemplate))
+	}
+}
+
+// RollbackTemplateAdmin возвращает шаблон к выбранной версии, создавая новую версию
+func RollbackTemplateAdmin(log *slog.Logger, provider TemplateVersionProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.template.RollbackTemplateAdmin"
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "неверный ID шаблона", http.StatusBadRequest)
+			return
+		}
+
+		var req struct {
+			Version       int        `json:"version"`
+			EffectiveFrom *time.Time `json:"effective_from"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Version <= 0 {
+			http.Error(w, "Неверный JSON: нужна версия для отката", http.StatusBadRequest)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		newVersion, err := provider.RollbackTemplateAdmin(ctx, id, req.Version, req.EffectiveFrom)
+		if err != nil {
+			writeVersionError(w, log, op, err)
+			return
+		}
+
+		render.JSON(w, r, map[string]interface{}{"status": "ok", "version": newVersion})
+	}
+}
+
+func writeVersionError(w http.ResponseWriter, log *slog.Logger, op string, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "версия шаблона не найдена", http.StatusNotFound)
+		return
+	}
+
+	log.Error("Ошибка работы с версиями шаблона", "op", op, "error", err)
+	http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+}
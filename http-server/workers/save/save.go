@@ -3,19 +3,34 @@ package save
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/go-chi/render"
 	"log/slog"
 	"net/http"
 	"time"
+	"vue-golang/http-server/order-norm/etag"
+	"vue-golang/http-server/order-norm/status"
+	"vue-golang/internal/middleware/auth"
+	"vue-golang/internal/service/assignments"
 	"vue-golang/internal/storage"
 )
 
 type ResultWorkers interface {
-	SaveOperationWorkers(ctx context.Context, req storage.SaveWorkers) error
+	SaveOperationWorkers(ctx context.Context, req storage.SaveWorkers) (int, error)
+	GetNormOrder(ctx context.Context, id int64) (*storage.GetOrderDetails, error)
 }
 
-func SaveWorkersOperation(log *slog.Logger, result ResultWorkers) http.HandlerFunc {
+type StatusLifecycle interface {
+	Prepare(ctx context.Context, productID int64, to, actor, reason string) (storage.StatusChange, error)
+}
+
+// AssignmentValidator раскладывает деления нормы в назначения и сверяет их с нормировкой и справочником сотрудников
+type AssignmentValidator interface {
+	Prepare(ctx context.Context, req storage.SaveWorkers) (storage.SaveWorkers, error)
+}
+
+func SaveWorkersOperation(log *slog.Logger, result ResultWorkers, statuses StatusLifecycle, validator AssignmentValidator) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.executor.SaveWorkersOperation"
 
@@ -26,7 +41,7 @@ func SaveWorkersOperation(log *slog.Logger, result ResultWorkers) http.HandlerFu
 			return
 		}
 
-		if len(req.Assignments) == 0 {
+		if len(req.Assignments) == 0 && len(req.Splits) == 0 {
 			log.Warn("Пустой лист назначения сотрудников на операции", slog.String("op", op))
 			http.Error(w, "No assignments provided", http.StatusBadRequest)
 			return
@@ -55,11 +70,61 @@ func SaveWorkersOperation(log *slog.Logger, result ResultWorkers) http.HandlerFu
 		//	slog.Any("sample", req.Assignments[0]),
 		//)
 
+		// Назначения перезаписываются целиком, поэтому без версии корня чужие назначения затёрлись бы молча
+		if req.RootProductID == 0 {
+			http.Error(w, "root_product_id is required", http.StatusBadRequest)
+			return
+		}
+		var err error
+		req.RowVersion, err = etag.Require(r, req.RowVersion)
+		if err != nil {
+			etag.WriteError(w, err)
+			return
+		}
+
 		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		defer cancel()
 
-		err := result.SaveOperationWorkers(ctx, req)
+		prepared, err := validator.Prepare(ctx, req)
+		if err != nil {
+			var verr *assignments.ValidationError
+			if errors.As(err, &verr) {
+				log.Warn("Назначения не прошли проверку", slog.String("op", op),
+					slog.Int("errors", len(verr.Errors)), slog.Int("split_errors", len(verr.SplitErrors)))
+				render.Status(r, http.StatusUnprocessableEntity)
+				render.JSON(w, r, map[string]interface{}{
+					"error":        assignments.ErrInvalidAssignments.Error(),
+					"errors":       verr.Errors,
+					"split_errors": verr.SplitErrors,
+					"assignments":  prepared.Assignments,
+				})
+				return
+			}
+			log.Error("Ошибка проверки назначений", slog.String("op", op), slog.String("error", err.Error()))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		req = prepared
+
+		req.ChangedBy = auth.Actor(r)
+		if req.UpdateStatus != "" {
+			change, err := statuses.Prepare(ctx, req.RootProductID, req.UpdateStatus, req.ChangedBy, "")
+			if err != nil {
+				status.WriteError(w, r, log, op, err)
+				return
+			}
+			req.StatusChange = &change
+		}
+
+		version, err := result.SaveOperationWorkers(ctx, req)
 		if err != nil {
+			if etag.WriteConflict(w, r, log, op, result, err) {
+				return
+			}
+			if errors.Is(err, storage.ErrStatusConflict) || errors.Is(err, storage.ErrPeriodClosed) {
+				status.WriteError(w, r, log, op, err)
+				return
+			}
 			log.Error("Ошибка сохранения назначении сотрудников", slog.String("op", op), slog.String("error", err.Error()))
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
@@ -69,10 +134,14 @@ func SaveWorkersOperation(log *slog.Logger, result ResultWorkers) http.HandlerFu
 		//	slog.Int("saved_count", len(req.Assignments)),
 		//)
 
+		if req.RootProductID != 0 {
+			etag.Set(w, version)
+		}
 		render.JSON(w, r, map[string]interface{}{
-			"status":  "success",
-			"saved":   len(req.Assignments),
-			"details": req.Assignments,
+			"status":      "success",
+			"saved":       len(req.Assignments),
+			"details":     req.Assignments,
+			"row_version": version,
 		})
 	}
 }
@@ -0,0 +1,93 @@
+package save
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"vue-golang/internal/storage"
+)
+
+type MockResultWorkers struct {
+	mock.Mock
+}
+
+func (m *MockResultWorkers) SaveOperationWorkers(ctx context.Context, req storage.SaveWorkers) (int, error) {
+	args := m.Called(ctx, req)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockResultWorkers) GetNormOrder(ctx context.Context, id int64) (*storage.GetOrderDetails, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*storage.GetOrderDetails), args.Error(1)
+}
+
+type MockStatusLifecycle struct {
+	mock.Mock
+}
+
+func (m *MockStatusLifecycle) Prepare(ctx context.Context, productID int64, to, actor, reason string) (storage.StatusChange, error) {
+	args := m.Called(ctx, productID, to, actor, reason)
+	return args.Get(0).(storage.StatusChange), args.Error(1)
+}
+
+type MockAssignmentValidator struct {
+	mock.Mock
+}
+
+func (m *MockAssignmentValidator) Prepare(ctx context.Context, req storage.SaveWorkers) (storage.SaveWorkers, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(storage.SaveWorkers), args.Error(1)
+}
+
+const assignment = `"assignments": [{"product_id": 10, "employee_id": 1, "operation_name": "сборка", "actual_minutes": 30}]`
+
+// Тест: без root_product_id — 400, без If-Match и row_version — 428; назначения не проверяются и не пишутся
+func TestSaveWorkersOperation_VersionRequired(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"нет корня", `{` + assignment + `, "row_version": 3}`, http.StatusBadRequest},
+		{"нет версии", `{` + assignment + `, "root_product_id": 10}`, http.StatusPreconditionRequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, statuses, validator := new(MockResultWorkers), new(MockStatusLifecycle), new(MockAssignmentValidator)
+
+			rr := httptest.NewRecorder()
+			SaveWorkersOperation(slog.Default(), result, statuses, validator).
+				ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body)))
+
+			assert.Equal(t, tt.code, rr.Code)
+			validator.AssertNotCalled(t, "Prepare", mock.Anything, mock.Anything)
+			result.AssertNotCalled(t, "SaveOperationWorkers", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+// Тест: версия из If-Match доходит до хранилища
+func TestSaveWorkersOperation_IfMatch(t *testing.T) {
+	result, statuses, validator := new(MockResultWorkers), new(MockStatusLifecycle), new(MockAssignmentValidator)
+	version := 3
+	prepared := storage.SaveWorkers{RootProductID: 10, RowVersion: &version}
+	validator.On("Prepare", mock.Anything, mock.MatchedBy(func(req storage.SaveWorkers) bool {
+		return req.RootProductID == 10 && req.RowVersion != nil && *req.RowVersion == 3
+	})).Return(prepared, nil)
+	result.On("SaveOperationWorkers", mock.Anything, mock.Anything).Return(4, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{`+assignment+`, "root_product_id": 10}`))
+	req.Header.Set("If-Match", `"3"`)
+	rr := httptest.NewRecorder()
+	SaveWorkersOperation(slog.Default(), result, statuses, validator).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `"4"`, rr.Header().Get("ETag"))
+	result.AssertExpectations(t)
+}
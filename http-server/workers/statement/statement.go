@@ -0,0 +1,110 @@
+package statement
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	"vue-golang/internal/service/statement"
+	"vue-golang/internal/storage"
+)
+
+type EmployeeStatement interface {
+	Statement(ctx context.Context, employeeID int64, from, to time.Time) (storage.EmployeeStatement, error)
+	Excel(ctx context.Context, employeeID int64, from, to time.Time) ([]byte, error)
+}
+
+// GetEmployeeStatement — выработка и заработок сотрудника за период: ?from=&to= (2006-01-02, по умолчанию текущий месяц)
+func GetEmployeeStatement(log *slog.Logger, st EmployeeStatement) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.workers.GetEmployeeStatement"
+
+		id, from, to, ok := parseParams(w, r)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		result, err := st.Statement(ctx, id, from, to)
+		if err != nil {
+			writeError(w, log, op, err)
+			return
+		}
+
+		render.JSON(w, r, result)
+	}
+}
+
+// ExportEmployeeStatement — та же ведомость файлом xlsx
+func ExportEmployeeStatement(log *slog.Logger, st EmployeeStatement) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.workers.ExportEmployeeStatement"
+
+		id, from, to, ok := parseParams(w, r)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		excelBytes, err := st.Excel(ctx, id, from, to)
+		if err != nil {
+			writeError(w, log, op, err)
+			return
+		}
+
+		fileName := fmt.Sprintf("statement_%d_%s_%s.xlsx", id, from.Format("2006-01-02"), to.Format("2006-01-02"))
+
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", "attachment; filename="+fileName)
+		w.Write(excelBytes)
+	}
+}
+
+func parseParams(w http.ResponseWriter, r *http.Request) (int64, time.Time, time.Time, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return 0, time.Time{}, time.Time{}, false
+	}
+
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	to := now
+
+	if s := r.URL.Query().Get("from"); s != "" {
+		if from, err = time.Parse("2006-01-02", s); err != nil {
+			http.Error(w, "invalid from date", http.StatusBadRequest)
+			return 0, time.Time{}, time.Time{}, false
+		}
+	}
+	if s := r.URL.Query().Get("to"); s != "" {
+		if to, err = time.Parse("2006-01-02", s); err != nil {
+			http.Error(w, "invalid to date", http.StatusBadRequest)
+			return 0, time.Time{}, time.Time{}, false
+		}
+	}
+	if to.Before(from) {
+		http.Error(w, "from date is after to date", http.StatusBadRequest)
+		return 0, time.Time{}, time.Time{}, false
+	}
+
+	return id, from, to, true
+}
+
+func writeError(w http.ResponseWriter, log *slog.Logger, op string, err error) {
+	if errors.Is(err, statement.ErrEmployeeNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	log.Error("Ошибка формирования ведомости сотрудника", slog.String("op", op), slog.String("error", err.Error()))
+	http.Error(w, "Internal server error", http.StatusInternalServerError)
+}
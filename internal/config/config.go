@@ -19,6 +19,9 @@ type Config struct {
 
 	AdminLogin string `yaml:"admin_login"`
 	AdminPass  string `yaml:"admin_pass"`
+
+	// Допуск превышения нормы по операции при назначении сотрудников (доля: 0.1 — 10%)
+	AssignmentTolerance float64 `yaml:"assignment_tolerance" env-default:"0.1"`
 }
 
 type HTTPServer struct {
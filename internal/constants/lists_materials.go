@@ -1,5 +1,7 @@
 package constants
 
+import "sort"
+
 var (
 	// TODO замки
 	MnogozapZamok = map[string]bool{
@@ -89,4 +91,73 @@ var (
 	PetliRDRH = map[string]bool{
 		"Петля роликовая RDRH": true,
 	}
+
+	// Лоджии
+	LoggiaFrame = map[string]bool{
+		"Рама нижняя": true,
+	}
+
+	LoggiaStv = map[string]bool{
+		"Створка верх/низ": true,
+	}
+
+	// TODO витражи: наименования не сверены с выгрузкой Klaes, реальных витражей в данных пока нет.
+	// Это единственный список для витражей; до сверки правильные наименования можно завести
+	// признаками реестра MullionCount/TransomCount/VitragePanelCount (тип vitrage) — они важнее полей Context.
+	VitrageMullion = map[string]bool{
+		"Стойка":           true,
+		"Стойка усиленная": true,
+		"Стойка угловая":   true,
+	}
+
+	VitrageTransom = map[string]bool{
+		"Ригель":           true,
+		"Ригель усиленный": true,
+	}
+
+	VitrageGlass = map[string]bool{
+		"Стеклопакет": true,
+		"Стекло":      true,
+		"Заполнение":  true,
+	}
 )
+
+// legacyMaterialNames — наименования, которые GetOrderMaterials выбирал всегда. Списки выше шире
+// (например, «Ригель облег. двухпол. КП40», замки MACO/KALE), но встроенные признаки окон и дверей
+// считаются только по этим материалам: расширение списка поменяло бы уже посчитанные нормы.
+var legacyMaterialNames = []string{
+	"импост", "стойка-импост", "профиль импостный", "импост в дверь", "Накладка на цилиндр Stublina",
+	"Створка Т-образная", "Створка-коробка", "Створка Т - образ.", "Петля роликовая RDRH",
+	"Многозапорный замок Stublina с управлением от ручки", "Петля роликовая для КП45",
+	"Петля Фурал дверная 2-част. с подшипником", "Петля дверная трехсекционная с удлиненной базой", "Притвор КП40",
+	"Петля двухсекционная 67мм", "Накладка на цилиндр Stublina (под покраску)", "Замок Elementis 1155 (D30) (для бугельных ручек)",
+	"Замок Elementis 1153 (D30) (под нажимной гарнитур)", "Штульп", "Створка оконная", "Створка оконная усиленная прямоугольная",
+	"Фурнитурная тяга", "Многозапорный замок KFV AS4350 с управлением от ручки", "Многозапорный замок KFV AS2750 с управлением от ключа",
+	"Рама нижняя", "Створка оконная усиленная", "Створка верх/низ",
+}
+
+// BuiltinMaterialNames — наименования для встроенных признаков Context, которые выбирает GetOrderMaterials
+// (вместе с наименованиями из реестра признаков): прежний список и материалы витражей, у которых
+// сохранённых норм ещё нет
+func BuiltinMaterialNames() []string {
+	seen := make(map[string]bool)
+	var names []string
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	for _, name := range legacyMaterialNames {
+		add(name)
+	}
+	for _, list := range []map[string]bool{VitrageMullion, VitrageTransom, VitrageGlass} {
+		for name := range list {
+			add(name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,17 @@
+package constants
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// Тест: для окон и дверей выбирается прежний список материалов, новые наименования — только у витражей
+func TestBuiltinMaterialNames(t *testing.T) {
+	names := BuiltinMaterialNames()
+
+	assert.Subset(t, names, legacyMaterialNames)
+	assert.Contains(t, names, "Стойка")
+	assert.NotContains(t, names, "Ригель облег. двухпол. КП40")
+	assert.NotContains(t, names, "Замок MACO G-TS 57819(232011)")
+	assert.NotContains(t, names, "Замок KALE 153 D30мм E85 N8 (с защёлкой)")
+}
@@ -0,0 +1,15 @@
+package auth
+
+import "net/http"
+
+// Actor — кто выполняет запрос, для истории изменений.
+// Фронтенд передаёт пользователя в X-User; в админке берётся логин BasicAuth; иначе остаётся IP (после middleware.RealIP).
+func Actor(r *http.Request) string {
+	if user := r.Header.Get("X-User"); user != "" {
+		return user
+	}
+	if user, _, ok := r.BasicAuth(); ok && user != "" {
+		return user
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,206 @@
+package assignments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"vue-golang/internal/storage"
+)
+
+// DefaultTolerance — на сколько (доля нормы) фактические минуты по операции могут превышать норму
+const DefaultTolerance = 0.1
+
+var ErrInvalidAssignments = errors.New("назначения сотрудников не прошли проверку")
+
+// AssignmentError — ошибка в конкретном назначении; Index — номер в assignments запроса
+type AssignmentError struct {
+	Index         int    `json:"index"`
+	ProductID     int64  `json:"product_id"`
+	OperationName string `json:"operation_name"`
+	EmployeeID    int64  `json:"employee_id"`
+	Field         string `json:"field"`
+	Message       string `json:"message"`
+}
+
+// ValidationError — все найденные ошибки назначений и делений нормы
+type ValidationError struct {
+	Errors      []AssignmentError `json:"errors"`
+	SplitErrors []SplitError      `json:"split_errors,omitempty"`
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Errors)+len(e.SplitErrors))
+	for _, se := range e.SplitErrors {
+		parts = append(parts, fmt.Sprintf("splits[%d].%s: %s", se.Split, se.Field, se.Message))
+	}
+	for _, ae := range e.Errors {
+		parts = append(parts, fmt.Sprintf("assignments[%d].%s: %s", ae.Index, ae.Field, ae.Message))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidAssignments
+}
+
+type Storage interface {
+	GetAssignmentProducts(ctx context.Context, ids []int64) (map[int64]storage.AssignmentProduct, error)
+	GetAssignmentEmployees(ctx context.Context, ids []int64) (map[int64]storage.AssignmentEmployee, error)
+}
+
+type Validator struct {
+	storage   Storage
+	tolerance float64
+}
+
+// NewValidator — tolerance < 0 заменяется на DefaultTolerance
+func NewValidator(storage Storage, tolerance float64) *Validator {
+	if tolerance < 0 {
+		tolerance = DefaultTolerance
+	}
+	return &Validator{storage: storage, tolerance: tolerance}
+}
+
+// Prepare раскладывает деления нормы (splits) в назначения и проверяет итог. Возвращает запрос с готовыми
+// назначениями; при ошибках — *ValidationError, а номера в Errors относятся к возвращённым назначениям.
+func (v *Validator) Prepare(ctx context.Context, req storage.SaveWorkers) (storage.SaveWorkers, error) {
+	const op = "service.assignments.Prepare"
+
+	productIDs, employeeIDs := ids(req.Assignments, req.Splits)
+
+	products, err := v.storage.GetAssignmentProducts(ctx, productIDs)
+	if err != nil {
+		return req, fmt.Errorf("%s: %w", op, err)
+	}
+	employees, err := v.storage.GetAssignmentEmployees(ctx, employeeIDs)
+	if err != nil {
+		return req, fmt.Errorf("%s: %w", op, err)
+	}
+
+	assignments, splitErrs := ExpandSplits(req, products)
+	if len(splitErrs) > 0 {
+		return req, &ValidationError{SplitErrors: splitErrs}
+	}
+	req.Assignments = assignments
+	req.Splits = nil
+
+	return req, Check(req, products, employees, v.tolerance)
+}
+
+type operationKey struct {
+	productID int64
+	name      string
+}
+
+// Check сверяет назначения с нормировкой и справочником сотрудников: операция есть у изделия, изделие из этой сборки,
+// сотрудник активен и состоит в бригаде, которая делает изделие, а сумма минут по операции не выше нормы с допуском
+func Check(req storage.SaveWorkers, products map[int64]storage.AssignmentProduct, employees map[int64]storage.AssignmentEmployee, tolerance float64) error {
+	var errs []AssignmentError
+	add := func(i int, a storage.OperationWorkers, field, message string) {
+		errs = append(errs, AssignmentError{Index: i, ProductID: a.ProductID, OperationName: a.OperationName,
+			EmployeeID: a.EmployeeID, Field: field, Message: message})
+	}
+
+	allocated := make(map[operationKey]float64)
+	indexes := make(map[operationKey][]int)
+
+	for i, a := range req.Assignments {
+		if a.ActualMinutes < 0 {
+			add(i, a, "actual_minutes", "минуты не могут быть отрицательными")
+		}
+
+		product, ok := products[a.ProductID]
+		if !ok {
+			add(i, a, "product_id", fmt.Sprintf("изделие id=%d не найдено", a.ProductID))
+		} else {
+			if req.RootProductID != 0 && product.RootID != req.RootProductID {
+				add(i, a, "product_id", fmt.Sprintf("изделие id=%d не входит в сборку id=%d", a.ProductID, req.RootProductID))
+			}
+			if _, ok := product.Operations[a.OperationName]; !ok {
+				add(i, a, "operation_name", fmt.Sprintf("операции %q нет в нормировке изделия", a.OperationName))
+			} else {
+				key := operationKey{a.ProductID, a.OperationName}
+				allocated[key] += a.ActualMinutes
+				indexes[key] = append(indexes[key], i)
+			}
+		}
+
+		employee, ok := employees[a.EmployeeID]
+		switch {
+		case !ok:
+			add(i, a, "employee_id", fmt.Sprintf("сотрудник id=%d не найден", a.EmployeeID))
+		case !employee.Active:
+			add(i, a, "employee_id", fmt.Sprintf("сотрудник %s неактивен", employee.Name))
+		case product.Type != "":
+			// тип без бригады (или сотрудник без бригад) назначать не мешает — как и в списке сотрудников для выбора
+			team, known := storage.ProductTeams[product.Type]
+			if known && len(employee.Teams) > 0 && !contains(employee.Teams, team) {
+				add(i, a, "employee_id", fmt.Sprintf("сотрудник %s не состоит в бригаде %s", employee.Name, team))
+			}
+		}
+	}
+
+	for key, minutes := range allocated {
+		norm := products[key.productID].Operations[key.name].Minutes
+		limit := norm * (1 + tolerance)
+		if minutes <= limit+1e-6 {
+			continue
+		}
+		for _, i := range indexes[key] {
+			add(i, req.Assignments[i], "actual_minutes",
+				fmt.Sprintf("по операции назначено %.2f мин при норме %.2f (допуск %.0f%%)", minutes, norm, tolerance*100))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	// по номеру назначения, чтобы ответ не зависел от порядка обхода map
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Index < errs[j].Index })
+	return &ValidationError{Errors: errs}
+}
+
+// ids — изделия и сотрудники из назначений и делений, без повторов
+func ids(assignments []storage.OperationWorkers, splits []storage.OperationSplit) ([]int64, []int64) {
+	seenProducts := make(map[int64]bool)
+	seenEmployees := make(map[int64]bool)
+	var products, employees []int64
+
+	addProduct := func(id int64) {
+		if !seenProducts[id] {
+			seenProducts[id] = true
+			products = append(products, id)
+		}
+	}
+	addEmployee := func(id int64) {
+		if !seenEmployees[id] {
+			seenEmployees[id] = true
+			employees = append(employees, id)
+		}
+	}
+
+	for _, a := range assignments {
+		addProduct(a.ProductID)
+		addEmployee(a.EmployeeID)
+	}
+	for _, s := range splits {
+		addProduct(s.ProductID)
+		for _, share := range s.Shares {
+			addEmployee(share.EmployeeID)
+		}
+	}
+
+	return products, employees
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
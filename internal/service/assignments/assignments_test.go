@@ -0,0 +1,75 @@
+package assignments
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"vue-golang/internal/storage"
+)
+
+func fixtures() (map[int64]storage.AssignmentProduct, map[int64]storage.AssignmentEmployee) {
+	products := map[int64]storage.AssignmentProduct{
+		10: {ID: 10, RootID: 10, Type: "window", Operations: map[string]storage.NormOperation{
+			"cut":  {Name: "cut", Minutes: 30},
+			"pack": {Name: "pack", Minutes: 10},
+		}},
+		11: {ID: 11, RootID: 10, Type: "window", Operations: map[string]storage.NormOperation{"cut": {Name: "cut", Minutes: 20}}},
+		20: {ID: 20, RootID: 20, Type: "vitrage", Operations: map[string]storage.NormOperation{"cut": {Name: "cut", Minutes: 60}}},
+	}
+	employees := map[int64]storage.AssignmentEmployee{
+		1: {ID: 1, Name: "Иванов", Active: true, Teams: []string{"windows"}},
+		2: {ID: 2, Name: "Петров", Active: true, Teams: []string{"windows"}},
+		3: {ID: 3, Name: "Сидоров", Active: false, Teams: []string{"windows"}},
+		4: {ID: 4, Name: "Козлов", Active: true, Teams: []string{"vitrages"}},
+	}
+	return products, employees
+}
+
+// Тест: корректные назначения, включая превышение нормы в пределах допуска
+func TestCheck_Valid(t *testing.T) {
+	products, employees := fixtures()
+
+	req := storage.SaveWorkers{RootProductID: 10, Assignments: []storage.OperationWorkers{
+		{ProductID: 10, OperationName: "cut", EmployeeID: 1, ActualMinutes: 20},
+		{ProductID: 10, OperationName: "cut", EmployeeID: 2, ActualMinutes: 13},
+		{ProductID: 11, OperationName: "cut", EmployeeID: 1, ActualMinutes: 20},
+	}}
+
+	assert.NoError(t, Check(req, products, employees, 0.1))
+}
+
+// Тест: каждая ошибка привязана к своему назначению и полю
+func TestCheck_Errors(t *testing.T) {
+	products, employees := fixtures()
+
+	req := storage.SaveWorkers{RootProductID: 10, Assignments: []storage.OperationWorkers{
+		{ProductID: 10, OperationName: "glue", EmployeeID: 1, ActualMinutes: 5}, // 0: нет операции
+		{ProductID: 10, OperationName: "pack", EmployeeID: 3, ActualMinutes: 5}, // 1: неактивный
+		{ProductID: 10, OperationName: "pack", EmployeeID: 4, ActualMinutes: 5}, // 2: чужая бригада
+		{ProductID: 20, OperationName: "cut", EmployeeID: 4, ActualMinutes: 10}, // 3: не из сборки
+		{ProductID: 11, OperationName: "cut", EmployeeID: 1, ActualMinutes: 15}, // 4: превышение
+		{ProductID: 11, OperationName: "cut", EmployeeID: 2, ActualMinutes: 15}, // 5: превышение
+		{ProductID: 99, OperationName: "cut", EmployeeID: 77, ActualMinutes: 1}, // 6: нет изделия и сотрудника
+	}}
+
+	err := Check(req, products, employees, 0.1)
+	assert.True(t, errors.Is(err, ErrInvalidAssignments))
+
+	var verr *ValidationError
+	assert.True(t, errors.As(err, &verr))
+
+	got := make([][2]interface{}, 0, len(verr.Errors))
+	for _, e := range verr.Errors {
+		got = append(got, [2]interface{}{e.Index, e.Field})
+	}
+	assert.Equal(t, [][2]interface{}{
+		{0, "operation_name"},
+		{1, "employee_id"},
+		{2, "employee_id"},
+		{3, "product_id"},
+		{4, "actual_minutes"},
+		{5, "actual_minutes"},
+		{6, "product_id"},
+		{6, "employee_id"},
+	}, got)
+}
@@ -0,0 +1,185 @@
+package assignments
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"vue-golang/internal/storage"
+)
+
+// Точность долей: минуты — до сотых, норма (value) — до десятитысячных
+const (
+	minutesPrecision = 2
+	valuePrecision   = 4
+)
+
+// percentTolerance — допуск суммы процентов к 100 (ввод с округлением вроде 33.33 × 3)
+const percentTolerance = 0.05
+
+// SplitError — ошибка в описании деления; Split — номер в splits запроса
+type SplitError struct {
+	Split         int    `json:"split"`
+	ProductID     int64  `json:"product_id"`
+	OperationName string `json:"operation_name"`
+	Field         string `json:"field"`
+	Message       string `json:"message"`
+}
+
+// ExpandSplits превращает деления нормы в назначения: доли считаются от нормы операции и округляются так,
+// что их сумма точно равна норме. Ручные назначения поделённых операций убираются.
+func ExpandSplits(req storage.SaveWorkers, products map[int64]storage.AssignmentProduct) ([]storage.OperationWorkers, []SplitError) {
+	if len(req.Splits) == 0 {
+		return req.Assignments, nil
+	}
+
+	var errs []SplitError
+	add := func(i int, s storage.OperationSplit, field, message string) {
+		errs = append(errs, SplitError{Split: i, ProductID: s.ProductID, OperationName: s.OperationName, Field: field, Message: message})
+	}
+
+	split := make(map[operationKey]bool, len(req.Splits))
+	var expanded []storage.OperationWorkers
+
+	for i, s := range req.Splits {
+		key := operationKey{s.ProductID, s.OperationName}
+		if split[key] {
+			add(i, s, "operation_name", "операция поделена дважды")
+			continue
+		}
+		split[key] = true
+
+		operation, ok := products[s.ProductID].Operations[s.OperationName]
+		if !ok {
+			add(i, s, "operation_name", fmt.Sprintf("операции %q нет в нормировке изделия id=%d", s.OperationName, s.ProductID))
+			continue
+		}
+
+		weights, field, err := splitWeights(s)
+		if err != nil {
+			add(i, s, field, err.Error())
+			continue
+		}
+
+		minutes := apportion(operation.Minutes, weights, minutesPrecision)
+		values := apportion(operation.Value, weights, valuePrecision)
+		for j, share := range s.Shares {
+			expanded = append(expanded, storage.OperationWorkers{
+				ProductID:     s.ProductID,
+				OperationName: s.OperationName,
+				EmployeeID:    share.EmployeeID,
+				ActualMinutes: minutes[j],
+				ActualValue:   values[j],
+				Notes:         share.Notes,
+			})
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	assignments := make([]storage.OperationWorkers, 0, len(req.Assignments)+len(expanded))
+	for _, a := range req.Assignments {
+		if !split[operationKey{a.ProductID, a.OperationName}] {
+			assignments = append(assignments, a)
+		}
+	}
+
+	return append(assignments, expanded...), nil
+}
+
+// splitWeights — веса долей по стратегии; при ошибке возвращает поле, к которому она относится
+func splitWeights(s storage.OperationSplit) ([]float64, string, error) {
+	if len(s.Shares) == 0 {
+		return nil, "shares", fmt.Errorf("не указаны исполнители")
+	}
+
+	seen := make(map[int64]bool, len(s.Shares))
+	for j, share := range s.Shares {
+		if share.EmployeeID == 0 {
+			return nil, fmt.Sprintf("shares[%d].employee_id", j), fmt.Errorf("не указан сотрудник")
+		}
+		if seen[share.EmployeeID] {
+			return nil, fmt.Sprintf("shares[%d].employee_id", j), fmt.Errorf("сотрудник указан дважды")
+		}
+		seen[share.EmployeeID] = true
+	}
+
+	weights := make([]float64, len(s.Shares))
+	switch s.Strategy {
+	case storage.SplitEqual, "":
+		for j := range weights {
+			weights[j] = 1
+		}
+
+	case storage.SplitPercent:
+		var total float64
+		for j, share := range s.Shares {
+			if share.Percent < 0 {
+				return nil, fmt.Sprintf("shares[%d].percent", j), fmt.Errorf("процент не может быть отрицательным")
+			}
+			weights[j] = share.Percent
+			total += share.Percent
+		}
+		if math.Abs(total-100) > percentTolerance {
+			return nil, "shares", fmt.Errorf("сумма процентов %.2f, должна быть 100", total)
+		}
+
+	case storage.SplitHours:
+		var total float64
+		for j, share := range s.Shares {
+			if share.Hours < 0 {
+				return nil, fmt.Sprintf("shares[%d].hours", j), fmt.Errorf("часы не могут быть отрицательными")
+			}
+			weights[j] = share.Hours
+			total += share.Hours
+		}
+		if total == 0 {
+			return nil, "shares", fmt.Errorf("не указаны отработанные часы")
+		}
+
+	default:
+		return nil, "strategy", fmt.Errorf("неизвестная стратегия %q: equal, percent или hours", s.Strategy)
+	}
+
+	return weights, "", nil
+}
+
+// apportion делит total пропорционально весам с округлением до precision знаков методом наибольших остатков:
+// каждая доля округляется вниз, а недостающие единицы получают доли с наибольшей отброшенной частью
+func apportion(total float64, weights []float64, precision int) []float64 {
+	scale := math.Pow10(precision)
+	units := int64(math.Round(total * scale))
+
+	var sum float64
+	for _, w := range weights {
+		sum += w
+	}
+
+	shares := make([]int64, len(weights))
+	remainders := make([]float64, len(weights))
+	var assigned int64
+	for i, w := range weights {
+		exact := float64(units) * w / sum
+		shares[i] = int64(math.Floor(exact))
+		remainders[i] = exact - float64(shares[i])
+		assigned += shares[i]
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return remainders[order[a]] > remainders[order[b]] })
+
+	for i := int64(0); i < units-assigned; i++ {
+		shares[order[int(i)%len(order)]]++
+	}
+
+	result := make([]float64, len(weights))
+	for i, s := range shares {
+		result[i] = float64(s) / scale
+	}
+
+	return result
+}
@@ -0,0 +1,72 @@
+package assignments
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"vue-golang/internal/storage"
+)
+
+// Тест: доли округляются так, что сумма точно равна норме
+func TestApportion(t *testing.T) {
+	shares := apportion(100, []float64{1, 1, 1}, 2)
+	assert.Equal(t, []float64{33.34, 33.33, 33.33}, shares)
+
+	shares = apportion(0.5, []float64{6, 2}, 4)
+	assert.Equal(t, []float64{0.375, 0.125}, shares)
+
+	var sum float64
+	for _, s := range apportion(47.77, []float64{3.5, 2, 1.25}, 2) {
+		sum += s
+	}
+	assert.InDelta(t, 47.77, sum, 1e-9)
+}
+
+// Тест: деления раскладываются в назначения по стратегиям, ручные назначения поделённой операции заменяются
+func TestExpandSplits(t *testing.T) {
+	products, _ := fixtures()
+	products[10].Operations["cut"] = storage.NormOperation{Name: "cut", Minutes: 30, Value: 0.5}
+
+	req := storage.SaveWorkers{
+		Assignments: []storage.OperationWorkers{
+			{ProductID: 10, OperationName: "cut", EmployeeID: 1, ActualMinutes: 99},
+			{ProductID: 10, OperationName: "pack", EmployeeID: 1, ActualMinutes: 10},
+		},
+		Splits: []storage.OperationSplit{
+			{ProductID: 10, OperationName: "cut", Strategy: storage.SplitHours, Shares: []storage.SplitShare{
+				{EmployeeID: 1, Hours: 6}, {EmployeeID: 2, Hours: 2},
+			}},
+			{ProductID: 11, OperationName: "cut", Strategy: storage.SplitPercent, Shares: []storage.SplitShare{
+				{EmployeeID: 1, Percent: 70}, {EmployeeID: 2, Percent: 30},
+			}},
+		},
+	}
+
+	assignments, errs := ExpandSplits(req, products)
+	assert.Empty(t, errs)
+	assert.Equal(t, []storage.OperationWorkers{
+		{ProductID: 10, OperationName: "pack", EmployeeID: 1, ActualMinutes: 10},
+		{ProductID: 10, OperationName: "cut", EmployeeID: 1, ActualMinutes: 22.5, ActualValue: 0.375},
+		{ProductID: 10, OperationName: "cut", EmployeeID: 2, ActualMinutes: 7.5, ActualValue: 0.125},
+		{ProductID: 11, OperationName: "cut", EmployeeID: 1, ActualMinutes: 14},
+		{ProductID: 11, OperationName: "cut", EmployeeID: 2, ActualMinutes: 6},
+	}, assignments)
+}
+
+// Тест: ошибки делений относятся к своему делению и полю
+func TestExpandSplits_Errors(t *testing.T) {
+	products, _ := fixtures()
+
+	req := storage.SaveWorkers{Splits: []storage.OperationSplit{
+		{ProductID: 10, OperationName: "cut", Strategy: storage.SplitPercent, Shares: []storage.SplitShare{
+			{EmployeeID: 1, Percent: 60}, {EmployeeID: 2, Percent: 30},
+		}},
+		{ProductID: 10, OperationName: "glue", Shares: []storage.SplitShare{{EmployeeID: 1}}},
+		{ProductID: 10, OperationName: "pack", Strategy: "random", Shares: []storage.SplitShare{{EmployeeID: 1}}},
+	}}
+
+	_, errs := ExpandSplits(req, products)
+	assert.Len(t, errs, 3)
+	assert.Equal(t, "shares", errs[0].Field)
+	assert.Equal(t, "operation_name", errs[1].Field)
+	assert.Equal(t, "strategy", errs[2].Field)
+}
@@ -0,0 +1,139 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+	"vue-golang/internal/storage"
+)
+
+var ErrRevisionNotFound = errors.New("ревизия не найдена")
+
+// Виды событий в хронологии изделия
+const (
+	EventRevision = "revision"
+	EventStatus   = "status"
+)
+
+type HistoryStorage interface {
+	GetProductRevisions(ctx context.Context, productID int64) ([]storage.ProductRevision, error)
+	GetStatusHistory(ctx context.Context, productID int64) ([]storage.StatusChange, error)
+}
+
+// Totals — итоги ревизии по операциям
+type Totals struct {
+	TotalTime  float64 `json:"total_time"`
+	Operations int     `json:"operations"`
+	Value      float64 `json:"value"`
+	Minutes    float64 `json:"minutes"`
+	Executors  int     `json:"executors"`
+}
+
+// Event — запись хронологии: ревизия (с числом изменений относительно предыдущей) или смена статуса
+type Event struct {
+	At        time.Time `json:"at"`
+	Type      string    `json:"type"`
+	ChangedBy string    `json:"changed_by"`
+
+	Revision   int     `json:"revision,omitempty"`
+	Kind       string  `json:"kind,omitempty"`
+	Totals     *Totals `json:"totals,omitempty"`
+	Fields     int     `json:"fields_changed"`
+	Operations int     `json:"operations_changed"`
+	Executors  int     `json:"executors_changed"`
+
+	Status *storage.StatusChange `json:"status,omitempty"`
+}
+
+type Service struct {
+	storage HistoryStorage
+}
+
+func NewService(storage HistoryStorage) *Service {
+	return &Service{storage: storage}
+}
+
+// Timeline — ревизии и смены статуса изделия по времени
+func (s *Service) Timeline(ctx context.Context, productID int64) ([]Event, error) {
+	const op = "service.audit.Timeline"
+
+	revisions, err := s.storage.GetProductRevisions(ctx, productID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	statuses, err := s.storage.GetStatusHistory(ctx, productID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	events := make([]Event, 0, len(revisions)+len(statuses))
+	for i, r := range revisions {
+		e := Event{At: r.CreatedAt, Type: EventRevision, ChangedBy: r.ChangedBy, Revision: r.Revision, Kind: r.Kind, Totals: totals(r.Snapshot)}
+		if i > 0 {
+			diff := Compare(revisions[i-1], r)
+			e.Fields, e.Operations, e.Executors = len(diff.Fields), len(diff.Operations), len(diff.Executors)
+		}
+		events = append(events, e)
+	}
+	for i := range statuses {
+		c := statuses[i]
+		events = append(events, Event{At: c.ChangedAt, Type: EventStatus, ChangedBy: c.ChangedBy, Status: &c})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].At.Before(events[j].At) })
+
+	return events, nil
+}
+
+// Diff сравнивает ревизии from и to. to=0 — последняя ревизия, from=0 — предыдущая перед to.
+func (s *Service) Diff(ctx context.Context, productID int64, from, to int) (Diff, error) {
+	const op = "service.audit.Diff"
+
+	revisions, err := s.storage.GetProductRevisions(ctx, productID)
+	if err != nil {
+		return Diff{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(revisions) == 0 {
+		return Diff{}, fmt.Errorf("%s: у изделия id=%d нет ревизий: %w", op, productID, ErrRevisionNotFound)
+	}
+
+	if to == 0 {
+		to = revisions[len(revisions)-1].Revision
+	}
+	if from == 0 {
+		from = to - 1
+	}
+
+	fromRevision, ok := findRevision(revisions, from)
+	if !ok {
+		return Diff{}, fmt.Errorf("%s: ревизия %d: %w", op, from, ErrRevisionNotFound)
+	}
+	toRevision, ok := findRevision(revisions, to)
+	if !ok {
+		return Diff{}, fmt.Errorf("%s: ревизия %d: %w", op, to, ErrRevisionNotFound)
+	}
+
+	return Compare(fromRevision, toRevision), nil
+}
+
+func findRevision(revisions []storage.ProductRevision, revision int) (storage.ProductRevision, bool) {
+	for _, r := range revisions {
+		if r.Revision == revision {
+			return r, true
+		}
+	}
+	return storage.ProductRevision{}, false
+}
+
+func totals(snapshot storage.ProductSnapshot) *Totals {
+	t := &Totals{TotalTime: snapshot.TotalTime, Operations: len(snapshot.Operations)}
+	for _, o := range snapshot.Operations {
+		t.Value += o.Value
+		t.Minutes += o.Minutes
+		t.Executors += len(o.Executors)
+	}
+	return t
+}
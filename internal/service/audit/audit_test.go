@@ -0,0 +1,170 @@
+package audit
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+	"vue-golang/internal/storage"
+)
+
+type fakeHistoryStorage struct {
+	revisions []storage.ProductRevision
+	statuses  []storage.StatusChange
+}
+
+func (f *fakeHistoryStorage) GetProductRevisions(ctx context.Context, productID int64) ([]storage.ProductRevision, error) {
+	return f.revisions, nil
+}
+
+func (f *fakeHistoryStorage) GetStatusHistory(ctx context.Context, productID int64) ([]storage.StatusChange, error) {
+	return f.statuses, nil
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func newHistory() *fakeHistoryStorage {
+	at := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+
+	baseline := storage.ProductSnapshot{
+		TotalTime: 1.5, Type: "window", Status: "in_production", TemplateVersion: intPtr(1),
+		Operations: []storage.SnapshotOperation{
+			{Name: "napil", Label: "Напил", Count: 1, Value: 0.5, Minutes: 30},
+			{Name: "sborka", Label: "Сборка", Count: 1, Value: 1, Minutes: 60},
+		},
+	}
+	renormed := storage.ProductSnapshot{
+		TotalTime: 1.7, Type: "window", Status: "in_production", TemplateVersion: intPtr(2),
+		Operations: []storage.SnapshotOperation{
+			{Name: "napil", Label: "Напил", Count: 1, Value: 0.5, Minutes: 30},
+			{Name: "sborka", Label: "Сборка", Count: 1, Value: 1.1, Minutes: 66},
+			{Name: "opres", Label: "Опрессовка", Count: 1, Value: 0.1, Minutes: 6},
+		},
+	}
+	assigned := storage.ProductSnapshot{
+		TotalTime: 1.7, Type: "window", Status: "assigned", TemplateVersion: intPtr(2),
+		Operations: []storage.SnapshotOperation{
+			{Name: "napil", Label: "Напил", Count: 1, Value: 0.5, Minutes: 30,
+				Executors: []storage.SnapshotExecutor{{EmployeeID: 1, EmployeeName: "Доронин М.А", ActualMinutes: 30, ActualValue: 0.5}}},
+			{Name: "sborka", Label: "Сборка", Count: 1, Value: 1.1, Minutes: 66,
+				Executors: []storage.SnapshotExecutor{{EmployeeID: 2, EmployeeName: "Попов А.В", ActualMinutes: 66, ActualValue: 1.1}}},
+			{Name: "opres", Label: "Опрессовка", Count: 1, Value: 0.1, Minutes: 6},
+		},
+	}
+	reassigned := assigned
+	reassigned.Operations = []storage.SnapshotOperation{
+		assigned.Operations[0],
+		{Name: "sborka", Label: "Сборка", Count: 1, Value: 1.1, Minutes: 66,
+			Executors: []storage.SnapshotExecutor{
+				{EmployeeID: 2, EmployeeName: "Попов А.В", ActualMinutes: 33, ActualValue: 0.55},
+				{EmployeeID: 3, EmployeeName: "Сувориков А.В", ActualMinutes: 33, ActualValue: 0.55},
+			}},
+		assigned.Operations[2],
+	}
+
+	return &fakeHistoryStorage{
+		revisions: []storage.ProductRevision{
+			{ProductID: 5, Revision: 1, Kind: storage.RevisionBaseline, Snapshot: baseline, CreatedAt: at},
+			{ProductID: 5, Revision: 2, Kind: storage.RevisionNorm, ChangedBy: "ivanov", Snapshot: renormed, CreatedAt: at},
+			{ProductID: 5, Revision: 3, Kind: storage.RevisionExecutors, ChangedBy: "petrov", Snapshot: assigned, CreatedAt: at.Add(2 * time.Hour)},
+			{ProductID: 5, Revision: 4, Kind: storage.RevisionExecutors, ChangedBy: "petrov", Snapshot: reassigned, CreatedAt: at.Add(3 * time.Hour)},
+		},
+		statuses: []storage.StatusChange{
+			{ProductID: 5, From: "in_production", To: "assigned", ChangedBy: "petrov", ChangedAt: at.Add(2 * time.Hour)},
+		},
+	}
+}
+
+// Тест: перенормировка — изменились итоги, версия шаблона и нормы операций
+func TestDiff_Norm(t *testing.T) {
+	service := NewService(newHistory())
+
+	diff, err := service.Diff(context.Background(), 5, 1, 2)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []FieldChange{
+		{Field: "total_time", Before: "1.5", After: "1.7"},
+		{Field: "template_version", Before: "1", After: "2"},
+	}, diff.Fields)
+	assert.Equal(t, []OperationChange{
+		{Name: "sborka", Status: StatusChanged,
+			Before: &OperationValues{Label: "Сборка", Count: 1, Value: 1, Minutes: 60},
+			After:  &OperationValues{Label: "Сборка", Count: 1, Value: 1.1, Minutes: 66}},
+		{Name: "opres", Status: StatusAdded, After: &OperationValues{Label: "Опрессовка", Count: 1, Value: 0.1, Minutes: 6}},
+	}, diff.Operations)
+	assert.Empty(t, diff.Executors)
+}
+
+// Тест: финальная правка — в разнице видны данные изделия и пересчитанный Н/руб
+func TestDiff_Final(t *testing.T) {
+	coefficient := 1.2
+	before := storage.ProductSnapshot{TotalTime: 1.7, Type: "window", Status: "assigned", CustomerType: "physical", Sqr: 2.1, NormMoney: 850}
+	after := before
+	after.Status = "final"
+	after.CustomerType = "dealer"
+	after.Coefficient = &coefficient
+	after.NormMoney = 1020
+
+	service := NewService(&fakeHistoryStorage{revisions: []storage.ProductRevision{
+		{ProductID: 5, Revision: 1, Kind: storage.RevisionBaseline, Snapshot: before},
+		{ProductID: 5, Revision: 2, Kind: storage.RevisionFinal, ChangedBy: "ivanov", Snapshot: after},
+	}})
+
+	diff, err := service.Diff(context.Background(), 5, 1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []FieldChange{
+		{Field: "status", Before: "assigned", After: "final"},
+		{Field: "customer_type", Before: "physical", After: "dealer"},
+		{Field: "coefficient", Before: "", After: "1.2"},
+		{Field: "norm_money", Before: "850", After: "1020"},
+	}, diff.Fields)
+	assert.Empty(t, diff.Operations)
+}
+
+// Тест: по умолчанию сравниваются последняя и предыдущая ревизии; видно, что сборку разделили на двоих
+func TestDiff_ExecutorsDefault(t *testing.T) {
+	service := NewService(newHistory())
+
+	diff, err := service.Diff(context.Background(), 5, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, diff.FromRevision)
+	assert.Equal(t, 4, diff.ToRevision)
+	assert.Empty(t, diff.Fields)
+	assert.Empty(t, diff.Operations)
+
+	if assert.Len(t, diff.Executors, 2) {
+		assert.Equal(t, StatusChanged, diff.Executors[0].Status)
+		assert.Equal(t, 66.0, diff.Executors[0].Before.ActualMinutes)
+		assert.Equal(t, 33.0, diff.Executors[0].After.ActualMinutes)
+		assert.Equal(t, StatusAdded, diff.Executors[1].Status)
+		assert.Equal(t, "Сувориков А.В", diff.Executors[1].EmployeeName)
+	}
+
+	_, err = service.Diff(context.Background(), 5, 1, 9)
+	assert.ErrorIs(t, err, ErrRevisionNotFound)
+}
+
+func TestTimeline(t *testing.T) {
+	service := NewService(newHistory())
+
+	events, err := service.Timeline(context.Background(), 5)
+	assert.NoError(t, err)
+
+	var kinds []string
+	for _, e := range events {
+		if e.Type == EventStatus {
+			kinds = append(kinds, "status:"+e.Status.To)
+		} else {
+			kinds = append(kinds, e.Kind)
+		}
+	}
+	assert.Equal(t, []string{"baseline", "norm", "executors", "status:assigned", "executors"}, kinds)
+
+	assert.Equal(t, 2, events[1].Fields, "total_time и template_version")
+	assert.Equal(t, 2, events[1].Operations)
+	assert.Equal(t, 2, events[2].Executors)
+	assert.Equal(t, 3, events[4].Totals.Executors)
+	assert.InDelta(t, 1.7, events[4].Totals.Value, 1e-9)
+}
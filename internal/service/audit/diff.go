@@ -0,0 +1,193 @@
+package audit
+
+import (
+	"strconv"
+	"vue-golang/internal/storage"
+)
+
+// Статусы изменений между ревизиями
+const (
+	StatusAdded   = "added"
+	StatusRemoved = "removed"
+	StatusChanged = "changed"
+)
+
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// OperationValues — норма операции без назначений
+type OperationValues struct {
+	Label   string  `json:"operation_label"`
+	Count   float64 `json:"count"`
+	Value   float64 `json:"value"`
+	Minutes float64 `json:"minutes"`
+}
+
+// OperationChange — изменение нормы операции, операции сопоставляются по operation_name
+type OperationChange struct {
+	Name   string           `json:"operation_name"`
+	Status string           `json:"status"`
+	Before *OperationValues `json:"before,omitempty"`
+	After  *OperationValues `json:"after,omitempty"`
+}
+
+// ExecutorChange — изменение назначения сотрудника на операцию
+type ExecutorChange struct {
+	Operation    string                    `json:"operation_name"`
+	EmployeeID   int64                     `json:"employee_id"`
+	EmployeeName string                    `json:"employee_name"`
+	Status       string                    `json:"status"`
+	Before       *storage.SnapshotExecutor `json:"before,omitempty"`
+	After        *storage.SnapshotExecutor `json:"after,omitempty"`
+}
+
+type Diff struct {
+	ProductID    int64             `json:"product_id"`
+	FromRevision int               `json:"from_revision"`
+	ToRevision   int               `json:"to_revision"`
+	Fields       []FieldChange     `json:"fields"`
+	Operations   []OperationChange `json:"operations"`
+	Executors    []ExecutorChange  `json:"executors"`
+}
+
+// Empty — ревизии совпадают по содержимому
+func (d Diff) Empty() bool {
+	return len(d.Fields) == 0 && len(d.Operations) == 0 && len(d.Executors) == 0
+}
+
+// Compare сравнивает две ревизии изделия: итоги, нормы операций и назначенных сотрудников
+func Compare(from, to storage.ProductRevision) Diff {
+	diff := Diff{
+		ProductID:    to.ProductID,
+		FromRevision: from.Revision,
+		ToRevision:   to.Revision,
+		Fields:       []FieldChange{},
+	}
+
+	addField := func(field, before, after string) {
+		if before != after {
+			diff.Fields = append(diff.Fields, FieldChange{Field: field, Before: before, After: after})
+		}
+	}
+	addField("total_time", formatFloat(from.Snapshot.TotalTime), formatFloat(to.Snapshot.TotalTime))
+	addField("type", from.Snapshot.Type, to.Snapshot.Type)
+	addField("status", from.Snapshot.Status, to.Snapshot.Status)
+	addField("template_version", formatVersion(from.Snapshot.TemplateVersion), formatVersion(to.Snapshot.TemplateVersion))
+	addField("customer_type", from.Snapshot.CustomerType, to.Snapshot.CustomerType)
+	addField("systema", from.Snapshot.Systema, to.Snapshot.Systema)
+	addField("profile", from.Snapshot.Profile, to.Snapshot.Profile)
+	addField("type_izd", from.Snapshot.TypeIzd, to.Snapshot.TypeIzd)
+	addField("brigade", from.Snapshot.Brigade, to.Snapshot.Brigade)
+	addField("sqr", formatFloat(from.Snapshot.Sqr), formatFloat(to.Snapshot.Sqr))
+	addField("coefficient", formatOptionalFloat(from.Snapshot.Coefficient), formatOptionalFloat(to.Snapshot.Coefficient))
+	addField("norm_money", formatFloat(from.Snapshot.NormMoney), formatFloat(to.Snapshot.NormMoney))
+
+	diff.Operations = compareOperations(from.Snapshot.Operations, to.Snapshot.Operations)
+	diff.Executors = compareExecutors(from.Snapshot.Operations, to.Snapshot.Operations)
+
+	return diff
+}
+
+func compareOperations(from, to []storage.SnapshotOperation) []OperationChange {
+	changes := []OperationChange{}
+
+	toByName := make(map[string]OperationValues, len(to))
+	for _, o := range to {
+		toByName[o.Name] = values(o)
+	}
+	fromByName := make(map[string]bool, len(from))
+
+	for _, o := range from {
+		fromByName[o.Name] = true
+		before := values(o)
+
+		after, ok := toByName[o.Name]
+		if !ok {
+			changes = append(changes, OperationChange{Name: o.Name, Status: StatusRemoved, Before: &before})
+			continue
+		}
+		if before != after {
+			changes = append(changes, OperationChange{Name: o.Name, Status: StatusChanged, Before: &before, After: &after})
+		}
+	}
+
+	for _, o := range to {
+		if !fromByName[o.Name] {
+			after := values(o)
+			changes = append(changes, OperationChange{Name: o.Name, Status: StatusAdded, After: &after})
+		}
+	}
+
+	return changes
+}
+
+// compareExecutors сопоставляет назначения по паре операция + сотрудник, как unique_assignment в базе
+func compareExecutors(from, to []storage.SnapshotOperation) []ExecutorChange {
+	type key struct {
+		operation string
+		employee  int64
+	}
+
+	changes := []ExecutorChange{}
+
+	toByKey := make(map[key]storage.SnapshotExecutor)
+	for _, o := range to {
+		for _, e := range o.Executors {
+			toByKey[key{o.Name, e.EmployeeID}] = e
+		}
+	}
+	fromByKey := make(map[key]bool)
+
+	for _, o := range from {
+		for _, e := range o.Executors {
+			k := key{o.Name, e.EmployeeID}
+			fromByKey[k] = true
+			before := e
+
+			after, ok := toByKey[k]
+			if !ok {
+				changes = append(changes, ExecutorChange{Operation: o.Name, EmployeeID: e.EmployeeID, EmployeeName: e.EmployeeName, Status: StatusRemoved, Before: &before})
+				continue
+			}
+			if before != after {
+				changes = append(changes, ExecutorChange{Operation: o.Name, EmployeeID: e.EmployeeID, EmployeeName: e.EmployeeName, Status: StatusChanged, Before: &before, After: &after})
+			}
+		}
+	}
+
+	for _, o := range to {
+		for _, e := range o.Executors {
+			if !fromByKey[key{o.Name, e.EmployeeID}] {
+				after := e
+				changes = append(changes, ExecutorChange{Operation: o.Name, EmployeeID: e.EmployeeID, EmployeeName: e.EmployeeName, Status: StatusAdded, After: &after})
+			}
+		}
+	}
+
+	return changes
+}
+
+func values(o storage.SnapshotOperation) OperationValues {
+	return OperationValues{Label: o.Label, Count: o.Count, Value: o.Value, Minutes: o.Minutes}
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func formatOptionalFloat(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return formatFloat(*f)
+}
+
+func formatVersion(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
@@ -0,0 +1,291 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"golang.org/x/sync/errgroup"
+	"sort"
+	"strconv"
+	"vue-golang/internal/service/recalculate"
+	"vue-golang/internal/service/templates"
+	"vue-golang/internal/storage"
+)
+
+const (
+	// DefaultParallel — сколько позиций считается одновременно; каждая позиция сама делает 4 запроса к базе
+	DefaultParallel = 4
+	MaxParallel     = 8
+)
+
+type OrderStorage interface {
+	GetOrderDetails(ctx context.Context, orderNum string) ([]*storage.ResultOrderDetails, error)
+	GetOrderPositionAttributes(ctx context.Context, orderNum string) (map[int]storage.PositionAttributes, error)
+	GetAllTemplates(ctx context.Context) ([]*storage.Template, error)
+	GetOrderMaterials(ctx context.Context, orderNum string, pos int) ([]*storage.KlaesMaterials, error)
+	GetAllContextFeaturesAdmin(ctx context.Context) ([]storage.ContextFeature, error)
+}
+
+type NormCalculator interface {
+	CalculateNorm(ctx context.Context, orderNum string, pos int, typeIzd string, templateCode string, itemCount int, permisDopMaterial bool, attrs recalculate.OrderAttributes) ([]storage.Operation, recalculate.Context, error)
+}
+
+// PositionInput — атрибуты позиции и, при необходимости, явно выбранный шаблон и количество
+type PositionInput struct {
+	Position     int    `json:"position"`
+	Systema      string `json:"systema"`
+	Profile      string `json:"profile"`
+	TypeIzd      string `json:"type_izd"`
+	TemplateCode string `json:"template"`
+	Count        int    `json:"count"`
+}
+
+type Request struct {
+	OrderNum string `json:"order_num"`
+
+	// Атрибуты по умолчанию для позиций, которые ещё не нормировали: в заказе Dem их нет
+	Systema string `json:"systema"`
+	Profile string `json:"profile"`
+	TypeIzd string `json:"type_izd"`
+
+	// Переопределения по позициям
+	Positions []PositionInput `json:"positions"`
+
+	Parallel int `json:"parallel"`
+}
+
+// PositionDraft — черновик нормировки одной позиции
+type PositionDraft struct {
+	Position        int                 `json:"position"`
+	NamePosition    string              `json:"name_position"`
+	Count           int                 `json:"count"`
+	Sqr             float64             `json:"sqr"`
+	Systema         string              `json:"systema"`
+	Profile         string              `json:"profile"`
+	TypeIzd         string              `json:"type_izd"`
+	TemplateCode    string              `json:"template"`
+	TemplateName    string              `json:"template_name"`
+	Type            string              `json:"type"`
+	Alternatives    []string            `json:"alternatives,omitempty"`
+	Operations      []storage.Operation `json:"operations"`
+	TemplateVersion int                 `json:"template_version"`
+	TotalValue      float64             `json:"total_value"`
+	TotalMinutes    float64             `json:"total_minutes"`
+	Error           string              `json:"error,omitempty"`
+}
+
+type OrderDraft struct {
+	OrderNum     string          `json:"order_num"`
+	Positions    []PositionDraft `json:"positions"`
+	TotalValue   float64         `json:"total_value"`
+	TotalMinutes float64         `json:"total_minutes"`
+	Failed       int             `json:"failed"`
+}
+
+type Service struct {
+	orders OrderStorage
+	calc   NormCalculator
+}
+
+func NewService(orders OrderStorage, calc NormCalculator) *Service {
+	return &Service{orders: orders, calc: calc}
+}
+
+// CalculateOrder считает черновик нормировки по всем позициям заказа Dem.
+// Ошибка одной позиции не останавливает остальные — она возвращается в PositionDraft.Error.
+func (s *Service) CalculateOrder(ctx context.Context, req Request) (*OrderDraft, error) {
+	const op = "service.batch.CalculateOrder"
+
+	details, err := s.orders.GetOrderDetails(ctx, req.OrderNum)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(details) == 0 {
+		return nil, fmt.Errorf("%s: в заказе %s нет позиций", op, req.OrderNum)
+	}
+
+	all, err := s.orders.GetAllTemplates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	features, err := s.orders.GetAllContextFeaturesAdmin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	features = recalculate.ActiveFeatures(features)
+
+	byCode := make(map[string]*storage.Template, len(all))
+	for _, t := range all {
+		byCode[t.Code] = t
+	}
+
+	overrides := make(map[int]PositionInput, len(req.Positions))
+	for _, p := range req.Positions {
+		overrides[p.Position] = p
+	}
+
+	stored, err := s.orders.GetOrderPositionAttributes(ctx, req.OrderNum)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	drafts := positionDrafts(details, req, stored, overrides)
+
+	parallel := req.Parallel
+	if parallel <= 0 {
+		parallel = DefaultParallel
+	}
+	if parallel > MaxParallel {
+		parallel = MaxParallel
+	}
+
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(parallel)
+
+	for i := range drafts {
+		d := &drafts[i]
+
+		if d.TemplateCode == "" && (d.Systema == "" || d.TypeIzd == "" || d.Profile == "") {
+			d.Error = "нет systema/type_izd/profile позиции для подбора шаблона: укажите их или шаблон в запросе"
+			continue
+		}
+		if d.TemplateCode != "" && byCode[d.TemplateCode] == nil {
+			d.Error = fmt.Sprintf("шаблон %s не найден", d.TemplateCode)
+			continue
+		}
+
+		g.Go(func() error {
+			template := byCode[d.TemplateCode]
+			if template == nil {
+				template = s.selectTemplate(gCtx, req.OrderNum, d, all, byCode, features)
+				if template == nil {
+					return nil
+				}
+			}
+
+			d.TemplateCode = template.Code
+			d.TemplateName = template.Name
+			d.Type = template.Category
+
+			attrs := recalculate.OrderAttributes{Systema: d.Systema, Profile: d.Profile}
+
+			// как и в ручном расчёте, доп. материалы из dem_price учитываются только для дверей
+			operations, normCtx, err := s.calc.CalculateNorm(gCtx, req.OrderNum, d.Position, d.Type, d.TemplateCode, d.Count, d.Type == "door", attrs)
+			if err != nil {
+				d.Error = err.Error()
+				return nil
+			}
+
+			d.Operations = operations
+			d.TemplateVersion = normCtx.TemplateVersion
+			for _, o := range operations {
+				d.TotalValue += o.Value
+				d.TotalMinutes += o.Minutes
+			}
+			return nil
+		})
+	}
+
+	// горутины ошибок не возвращают, Wait нужен только чтобы дождаться всех позиций
+	_ = g.Wait()
+
+	draft := &OrderDraft{OrderNum: req.OrderNum, Positions: drafts}
+	for _, d := range drafts {
+		if d.Error != "" {
+			draft.Failed++
+			continue
+		}
+		draft.TotalValue += d.TotalValue
+		draft.TotalMinutes += d.TotalMinutes
+	}
+
+	return draft, nil
+}
+
+// selectTemplate подбирает шаблон позиции тем же подбором, что и /api/template/match: по атрибутам,
+// названию позиции и её материалам. Остальные подходящие шаблоны уходят в Alternatives, ошибка — в d.Error.
+func (s *Service) selectTemplate(ctx context.Context, orderNum string, d *PositionDraft, all []*storage.Template,
+	byCode map[string]*storage.Template, features []storage.ContextFeature) *storage.Template {
+	materials, err := s.orders.GetOrderMaterials(ctx, orderNum, d.Position)
+	if err != nil {
+		d.Error = err.Error()
+		return nil
+	}
+
+	suggestions := templates.Match(all, templates.MatchInput{
+		Attributes:   templates.Attributes{Systema: d.Systema, TypeIzd: d.TypeIzd, Profile: d.Profile},
+		NamePosition: d.NamePosition,
+		Materials:    materials,
+		Features:     features,
+	})
+	if len(suggestions) == 0 {
+		d.Error = "не удалось подобрать шаблон по systema/type_izd/profile, названию позиции и материалам"
+		return nil
+	}
+
+	for _, alt := range suggestions[1:] {
+		d.Alternatives = append(d.Alternatives, alt.Code)
+	}
+	return byCode[suggestions[0].Code]
+}
+
+// positionDrafts — позиции заказа по порядку. Атрибуты берутся из переопределения позиции,
+// затем из её последней нормировки, затем из значений запроса по умолчанию.
+// GetOrderDetails группирует по позиции и типу, поэтому одна позиция может прийти несколько раз — берём первую строку.
+func positionDrafts(details []*storage.ResultOrderDetails, req Request, stored map[int]storage.PositionAttributes, overrides map[int]PositionInput) []PositionDraft {
+	seen := make(map[int]bool, len(details))
+	drafts := make([]PositionDraft, 0, len(details))
+
+	for _, detail := range details {
+		pos, err := strconv.Atoi(detail.Position)
+		if err != nil || seen[pos] {
+			continue
+		}
+		seen[pos] = true
+
+		d := PositionDraft{
+			Position:     pos,
+			NamePosition: detail.NamePosition,
+			Count:        detail.Count,
+			Sqr:          detail.Sqr,
+			Systema:      req.Systema,
+			Profile:      req.Profile,
+			TypeIzd:      req.TypeIzd,
+		}
+
+		if a, ok := stored[pos]; ok {
+			d.Systema = firstNonEmpty(a.Systema, d.Systema)
+			d.Profile = firstNonEmpty(a.Profile, d.Profile)
+			d.TypeIzd = firstNonEmpty(a.TypeIzd, d.TypeIzd)
+		}
+
+		if o, ok := overrides[pos]; ok {
+			d.Systema = firstNonEmpty(o.Systema, d.Systema)
+			d.Profile = firstNonEmpty(o.Profile, d.Profile)
+			d.TypeIzd = firstNonEmpty(o.TypeIzd, d.TypeIzd)
+			if o.Count > 0 {
+				d.Count = o.Count
+			}
+			d.TemplateCode = o.TemplateCode
+		}
+
+		if d.Count <= 0 {
+			d.Count = 1
+		}
+
+		drafts = append(drafts, d)
+	}
+
+	sort.Slice(drafts, func(i, j int) bool { return drafts[i].Position < drafts[j].Position })
+
+	return drafts
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,188 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"vue-golang/internal/service/recalculate"
+	"vue-golang/internal/storage"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+type fakeOrders struct {
+	details   []*storage.ResultOrderDetails
+	stored    map[int]storage.PositionAttributes
+	materials map[int][]*storage.KlaesMaterials
+	templates []*storage.Template
+}
+
+func (f *fakeOrders) GetOrderDetails(ctx context.Context, orderNum string) ([]*storage.ResultOrderDetails, error) {
+	return f.details, nil
+}
+
+func (f *fakeOrders) GetOrderPositionAttributes(ctx context.Context, orderNum string) (map[int]storage.PositionAttributes, error) {
+	return f.stored, nil
+}
+
+func (f *fakeOrders) GetOrderMaterials(ctx context.Context, orderNum string, pos int) ([]*storage.KlaesMaterials, error) {
+	return f.materials[pos], nil
+}
+
+func (f *fakeOrders) GetAllContextFeaturesAdmin(ctx context.Context) ([]storage.ContextFeature, error) {
+	return nil, nil
+}
+
+func (f *fakeOrders) GetAllTemplates(ctx context.Context) ([]*storage.Template, error) {
+	return f.templates, nil
+}
+
+type fakeCalc struct {
+	mu       sync.Mutex
+	calls    map[int]string
+	inFlight int32
+	maxSeen  int32
+}
+
+func (f *fakeCalc) CalculateNorm(ctx context.Context, orderNum string, pos int, typeIzd string, templateCode string, itemCount int, permisDopMaterial bool, attrs recalculate.OrderAttributes) ([]storage.Operation, recalculate.Context, error) {
+	n := atomic.AddInt32(&f.inFlight, 1)
+	defer atomic.AddInt32(&f.inFlight, -1)
+	for {
+		seen := atomic.LoadInt32(&f.maxSeen)
+		if n <= seen || atomic.CompareAndSwapInt32(&f.maxSeen, seen, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	f.mu.Lock()
+	f.calls[pos] = templateCode
+	f.mu.Unlock()
+
+	if pos == 4 {
+		return nil, recalculate.Context{}, errors.New("materials: нет материалов")
+	}
+
+	return []storage.Operation{
+		{Name: "сборка", Value: 0.5 * float64(itemCount), Minutes: 30 * float64(itemCount)},
+	}, recalculate.Context{TemplateVersion: 2}, nil
+}
+
+func newFakeOrders() *fakeOrders {
+	details := []*storage.ResultOrderDetails{
+		{Position: "3", NamePosition: "Дверь", Count: 1},
+		{Position: "1", NamePosition: "Окно", Count: 2},
+		{Position: "1", NamePosition: "Окно", Count: 2}, // позиция с двумя типами в dem_plan
+		{Position: "2", NamePosition: "Окно", Count: 1},
+		{Position: "4", NamePosition: "Окно", Count: 1},
+		{Position: "5", NamePosition: "Окно", Count: 1},
+		{Position: "6", NamePosition: "Окно", Count: 1},
+	}
+	return &fakeOrders{
+		details: details,
+		templates: []*storage.Template{
+			{ID: 23, Code: "23", Name: "окна КП45", Category: "window", Systema: strPtr("х"), TypeIzd: strPtr("окно пов.-отк."), Profile: strPtr("сх")},
+			{ID: 22, Code: "22", Name: "створки окна КП45", Category: "window", Systema: strPtr("х"), TypeIzd: strPtr("окно пов.-отк."), Profile: strPtr("сх")},
+			{ID: 56, Code: "56", Name: "Двери 1П КП45", Category: "door", Systema: strPtr("х"), TypeIzd: strPtr("1П"), Profile: strPtr("сх")},
+		},
+	}
+}
+
+func TestCalculateOrder(t *testing.T) {
+	calc := &fakeCalc{calls: map[int]string{}}
+	service := NewService(newFakeOrders(), calc)
+
+	draft, err := service.CalculateOrder(context.Background(), Request{
+		OrderNum: "Ф-100",
+		Systema:  "х",
+		Profile:  "сх",
+		TypeIzd:  "окно пов.-отк.",
+		Positions: []PositionInput{
+			{Position: 3, TypeIzd: "1П"},
+			{Position: 2, TemplateCode: "23", Count: 3},
+			{Position: 6, TypeIzd: "2П"},
+		},
+		Parallel: 2,
+	})
+	assert.NoError(t, err)
+
+	assert.Len(t, draft.Positions, 6, "повторная строка позиции 1 не считается дважды")
+	assert.LessOrEqual(t, calc.maxSeen, int32(2), "одновременно не больше parallel позиций")
+
+	byPos := make(map[int]PositionDraft)
+	for _, d := range draft.Positions {
+		byPos[d.Position] = d
+	}
+
+	assert.Equal(t, 1, draft.Positions[0].Position, "позиции отсортированы")
+
+	assert.Equal(t, "22", byPos[1].TemplateCode, "при равных совпадениях — меньший ID")
+	assert.Equal(t, []string{"23"}, byPos[1].Alternatives)
+	assert.Equal(t, 1.0, byPos[1].TotalValue, "count=2 из заказа")
+	assert.Equal(t, 2, byPos[1].TemplateVersion)
+
+	assert.Equal(t, "23", byPos[2].TemplateCode, "шаблон выбран явно")
+	assert.Equal(t, 3, byPos[2].Count)
+	assert.Empty(t, byPos[2].Alternatives)
+
+	assert.Equal(t, "56", byPos[3].TemplateCode)
+	assert.Equal(t, "door", byPos[3].Type)
+
+	assert.Contains(t, byPos[4].Error, "нет материалов")
+	assert.Contains(t, byPos[6].Error, "не удалось подобрать шаблон")
+	_, called := calc.calls[6]
+	assert.False(t, called)
+
+	assert.Equal(t, 2, draft.Failed)
+	assert.InDelta(t, 1.0+1.5+0.5+0.5, draft.TotalValue, 1e-9)
+}
+
+// Атрибуты позиции берутся из её прошлой нормировки; без атрибутов шаблон не подбирается
+func TestCalculateOrder_PositionAttributes(t *testing.T) {
+	orders := newFakeOrders()
+	orders.details = []*storage.ResultOrderDetails{
+		{Position: "1", NamePosition: "Дверь", Count: 1},
+		{Position: "2", NamePosition: "Окно", Count: 1},
+		{Position: "3", NamePosition: "Окно", Count: 1},
+	}
+	orders.stored = map[int]storage.PositionAttributes{
+		1: {Systema: "х", TypeIzd: "1П", Profile: "сх"},
+		3: {Systema: "х", TypeIzd: "окно пов.-отк."},
+	}
+
+	calc := &fakeCalc{calls: map[int]string{}}
+	draft, err := NewService(orders, calc).CalculateOrder(context.Background(), Request{OrderNum: "Ф-101"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "56", draft.Positions[0].TemplateCode)
+	assert.Equal(t, "1П", draft.Positions[0].TypeIzd)
+	assert.Contains(t, draft.Positions[1].Error, "нет systema/type_izd/profile")
+	assert.Contains(t, draft.Positions[2].Error, "нет systema/type_izd/profile")
+	assert.Equal(t, map[int]string{1: "56"}, calc.calls)
+}
+
+// Материалы позиции участвуют в подборе: без них при равных атрибутах выбрался бы оконный шаблон с меньшим ID
+func TestCalculateOrder_MatchByMaterials(t *testing.T) {
+	orders := newFakeOrders()
+	orders.details = []*storage.ResultOrderDetails{{Position: "1", NamePosition: "Изделие", Count: 1}}
+	orders.templates = []*storage.Template{
+		{ID: 23, Code: "23", Name: "окна КП45", Category: "window", Systema: strPtr("х"), Profile: strPtr("сх")},
+		{ID: 56, Code: "56", Name: "Двери КП45", Category: "door", Systema: strPtr("х"), Profile: strPtr("сх")},
+	}
+	orders.materials = map[int][]*storage.KlaesMaterials{1: {{NameMat: "Петля роликовая для КП45", Count: 2}}}
+
+	calc := &fakeCalc{calls: map[int]string{}}
+	draft, err := NewService(orders, calc).CalculateOrder(context.Background(), Request{
+		OrderNum: "Ф-102", Systema: "х", Profile: "сх", TypeIzd: "1П",
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "56", draft.Positions[0].TemplateCode, "петли — признак двери")
+	assert.Equal(t, []string{"23"}, draft.Positions[0].Alternatives)
+}
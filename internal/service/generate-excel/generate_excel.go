@@ -2,14 +2,19 @@ package generate_excel
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/xuri/excelize/v2"
 	"math"
 	"strings"
+	"time"
 	"vue-golang/internal/storage"
 	"vue-golang/internal/storage/mysql"
 )
 
+// ErrMixedReportTypes — в фильтре лоджии/витражи вместе с окнами или дверями: у них разные колонки и сводки
+var ErrMixedReportTypes = errors.New("лоджии и витражи выгружаются отдельно от окон и дверей")
+
 type GenerateExcelStorage interface {
 	GetPEOProductsByCategory(ctx context.Context, filter mysql.ProductFilter) ([]storage.PEOProduct, []storage.GetWorkers, error)
 }
@@ -23,37 +28,43 @@ func NewGenerateService(storage GenerateExcelStorage) *GenerateExcelService {
 }
 
 func (g *GenerateExcelService) GenerateExcel(ctx context.Context, filter mysql.ProductFilter) ([]byte, error) {
+	reportType, err := getReportType(filter.Type)
+	if err != nil {
+		return nil, err
+	}
 
 	products, employees, err := g.storage.GetPEOProductsByCategory(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("fetch data: %w", err)
 	}
 
-	reportType := getReportType(filter.Type)
-
+	// Книга: изделия, сводка по видам, по сотрудникам, по заказам и параметры отчёта.
+	// Итоги — формулами по листу изделий, чтобы бухгалтерия могла их проверить.
 	f := excelize.NewFile()
 	defer f.Close()
-	sheet := "Отчет ПЭО"
+	sheet := detailSheet
 	f.SetSheetName("Sheet1", sheet)
 
 	// --- СТИЛИ ---
-	// Жирный шрифт для шапки
-	headerStyle, _ := f.NewStyle(&excelize.Style{
-		Font:   &excelize.Font{Bold: true},
-		Fill:   excelize.Fill{Type: "pattern", Color: []string{"E0E0E0"}, Pattern: 1},
-		Border: []excelize.Border{{Type: "bottom", Color: "000000", Style: 2}},
-	})
+	styles := newReportStyles(f)
+	headerStyle := styles.header
 
 	// 2. ФОРМИРУЕМ ШАПКУ
 	var baseHeaders []string
 	if reportType == "window" {
 		baseHeaders = []string{"Спецификация", "№ Заказа", "Корп/дил", "Заказчик", "Вид продукции", "Система", "Наименование", "Профиль", "Кол-во", "Площадь", "Н/час",
 			"Изготовитель", "Н/руб", "защ. Пленки", "пленка н/р"}
+	} else if reportType == "door" {
+		baseHeaders = []string{"Спецификация", "№ Заказа", "Корп/дил", "Заказчик", "Система", "Тип двери", "Профиль", "Кол-во", "Площадь", "Н/час",
+			"Изготовитель", "Н/руб"}
 	} else if reportType == "loggia" {
-		baseHeaders = []string{"Витраж", "№ Заказа", "Корп/дил", "Заказчик", "Наименование", "Кол-во", "Площадь", "Площадь створки", "Н/час", "Изготовитель", "Н/час", "Н/руб", "Разница"}
-	} //else {
-	//baseHeaders = []string{"ID", "Заказ", "Клиент", "Тип", "Дата", "Сумма"}
-	//}
+		// Второй Н/час — распределённые по сотрудникам, «Разница» — сколько нормы не распределено
+		baseHeaders = []string{"Витраж", "№ Заказа", "Корп/дил", "Заказчик", "Вид продукции", "Наименование", "Кол-во", "Площадь", "Площадь створки", "Н/час",
+			"Изготовитель", "Н/час", "Н/руб", "Разница"}
+	}
+	// Какой коэффициент применён: значение и с какой даты действует (или «вручную», если задан у изделия),
+	// и множитель направления заказчика
+	baseHeaders = append(baseHeaders, "Коэф.", "Коэф. действует", "Множитель")
 
 	// 2. Пишем базовую шапку
 	for i, name := range baseHeaders {
@@ -94,22 +105,49 @@ func (g *GenerateExcelService) GenerateExcel(ctx context.Context, filter mysql.P
 			f.SetCellValue(sheet, cellName(11, rowNum), round(p.TotalTime)) // Н/час
 			f.SetCellValue(sheet, cellName(12, rowNum), p.Brigade)
 			f.SetCellValue(sheet, cellName(13, rowNum), round(p.NormMoney))
+		} else if reportType == "door" {
+			// Заполняем 12 колонок для Дверей
+			f.SetCellValue(sheet, cellName(1, rowNum), p.ParentAssembly)    // Спецификация
+			f.SetCellValue(sheet, cellName(2, rowNum), p.OrderNum)          // № Заказа
+			f.SetCellValue(sheet, cellName(3, rowNum), p.CustomerType)      // Корп/дил
+			f.SetCellValue(sheet, cellName(4, rowNum), p.Customer)          // Заказчик
+			f.SetCellValue(sheet, cellName(5, rowNum), p.Systema)           // Система
+			f.SetCellValue(sheet, cellName(6, rowNum), p.TypeIzd)           // Тип двери (1П/1.5П/2П)
+			f.SetCellValue(sheet, cellName(7, rowNum), p.Profile)           // Профиль
+			f.SetCellValue(sheet, cellName(8, rowNum), p.Count)             // Кол-во
+			f.SetCellValue(sheet, cellName(9, rowNum), round(p.Sqr))        // Площадь
+			f.SetCellValue(sheet, cellName(10, rowNum), round(p.TotalTime)) // Н/час
+			f.SetCellValue(sheet, cellName(11, rowNum), p.Brigade)          // Изготовитель
+			f.SetCellValue(sheet, cellName(12, rowNum), round(p.NormMoney)) // Н/руб
 		} else if reportType == "loggia" {
-			// Заполняем 13 колонок для Лоджий
-			//"Витраж", "№ Заказа", "Корп/дил", "Заказчик", "Наименование", "Кол-во", "Площадь", "Площадь ", "Н/час", "Изготовитель", "Н/час", "Н/руб", "Разница"}
-			f.SetCellValue(sheet, cellName(1, rowNum), p.ParentAssembly) // Витраж (ID/Позиция)
-			f.SetCellValue(sheet, cellName(2, rowNum), p.OrderNum)       // № Заказа
-			f.SetCellValue(sheet, cellName(3, rowNum), p.CustomerType)   // Корп/дил
-			f.SetCellValue(sheet, cellName(4, rowNum), p.Customer)       // Заказчик
-			f.SetCellValue(sheet, cellName(5, rowNum), p.TypeIzd)        // Наименование
-			f.SetCellValue(sheet, cellName(6, rowNum), p.Count)          // Кол-во
-			f.SetCellValue(sheet, cellName(7, rowNum), round(p.Sqr))     // Площадь
-			f.SetCellValue(sheet, cellName(8, rowNum), "-")              // Площадь створки для лоджии(пока пусто)
-			f.SetCellValue(sheet, cellName(9, rowNum), round(p.TotalTime))
-			f.SetCellValue(sheet, cellName(10, rowNum), p.Brigade)
-			f.SetCellValue(sheet, cellName(11, rowNum), round(p.NormMoney))
+			// Заполняем 14 колонок для Лоджий и витражей
+			distributed := distributedHours(p)
+			f.SetCellValue(sheet, cellName(1, rowNum), p.ParentAssembly)    // Витраж (ID/Позиция)
+			f.SetCellValue(sheet, cellName(2, rowNum), p.OrderNum)          // № Заказа
+			f.SetCellValue(sheet, cellName(3, rowNum), p.CustomerType)      // Корп/дил
+			f.SetCellValue(sheet, cellName(4, rowNum), p.Customer)          // Заказчик
+			f.SetCellValue(sheet, cellName(5, rowNum), convertType(p.Type)) // Вид продукции
+			f.SetCellValue(sheet, cellName(6, rowNum), p.TypeIzd)           // Наименование
+			f.SetCellValue(sheet, cellName(7, rowNum), p.Count)             // Кол-во
+			f.SetCellValue(sheet, cellName(8, rowNum), round(p.Sqr))        // Площадь
+			if p.SqrStv != nil {
+				f.SetCellValue(sheet, cellName(9, rowNum), round(*p.SqrStv)) // Площадь створки
+			} else {
+				f.SetCellValue(sheet, cellName(9, rowNum), "-")
+			}
+			f.SetCellValue(sheet, cellName(10, rowNum), round(p.TotalTime)) // Н/час
+			f.SetCellValue(sheet, cellName(11, rowNum), p.Brigade)          // Изготовитель
+			f.SetCellValue(sheet, cellName(12, rowNum), round(distributed)) // Н/час распределено
+			f.SetCellValue(sheet, cellName(13, rowNum), round(p.NormMoney)) // Н/руб
+			f.SetCellValue(sheet, cellName(14, rowNum), round(p.TotalTime-distributed))
 		}
 
+		if p.Coefficient != nil {
+			f.SetCellValue(sheet, cellName(len(baseHeaders)-2, rowNum), *p.Coefficient)
+		}
+		f.SetCellValue(sheet, cellName(len(baseHeaders)-1, rowNum), coefficientOrigin(p.CoefficientSource, p.CoefficientFrom))
+		f.SetCellValue(sheet, cellName(len(baseHeaders), rowNum), p.CustomerFactor)
+
 		// 4. Сотрудники (всегда СРАБОТАЕТ ПРАВИЛЬНО благодаря empColMap)
 		for empID, val := range p.EmployeeValue {
 			if colIdx, ok := empColMap[empID]; ok {
@@ -134,57 +172,31 @@ func (g *GenerateExcelService) GenerateExcel(ctx context.Context, filter mysql.P
 	// 5. Авто-ширина колонок (базовая реализация)
 	f.SetColWidth(sheet, "A", "G", 15)
 
-	winStats := g.getWindowStats(products)
-	doorStats := g.getDoorStats(products)
-
-	var allStats []StatsRow
+	layout := newDetailLayout(reportType, len(products), len(baseHeaders), empColMap)
+	writeDetailTotals(f, layout, styles)
 
-	if reportType == "window" {
-		allStats = append(allStats, winStats...)
-		allStats = append(allStats, doorStats...)
-	} else if reportType == "loggia" {
-		//allStats = append(allStats, loggiaStats...)
-	}
-
-	startRowStats := len(products) + 10
-	f.SetCellValue(sheet, cellName(1, startRowStats), "Сводная статистика")
-
-	// 1. Создаем стиль для шапки статистики (серый фон, жирный)
-	statsHeaderStyle, _ := f.NewStyle(&excelize.Style{
-		Font: &excelize.Font{Bold: true},
-		Fill: excelize.Fill{Type: "pattern", Color: []string{"CCCCCC"}, Pattern: 1},
-		Border: []excelize.Border{
-			{Type: "left", Color: "000000", Style: 1},
-			{Type: "top", Color: "000000", Style: 1},
-			{Type: "bottom", Color: "000000", Style: 1},
-			{Type: "right", Color: "000000", Style: 1},
-		},
-	})
-
-	// 2. Пишем шапку таблицы статистики
-	statsHeaders := []string{"Наименование", "Кол-во (шт)", "Площадь (м2)", "Н/час всего", "Н/руб (сумма)"}
-	for i, name := range statsHeaders {
-		cell := cellName(i+1, startRowStats+1)
-		f.SetCellValue(sheet, cell, name)
-		f.SetCellStyle(sheet, cell, cell, statsHeaderStyle)
+	// Сводные таблицы по видам изделий: у лоджий и витражей добавляется площадь створки
+	var sections []statsSection
+	switch reportType {
+	case "window":
+		sections = append(sections, statsSection{Title: "Окна", Rows: g.getWindowStats(products)},
+			statsSection{Title: "Двери", Rows: g.getDoorStats(products)})
+	case "door":
+		sections = append(sections, statsSection{Title: "Двери", Rows: g.getDoorStats(products)})
+	case "loggia":
+		sections = append(sections, statsSection{Title: "Лоджии", Rows: g.getLoggiaStats(products), WithSash: true},
+			statsSection{Title: "Витражи", Rows: g.getVitrageStats(products), WithSash: true})
 	}
 
-	// 3. Выводим данные из winStats
-	for i, row := range allStats {
-		currentRow := startRowStats + 2 + i
+	writeCategorySheet(f, sections, styles)
+	writeEmployeeSheet(f, products, employees, layout, styles)
+	writeOrderSheet(f, products, layout, styles)
+	writeFilterSheet(f, filter, len(products), len(employees), styles)
 
-		f.SetCellValue(sheet, cellName(1, currentRow), row.Label)
-		f.SetCellValue(sheet, cellName(2, currentRow), row.Count)
-		f.SetCellValue(sheet, cellName(3, currentRow), round(row.Sqr))
-		f.SetCellValue(sheet, cellName(4, currentRow), round(row.Hours))
-		f.SetCellValue(sheet, cellName(5, currentRow), round(row.Money))
-
-		// Если это последняя строка (ИТОГО), можно сделать её жирной
-		if row.Label == "Всего окон" {
-			boldStyle, _ := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
-			f.SetCellStyle(sheet, cellName(1, currentRow), cellName(5, currentRow), boldStyle)
-		}
-	}
+	// Формулы считает Excel при открытии
+	fullCalc := true
+	f.SetCalcProps(&excelize.CalcPropsOptions{FullCalcOnLoad: &fullCalc})
+	f.SetActiveSheet(0)
 
 	// Генерируем буфер
 	buf, err := f.WriteToBuffer()
@@ -200,16 +212,30 @@ func cellName(col, row int) string {
 	return name
 }
 
-func getReportType(types []string) string {
+// getReportType — набор колонок отчёта: окна (вместе с дверями, если выбраны и те, и другие), только двери,
+// лоджии и витражи. Без фильтра по типу — окна. Лоджии и витражи вместе с окнами или дверями — ErrMixedReportTypes:
+// в одной шапке их строки получили бы чужие колонки, а сводки по ним пропали бы.
+func getReportType(types []string) (string, error) {
+	selected := make(map[string]bool, len(types))
 	for _, t := range types {
-		switch t {
-		case "window", "door", "glyhar":
-			return "window"
-		case "loggia", "vitrage":
-			return "loggia"
-		}
+		selected[t] = true
 	}
-	return "window"
+
+	windows := selected["window"] || selected["glyhar"]
+	doors := selected["door"]
+	loggias := selected["loggia"] || selected["vitrage"]
+
+	switch {
+	case loggias && (windows || doors):
+		return "", fmt.Errorf("%w: %s", ErrMixedReportTypes, strings.Join(types, ", "))
+	case windows:
+		return "window", nil
+	case doors:
+		return "door", nil
+	case loggias:
+		return "loggia", nil
+	}
+	return "window", nil
 }
 
 func convertType(nameType string) string {
@@ -220,11 +246,30 @@ func convertType(nameType string) string {
 		return "дверь"
 	case "glyhar":
 		return "окно"
+	case "loggia":
+		return "лоджия"
+	case "vitrage":
+		return "витраж"
 	default:
 		return ""
 	}
 }
 
+// coefficientOrigin — откуда взят коэффициент, для колонки отчёта
+func coefficientOrigin(source string, from *time.Time) string {
+	switch source {
+	case storage.CoefSourceProduct:
+		return "вручную"
+	case storage.CoefSourceHistory:
+		if from != nil {
+			return "с " + from.Format("02.01.2006")
+		}
+	case storage.CoefSourceType:
+		return "текущий"
+	}
+	return ""
+}
+
 func round(num float64) float64 {
 	return math.Round(num*1000) / 1000
 }
@@ -232,11 +277,72 @@ func round(num float64) float64 {
 //TODO суммарная статистика по заказам
 
 type StatsRow struct {
-	Label string  // Название (например, "Холодные окна")
-	Count int     // Кол-во
-	Sqr   float64 // Площадь
-	Hours float64 // Н/час
-	Money float64 // Сумма (Н/руб)
+	Label  string  // Название (например, "Холодные окна")
+	Count  int     // Кол-во
+	Sqr    float64 // Площадь
+	SqrStv float64 // Площадь створки
+	Hours  float64 // Н/час
+	Money  float64 // Сумма (Н/руб)
+	Total  bool    // Итоговая строка: сумма строк таблицы над ней, жирным
+}
+
+// statsSection — сводная таблица по одному виду изделий
+type statsSection struct {
+	Title    string
+	Rows     []StatsRow
+	WithSash bool // колонка «Площадь створки»
+}
+
+// writeStatsSection пишет заголовок, шапку и строки таблицы начиная со строки row; возвращает номер последней строки.
+// Итоговая строка — формула SUM по строкам секции над ней.
+func writeStatsSection(f *excelize.File, sheet string, row int, section statsSection, styles reportStyles) int {
+	f.SetCellValue(sheet, cellName(1, row), section.Title)
+	f.SetCellStyle(sheet, cellName(1, row), cellName(1, row), styles.bold)
+	row++
+
+	headers := []string{"Наименование", "Кол-во (шт)", "Площадь (м2)"}
+	if section.WithSash {
+		headers = append(headers, "Площадь створки (м2)")
+	}
+	headers = append(headers, "Н/час всего", "Н/руб (сумма)")
+
+	for i, name := range headers {
+		cell := cellName(i+1, row)
+		f.SetCellValue(sheet, cell, name)
+		f.SetCellStyle(sheet, cell, cell, styles.statsHeader)
+	}
+
+	first := row + 1
+	moneyCol := len(headers)
+	for _, stats := range section.Rows {
+		row++
+
+		values := []interface{}{stats.Label, stats.Count, round(stats.Sqr)}
+		if section.WithSash {
+			values = append(values, round(stats.SqrStv))
+		}
+		values = append(values, round(stats.Hours), round(stats.Money))
+
+		numberStyle, moneyStyle := styles.number, styles.money
+		if stats.Total {
+			numberStyle, moneyStyle = styles.boldNumber, styles.boldMoney
+			f.SetCellStyle(sheet, cellName(1, row), cellName(2, row), styles.bold)
+		}
+
+		for i, v := range values {
+			cell := cellName(i+1, row)
+			if stats.Total && i > 0 {
+				f.SetCellFormula(sheet, cell, fmt.Sprintf("SUM(%s:%s)", cellName(i+1, first), cellName(i+1, row-1)))
+			} else {
+				f.SetCellValue(sheet, cell, v)
+			}
+		}
+
+		f.SetCellStyle(sheet, cellName(3, row), cellName(moneyCol-1, row), numberStyle)
+		f.SetCellStyle(sheet, cellName(moneyCol, row), cellName(moneyCol, row), moneyStyle)
+	}
+
+	return row
 }
 
 func (g *GenerateExcelService) getWindowStats(products []storage.PEOProduct) []StatsRow {
@@ -246,6 +352,7 @@ func (g *GenerateExcelService) getWindowStats(products []storage.PEOProduct) []S
 	hotWindow.Label = "Теплые окна"
 	vitrageDoor.Label = "Витраж к двери"
 	totalWindow.Label = "Всего окон"
+	totalWindow.Total = true
 
 	unknown.Label = "Неизвестное изделие(окна)"
 
@@ -268,10 +375,7 @@ func (g *GenerateExcelService) getWindowStats(products []storage.PEOProduct) []S
 
 	}
 
-	totalWindow.Count = coldWindow.Count + hotWindow.Count + vitrageDoor.Count
-	totalWindow.Sqr = coldWindow.Sqr + hotWindow.Sqr + vitrageDoor.Sqr
-	totalWindow.Hours = coldWindow.Hours + hotWindow.Hours + vitrageDoor.Hours
-	totalWindow.Money = coldWindow.Money + hotWindow.Money + vitrageDoor.Money
+	sumStats(&totalWindow, coldWindow, hotWindow, vitrageDoor)
 
 	var result []StatsRow
 
@@ -285,11 +389,13 @@ func (g *GenerateExcelService) getWindowStats(products []storage.PEOProduct) []S
 }
 
 func (g *GenerateExcelService) getDoorStats(products []storage.PEOProduct) []StatsRow {
-	var door1P, door15P, door2P, coldDoor, hotDoor, unknown StatsRow
+	var door1P, door15P, door2P, totalDoor, coldDoor, hotDoor, unknown StatsRow
 
 	door1P.Label = "Всего 1П дверей"
 	door15P.Label = "Всего 1.5П дверей"
 	door2P.Label = "Всего 2П дверей"
+	totalDoor.Label = "Всего дверей"
+	totalDoor.Total = true
 
 	hotDoor.Label = "Всего теплых дверей"
 	coldDoor.Label = "Всего холодных дверей"
@@ -319,11 +425,14 @@ func (g *GenerateExcelService) getDoorStats(products []storage.PEOProduct) []Sta
 		}
 	}
 
+	sumStats(&totalDoor, door1P, door15P, door2P)
+
 	var result []StatsRow
 
 	result = append(result, door1P)
 	result = append(result, door15P)
 	result = append(result, door2P)
+	result = append(result, totalDoor)
 	result = append(result, coldDoor)
 	result = append(result, hotDoor)
 	result = append(result, unknown)
@@ -331,10 +440,72 @@ func (g *GenerateExcelService) getDoorStats(products []storage.PEOProduct) []Sta
 	return result
 }
 
+func (g *GenerateExcelService) getLoggiaStats(products []storage.PEOProduct) []StatsRow {
+	return getGlazingStats(products, "loggia", "лоджии", "лоджий")
+}
+
+func (g *GenerateExcelService) getVitrageStats(products []storage.PEOProduct) []StatsRow {
+	return getGlazingStats(products, "vitrage", "витражи", "витражей")
+}
+
+// getGlazingStats — статистика лоджий или витражей по системе (холодные/теплые); plural и genitive — «лоджии»/«лоджий»
+func getGlazingStats(products []storage.PEOProduct, productType, plural, genitive string) []StatsRow {
+	var cold, hot, total, unknown StatsRow
+
+	cold.Label = "Холодные " + plural
+	hot.Label = "Теплые " + plural
+	total.Label = "Всего " + genitive
+	total.Total = true
+
+	unknown.Label = "Неизвестное изделие(" + plural + ")"
+
+	for _, p := range products {
+		if p.Type != productType {
+			continue
+		}
+
+		systema := strings.ToLower(p.Systema)
+		if systema == "х" || systema == "x" {
+			addStats(&cold, p)
+		} else if systema == "т" {
+			addStats(&hot, p)
+		} else {
+			addStats(&unknown, p)
+		}
+	}
+
+	sumStats(&total, cold, hot)
+
+	return []StatsRow{cold, hot, total, unknown}
+}
+
 // Вспомогательная функция, чтобы не дублировать код прибавления цифр
 func addStats(row *StatsRow, p storage.PEOProduct) {
 	row.Count += p.Count
 	row.Sqr += p.Sqr
+	if p.SqrStv != nil {
+		row.SqrStv += *p.SqrStv
+	}
 	row.Hours += p.TotalTime
 	row.Money += p.NormMoney
 }
+
+// sumStats складывает строки в итоговую
+func sumStats(total *StatsRow, rows ...StatsRow) {
+	for _, r := range rows {
+		total.Count += r.Count
+		total.Sqr += r.Sqr
+		total.SqrStv += r.SqrStv
+		total.Hours += r.Hours
+		total.Money += r.Money
+	}
+}
+
+// distributedHours — Н/час изделия, распределённые по сотрудникам
+func distributedHours(p storage.PEOProduct) float64 {
+	var sum float64
+	for _, v := range p.EmployeeValue {
+		sum += v
+	}
+	return sum
+}
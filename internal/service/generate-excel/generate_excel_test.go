@@ -0,0 +1,189 @@
+package generate_excel
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+	"testing"
+	"time"
+	"vue-golang/internal/service/statement"
+	"vue-golang/internal/storage"
+	"vue-golang/internal/storage/mysql"
+)
+
+type fakeStorage struct {
+	products  []storage.PEOProduct
+	employees []storage.GetWorkers
+}
+
+func (f fakeStorage) GetPEOProductsByCategory(_ context.Context, _ mysql.ProductFilter) ([]storage.PEOProduct, []storage.GetWorkers, error) {
+	return f.products, f.employees, nil
+}
+
+func sqr(v float64) *float64 { return &v }
+
+// findRow — значения строки листа, в первой колонке которой стоит label (nil — нет такой); формулы вычисляются
+func findRow(t *testing.T, f *excelize.File, sheet, label string) []string {
+	rows, err := f.GetRows(sheet)
+	require.NoError(t, err)
+	for i, r := range rows {
+		if len(r) == 0 || r[0] != label {
+			continue
+		}
+		cols, err := f.GetCols(sheet)
+		require.NoError(t, err)
+		values := make([]string, 0, len(cols))
+		for col := 1; col <= len(cols); col++ {
+			cell := cellName(col, i+1)
+			formula, err := f.GetCellFormula(sheet, cell)
+			require.NoError(t, err)
+			var v string
+			if formula != "" {
+				v, err = f.CalcCellValue(sheet, cell, excelize.Options{RawCellValue: true})
+			} else {
+				v, err = f.GetCellValue(sheet, cell, excelize.Options{RawCellValue: true})
+			}
+			require.NoError(t, err)
+			values = append(values, v)
+		}
+		// хвост пустых ячеек не нужен
+		for len(values) > 0 && values[len(values)-1] == "" {
+			values = values[:len(values)-1]
+		}
+		return values
+	}
+	return nil
+}
+
+func generate(t *testing.T, types []string, products []storage.PEOProduct) *excelize.File {
+	svc := NewGenerateService(fakeStorage{products: products, employees: []storage.GetWorkers{{ID: 1, Name: "Иванов"}}})
+	data, err := svc.GenerateExcel(context.Background(), mysql.ProductFilter{Type: types})
+	require.NoError(t, err)
+
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestGetReportType(t *testing.T) {
+	for _, tc := range []struct {
+		types []string
+		want  string
+	}{
+		{nil, "window"},
+		{[]string{"door", "window"}, "window"},
+		{[]string{"door"}, "door"},
+		{[]string{"vitrage", "loggia"}, "loggia"},
+	} {
+		got, err := getReportType(tc.types)
+		assert.NoError(t, err, tc.types)
+		assert.Equal(t, tc.want, got, tc.types)
+	}
+
+	for _, types := range [][]string{{"door", "loggia"}, {"window", "vitrage"}, {"glyhar", "door", "loggia"}} {
+		_, err := getReportType(types)
+		assert.ErrorIs(t, err, ErrMixedReportTypes, types)
+	}
+}
+
+// Тест: у дверей своя шапка, сводка по 1П/1.5П/2П с итогом
+func TestGenerateExcel_Doors(t *testing.T) {
+	f := generate(t, []string{"door"}, []storage.PEOProduct{
+		{ID: 1, OrderNum: "100", Type: "door", TypeIzd: "1П", Systema: "т", Count: 1, Sqr: 2, TotalTime: 3, NormMoney: 30},
+		{ID: 2, OrderNum: "100", Type: "door", TypeIzd: "2ПТ", Systema: "х", Count: 2, Sqr: 4, TotalTime: 5, NormMoney: 50},
+	})
+
+	header := findRow(t, f, detailSheet, "Спецификация")
+	require.NotNil(t, header)
+	assert.Equal(t, "Тип двери", header[5])
+	assert.Equal(t, "Иванов", header[len(header)-1])
+
+	assert.Equal(t, []string{"Всего 1П дверей", "1", "2", "3", "30"}, findRow(t, f, categorySheet, "Всего 1П дверей"))
+	assert.Equal(t, []string{"Всего 2П дверей", "2", "4", "5", "50"}, findRow(t, f, categorySheet, "Всего 2П дверей"))
+	assert.Equal(t, []string{"Всего дверей", "3", "6", "8", "80"}, findRow(t, f, categorySheet, "Всего дверей"))
+	assert.Nil(t, findRow(t, f, categorySheet, "Всего окон"))
+}
+
+// Тест: у лоджий площадь створки берётся из sqr_stv, без неё — «-»; лоджии и витражи в отдельных сводках
+func TestGenerateExcel_Loggias(t *testing.T) {
+	f := generate(t, []string{"loggia", "vitrage"}, []storage.PEOProduct{
+		{ID: 1, ParentAssembly: "В1", Type: "loggia", Systema: "т", Count: 1, Sqr: 5, SqrStv: sqr(1.5), TotalTime: 4, NormMoney: 40,
+			EmployeeValue: map[int64]float64{1: 3}},
+		{ID: 2, ParentAssembly: "В2", Type: "vitrage", Systema: "х", Count: 1, Sqr: 6, TotalTime: 2, NormMoney: 20},
+	})
+
+	header := findRow(t, f, detailSheet, "Витраж")
+	require.NotNil(t, header)
+	assert.Equal(t, "Площадь створки", header[8])
+
+	loggia := findRow(t, f, detailSheet, "В1")
+	assert.Equal(t, "лоджия", loggia[4])
+	assert.Equal(t, "1.5", loggia[8])
+	assert.Equal(t, "3", loggia[11]) // распределено
+	assert.Equal(t, "1", loggia[13]) // разница
+	assert.Equal(t, "-", findRow(t, f, detailSheet, "В2")[8])
+
+	assert.Equal(t, []string{"Всего лоджий", "1", "5", "1.5", "4", "40"}, findRow(t, f, categorySheet, "Всего лоджий"))
+	assert.Equal(t, []string{"Всего витражей", "1", "6", "0", "2", "20"}, findRow(t, f, categorySheet, "Всего витражей"))
+	assert.NotNil(t, findRow(t, f, categorySheet, "Лоджии"))
+	assert.NotNil(t, findRow(t, f, categorySheet, "Витражи"))
+}
+
+// Тест: итоги по сотрудникам, заказам и заказчикам считаются формулами по листу изделий;
+// сумма сотрудника учитывает множитель направления заказчика
+func TestGenerateExcel_Workbook(t *testing.T) {
+	coef := 100.0
+	f := generate(t, []string{"window"}, []storage.PEOProduct{
+		{ID: 1, OrderNum: "200", Customer: "ООО Рога", Type: "window", Systema: "т", Count: 2, Sqr: 3, TotalTime: 1.5, NormMoney: 150,
+			Coefficient: &coef, CustomerFactor: 1, EmployeeValue: map[int64]float64{1: 1.5}, EmployeeMinutes: map[int64]float64{1: 80}},
+		{ID: 2, OrderNum: "100", Customer: "ООО Рога", Type: "window", Systema: "х", Count: 1, Sqr: 1, TotalTime: 0.5, NormMoney: 60,
+			Coefficient: &coef, CustomerFactor: 1.2, EmployeeValue: map[int64]float64{1: 0.25}, EmployeeMinutes: map[int64]float64{1: 20}},
+	})
+
+	assert.Equal(t, []string{detailSheet, categorySheet, employeeSheet, orderSheet, filterSheet}, f.GetSheetList())
+
+	total := findRow(t, f, detailSheet, "Итого")
+	require.NotNil(t, total)
+	assert.Equal(t, "3", total[8])    // Кол-во
+	assert.Equal(t, "2", total[10])   // Н/час
+	assert.Equal(t, "210", total[12]) // Н/руб
+	assert.Equal(t, "1.75", total[len(total)-1])
+
+	assert.Equal(t, []string{"Иванов", "2", "100", "1.75", "180"}, findRow(t, f, employeeSheet, "Иванов"))
+
+	assert.Equal(t, []string{"100", "ООО Рога", "", "1", "1", "0.5", "60"}, findRow(t, f, orderSheet, "100"))
+	assert.Equal(t, []string{"200", "ООО Рога", "", "2", "3", "1.5", "150"}, findRow(t, f, orderSheet, "200"))
+	assert.Equal(t, []string{"ООО Рога", "2", "3", "4", "2", "210"}, findRow(t, f, orderSheet, "ООО Рога"))
+
+	types, err := f.GetCellValue(filterSheet, "B5")
+	require.NoError(t, err)
+	assert.Equal(t, "окно", types)
+
+	statuses, err := f.GetCellValue(filterSheet, "B6")
+	require.NoError(t, err)
+	assert.Equal(t, "назначено, готово", statuses)
+}
+
+// Тест: сумма сотрудника в отчёте совпадает с итогом его ведомости по тем же операциям,
+// хотя при округлении каждой строки до копеек вышло бы 26.82
+func TestGenerateExcel_EmployeeMoneyMatchesStatement(t *testing.T) {
+	coef := 77.77
+	var products []storage.PEOProduct
+	var lines []storage.StatementLine
+	for i, order := range []string{"301", "302", "303"} {
+		products = append(products, storage.PEOProduct{ID: int64(i + 1), OrderNum: order, Customer: "ООО Рога", Type: "window",
+			Count: 1, TotalTime: 0.1, Coefficient: &coef, CustomerFactor: 1.15,
+			EmployeeValue: map[int64]float64{1: 0.1}, EmployeeMinutes: map[int64]float64{1: 6}})
+		lines = append(lines, storage.StatementLine{ProductID: int64(i + 1), OrderNum: order,
+			ActualMinutes: 6, ActualValue: 0.1, Coefficient: &coef, CustomerFactor: 1.15})
+	}
+
+	f := generate(t, []string{"window"}, products)
+	st := statement.Build(storage.GetWorkers{ID: 1, Name: "Иванов"}, time.Time{}, time.Time{}, lines)
+
+	assert.Equal(t, 26.83, st.Total.Money)
+	assert.Equal(t, "26.83", findRow(t, f, employeeSheet, "Иванов")[4])
+}
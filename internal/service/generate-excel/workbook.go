@@ -0,0 +1,387 @@
+package generate_excel
+
+import (
+	"fmt"
+	"github.com/xuri/excelize/v2"
+	"sort"
+	"strings"
+	"time"
+	"vue-golang/internal/storage"
+	"vue-golang/internal/storage/mysql"
+)
+
+// Листы книги отчёта ПЭО
+const (
+	detailSheet   = "Отчет ПЭО"
+	categorySheet = "Сводка по видам"
+	employeeSheet = "По сотрудникам"
+	orderSheet    = "По заказам"
+	filterSheet   = "Параметры"
+)
+
+// Форматы чисел: площадь и часы — до тысячных, деньги — рубли с копейками
+const (
+	numberFormat = "0.000"
+	moneyFormat  = "#,##0.00"
+)
+
+type reportStyles struct {
+	header      int
+	statsHeader int
+	bold        int
+	number      int
+	money       int
+	boldNumber  int
+	boldMoney   int
+}
+
+func newReportStyles(f *excelize.File) reportStyles {
+	numberFmt, moneyFmt := numberFormat, moneyFormat
+	bold := &excelize.Font{Bold: true}
+
+	var s reportStyles
+	s.header, _ = f.NewStyle(&excelize.Style{
+		Font:   bold,
+		Fill:   excelize.Fill{Type: "pattern", Color: []string{"E0E0E0"}, Pattern: 1},
+		Border: []excelize.Border{{Type: "bottom", Color: "000000", Style: 2}},
+	})
+	s.statsHeader, _ = f.NewStyle(&excelize.Style{
+		Font: bold,
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"CCCCCC"}, Pattern: 1},
+		Border: []excelize.Border{
+			{Type: "left", Color: "000000", Style: 1},
+			{Type: "top", Color: "000000", Style: 1},
+			{Type: "bottom", Color: "000000", Style: 1},
+			{Type: "right", Color: "000000", Style: 1},
+		},
+	})
+	s.bold, _ = f.NewStyle(&excelize.Style{Font: bold})
+	s.number, _ = f.NewStyle(&excelize.Style{CustomNumFmt: &numberFmt})
+	s.money, _ = f.NewStyle(&excelize.Style{CustomNumFmt: &moneyFmt})
+	s.boldNumber, _ = f.NewStyle(&excelize.Style{Font: bold, CustomNumFmt: &numberFmt})
+	s.boldMoney, _ = f.NewStyle(&excelize.Style{Font: bold, CustomNumFmt: &moneyFmt})
+	return s
+}
+
+// detailLayout — где на листе изделий лежат данные, на которые ссылаются формулы сводных листов.
+// Номера колонок с 1; 0 — колонки в этом наборе нет.
+type detailLayout struct {
+	firstRow, lastRow int
+	orderCol          int
+	customerCol       int
+	countCol          int
+	sqrCol            int
+	hoursCol          int
+	moneyCol          int
+	coefficientCol    int
+	factorCol         int
+	// Прочие числовые колонки, по которым нужен итог (площадь створки, распределено, разница)
+	extraNumberCols []int
+	employeeCols    map[int64]int
+}
+
+// newDetailLayout — колонки набора reportType (см. шапки в GenerateExcel)
+func newDetailLayout(reportType string, products, headers int, employeeCols map[int64]int) detailLayout {
+	l := detailLayout{
+		firstRow:       2,
+		lastRow:        products + 1,
+		orderCol:       2,
+		customerCol:    4,
+		coefficientCol: headers - 2,
+		factorCol:      headers,
+		employeeCols:   employeeCols,
+	}
+
+	switch reportType {
+	case "door":
+		l.countCol, l.sqrCol, l.hoursCol, l.moneyCol = 8, 9, 10, 12
+	case "loggia":
+		l.countCol, l.sqrCol, l.hoursCol, l.moneyCol = 7, 8, 10, 13
+		l.extraNumberCols = []int{9, 12, 14}
+	default:
+		l.countCol, l.sqrCol, l.hoursCol, l.moneyCol = 9, 10, 11, 13
+	}
+
+	return l
+}
+
+// column — диапазон колонки листа изделий для формул другого листа: 'Отчет ПЭО'!$K$2:$K$10
+func (l detailLayout) column(col int) string {
+	name, _ := excelize.ColumnNumberToName(col)
+	return fmt.Sprintf("'%s'!$%s$%d:$%s$%d", detailSheet, name, l.firstRow, name, l.lastRow)
+}
+
+// writeDetailTotals — строка «Итого» под изделиями с формулами SUM и форматы чисел колонок
+func writeDetailTotals(f *excelize.File, l detailLayout, styles reportStyles) {
+	if l.lastRow < l.firstRow {
+		return
+	}
+
+	numberCols := append([]int{l.sqrCol, l.hoursCol}, l.extraNumberCols...)
+	for _, col := range l.employeeCols {
+		numberCols = append(numberCols, col)
+	}
+
+	for _, col := range numberCols {
+		f.SetCellStyle(detailSheet, cellName(col, l.firstRow), cellName(col, l.lastRow), styles.number)
+	}
+	f.SetCellStyle(detailSheet, cellName(l.moneyCol, l.firstRow), cellName(l.moneyCol, l.lastRow), styles.money)
+
+	row := l.lastRow + 1
+	f.SetCellValue(detailSheet, cellName(1, row), "Итого")
+	f.SetCellStyle(detailSheet, cellName(1, row), cellName(1, row), styles.bold)
+
+	sumCol := func(col, style int) {
+		cell := cellName(col, row)
+		f.SetCellFormula(detailSheet, cell, fmt.Sprintf("SUM(%s:%s)", cellName(col, l.firstRow), cellName(col, l.lastRow)))
+		f.SetCellStyle(detailSheet, cell, cell, style)
+	}
+
+	sumCol(l.countCol, styles.bold)
+	for _, col := range numberCols {
+		sumCol(col, styles.boldNumber)
+	}
+	sumCol(l.moneyCol, styles.boldMoney)
+}
+
+// writeCategorySheet — сводные таблицы по видам изделий
+func writeCategorySheet(f *excelize.File, sections []statsSection, styles reportStyles) {
+	f.NewSheet(categorySheet)
+	f.SetCellValue(categorySheet, "A1", "Сводная статистика")
+	f.SetCellStyle(categorySheet, "A1", "A1", styles.bold)
+
+	row := 3
+	for _, section := range sections {
+		row = writeStatsSection(f, categorySheet, row, section, styles) + 2
+	}
+
+	f.SetColWidth(categorySheet, "A", "A", 30)
+	f.SetColWidth(categorySheet, "B", "F", 16)
+}
+
+// writeEmployeeSheet — итоги по сотрудникам. Н/час и сумма — формулы по колонке сотрудника на листе изделий
+// (сумма = Н/час × коэффициент изделия × множитель направления заказчика, до копеек только итог — как в ведомости и снимке
+// закрытия периода), минуты — значением: на листе изделий их нет.
+func writeEmployeeSheet(f *excelize.File, products []storage.PEOProduct, employees []storage.GetWorkers, l detailLayout, styles reportStyles) {
+	f.NewSheet(employeeSheet)
+	headers := []string{"Сотрудник", "Изделий", "Факт мин", "Н/час", "Сумма, руб"}
+	writeHeader(f, employeeSheet, 1, headers, styles.header)
+
+	row := 1
+	for _, emp := range employees {
+		row++
+
+		var productsDone int
+		var minutes, value, money float64
+		for _, p := range products {
+			v, ok := p.EmployeeValue[emp.ID]
+			if !ok {
+				continue
+			}
+			productsDone++
+			minutes += p.EmployeeMinutes[emp.ID]
+			value += v
+			if p.Coefficient != nil {
+				money += storage.ExecutorMoney(v, *p.Coefficient, p.CustomerFactor)
+			}
+		}
+
+		f.SetCellValue(employeeSheet, cellName(1, row), emp.Name)
+		f.SetCellValue(employeeSheet, cellName(2, row), productsDone)
+		f.SetCellValue(employeeSheet, cellName(3, row), round(minutes))
+		if col, ok := l.employeeCols[emp.ID]; ok && l.lastRow >= l.firstRow {
+			f.SetCellFormula(employeeSheet, cellName(4, row), fmt.Sprintf("SUM(%s)", l.column(col)))
+			f.SetCellFormula(employeeSheet, cellName(5, row), fmt.Sprintf("ROUND(SUMPRODUCT(%s,%s,%s),2)",
+				l.column(col), l.column(l.coefficientCol), l.column(l.factorCol)))
+		} else {
+			f.SetCellValue(employeeSheet, cellName(4, row), round(value))
+			f.SetCellValue(employeeSheet, cellName(5, row), round(money))
+		}
+	}
+
+	writeSumRow(f, employeeSheet, 2, row, []int{2, 3, 4, 5}, styles)
+	styleColumns(f, employeeSheet, 2, row+1, []int{3, 4}, []int{5}, styles)
+
+	f.SetColWidth(employeeSheet, "A", "A", 30)
+	f.SetColWidth(employeeSheet, "B", "E", 14)
+	f.SetPanes(employeeSheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2"})
+}
+
+// writeOrderSheet — итоги по заказам и по заказчикам: формулы SUMIF по листу изделий
+func writeOrderSheet(f *excelize.File, products []storage.PEOProduct, l detailLayout, styles reportStyles) {
+	f.NewSheet(orderSheet)
+
+	type orderInfo struct {
+		customer, customerType string
+	}
+	orders := make(map[string]orderInfo)
+	customerOrders := make(map[string]map[string]bool)
+	for _, p := range products {
+		if _, ok := orders[p.OrderNum]; !ok {
+			orders[p.OrderNum] = orderInfo{customer: p.Customer, customerType: p.CustomerType}
+		}
+		if customerOrders[p.Customer] == nil {
+			customerOrders[p.Customer] = make(map[string]bool)
+		}
+		customerOrders[p.Customer][p.OrderNum] = true
+	}
+
+	orderNums := make([]string, 0, len(orders))
+	for num := range orders {
+		orderNums = append(orderNums, num)
+	}
+	sort.Strings(orderNums)
+
+	customers := make([]string, 0, len(customerOrders))
+	for c := range customerOrders {
+		customers = append(customers, c)
+	}
+	sort.Strings(customers)
+
+	// SUMIF по колонке листа изделий с ключом из первой колонки строки
+	sumIf := func(row, keyCol, criteriaCol, valueCol int) string {
+		return fmt.Sprintf("SUMIF(%s,%s,%s)", l.column(criteriaCol), cellName(keyCol, row), l.column(valueCol))
+	}
+
+	// Заказы
+	row := 1
+	writeHeader(f, orderSheet, row, []string{"№ Заказа", "Заказчик", "Корп/дил", "Кол-во", "Площадь", "Н/час", "Н/руб"}, styles.header)
+	first := row + 1
+	for _, num := range orderNums {
+		row++
+		f.SetCellValue(orderSheet, cellName(1, row), num)
+		f.SetCellValue(orderSheet, cellName(2, row), orders[num].customer)
+		f.SetCellValue(orderSheet, cellName(3, row), orders[num].customerType)
+		for i, col := range []int{l.countCol, l.sqrCol, l.hoursCol, l.moneyCol} {
+			f.SetCellFormula(orderSheet, cellName(4+i, row), sumIf(row, 1, l.orderCol, col))
+		}
+	}
+	writeSumRow(f, orderSheet, first, row, []int{4, 5, 6, 7}, styles)
+	styleColumns(f, orderSheet, first, row+1, []int{5, 6}, []int{7}, styles)
+
+	// Заказчики
+	row += 3
+	writeHeader(f, orderSheet, row, []string{"Заказчик", "Заказов", "Кол-во", "Площадь", "Н/час", "Н/руб"}, styles.header)
+	first = row + 1
+	for _, c := range customers {
+		row++
+		f.SetCellValue(orderSheet, cellName(1, row), c)
+		f.SetCellValue(orderSheet, cellName(2, row), len(customerOrders[c]))
+		for i, col := range []int{l.countCol, l.sqrCol, l.hoursCol, l.moneyCol} {
+			f.SetCellFormula(orderSheet, cellName(3+i, row), sumIf(row, 1, l.customerCol, col))
+		}
+	}
+	writeSumRow(f, orderSheet, first, row, []int{2, 3, 4, 5, 6}, styles)
+	styleColumns(f, orderSheet, first, row+1, []int{4, 5}, []int{6}, styles)
+
+	f.SetColWidth(orderSheet, "A", "B", 30)
+	f.SetColWidth(orderSheet, "C", "G", 14)
+}
+
+// statusNames — статусы отчёта по-русски для листа параметров
+var statusNames = map[string]string{
+	mysql.StatusAssigned: "назначено",
+	mysql.StatusFinal:    "готово",
+}
+
+func statusesName(statuses []string) string {
+	names := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		if name, ok := statusNames[status]; ok {
+			names = append(names, name)
+		} else {
+			names = append(names, status)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// writeFilterSheet — с какими параметрами сформирован отчёт
+func writeFilterSheet(f *excelize.File, filter mysql.ProductFilter, products, employees int, styles reportStyles) {
+	f.NewSheet(filterSheet)
+
+	orderNum := filter.OrderNum
+	if orderNum == "" {
+		orderNum = "все"
+	}
+
+	var types []string
+	for _, t := range filter.Type {
+		if t == "" {
+			continue
+		}
+		if name := convertType(t); name != "" {
+			types = append(types, name)
+		} else {
+			types = append(types, t)
+		}
+	}
+	typesName := strings.Join(types, ", ")
+	if typesName == "" {
+		typesName = "все"
+	}
+
+	params := [][2]interface{}{
+		{"Дата готовности с", formatDate(filter.From)},
+		{"Дата готовности по", formatDate(filter.To)},
+		{"№ заказа (содержит)", orderNum},
+		{"Виды изделий", typesName},
+		{"Статусы", statusesName(mysql.ReportStatuses)},
+		{"Изделий в отчёте", products},
+		{"Сотрудников в отчёте", employees},
+		{"Сформирован", time.Now().Format("02.01.2006 15:04")},
+	}
+
+	writeHeader(f, filterSheet, 1, []string{"Параметр", "Значение"}, styles.header)
+	for i, p := range params {
+		f.SetCellValue(filterSheet, cellName(1, i+2), p[0])
+		f.SetCellValue(filterSheet, cellName(2, i+2), p[1])
+	}
+
+	f.SetColWidth(filterSheet, "A", "A", 25)
+	f.SetColWidth(filterSheet, "B", "B", 30)
+}
+
+// writeSumRow — строка «Итого» с SUM по строкам [first, last] в колонках cols; пустая таблица — нули
+func writeSumRow(f *excelize.File, sheet string, first, last int, cols []int, styles reportStyles) {
+	row := last + 1
+	f.SetCellValue(sheet, cellName(1, row), "Итого")
+	for _, col := range cols {
+		if last < first {
+			f.SetCellValue(sheet, cellName(col, row), 0)
+			continue
+		}
+		f.SetCellFormula(sheet, cellName(col, row), fmt.Sprintf("SUM(%s:%s)", cellName(col, first), cellName(col, last)))
+	}
+	f.SetCellStyle(sheet, cellName(1, row), cellName(cols[len(cols)-1], row), styles.bold)
+}
+
+// styleColumns задаёт формат чисел колонкам в строках [first, last]; строка last — итоговая, жирная
+func styleColumns(f *excelize.File, sheet string, first, last int, numberCols, moneyCols []int, styles reportStyles) {
+	for _, col := range numberCols {
+		if last > first {
+			f.SetCellStyle(sheet, cellName(col, first), cellName(col, last-1), styles.number)
+		}
+		f.SetCellStyle(sheet, cellName(col, last), cellName(col, last), styles.boldNumber)
+	}
+	for _, col := range moneyCols {
+		if last > first {
+			f.SetCellStyle(sheet, cellName(col, first), cellName(col, last-1), styles.money)
+		}
+		f.SetCellStyle(sheet, cellName(col, last), cellName(col, last), styles.boldMoney)
+	}
+}
+
+func writeHeader(f *excelize.File, sheet string, row int, headers []string, style int) {
+	for i, name := range headers {
+		f.SetCellValue(sheet, cellName(i+1, row), name)
+	}
+	f.SetCellStyle(sheet, cellName(1, row), cellName(len(headers), row), style)
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return "—"
+	}
+	return t.Format("02.01.2006")
+}
@@ -0,0 +1,205 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"vue-golang/internal/storage"
+)
+
+// Статусы нормировки. Значения совпадают с тем, что уже лежит в dem_product_instances_al и что показывает фронтенд:
+// «нормировано» исторически хранится как in_production.
+const (
+	Draft    = "draft"
+	Normed   = "in_production"
+	Assigned = "assigned"
+	Final    = "final"
+	Cancel   = "cancel"
+)
+
+var (
+	ErrUnknownStatus     = errors.New("неизвестный статус нормировки")
+	ErrIllegalTransition = errors.New("недопустимый переход статуса")
+	ErrReasonRequired    = errors.New("не указана причина")
+)
+
+// aliases — названия статусов из описания жизненного цикла, которые клиент может прислать вместо хранимых
+var aliases = map[string]string{
+	"normed":    Normed,
+	"cancelled": Cancel,
+}
+
+// transitions — разрешённые переходы вперёд и отмена. Пустой статус (старые записи) считается черновиком.
+// Повторная установка того же статуса (пересохранение) разрешена всегда и в историю не пишется.
+var transitions = map[string][]string{
+	Draft:    {Normed, Cancel},
+	Normed:   {Assigned, Cancel},
+	Assigned: {Final, Normed, Cancel}, // Normed — перенормировка уже назначенного наряда
+	Final:    {},
+	Cancel:   {},
+}
+
+// reopens — куда возвращается наряд при переоткрытии: финальный снова к назначению, отменённый — к нормировке
+var reopens = map[string]string{
+	Final:  Assigned,
+	Cancel: Normed,
+}
+
+// TransitionError — переход запрещён; Allowed — куда можно перейти из текущего статуса
+type TransitionError struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Allowed []string `json:"allowed"`
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("%s: %q → %q", ErrIllegalTransition, e.From, e.To)
+}
+
+func (e *TransitionError) Unwrap() error {
+	return ErrIllegalTransition
+}
+
+// Normalize приводит статус клиента к хранимому значению
+func Normalize(status string) (string, error) {
+	status = strings.ToLower(strings.TrimSpace(status))
+	if alias, ok := aliases[status]; ok {
+		return alias, nil
+	}
+	if _, ok := transitions[status]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownStatus, status)
+	}
+	return status, nil
+}
+
+// Initial проверяет статус новой нормировки: сохранить можно только черновик или сразу нормированный наряд
+func Initial(status string) (string, error) {
+	if strings.TrimSpace(status) == "" {
+		return Draft, nil
+	}
+
+	normalized, err := Normalize(status)
+	if err != nil {
+		return "", err
+	}
+	if normalized != Draft && normalized != Normed {
+		return "", &TransitionError{To: normalized, Allowed: []string{Draft, Normed}}
+	}
+	return normalized, nil
+}
+
+// CheckTransition — можно ли перейти из from в to обычным действием (без переоткрытия)
+func CheckTransition(from, to string) error {
+	if from == to {
+		return nil
+	}
+
+	state := from
+	if state == "" {
+		state = Draft
+	}
+
+	allowed, known := transitions[state]
+	if !known {
+		// статус из старых данных, которого нет в жизненном цикле, — разрешаем только отмену
+		allowed = []string{Cancel}
+	}
+
+	for _, a := range allowed {
+		if a == to {
+			return nil
+		}
+	}
+
+	return &TransitionError{From: from, To: to, Allowed: allowed}
+}
+
+type StatusStorage interface {
+	GetProductStatus(ctx context.Context, id int64) (string, error)
+	ChangeStatus(ctx context.Context, change storage.StatusChange) error
+	GetStatusHistory(ctx context.Context, productID int64) ([]storage.StatusChange, error)
+}
+
+type Service struct {
+	storage StatusStorage
+}
+
+func NewService(storage StatusStorage) *Service {
+	return &Service{storage: storage}
+}
+
+// Prepare проверяет переход изделия в статус to и возвращает его для записи в транзакции сохранения
+// (UpdateNormOrder, UpdateFinalOrder, SaveOperationWorkers). Сама запись сверяет From ещё раз под блокировкой.
+func (s *Service) Prepare(ctx context.Context, productID int64, to, actor, reason string) (storage.StatusChange, error) {
+	const op = "service.lifecycle.Prepare"
+
+	to, err := Normalize(to)
+	if err != nil {
+		return storage.StatusChange{}, err
+	}
+
+	from, err := s.storage.GetProductStatus(ctx, productID)
+	if err != nil {
+		return storage.StatusChange{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := CheckTransition(from, to); err != nil {
+		return storage.StatusChange{}, err
+	}
+
+	return storage.StatusChange{ProductID: productID, From: from, To: to, ChangedBy: actor, Reason: reason}, nil
+}
+
+// Transition переводит наряд (корень и sub-изделия) в статус to
+func (s *Service) Transition(ctx context.Context, rootProductID int64, to, actor, reason string) (storage.StatusChange, error) {
+	const op = "service.lifecycle.Transition"
+
+	change, err := s.Prepare(ctx, rootProductID, to, actor, reason)
+	if err != nil {
+		return storage.StatusChange{}, err
+	}
+
+	if err := s.storage.ChangeStatus(ctx, change); err != nil {
+		return storage.StatusChange{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return change, nil
+}
+
+// Reopen возвращает финальный или отменённый наряд в работу. Причина обязательна — она остаётся в истории.
+func (s *Service) Reopen(ctx context.Context, rootProductID int64, actor, reason string) (storage.StatusChange, error) {
+	const op = "service.lifecycle.Reopen"
+
+	if strings.TrimSpace(reason) == "" {
+		return storage.StatusChange{}, ErrReasonRequired
+	}
+
+	from, err := s.storage.GetProductStatus(ctx, rootProductID)
+	if err != nil {
+		return storage.StatusChange{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	to, ok := reopens[from]
+	if !ok {
+		return storage.StatusChange{}, &TransitionError{From: from, To: "reopen", Allowed: []string{}}
+	}
+
+	change := storage.StatusChange{ProductID: rootProductID, From: from, To: to, ChangedBy: actor, Reason: reason}
+	if err := s.storage.ChangeStatus(ctx, change); err != nil {
+		return storage.StatusChange{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return change, nil
+}
+
+func (s *Service) History(ctx context.Context, productID int64) ([]storage.StatusChange, error) {
+	const op = "service.lifecycle.History"
+
+	history, err := s.storage.GetStatusHistory(ctx, productID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return history, nil
+}
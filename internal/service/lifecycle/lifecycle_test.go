@@ -0,0 +1,132 @@
+package lifecycle
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"vue-golang/internal/storage"
+)
+
+type fakeStatusStorage struct {
+	statuses map[int64]string
+	history  []storage.StatusChange
+}
+
+func (f *fakeStatusStorage) GetProductStatus(ctx context.Context, id int64) (string, error) {
+	status, ok := f.statuses[id]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return status, nil
+}
+
+func (f *fakeStatusStorage) ChangeStatus(ctx context.Context, change storage.StatusChange) error {
+	if f.statuses[change.ProductID] != change.From {
+		return storage.ErrStatusConflict
+	}
+	if change.From != change.To {
+		f.statuses[change.ProductID] = change.To
+		f.history = append(f.history, change)
+	}
+	return nil
+}
+
+func (f *fakeStatusStorage) GetStatusHistory(ctx context.Context, productID int64) ([]storage.StatusChange, error) {
+	return f.history, nil
+}
+
+func TestCheckTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		ok       bool
+	}{
+		{"", Normed, true},
+		{Draft, Normed, true},
+		{Draft, Assigned, false},
+		{Normed, Assigned, true},
+		{Normed, Final, false},
+		{Assigned, Final, true},
+		{Assigned, Normed, true},
+		{Assigned, Assigned, true},
+		{Final, Final, true},
+		{Final, Normed, false},
+		{Final, Cancel, false},
+		{Cancel, Normed, false},
+		{"archived", Cancel, true},
+		{"archived", Normed, false},
+	}
+
+	for _, tt := range tests {
+		err := CheckTransition(tt.from, tt.to)
+		if tt.ok {
+			assert.NoError(t, err, "%q → %q", tt.from, tt.to)
+		} else {
+			assert.ErrorIs(t, err, ErrIllegalTransition, "%q → %q", tt.from, tt.to)
+		}
+	}
+
+	var transitionErr *TransitionError
+	if assert.ErrorAs(t, CheckTransition(Normed, Final), &transitionErr) {
+		assert.Equal(t, []string{Assigned, Cancel}, transitionErr.Allowed)
+	}
+}
+
+func TestNormalizeAndInitial(t *testing.T) {
+	status, err := Normalize(" Normed ")
+	assert.NoError(t, err)
+	assert.Equal(t, Normed, status)
+
+	_, err = Normalize("done")
+	assert.ErrorIs(t, err, ErrUnknownStatus)
+
+	status, err = Initial("")
+	assert.NoError(t, err)
+	assert.Equal(t, Draft, status)
+
+	status, err = Initial("in_production")
+	assert.NoError(t, err)
+	assert.Equal(t, Normed, status)
+
+	_, err = Initial(Final)
+	assert.ErrorIs(t, err, ErrIllegalTransition)
+}
+
+func TestService_Lifecycle(t *testing.T) {
+	store := &fakeStatusStorage{statuses: map[int64]string{1: Normed}}
+	service := NewService(store)
+	ctx := context.Background()
+
+	_, err := service.Transition(ctx, 1, Final, "ivanov", "")
+	assert.ErrorIs(t, err, ErrIllegalTransition, "нельзя закрыть неназначенный наряд")
+
+	_, err = service.Transition(ctx, 1, Assigned, "ivanov", "")
+	assert.NoError(t, err)
+	_, err = service.Transition(ctx, 1, Final, "petrov", "")
+	assert.NoError(t, err)
+
+	_, err = service.Transition(ctx, 1, Cancel, "petrov", "")
+	assert.ErrorIs(t, err, ErrIllegalTransition, "финальный наряд отменяется только после переоткрытия")
+
+	_, err = service.Reopen(ctx, 1, "admin", " ")
+	assert.ErrorIs(t, err, ErrReasonRequired)
+
+	change, err := service.Reopen(ctx, 1, "admin", "ошибка в бригаде")
+	assert.NoError(t, err)
+	assert.Equal(t, storage.StatusChange{ProductID: 1, From: Final, To: Assigned, ChangedBy: "admin", Reason: "ошибка в бригаде"}, change)
+
+	_, err = service.Reopen(ctx, 1, "admin", "ещё раз")
+	assert.ErrorIs(t, err, ErrIllegalTransition, "переоткрыть можно только финальный или отменённый наряд")
+
+	history, err := service.History(ctx, 1)
+	assert.NoError(t, err)
+	var path []string
+	for _, h := range history {
+		path = append(path, h.From+"→"+h.To)
+	}
+	assert.Equal(t, []string{"in_production→assigned", "assigned→final", "final→assigned"}, path)
+
+	_, err = service.Transition(ctx, 2, Cancel, "ivanov", "")
+	assert.True(t, errors.Is(err, sql.ErrNoRows))
+}
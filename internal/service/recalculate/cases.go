@@ -0,0 +1,105 @@
+package recalculate
+
+import (
+	"math"
+	"vue-golang/internal/storage"
+)
+
+// Допуск сравнения ожидаемых значений: нормы хранятся с точностью до тысячных
+const caseTolerance = 0.0005
+
+// CaseDiff — расхождение результата примера с ожидаемым. Field: "value", "minutes" или "missing"
+type CaseDiff struct {
+	Operation string  `json:"operation"`
+	Field     string  `json:"field"`
+	Expected  float64 `json:"expected"`
+	Actual    float64 `json:"actual"`
+}
+
+type CaseResult struct {
+	CaseID int64      `json:"case_id"`
+	Name   string     `json:"name"`
+	Passed bool       `json:"passed"`
+	Error  string     `json:"error,omitempty"`
+	Diffs  []CaseDiff `json:"diffs,omitempty"`
+}
+
+type CasesReport struct {
+	Passed int          `json:"passed"`
+	Failed int          `json:"failed"`
+	Cases  []CaseResult `json:"cases"`
+}
+
+// RunCase считает операции шаблона по замороженным материалам примера так же, как calculate
+func RunCase(template *storage.Template, tc storage.TemplateTestCase, features []storage.ContextFeature) ([]storage.Operation, error) {
+	attrs := OrderAttributes{Systema: tc.Systema, Profile: tc.Profile}
+	buildContext, err := ruleContext(tc.Materials, tc.DopInfo, tc.TypeIzd, tc.ItemCount, features, attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	return ApplyRules(template.Operations, template.Rules, buildContext, tc.ItemCount), nil
+}
+
+// RunTemplateCases прогоняет все примеры через шаблон и сравнивает value/minutes с ожидаемыми
+func RunTemplateCases(template *storage.Template, cases []storage.TemplateTestCase, features []storage.ContextFeature) CasesReport {
+	report := CasesReport{Cases: make([]CaseResult, 0, len(cases))}
+
+	for _, tc := range cases {
+		res := CaseResult{CaseID: tc.ID, Name: tc.Name}
+
+		operations, err := RunCase(template, tc, features)
+		if err != nil {
+			res.Error = err.Error()
+		} else {
+			res.Diffs = compareExpected(operations, tc.Expected)
+		}
+
+		res.Passed = res.Error == "" && len(res.Diffs) == 0
+		if res.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+		report.Cases = append(report.Cases, res)
+	}
+
+	return report
+}
+
+// ExpectedFromOperations замораживает текущий результат расчёта как ожидаемый, с точностью до тысячных
+func ExpectedFromOperations(operations []storage.Operation) []storage.ExpectedOperation {
+	expected := make([]storage.ExpectedOperation, 0, len(operations))
+	for _, o := range operations {
+		expected = append(expected, storage.ExpectedOperation{
+			Name:    o.Name,
+			Value:   math.Round(o.Value*1000) / 1000,
+			Minutes: math.Round(o.Minutes*1000) / 1000,
+		})
+	}
+	return expected
+}
+
+func compareExpected(operations []storage.Operation, expected []storage.ExpectedOperation) []CaseDiff {
+	byName := make(map[string]storage.Operation, len(operations))
+	for _, o := range operations {
+		byName[o.Name] = o
+	}
+
+	var diffs []CaseDiff
+	for _, exp := range expected {
+		actual, ok := byName[exp.Name]
+		if !ok {
+			diffs = append(diffs, CaseDiff{Operation: exp.Name, Field: "missing"})
+			continue
+		}
+		if math.Abs(actual.Value-exp.Value) > caseTolerance {
+			diffs = append(diffs, CaseDiff{Operation: exp.Name, Field: "value", Expected: exp.Value, Actual: actual.Value})
+		}
+		if math.Abs(actual.Minutes-exp.Minutes) > caseTolerance {
+			diffs = append(diffs, CaseDiff{Operation: exp.Name, Field: "minutes", Expected: exp.Minutes, Actual: actual.Minutes})
+		}
+	}
+
+	return diffs
+}
@@ -0,0 +1,42 @@
+package recalculate
+
+// OperationTrace — разбор расчёта одной операции шаблона для режима explain
+type OperationTrace struct {
+	Operation string `json:"operation"`
+
+	// Значения из шаблона (на одно изделие)
+	TemplateValue   float64 `json:"template_value"`
+	TemplateMinutes float64 `json:"template_minutes"`
+	TemplateCount   float64 `json:"template_count"`
+
+	// Значения до применения правил (после умножения на количество изделий)
+	BaseValue   float64 `json:"base_value"`
+	BaseMinutes float64 `json:"base_minutes"`
+	BaseCount   float64 `json:"base_count"`
+
+	Rules []RuleTrace `json:"rules"`
+
+	// Итог после всех правил
+	Value   float64 `json:"value"`
+	Minutes float64 `json:"minutes"`
+	Count   float64 `json:"count"`
+}
+
+// RuleTrace — одно правило, рассмотренное для операции
+type RuleTrace struct {
+	Index     int                    `json:"index"` // индекс правила в template.Rules
+	Priority  int                    `json:"priority"`
+	Mode      string                 `json:"mode"`
+	Condition map[string]interface{} `json:"condition"`
+	Matched   bool                   `json:"matched"`
+	FailedKey string                 `json:"failed_key,omitempty"` // первый ключ условия, который не выполнился
+	Applied   bool                   `json:"applied"`              // false для совпавшего правила с неизвестным mode
+	Skipped   string                 `json:"skipped,omitempty"`    // "exclusive" или "stop" — правило не рассматривалось
+	UnitField string                 `json:"unit_field,omitempty"`
+	Units     float64                `json:"units,omitempty"` // значение unitField для multiplied-режимов
+
+	ValueBefore   float64 `json:"value_before"`
+	ValueAfter    float64 `json:"value_after"`
+	MinutesBefore float64 `json:"minutes_before"`
+	MinutesAfter  float64 `json:"minutes_after"`
+}
@@ -0,0 +1,555 @@
+package recalculate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Язык выражений для условий правил ($expr) и unitField.
+//
+// Поддерживается:
+//   - числа, строки в одинарных или двойных кавычках, true/false;
+//   - идентификаторы: поля Context (вид изделия — Type), признаки реестра, ItemCount, атрибуты заказа systema/profile;
+//   - арифметика + - * / (деление на ноль даёт 0), унарный минус;
+//   - сравнения == != < <= > >= (строки сравниваются без учёта регистра);
+//   - логика && || ! и скобки.
+//
+// Неизвестный идентификатор равен 0, как и неизвестный unitField.
+
+// Expr — разобранное выражение
+type Expr interface {
+	eval(ctx Context) (interface{}, error)
+}
+
+// ParseExpr разбирает выражение. Ошибка содержит позицию символа, на котором разбор остановился.
+func ParseExpr(src string) (Expr, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &exprParser{tokens: tokens}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("позиция %d: неожиданный символ %q", tok.pos, tok.text)
+	}
+
+	return e, nil
+}
+
+// EvalNumber вычисляет выражение как число (bool → 1/0)
+func EvalNumber(e Expr, ctx Context) (float64, error) {
+	v, err := e.eval(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return toNumber(v)
+}
+
+// EvalBool вычисляет выражение как условие (число ≠ 0 считается истиной)
+func EvalBool(e Expr, ctx Context) (bool, error) {
+	v, err := e.eval(ctx)
+	if err != nil {
+		return false, err
+	}
+	return toBool(v)
+}
+
+// isIdent — unitField из одного идентификатора обрабатывается по-старому, без разбора выражения
+func isIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if !(r == '_' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r))) {
+			return false
+		}
+	}
+	return true
+}
+
+// --- лексер ---
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokKind
+	text string
+	num  float64
+	pos  int
+}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	runes := []rune(src)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			text := string(runes[start:i])
+			num, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("позиция %d: некорректное число %q", start, text)
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: text, num: num, pos: start})
+
+		case r == '_' || unicode.IsLetter(r):
+			start := i
+			for i < len(runes) && (runes[i] == '_' || unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: string(runes[start:i]), pos: start})
+
+		case r == '\'' || r == '"':
+			start := i
+			i++
+			for i < len(runes) && runes[i] != r {
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("позиция %d: незакрытая строка", start)
+			}
+			tokens = append(tokens, token{kind: tokString, text: string(runes[start+1 : i]), pos: start})
+			i++
+
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: i})
+			i++
+
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: i})
+			i++
+
+		default:
+			if i+1 < len(runes) {
+				two := string(runes[i : i+2])
+				switch two {
+				case "==", "!=", "<=", ">=", "&&", "||":
+					tokens = append(tokens, token{kind: tokOp, text: two, pos: i})
+					i += 2
+					continue
+				}
+			}
+			switch r {
+			case '+', '-', '*', '/', '<', '>', '!':
+				tokens = append(tokens, token{kind: tokOp, text: string(r), pos: i})
+				i++
+			default:
+				return nil, fmt.Errorf("позиция %d: недопустимый символ %q", i, string(r))
+			}
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokEOF, pos: len(runes)})
+	return tokens, nil
+}
+
+// --- парсер (рекурсивный спуск) ---
+
+type exprParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *exprParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *exprParser) acceptOp(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok.kind != tokOp {
+		return "", false
+	}
+	for _, o := range ops {
+		if tok.text == o {
+			p.pos++
+			return o, true
+		}
+	}
+	return "", false
+}
+
+func (p *exprParser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOp("||"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: "||", left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseAnd() (Expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOp("&&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: "&&", left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseNot() (Expr, error) {
+	if _, ok := p.acceptOp("!"); ok {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryExpr{op: "!", operand: operand}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *exprParser) parseCompare() (Expr, error) {
+	left, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+	if op, ok := p.acceptOp("==", "!=", "<", "<=", ">", ">="); ok {
+		right, err := p.parseAdd()
+		if err != nil {
+			return nil, err
+		}
+		return &binaryExpr{op: op, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAdd() (Expr, error) {
+	left, err := p.parseMul()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOp("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseMul()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseMul() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOp("*", "/")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseUnary() (Expr, error) {
+	if _, ok := p.acceptOp("-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryExpr{op: "-", operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (Expr, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokNumber:
+		return literalExpr{value: tok.num}, nil
+	case tokString:
+		return literalExpr{value: tok.text}, nil
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			return literalExpr{value: true}, nil
+		case "false":
+			return literalExpr{value: false}, nil
+		}
+		return identExpr{name: tok.text}, nil
+	case tokLParen:
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, fmt.Errorf("позиция %d: ожидалась ')'", closing.pos)
+		}
+		return e, nil
+	case tokEOF:
+		return nil, fmt.Errorf("позиция %d: неожиданный конец выражения", tok.pos)
+	default:
+		return nil, fmt.Errorf("позиция %d: неожиданный символ %q", tok.pos, tok.text)
+	}
+}
+
+// --- узлы выражения ---
+
+type literalExpr struct {
+	value interface{}
+}
+
+func (e literalExpr) eval(Context) (interface{}, error) {
+	return e.value, nil
+}
+
+type identExpr struct {
+	name string
+}
+
+func (e identExpr) eval(ctx Context) (interface{}, error) {
+	return lookupField(e.name, ctx), nil
+}
+
+type unaryExpr struct {
+	op      string
+	operand Expr
+}
+
+func (e *unaryExpr) eval(ctx Context) (interface{}, error) {
+	v, err := e.operand.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.op == "!" {
+		b, err := toBool(v)
+		if err != nil {
+			return nil, err
+		}
+		return !b, nil
+	}
+
+	n, err := toNumber(v)
+	if err != nil {
+		return nil, err
+	}
+	return -n, nil
+}
+
+type binaryExpr struct {
+	op          string
+	left, right Expr
+}
+
+func (e *binaryExpr) eval(ctx Context) (interface{}, error) {
+	l, err := e.left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// && и || вычисляются лениво
+	switch e.op {
+	case "&&", "||":
+		lb, err := toBool(l)
+		if err != nil {
+			return nil, err
+		}
+		if e.op == "&&" && !lb {
+			return false, nil
+		}
+		if e.op == "||" && lb {
+			return true, nil
+		}
+		r, err := e.right.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return toBool(r)
+	}
+
+	r, err := e.right.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.op {
+	case "==", "!=":
+		eq, err := valuesEqual(l, r)
+		if err != nil {
+			return nil, err
+		}
+		return eq == (e.op == "=="), nil
+	}
+
+	ln, err := toNumber(l)
+	if err != nil {
+		return nil, err
+	}
+	rn, err := toNumber(r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.op {
+	case "+":
+		return ln + rn, nil
+	case "-":
+		return ln - rn, nil
+	case "*":
+		return ln * rn, nil
+	case "/":
+		if rn == 0 {
+			return 0.0, nil
+		}
+		return ln / rn, nil
+	case "<":
+		return ln < rn, nil
+	case "<=":
+		return ln <= rn, nil
+	case ">":
+		return ln > rn, nil
+	case ">=":
+		return ln >= rn, nil
+	}
+
+	return nil, fmt.Errorf("неизвестный оператор %q", e.op)
+}
+
+func valuesEqual(l, r interface{}) (bool, error) {
+	ls, lIsStr := l.(string)
+	rs, rIsStr := r.(string)
+	if lIsStr || rIsStr {
+		if !lIsStr || !rIsStr {
+			return false, fmt.Errorf("нельзя сравнить строку с нестроковым значением")
+		}
+		return strings.EqualFold(strings.TrimSpace(ls), strings.TrimSpace(rs)), nil
+	}
+
+	ln, err := toNumber(l)
+	if err != nil {
+		return false, err
+	}
+	rn, err := toNumber(r)
+	if err != nil {
+		return false, err
+	}
+	return ln == rn, nil
+}
+
+func toNumber(v interface{}) (float64, error) {
+	switch val := v.(type) {
+	case float64:
+		return val, nil
+	case bool:
+		if val {
+			return 1, nil
+		}
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("ожидалось число, получено %v", v)
+	}
+}
+
+func toBool(v interface{}) (bool, error) {
+	switch val := v.(type) {
+	case bool:
+		return val, nil
+	case float64:
+		return val != 0, nil
+	default:
+		return false, fmt.Errorf("ожидалось логическое значение, получено %v", v)
+	}
+}
+
+// lookupField возвращает значение идентификатора выражения:
+// признак реестра, атрибут заказа, логическое поле Context или числовое поле из getCountMaterials
+func lookupField(name string, ctx Context) interface{} {
+	if val, ok := ctx.Features[name]; ok {
+		return val
+	}
+
+	if get, ok := stringFields[name]; ok {
+		return get(ctx)
+	}
+	if get, ok := boolFields[name]; ok {
+		return get(ctx)
+	}
+	if name == "ItemCount" {
+		return float64(ctx.ItemCount)
+	}
+
+	return getCountMaterials(name, ctx, ctx.ItemCount)
+}
+
+// ExprIdents возвращает идентификаторы, на которые ссылается выражение, в порядке появления
+func ExprIdents(e Expr) []string {
+	var names []string
+	seen := make(map[string]bool)
+
+	var walk func(e Expr)
+	walk = func(e Expr) {
+		switch n := e.(type) {
+		case identExpr:
+			if !seen[n.name] {
+				seen[n.name] = true
+				names = append(names, n.name)
+			}
+		case *unaryExpr:
+			walk(n.operand)
+		case *binaryExpr:
+			walk(n.left)
+			walk(n.right)
+		}
+	}
+	walk(e)
+
+	return names
+}
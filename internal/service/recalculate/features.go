@@ -0,0 +1,134 @@
+package recalculate
+
+import (
+	"fmt"
+	"strings"
+	"vue-golang/internal/storage"
+)
+
+// Способы агрегации признаков реестра
+const (
+	AggregationSum    = "sum"    // сумма количества подходящих материалов
+	AggregationCount  = "count"  // количество строк подходящих материалов
+	AggregationDivide = "divide" // сумма количества, делённая на divisor
+)
+
+// ApplyFeatures считает признаки реестра по материалам позиции и складывает их в ctx.Features.
+// Признаки с другим типом изделия пропускаются, поэтому сюда можно передавать весь реестр.
+func ApplyFeatures(ctx *Context, materials []*storage.KlaesMaterials, features []storage.ContextFeature) {
+	if len(features) == 0 {
+		return
+	}
+
+	if ctx.Features == nil {
+		ctx.Features = make(map[string]float64, len(features))
+	}
+
+	for _, f := range features {
+		if f.ProductType != "" && f.ProductType != ctx.Type {
+			continue
+		}
+
+		var sum, rowCount float64
+		for _, m := range materials {
+			if !featureMatchesMaterial(f, m) {
+				continue
+			}
+			sum += m.Count
+			rowCount++
+		}
+
+		switch f.Aggregation {
+		case AggregationCount:
+			ctx.Features[f.Key] = rowCount
+		case AggregationDivide:
+			if f.Divisor != 0 {
+				ctx.Features[f.Key] = sum / f.Divisor
+			} else {
+				ctx.Features[f.Key] = sum
+			}
+		default:
+			ctx.Features[f.Key] = sum
+		}
+	}
+}
+
+// ActiveFeatures оставляет только включённые признаки — как GetContextFeatures, но по всему реестру
+func ActiveFeatures(features []storage.ContextFeature) []storage.ContextFeature {
+	active := make([]storage.ContextFeature, 0, len(features))
+	for _, f := range features {
+		if f.IsActive {
+			active = append(active, f)
+		}
+	}
+	return active
+}
+
+// FeatureKeys — ключи признаков реестра, которые ValidateTemplate принимает наравне с полями движка
+func FeatureKeys(features []storage.ContextFeature) []string {
+	keys := make([]string, 0, len(features))
+	for _, f := range features {
+		keys = append(keys, f.Key)
+	}
+	return keys
+}
+
+func featureMatchesMaterial(f storage.ContextFeature, m *storage.KlaesMaterials) bool {
+	name := strings.TrimSpace(m.NameMat)
+
+	matched := false
+	for _, n := range f.MatchNames {
+		if strings.EqualFold(strings.TrimSpace(n), name) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return false
+	}
+
+	if f.MinWidth != nil && m.Width < *f.MinWidth {
+		return false
+	}
+	if f.MaxWidth != nil && m.Width > *f.MaxWidth {
+		return false
+	}
+	if f.MinHeight != nil && m.Height < *f.MinHeight {
+		return false
+	}
+	if f.MaxHeight != nil && m.Height > *f.MaxHeight {
+		return false
+	}
+
+	return true
+}
+
+// ValidateFeature проверяет признак перед сохранением в реестр. Пустая агрегация допустима —
+// это sum, значение столбца по умолчанию.
+func ValidateFeature(f storage.ContextFeature) error {
+	if strings.TrimSpace(f.Key) == "" {
+		return fmt.Errorf("не указан ключ признака")
+	}
+	if len(f.MatchNames) == 0 {
+		return fmt.Errorf("признак %s: не указаны наименования материалов", f.Key)
+	}
+
+	switch f.Aggregation {
+	case "", AggregationSum, AggregationCount:
+	case AggregationDivide:
+		if f.Divisor == 0 {
+			return fmt.Errorf("признак %s: для агрегации divide нужен ненулевой divisor", f.Key)
+		}
+	default:
+		return fmt.Errorf("признак %s: неизвестная агрегация %q", f.Key, f.Aggregation)
+	}
+
+	if f.MinWidth != nil && f.MaxWidth != nil && *f.MinWidth > *f.MaxWidth {
+		return fmt.Errorf("признак %s: min_width больше max_width", f.Key)
+	}
+	if f.MinHeight != nil && f.MaxHeight != nil && *f.MinHeight > *f.MaxHeight {
+		return fmt.Errorf("признак %s: min_height больше max_height", f.Key)
+	}
+
+	return nil
+}
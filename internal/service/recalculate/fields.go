@@ -0,0 +1,136 @@
+package recalculate
+
+import (
+	"sort"
+	"vue-golang/internal/storage"
+)
+
+// Таблицы ниже — единственное описание режимов и полей движка: по ним считают applyRule,
+// fieldMatches, getCountMaterials и lookupField, и по ним же ValidateTemplate проверяет шаблоны.
+
+// ruleMode — режим правила; unit — количество берётся из unitField
+type ruleMode struct {
+	unit  bool
+	apply func(opr *storage.Operation, rule storage.Rule, count float64)
+}
+
+var ruleModes = map[string]ruleMode{
+	"set": {apply: func(opr *storage.Operation, rule storage.Rule, _ float64) {
+		opr.Value = rule.SetValue
+		opr.Minutes = rule.SetMinutes
+	}},
+	"multiplied": {unit: true, apply: func(opr *storage.Operation, rule storage.Rule, count float64) {
+		opr.Value = rule.ValuePerUnit * count
+		opr.Minutes = rule.MinutesPerUnit * count
+		opr.Count = count
+	}},
+	"additive": {apply: func(opr *storage.Operation, rule storage.Rule, _ float64) {
+		opr.Value += rule.ValuePerUnit
+		opr.Minutes += rule.MinutesPerUnit
+	}},
+	"additivePlusMultiplied": {unit: true, apply: func(opr *storage.Operation, rule storage.Rule, count float64) {
+		opr.Value += rule.ValuePerUnit * count
+		opr.Minutes += rule.MinutesPerUnit * count
+		opr.Count += count
+	}},
+	"minus": {apply: func(opr *storage.Operation, rule storage.Rule, _ float64) {
+		opr.Value -= rule.ValuePerUnit
+		opr.Minutes -= rule.MinutesPerUnit
+	}},
+}
+
+// Строковые атрибуты: в условиях сравниваются со строкой или списком строк без учёта регистра
+var stringFields = map[string]func(Context) string{
+	"systema": func(ctx Context) string { return ctx.Attrs.Systema },
+	"profile": func(ctx Context) string { return ctx.Attrs.Profile },
+	"Type":    func(ctx Context) string { return ctx.Type },
+}
+
+// Логические поля Context: в условиях ждут true/false
+var boolFields = map[string]func(Context) bool{
+	"HasImpost":      func(ctx Context) bool { return ctx.HasImpost },
+	"HasPetliRDRH":   func(ctx Context) bool { return ctx.HasPetliRDRH },
+	"HasPritvorKP40": func(ctx Context) bool { return ctx.HasPritvorKP40 },
+	"HasPetliFural":  func(ctx Context) bool { return ctx.HasPetliFural },
+}
+
+// Числовые поля Context: в условиях число или {min, max}, годятся для unitField и выражений
+var numberFields = map[string]func(Context) float64{
+	"ImpostCount":         func(ctx Context) float64 { return ctx.ImpostCount },
+	"CounterCount":        func(ctx Context) float64 { return ctx.CounterCount },
+	"StublinaCount":       func(ctx Context) float64 { return ctx.StublinaCount },
+	"StvWindowCount":      func(ctx Context) float64 { return ctx.StvWindowCount },
+	"StvTCount600":        func(ctx Context) float64 { return ctx.StvTCount600 },
+	"StvTCount400":        func(ctx Context) float64 { return ctx.StvTCount400 },
+	"StvCountForOpres":    func(ctx Context) float64 { return ctx.StvCountForOpres },
+	"MnogozapZamok":       func(ctx Context) float64 { return ctx.MnogozapZamok },
+	"StandZamok":          func(ctx Context) float64 { return ctx.StandZamok },
+	"PetliStand":          func(ctx Context) float64 { return ctx.PetliStand },
+	"PetliRolik":          func(ctx Context) float64 { return ctx.PetliRolik },
+	"Petli3Section":       func(ctx Context) float64 { return ctx.Petli3Section },
+	"PetliRDRH":           func(ctx Context) float64 { return ctx.PetliRDRH },
+	"PetliFural":          func(ctx Context) float64 { return ctx.PetliFural },
+	"PetliForNaveshCount": func(ctx Context) float64 { return ctx.PetliForNaveshCount },
+	"PritvorKP40":         func(ctx Context) float64 { return ctx.PritvorKP40 },
+	"TagCountWin":         func(ctx Context) float64 { return ctx.TagCountWin },
+	"VitragePanelCount":   func(ctx Context) float64 { return ctx.VitragePanelCount },
+	"MullionCount":        func(ctx Context) float64 { return ctx.MullionCount },
+	"TransomCount":        func(ctx Context) float64 { return ctx.TransomCount },
+	"GlassArea":           func(ctx Context) float64 { return ctx.GlassArea },
+}
+
+// Поля только для unitField: считаются от количества изделий позиции
+var unitOnlyFields = map[string]func(ctx Context, itemCount int) float64{
+	"HasImpostCount": func(ctx Context, _ int) float64 { return ctx.ImpostCount },
+	"HasPritvorKP40": func(_ Context, itemCount int) float64 { return float64(itemCount) },
+	"ItemCountForRDRH": func(ctx Context, itemCount int) float64 {
+		if ctx.HasPetliRDRH {
+			return float64(itemCount)
+		}
+		return 0
+	},
+}
+
+// RuleModes — режимы правил, которые понимает движок
+func RuleModes() []string {
+	return sortedKeys(ruleModes)
+}
+
+// UnitFields — поля движка, допустимые в unitField (кроме признаков реестра)
+func UnitFields() []string {
+	names := sortedKeys(numberFields)
+	names = append(names, sortedKeys(unitOnlyFields)...)
+	sort.Strings(names)
+	return names
+}
+
+// ConditionFields — ключи условий движка (кроме признаков реестра и $expr/$or/$and/$not)
+func ConditionFields() []string {
+	names := sortedKeys(stringFields)
+	names = append(names, sortedKeys(boolFields)...)
+	names = append(names, sortedKeys(numberFields)...)
+	sort.Strings(names)
+	return names
+}
+
+func isUnitField(name string) bool {
+	_, number := numberFields[name]
+	_, unitOnly := unitOnlyFields[name]
+	return number || unitOnly
+}
+
+// isExprField — идентификатор, который понимает lookupField
+func isExprField(name string) bool {
+	_, str := stringFields[name]
+	_, boolean := boolFields[name]
+	return str || boolean || name == "ItemCount" || isUnitField(name)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"golang.org/x/sync/errgroup"
 	"log"
+	"sort"
 	"strings"
 	"vue-golang/internal/constants"
 	"vue-golang/internal/storage"
@@ -13,7 +14,9 @@ import (
 type NormStorage interface {
 	GetOrderMaterials(ctx context.Context, orderNum string, pos int) ([]*storage.KlaesMaterials, error)
 	GetTemplateByCode(ctx context.Context, code string) (*storage.Template, error)
+	GetTemplateVersion(ctx context.Context, code string, version int) (*storage.Template, error)
 	GetDopInfoFromDemPrice(ctx context.Context, orderNum string) ([]*storage.DopInfoDemPrice, error)
+	GetContextFeatures(ctx context.Context, typeIzd string) ([]storage.ContextFeature, error)
 }
 
 type NormService struct {
@@ -63,10 +66,36 @@ type Context struct {
 	logSoedPrice    float64
 	logPritvorPrice float64
 
+	//Vitrage
+	VitragePanelCount float64 // количество полей заполнения
+	MullionCount      float64 // стойки
+	TransomCount      float64 // ригели
+	GlassArea         float64 // площадь заполнения, м²
+
+	// Признаки из реестра dem_context_features_al: ключ → значение
+	Features map[string]float64
+
+	// Атрибуты заказа для условий по системе/профилю и количество изделий для выражений
+	Attrs     OrderAttributes
+	ItemCount int
+
+	// Версия шаблона, по которой выполнен расчёт
+	TemplateVersion int
+
 	//StvorkiWith3Petli float64
 	// Добавишь больше признаков позже: тип профиля, площадь, кол-во камер и т.д.
 }
 
+// OrderAttributes — атрибуты позиции заказа, доступные в условиях правил как systema и profile;
+// вид изделия в условиях — поле Type
+type OrderAttributes struct {
+	Systema string `json:"systema"`
+	Profile string `json:"profile"`
+
+	// Версия шаблона, сохранённая в нормировке; 0 — действующая на сегодня
+	TemplateVersion int `json:"template_version"`
+}
+
 //func (s *NormService) CalculateNorm(ctx context.Context, orderNum string, pos int, typeIzd string, templateCode string, itemCount int) ([]storage.Operation, Context, error) {
 //	const op = "service.norm_service_rules.CalculateNorm"
 //	// Получаем материалы
@@ -102,13 +131,25 @@ type Context struct {
 //}
 
 // TODO приколы с горутинами
-func (s *NormService) CalculateNorm(ctx context.Context, orderNum string, pos int, typeIzd string, templateCode string, itemCount int, permisDopMaterial bool) ([]storage.Operation, Context, error) {
+func (s *NormService) CalculateNorm(ctx context.Context, orderNum string, pos int, typeIzd string, templateCode string, itemCount int, permisDopMaterial bool, attrs OrderAttributes) ([]storage.Operation, Context, error) {
+	result, buildContext, _, err := s.calculate(ctx, orderNum, pos, typeIzd, templateCode, itemCount, permisDopMaterial, attrs, false)
+	return result, buildContext, err
+}
+
+// CalculateNormExplain считает норму как CalculateNorm и дополнительно возвращает разбор
+// по каждой операции: базовые значения, все рассмотренные правила и их вклад
+func (s *NormService) CalculateNormExplain(ctx context.Context, orderNum string, pos int, typeIzd string, templateCode string, itemCount int, permisDopMaterial bool, attrs OrderAttributes) ([]storage.Operation, Context, []OperationTrace, error) {
+	return s.calculate(ctx, orderNum, pos, typeIzd, templateCode, itemCount, permisDopMaterial, attrs, true)
+}
+
+func (s *NormService) calculate(ctx context.Context, orderNum string, pos int, typeIzd string, templateCode string, itemCount int, permisDopMaterial bool, attrs OrderAttributes, explain bool) ([]storage.Operation, Context, []OperationTrace, error) {
 	const op = "service.norm_service_rules.CalculateNorm"
 
 	var (
 		materials []*storage.KlaesMaterials
 		template  *storage.Template
 		dopInfo   []*storage.DopInfoDemPrice
+		features  []storage.ContextFeature
 	)
 
 	g, gCtx := errgroup.WithContext(ctx)
@@ -122,7 +163,11 @@ func (s *NormService) CalculateNorm(ctx context.Context, orderNum string, pos in
 	})
 	g.Go(func() error {
 		var err error
-		template, err = s.storage.GetTemplateByCode(gCtx, templateCode)
+		if attrs.TemplateVersion > 0 {
+			template, err = s.storage.GetTemplateVersion(gCtx, templateCode, attrs.TemplateVersion)
+		} else {
+			template, err = s.storage.GetTemplateByCode(gCtx, templateCode)
+		}
 		if err != nil {
 			return fmt.Errorf("template: %w", err)
 		}
@@ -136,10 +181,18 @@ func (s *NormService) CalculateNorm(ctx context.Context, orderNum string, pos in
 		}
 		return nil
 	})
+	g.Go(func() error {
+		var err error
+		features, err = s.storage.GetContextFeatures(gCtx, typeIzd)
+		if err != nil {
+			return fmt.Errorf("features: %w", err)
+		}
+		return nil
+	})
 
 	err := g.Wait()
 	if err != nil {
-		return nil, Context{}, fmt.Errorf("%s %w", op, err)
+		return nil, Context{}, nil, fmt.Errorf("%s %w", op, err)
 	}
 
 	var dopInfoToUse []*storage.DopInfoDemPrice
@@ -149,14 +202,15 @@ func (s *NormService) CalculateNorm(ctx context.Context, orderNum string, pos in
 		dopInfoToUse = []*storage.DopInfoDemPrice{}
 	}
 
-	buildContext, err := BuildContext(materials, dopInfoToUse, typeIzd, itemCount)
+	buildContext, err := ruleContext(materials, dopInfoToUse, typeIzd, itemCount, features, attrs)
 	if err != nil {
-		return nil, Context{}, fmt.Errorf("%s %w", op, err)
+		return nil, Context{}, nil, fmt.Errorf("%s %w", op, err)
 	}
+	buildContext.TemplateVersion = template.Version
 
-	result := ApplyRules(template.Operations, template.Rules, buildContext, itemCount)
+	result, trace := applyRules(template.Operations, template.Rules, buildContext, itemCount, explain)
 
-	return result, buildContext, nil
+	return result, buildContext, trace, nil
 }
 
 func BuildContextGlyhar(materials []*storage.KlaesMaterials, itemCount int) Context {
@@ -314,11 +368,11 @@ func BuildContextLoggia(materials []*storage.KlaesMaterials, dopInfo []*storage.
 	for _, m := range materials {
 		name := strings.TrimSpace(m.NameMat)
 
-		if name == "Рама нижняя" {
+		if constants.LoggiaFrame[name] {
 			ctx.logRamCount += m.Count
 		}
 
-		if name == "Створка верх/низ" {
+		if constants.LoggiaStv[name] {
 			ctx.logStvCount += m.Count
 			//fmt.Println(ctx.logStvCount)
 		}
@@ -352,6 +406,57 @@ func BuildContextLoggia(materials []*storage.KlaesMaterials, dopInfo []*storage.
 	return ctx
 }
 
+func BuildContextVitrage(materials []*storage.KlaesMaterials) Context {
+	ctx := Context{Type: "vitrage"}
+
+	for _, m := range materials {
+		name := strings.TrimSpace(m.NameMat)
+
+		if constants.VitrageMullion[name] {
+			ctx.MullionCount += m.Count
+		}
+
+		if constants.VitrageTransom[name] {
+			ctx.TransomCount += m.Count
+		}
+
+		if constants.VitrageGlass[name] {
+			ctx.VitragePanelCount += m.Count
+			// размеры заполнения в мм
+			ctx.GlassArea += m.Width * m.Height / 1e6 * m.Count
+		}
+
+		if constants.StvWindow[name] {
+			ctx.StvWindowCount += m.Count
+		}
+	}
+
+	log.Printf("Смотрим материалы: Panels=%v, Mullions=%v, Transoms=%v, GlassArea=%.3f, Stv=%v", ctx.VitragePanelCount, ctx.MullionCount, ctx.TransomCount, ctx.GlassArea, ctx.StvWindowCount)
+
+	// Наименования витражей ещё не сверены — без этого предупреждения нормы молча останутся базовыми
+	if len(materials) > 0 && ctx.MullionCount == 0 && ctx.VitragePanelCount == 0 {
+		log.Printf("витраж: ни один из %d материалов не распознан как стойка или заполнение, проверьте наименования в constants.Vitrage*", len(materials))
+	}
+
+	return ctx
+}
+
+// ruleContext — Context, по которому применяются правила: встроенные поля типа изделия,
+// признаки реестра, атрибуты позиции и количество. Общий для расчёта и регрессионных примеров.
+func ruleContext(materials []*storage.KlaesMaterials, dopInfo []*storage.DopInfoDemPrice, typeIzd string, itemCount int,
+	features []storage.ContextFeature, attrs OrderAttributes) (Context, error) {
+	ctx, err := BuildContext(materials, dopInfo, typeIzd, itemCount)
+	if err != nil {
+		return Context{}, err
+	}
+
+	ApplyFeatures(&ctx, materials, features)
+	ctx.Attrs = attrs
+	ctx.ItemCount = itemCount
+
+	return ctx, nil
+}
+
 func BuildContext(materials []*storage.KlaesMaterials, dopInfo []*storage.DopInfoDemPrice, typeIzd string, itemCount int) (Context, error) {
 	switch typeIzd {
 	case "glyhar":
@@ -362,14 +467,27 @@ func BuildContext(materials []*storage.KlaesMaterials, dopInfo []*storage.DopInf
 		return BuildContextDoor(materials, dopInfo), nil
 	case "loggia":
 		return BuildContextLoggia(materials, dopInfo), nil
+	case "vitrage":
+		return BuildContextVitrage(materials), nil
 	default:
 		return Context{}, fmt.Errorf("неизвестный тип изделия: %s", typeIzd)
 	}
 }
 
 func ApplyRules(operations []storage.Operation, rules []storage.Rule, ctx Context, itemCount int) []storage.Operation {
+	result, _ := applyRules(operations, rules, ctx, itemCount, false)
+	return result
+}
+
+// ApplyRulesExplain применяет правила как ApplyRules и возвращает разбор по каждой операции
+func ApplyRulesExplain(operations []storage.Operation, rules []storage.Rule, ctx Context, itemCount int) ([]storage.Operation, []OperationTrace) {
+	return applyRules(operations, rules, ctx, itemCount, true)
+}
+
+func applyRules(operations []storage.Operation, rules []storage.Rule, ctx Context, itemCount int, explain bool) ([]storage.Operation, []OperationTrace) {
 	result := make([]storage.Operation, len(operations))
 	copy(result, operations)
+	ctx.ItemCount = itemCount
 
 	log.Printf("Загружено правил: %d", len(rules))
 	for i, r := range rules {
@@ -377,6 +495,20 @@ func ApplyRules(operations []storage.Operation, rules []storage.Rule, ctx Contex
 	}
 	//itemCount := 2
 
+	var trace []OperationTrace
+	if explain {
+		trace = make([]OperationTrace, len(result))
+		for i, o := range result {
+			trace[i] = OperationTrace{
+				Operation:       o.Name,
+				TemplateValue:   o.Value,
+				TemplateMinutes: o.Minutes,
+				TemplateCount:   o.Count,
+				Rules:           []RuleTrace{},
+			}
+		}
+	}
+
 	for i := range result {
 		if result[i].Group != "ign" {
 			result[i].Value *= float64(itemCount)
@@ -385,165 +517,234 @@ func ApplyRules(operations []storage.Operation, rules []storage.Rule, ctx Contex
 		}
 	}
 
+	order := ruleOrder(rules)
+
 	for i := range result {
-		for _, rule := range rules {
+		if explain {
+			trace[i].BaseValue = result[i].Value
+			trace[i].BaseMinutes = result[i].Minutes
+			trace[i].BaseCount = result[i].Count
+		}
+
+		// Совпавшее exclusive-правило с наибольшим приоритетом отменяет все остальные правила операции
+		exclusiveIdx := -1
+		for _, ruleIdx := range order {
+			rule := rules[ruleIdx]
+			if rule.Exclusive && rule.Operation == result[i].Name && MatchesCondition(rule.Condition, ctx) {
+				exclusiveIdx = ruleIdx
+				break
+			}
+		}
+
+		stopped := false
+		for _, ruleIdx := range order {
+			rule := rules[ruleIdx]
 			if rule.Operation != result[i].Name {
 				continue
 			}
 
-			if !MatchesCondition(rule.Condition, ctx) {
-				continue
+			matched, failedKey := matchCondition(rule.Condition, ctx)
+
+			rt := RuleTrace{
+				Index:         ruleIdx,
+				Priority:      rule.Priority,
+				Mode:          rule.Mode,
+				Condition:     rule.Condition,
+				Matched:       matched,
+				FailedKey:     failedKey,
+				UnitField:     rule.UnitField,
+				ValueBefore:   result[i].Value,
+				MinutesBefore: result[i].Minutes,
+			}
+
+			switch {
+			case stopped:
+				rt.Skipped = "stop"
+			case exclusiveIdx >= 0 && ruleIdx != exclusiveIdx:
+				rt.Skipped = "exclusive"
+			case matched:
+				rt.Applied, rt.Units = applyRule(&result[i], rule, ctx, itemCount)
+				stopped = rule.Stop
 			}
 
-			switch rule.Mode {
-			case "set":
-				result[i].Value = rule.SetValue
-				result[i].Minutes = rule.SetMinutes
-			case "multiplied":
-				count := getCountMaterials(rule.UnitField, ctx, itemCount)
-				result[i].Value = rule.ValuePerUnit * count
-				result[i].Minutes = rule.MinutesPerUnit * count
-				result[i].Count = count
-			case "additive":
-				result[i].Value += rule.ValuePerUnit
-				result[i].Minutes += rule.MinutesPerUnit
-			case "additivePlusMultiplied":
-				count := getCountMaterials(rule.UnitField, ctx, itemCount)
-				result[i].Value += rule.ValuePerUnit * count
-				result[i].Minutes += rule.MinutesPerUnit * count
-				result[i].Count += count
-			case "minus":
-				result[i].Value -= rule.ValuePerUnit
-				result[i].Minutes -= rule.MinutesPerUnit
-			default:
-				// По умолчанию — просто замена
-				//result[i].Value = rule.SetValue
-				//result[i].Minutes = rule.SetMinutes
+			if explain {
+				rt.ValueAfter = result[i].Value
+				rt.MinutesAfter = result[i].Minutes
+				trace[i].Rules = append(trace[i].Rules, rt)
 			}
-			//break // применили первое подходящее правило
+		}
+
+		if explain {
+			trace[i].Value = result[i].Value
+			trace[i].Minutes = result[i].Minutes
+			trace[i].Count = result[i].Count
 		}
 	}
 
 	//log.Printf("RULES", result)
 
-	return result
+	return result, trace
+}
+
+// ruleOrder возвращает индексы правил по убыванию priority; при равном приоритете сохраняется порядок шаблона
+func ruleOrder(rules []storage.Rule) []int {
+	order := make([]int, len(rules))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return rules[order[a]].Priority > rules[order[b]].Priority
+	})
+	return order
+}
+
+// applyRule применяет одно совпавшее правило к операции.
+// Возвращает false, если mode неизвестен, и значение unitField для multiplied-режимов.
+func applyRule(opr *storage.Operation, rule storage.Rule, ctx Context, itemCount int) (bool, float64) {
+	mode, ok := ruleModes[rule.Mode]
+	if !ok {
+		return false, 0
+	}
+
+	var count float64
+	if mode.unit {
+		count = getCountMaterials(rule.UnitField, ctx, itemCount)
+	}
+	mode.apply(opr, rule, count)
+
+	return true, count
 }
 
 func getCountMaterials(field string, ctx Context, itemCount int) float64 {
-	switch field {
-	case "HasImpostCount":
-		return ctx.ImpostCount
-	case "StvTCount600":
-		return ctx.StvTCount600
-	case "StvTCount400":
-		return ctx.StvTCount400
-	case "MnogozapZamok":
-		return ctx.MnogozapZamok
-	case "StandZamok":
-		return ctx.StandZamok
-	case "PetliRolik":
-		return ctx.PetliRolik
-	case "PetliStand":
-		return ctx.PetliStand
-	case "Petli3Section":
-		return ctx.Petli3Section
-	case "StublinaCount":
-		return ctx.StublinaCount
-	case "PritvorKP40":
-		return ctx.PritvorKP40
-	case "PetliFural":
-		return ctx.PetliFural
-	case "PetliRDRH":
-		return ctx.PetliRDRH
-	case "HasPritvorKP40":
-		return float64(itemCount)
-	case "StvWindowCount":
-		return ctx.StvWindowCount
-	case "TagCountWin":
-		return ctx.TagCountWin
-	case "PetliForNaveshCount":
-		return ctx.PetliForNaveshCount
-	case "CounterCount":
-		return ctx.CounterCount
-	case "StvCountForOpres":
-		return ctx.StvCountForOpres
-	case "ItemCountForRDRH":
-		if ctx.HasPetliRDRH {
-			return float64(itemCount)
-		}
+	// Признаки реестра имеют приоритет над встроенными полями
+	if val, ok := ctx.Features[field]; ok {
+		return val
+	}
+
+	// unitField может быть выражением, например "StvWindowCount / 4"
+	if field != "" && !isIdent(field) {
+		return unitFieldExpr(field, ctx)
+	}
+
+	if get, ok := unitOnlyFields[field]; ok {
+		return get(ctx, itemCount)
+	}
+	if get, ok := numberFields[field]; ok {
+		return get(ctx)
+	}
+
+	return 0
+}
+
+func unitFieldExpr(field string, ctx Context) float64 {
+	e, err := ParseExpr(field)
+	if err != nil {
+		log.Printf("unitField %q: %v", field, err)
 		return 0
-	//case "StvorkiWith3Petli":
-	//return ctx.StvorkiWith3Petli
-	default:
+	}
+	val, err := EvalNumber(e, ctx)
+	if err != nil {
+		log.Printf("unitField %q: %v", field, err)
 		return 0
 	}
+	return val
 }
 
 func MatchesCondition(condition map[string]interface{}, ctx Context) bool {
+	matched, _ := matchCondition(condition, ctx)
+	return matched
+}
+
+// matchCondition проверяет ключи условия в алфавитном порядке и возвращает первый невыполненный
+func matchCondition(condition map[string]interface{}, ctx Context) (bool, string) {
 	//log.Printf("CONDITION", condition)
-	for key, expected := range condition {
-		if !fieldMatches(key, expected, ctx) {
-			return false
+	keys := make([]string, 0, len(condition))
+	for key := range condition {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !fieldMatches(key, condition[key], ctx) {
+			return false, key
 		}
 	}
-	return true
+	return true, ""
 }
 
+// Специальные ключи условия
+const (
+	condExpr = "$expr" // строка-выражение, должна быть истинной
+	condOr   = "$or"   // массив условий, достаточно одного
+	condAnd  = "$and"  // массив условий, нужны все
+	condNot  = "$not"  // условие, которое не должно выполняться
+)
+
 func fieldMatches(key string, expected interface{}, ctx Context) bool {
 	//log.Printf("FIELD MATCHES", key, expected, ctx)
 	switch key {
-	case "HasImpost":
-		if val, ok := expected.(bool); ok {
-			return ctx.HasImpost == val
-		}
-	case "StublinaCount":
-		return compareFloatField(ctx.StublinaCount, expected)
-	case "StvTCount600":
-		return compareFloatField(ctx.StvTCount600, expected)
-	case "StvTCount400":
-		return compareFloatField(ctx.StvTCount400, expected)
-	case "HasPetliRDRH":
-		if val, ok := expected.(bool); ok {
-			return ctx.HasPetliRDRH == val
-		}
-	case "HasPritvorKP40":
-		if val, ok := expected.(bool); ok {
-			return ctx.HasPritvorKP40 == val
-		}
-	case "MnogozapZamok":
-		return compareFloatField(ctx.MnogozapZamok, expected)
-	case "StandZamok":
-		return compareFloatField(ctx.StandZamok, expected)
-	case "PetliRolik":
-		return compareFloatField(ctx.PetliRolik, expected)
-	case "PetliStand":
-		return compareFloatField(ctx.PetliStand, expected)
-	case "Petli3Section":
-		return compareFloatField(ctx.Petli3Section, expected)
-	case "PritvorKP40":
-		return compareFloatField(ctx.PritvorKP40, expected)
-	case "HasPetliFural":
-		if val, ok := expected.(bool); ok {
-			return ctx.HasPetliFural == val
-		}
-	case "PetliFural":
-		return compareFloatField(ctx.PetliFural, expected)
-	case "PetliRDRH":
-		return compareFloatField(ctx.PetliRDRH, expected)
-	case "StvWindowCount":
-		return compareFloatField(ctx.StvWindowCount, expected)
-	case "TagCountWin":
-		return compareFloatField(ctx.TagCountWin, expected)
-	case "PetliForNaveshCount":
-		return compareFloatField(ctx.PetliForNaveshCount, expected)
-	//case "StvorkiWith3Petli":
-	//return compareFloatField(ctx.StvorkiWith3Petli, expected)
-	//case "ImpostCount":
-	// Поддержка: {"min": 2} или просто 2
-	//return compareIntField(ctx.ImpostCount, expected)
-	default:
-		return false
+	case condExpr:
+		src, ok := expected.(string)
+		if !ok {
+			return false
+		}
+		e, err := ParseExpr(src)
+		if err != nil {
+			log.Printf("условие %q: %v", src, err)
+			return false
+		}
+		matched, err := EvalBool(e, ctx)
+		if err != nil {
+			log.Printf("условие %q: %v", src, err)
+			return false
+		}
+		return matched
+	case condOr, condAnd:
+		group, ok := expected.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, item := range group {
+			sub, ok := item.(map[string]interface{})
+			if !ok {
+				return false
+			}
+			matched := MatchesCondition(sub, ctx)
+			if key == condOr && matched {
+				return true
+			}
+			if key == condAnd && !matched {
+				return false
+			}
+		}
+		return key == condAnd
+	case condNot:
+		sub, ok := expected.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		return !MatchesCondition(sub, ctx)
+	}
+
+	if get, ok := stringFields[key]; ok {
+		return compareStringField(get(ctx), expected)
+	}
+
+	if val, ok := ctx.Features[key]; ok {
+		if want, isBool := expected.(bool); isBool {
+			return (val > 0) == want
+		}
+		return compareFloatField(val, expected)
 	}
+
+	if get, ok := boolFields[key]; ok {
+		val, isBool := expected.(bool)
+		return isBool && get(ctx) == val
+	}
+	if get, ok := numberFields[key]; ok {
+		return compareFloatField(get(ctx), expected)
+	}
+
 	return false
 }
 
@@ -568,3 +769,20 @@ func compareFloatField(actual float64, expected interface{}) bool {
 	}
 	return false
 }
+
+// compareStringField сравнивает атрибут заказа со строкой или списком строк без учёта регистра
+func compareStringField(actual string, expected interface{}) bool {
+	actual = strings.TrimSpace(actual)
+
+	if val, ok := expected.(string); ok {
+		return strings.EqualFold(actual, strings.TrimSpace(val))
+	}
+	if list, ok := expected.([]interface{}); ok {
+		for _, item := range list {
+			if val, ok := item.(string); ok && strings.EqualFold(actual, strings.TrimSpace(val)) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -48,6 +48,21 @@ func (m *MockNormStorage) GetTemplateByCode(ctx context.Context, code string) (*
 	return template, args.Error(1)
 }
 
+func (m *MockNormStorage) GetTemplateVersion(ctx context.Context, code string, version int) (*storage.Template, error) {
+	args := m.Called(ctx, code, version)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	template, ok := args.Get(0).(*storage.Template)
+	if !ok {
+		return nil, fmt.Errorf("expected *storage.Template, got %T", args.Get(0))
+	}
+
+	return template, args.Error(1)
+}
+
 func (m *MockNormStorage) GetDopInfoFromDemPrice(ctx context.Context, orderNum string) ([]*storage.DopInfoDemPrice, error) {
 	args := m.Called(ctx, orderNum)
 
@@ -63,6 +78,21 @@ func (m *MockNormStorage) GetDopInfoFromDemPrice(ctx context.Context, orderNum s
 	return dopInfo, args.Error(1)
 }
 
+func (m *MockNormStorage) GetContextFeatures(ctx context.Context, typeIzd string) ([]storage.ContextFeature, error) {
+	args := m.Called(ctx, typeIzd)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	features, ok := args.Get(0).([]storage.ContextFeature)
+	if !ok {
+		return nil, fmt.Errorf("expected []storage.ContextFeature, got %T", args.Get(0))
+	}
+
+	return features, args.Error(1)
+}
+
 func newMaterial(name string, count float64, width float64) *storage.KlaesMaterials {
 	return &storage.KlaesMaterials{
 		NameMat:    name,
@@ -105,12 +135,13 @@ func TestCalculateNorm_AdditivePlusMultiplied(t *testing.T) {
 	mockStorage.On("GetOrderMaterials", mock.Anything, "ORD-123", 1).Return(materials, nil)
 	mockStorage.On("GetDopInfoFromDemPrice", mock.Anything, "ORD-123").Return([]*storage.DopInfoDemPrice{}, nil)
 	mockStorage.On("GetTemplateByCode", mock.Anything, "56").Return(template, nil)
+	mockStorage.On("GetContextFeatures", mock.Anything, "door").Return([]storage.ContextFeature{}, nil)
 
 	// 5. Создаём сервис с моком
 	service := NewNormService(mockStorage)
 
 	// 6. Выполняем расчёт
-	operations, ctx, err := service.CalculateNorm(context.Background(), "ORD-123", 1, "door", "56", 2)
+	operations, ctx, err := service.CalculateNorm(context.Background(), "ORD-123", 1, "door", "56", 2, false, OrderAttributes{})
 
 	// 7. Проверяем результат
 	assert.NoError(t, err)
@@ -165,10 +196,11 @@ func TestCalculateNorm_Multiplied(t *testing.T) {
 	mockStorage.On("GetOrderMaterials", mock.Anything, "ORD-123", 1).Return(materials, nil)
 	mockStorage.On("GetDopInfoFromDemPrice", mock.Anything, "ORD-123").Return([]*storage.DopInfoDemPrice{}, nil)
 	mockStorage.On("GetTemplateByCode", mock.Anything, "56").Return(template, nil)
+	mockStorage.On("GetContextFeatures", mock.Anything, "door").Return([]storage.ContextFeature{}, nil)
 
 	service := NewNormService(mockStorage)
 
-	operation, ctx, err := service.CalculateNorm(context.Background(), "ORD-123", 1, "door", "56", 1)
+	operation, ctx, err := service.CalculateNorm(context.Background(), "ORD-123", 1, "door", "56", 1, false, OrderAttributes{})
 
 	assert.NoError(t, err)
 	assert.True(t, ctx.HasImpost)
@@ -200,9 +232,11 @@ func TestCalculateNorm_MaterialsError(t *testing.T) {
 		Return(([]*storage.DopInfoDemPrice)(nil), nil)
 	mockStorage.On("GetTemplateByCode", mock.Anything, "TEST").
 		Return((*storage.Template)(nil), nil)
+	mockStorage.On("GetContextFeatures", mock.Anything, "door").
+		Return([]storage.ContextFeature{}, nil)
 
 	service := NewNormService(mockStorage)
-	_, _, err := service.CalculateNorm(context.Background(), "ORD-123", 1, "door", "TEST", 1)
+	_, _, err := service.CalculateNorm(context.Background(), "ORD-123", 1, "door", "TEST", 1, false, OrderAttributes{})
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "materials:") // в твоём текущем коде префикс "materials:"
@@ -237,8 +271,10 @@ func TestCalculateNorm_ParallelCalls(t *testing.T) {
 		time.Sleep(5 * time.Millisecond)
 	}).Return([]*storage.DopInfoDemPrice{}, nil)
 
+	mockStorage.On("GetContextFeatures", mock.Anything, "door").Return([]storage.ContextFeature{}, nil)
+
 	service := NewNormService(mockStorage)
-	_, _, err := service.CalculateNorm(context.Background(), "ORD-123", 1, "door", "TEST", 1)
+	_, _, err := service.CalculateNorm(context.Background(), "ORD-123", 1, "door", "TEST", 1, false, OrderAttributes{})
 
 	assert.NoError(t, err)
 
@@ -643,7 +679,7 @@ func TestBuildContextGlyhar(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := BuildContextGlyhar(tt.materials)
+			got := BuildContextGlyhar(tt.materials, 1)
 
 			// Проверяем поля по одному — так понятнее, где ошибка
 			if got.Type != tt.wantCtx.Type {
@@ -1047,28 +1083,595 @@ func TestApplyRules_AdditivePlusMultiplied(t *testing.T) {
 	assert.Equal(t, 4.0, result[0].Count, "Count = 1*2 (базовое) + 2 (доп. за RDRH) = 4")
 }
 
+func TestApplyRulesExplain(t *testing.T) {
+	operations := []storage.Operation{
+		{Name: "установка петель RDRH", Value: 5.0, Minutes: 20.0, Count: 1.0},
+		{Name: "упаковка", Group: "ign", Value: 1.0, Minutes: 3.0, Count: 1.0},
+	}
+
+	rules := []storage.Rule{
+		{
+			Operation:      "установка петель RDRH",
+			Condition:      map[string]interface{}{"HasPetliRDRH": true},
+			Mode:           "additivePlusMultiplied",
+			UnitField:      "ItemCountForRDRH",
+			MinutesPerUnit: 4.5,
+		},
+		{
+			Operation:  "установка петель RDRH",
+			Condition:  map[string]interface{}{"HasImpost": true, "HasPetliRDRH": true},
+			Mode:       "set",
+			SetValue:   100,
+			SetMinutes: 100,
+		},
+		{
+			Operation:    "упаковка",
+			Condition:    map[string]interface{}{},
+			Mode:         "unknown",
+			ValuePerUnit: 1,
+		},
+	}
+
+	ctx := Context{
+		Type:         "door",
+		HasPetliRDRH: true,
+		PetliRDRH:    3.0,
+	}
+
+	result, trace := ApplyRulesExplain(operations, rules, ctx, 2)
+
+	// Результат совпадает с обычным ApplyRules
+	assert.Equal(t, ApplyRules(operations, rules, ctx, 2), result)
+	assert.Len(t, trace, 2)
+
+	door := trace[0]
+	assert.Equal(t, "установка петель RDRH", door.Operation)
+	assert.Equal(t, 20.0, door.TemplateMinutes)
+	assert.Equal(t, 40.0, door.BaseMinutes, "базовое умножение на itemCount")
+	assert.Len(t, door.Rules, 2)
+
+	assert.True(t, door.Rules[0].Matched)
+	assert.True(t, door.Rules[0].Applied)
+	assert.Equal(t, 2.0, door.Rules[0].Units)
+	assert.Equal(t, 40.0, door.Rules[0].MinutesBefore)
+	assert.Equal(t, 49.0, door.Rules[0].MinutesAfter)
+
+	assert.False(t, door.Rules[1].Matched)
+	assert.Equal(t, 1, door.Rules[1].Index)
+	assert.Equal(t, "HasImpost", door.Rules[1].FailedKey)
+	assert.Equal(t, door.Rules[1].MinutesBefore, door.Rules[1].MinutesAfter)
+	assert.Equal(t, 49.0, door.Minutes)
+
+	pack := trace[1]
+	assert.Equal(t, 3.0, pack.BaseMinutes, "операции ign не умножаются")
+	assert.Len(t, pack.Rules, 1)
+	assert.True(t, pack.Rules[0].Matched)
+	assert.False(t, pack.Rules[0].Applied, "неизвестный mode не применяется")
+}
+
 func TestBuildContext(t *testing.T) {
 	materials := []*storage.KlaesMaterials{
 		newMaterial("Импост", 1.0, 500.0),
 	}
 
-	ctx, err := BuildContext(materials, nil, "door")
+	ctx, err := BuildContext(materials, nil, "door", 1)
 	assert.NoError(t, err)
 	assert.Equal(t, "door", ctx.Type)
 	assert.True(t, ctx.HasImpost)
 
-	ctx, err = BuildContext(materials, nil, "window")
+	ctx, err = BuildContext(materials, nil, "window", 1)
 	assert.NoError(t, err)
 	assert.Equal(t, "window", ctx.Type)
 	assert.True(t, ctx.HasImpost)
 
-	ctx, err = BuildContext(materials, nil, "glyhar")
+	ctx, err = BuildContext(materials, nil, "glyhar", 1)
 	assert.NoError(t, err)
 	assert.Equal(t, "glyhar", ctx.Type)
 	assert.True(t, ctx.HasImpost)
 
+	ctx, err = BuildContext(materials, nil, "vitrage", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "vitrage", ctx.Type)
+
 	// Тест для неизвестного типа
-	_, err = BuildContext(materials, nil, "unknown")
+	_, err = BuildContext(materials, nil, "unknown", 1)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "неизвестный тип изделия")
 }
+
+func TestBuildContextVitrage(t *testing.T) {
+	materials := []*storage.KlaesMaterials{
+		{NameMat: "Стойка", Count: 3, Width: 2500},
+		{NameMat: " Стойка угловая ", Count: 1, Width: 2500},
+		{NameMat: "Ригель", Count: 4, Width: 900},
+		{NameMat: "Стеклопакет", Count: 4, Width: 1000, Height: 1500},
+		{NameMat: "Заполнение", Count: 2, Width: 500, Height: 1000},
+		{NameMat: "Створка оконная", Count: 1},
+		{NameMat: "Импост", Count: 2},
+	}
+
+	ctx := BuildContextVitrage(materials)
+
+	assert.Equal(t, "vitrage", ctx.Type)
+	assert.Equal(t, 4.0, ctx.MullionCount)
+	assert.Equal(t, 4.0, ctx.TransomCount)
+	assert.Equal(t, 6.0, ctx.VitragePanelCount)
+	assert.InDelta(t, 7.0, ctx.GlassArea, 1e-9, "4 × 1.5 м² + 2 × 0.5 м²")
+	assert.Equal(t, 1.0, ctx.StvWindowCount)
+	assert.False(t, ctx.HasImpost, "импосты в витраже не учитываются")
+
+	assert.Equal(t, 4.0, getCountMaterials("MullionCount", ctx, 1))
+	assert.Equal(t, 6.0, getCountMaterials("VitragePanelCount", ctx, 1))
+	assert.True(t, fieldMatches("GlassArea", map[string]interface{}{"min": 3.0}, ctx))
+	assert.True(t, fieldMatches("TransomCount", 4.0, ctx))
+
+	operations := []storage.Operation{{Name: "ust_zapoln", Value: 0.25, Minutes: 15, Count: 1}}
+	rules := []storage.Rule{
+		{Operation: "ust_zapoln", Condition: map[string]interface{}{"VitragePanelCount": map[string]interface{}{"min": 1.0}}, Mode: "multiplied", UnitField: "VitragePanelCount", MinutesPerUnit: 6},
+		{Operation: "ust_zapoln", Condition: map[string]interface{}{"GlassArea": map[string]interface{}{"min": 3.0}}, Mode: "additivePlusMultiplied", UnitField: "GlassArea", MinutesPerUnit: 1},
+	}
+
+	result := ApplyRules(operations, rules, ctx, 1)
+	assert.InDelta(t, 43.0, result[0].Minutes, 1e-9, "6 полей × 6 мин + 7 м² × 1 мин")
+}
+
+func TestApplyFeatures(t *testing.T) {
+	maxWidth := 615.0
+
+	features := []storage.ContextFeature{
+		{Key: "StvTCount600", ProductType: "window", MatchNames: []string{"Створка оконная"}, MaxWidth: &maxWidth, Aggregation: AggregationSum},
+		{Key: "StvRows", MatchNames: []string{"Створка оконная"}, Aggregation: AggregationCount},
+		{Key: "StvForOpres", MatchNames: []string{"Створка оконная"}, Aggregation: AggregationDivide, Divisor: 4},
+		{Key: "DoorOnly", ProductType: "door", MatchNames: []string{"Створка оконная"}, Aggregation: AggregationSum},
+	}
+
+	materials := []*storage.KlaesMaterials{
+		newMaterial("Створка оконная", 2.0, 600.0),
+		newMaterial(" Створка оконная ", 6.0, 900.0),
+		newMaterial("Импост", 1.0, 600.0),
+	}
+
+	ctx := Context{Type: "window"}
+	ApplyFeatures(&ctx, materials, features)
+
+	assert.Equal(t, 2.0, ctx.Features["StvTCount600"], "только створки шириной до 615мм")
+	assert.Equal(t, 2.0, ctx.Features["StvRows"], "две строки материалов")
+	assert.Equal(t, 2.0, ctx.Features["StvForOpres"], "(2 + 6) / 4")
+	_, ok := ctx.Features["DoorOnly"]
+	assert.False(t, ok, "признак другого типа изделия не считается")
+}
+
+func TestFeatureRegistryOverridesBuiltIn(t *testing.T) {
+	ctx := Context{
+		Type:         "window",
+		StvTCount600: 5.0,
+		Features:     map[string]float64{"StvTCount600": 1.0, "HasStvT": 3.0},
+	}
+
+	assert.Equal(t, 1.0, getCountMaterials("StvTCount600", ctx, 1))
+	assert.Equal(t, 3.0, getCountMaterials("HasStvT", ctx, 1))
+
+	assert.True(t, fieldMatches("HasStvT", true, ctx))
+	assert.False(t, fieldMatches("HasStvT", false, ctx))
+	assert.True(t, fieldMatches("StvTCount600", map[string]interface{}{"max": 1.0}, ctx))
+
+	operations := []storage.Operation{{Name: "dop_time_napil"}}
+	rules := []storage.Rule{
+		{
+			Operation:      "dop_time_napil",
+			Condition:      map[string]interface{}{"HasStvT": true},
+			Mode:           "multiplied",
+			UnitField:      "HasStvT",
+			MinutesPerUnit: 2,
+		},
+	}
+
+	result := ApplyRules(operations, rules, ctx, 1)
+	assert.Equal(t, 6.0, result[0].Minutes)
+}
+
+func TestValidateFeature(t *testing.T) {
+	assert.NoError(t, ValidateFeature(storage.ContextFeature{Key: "A", MatchNames: []string{"x"}, Aggregation: AggregationSum}))
+	assert.NoError(t, ValidateFeature(storage.ContextFeature{Key: "A", MatchNames: []string{"x"}})) // пусто — sum
+	assert.Error(t, ValidateFeature(storage.ContextFeature{Key: "", MatchNames: []string{"x"}, Aggregation: AggregationSum}))
+	assert.Error(t, ValidateFeature(storage.ContextFeature{Key: "A", Aggregation: AggregationSum}))
+	assert.Error(t, ValidateFeature(storage.ContextFeature{Key: "A", MatchNames: []string{"x"}, Aggregation: AggregationDivide}))
+	assert.Error(t, ValidateFeature(storage.ContextFeature{Key: "A", MatchNames: []string{"x"}, Aggregation: "avg"}))
+}
+
+func TestEvalExpr(t *testing.T) {
+	ctx := Context{
+		Type:           "door",
+		HasImpost:      true,
+		PetliStand:     3,
+		PetliRolik:     2,
+		StvWindowCount: 6,
+		ItemCount:      2,
+		Features:       map[string]float64{"Shpros": 4},
+		Attrs:          OrderAttributes{Systema: "KBE", Profile: "58"},
+	}
+
+	tests := []struct {
+		src  string
+		want interface{}
+	}{
+		{"PetliStand > PetliRolik", true},
+		{"PetliStand <= PetliRolik", false},
+		{"StvWindowCount / 4", 1.5},
+		{"StvWindowCount / 0", 0.0},
+		{"(PetliStand + PetliRolik) * ItemCount", 10.0},
+		{"-PetliRolik + 1", -1.0},
+		{"HasImpost && !(Shpros < 4)", true},
+		{"HasImpost && Shpros > 4 || systema == 'kbe'", true},
+		{`profile == "70"`, false},
+		{"UnknownField == 0", true},
+		{"Type != 'window'", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			e, err := ParseExpr(tt.src)
+			assert.NoError(t, err)
+
+			got, err := e.eval(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseExpr_Errors(t *testing.T) {
+	for _, src := range []string{"", "PetliStand >", "(HasImpost", "a ? b", "'abc", "1 2"} {
+		_, err := ParseExpr(src)
+		assert.Error(t, err, src)
+	}
+
+	// строку нельзя сравнивать с числом
+	e, err := ParseExpr("systema == 1")
+	assert.NoError(t, err)
+	_, err = EvalBool(e, Context{})
+	assert.Error(t, err)
+}
+
+func TestMatchesCondition_Groups(t *testing.T) {
+	ctx := Context{
+		HasImpost:  true,
+		PetliStand: 3,
+		PetliRolik: 2,
+		Type:       "door",
+		Attrs:      OrderAttributes{Systema: "KBE", Profile: "58"},
+	}
+
+	tests := []struct {
+		name      string
+		condition map[string]interface{}
+		want      bool
+	}{
+		{"expr", map[string]interface{}{"$expr": "PetliStand > PetliRolik"}, true},
+		{"expr с ошибкой", map[string]interface{}{"$expr": "PetliStand >"}, false},
+		{"or", map[string]interface{}{"$or": []interface{}{
+			map[string]interface{}{"HasImpost": false},
+			map[string]interface{}{"PetliStand": map[string]interface{}{"min": 3.0}},
+		}}, true},
+		{"or без совпадений", map[string]interface{}{"$or": []interface{}{
+			map[string]interface{}{"HasImpost": false},
+			map[string]interface{}{"PetliRolik": 5.0},
+		}}, false},
+		{"and", map[string]interface{}{"$and": []interface{}{
+			map[string]interface{}{"HasImpost": true},
+			map[string]interface{}{"PetliRolik": 2.0},
+		}}, true},
+		{"not", map[string]interface{}{"$not": map[string]interface{}{"HasImpost": true}}, false},
+		{"systema строкой", map[string]interface{}{"systema": "kbe"}, true},
+		{"profile списком", map[string]interface{}{"profile": []interface{}{"70", "58"}}, true},
+		{"тип изделия", map[string]interface{}{"Type": "window"}, false},
+		{"вместе с обычным ключом", map[string]interface{}{"HasImpost": true, "systema": "Rehau"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, MatchesCondition(tt.condition, ctx))
+		})
+	}
+}
+
+func TestApplyRules_UnitFieldExpression(t *testing.T) {
+	operations := []storage.Operation{{Name: "установка створок", Minutes: 0}}
+	rules := []storage.Rule{
+		{
+			Operation:      "установка створок",
+			Condition:      map[string]interface{}{"$expr": "StvWindowCount >= 4"},
+			Mode:           "multiplied",
+			UnitField:      "StvWindowCount / 4",
+			MinutesPerUnit: 10,
+		},
+	}
+
+	result := ApplyRules(operations, rules, Context{StvWindowCount: 6}, 1)
+
+	assert.Equal(t, 15.0, result[0].Minutes, "6 / 4 * 10")
+	assert.Equal(t, 1.5, result[0].Count)
+}
+
+func TestValidateRules(t *testing.T) {
+	valid := []storage.Rule{
+		{Operation: "a", Condition: map[string]interface{}{
+			"$or": []interface{}{
+				map[string]interface{}{"$expr": "PetliStand > PetliRolik"},
+				map[string]interface{}{"$not": map[string]interface{}{"systema": []interface{}{"KBE"}}},
+			},
+		}, UnitField: "StvWindowCount / 4"},
+		{Operation: "b", Condition: map[string]interface{}{"HasImpost": true}, UnitField: "HasImpostCount"},
+	}
+	assert.NoError(t, ValidateRules(valid))
+
+	invalid := [][]storage.Rule{
+		{{Operation: "a", Condition: map[string]interface{}{"$expr": "PetliStand >"}}},
+		{{Operation: "a", Condition: map[string]interface{}{"$expr": 5.0}}},
+		{{Operation: "a", Condition: map[string]interface{}{"$or": []interface{}{}}}},
+		{{Operation: "a", Condition: map[string]interface{}{"$and": []interface{}{"HasImpost"}}}},
+		{{Operation: "a", Condition: map[string]interface{}{"$not": map[string]interface{}{"$expr": "(("}}}},
+		{{Operation: "a", Condition: map[string]interface{}{"profile": 58.0}}},
+		{{Operation: "a", UnitField: "StvWindowCount /"}},
+	}
+	for _, rules := range invalid {
+		assert.Error(t, ValidateRules(rules), "%v", rules[0])
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	valid := storage.Template{
+		Code:       "56",
+		Name:       "Дверь",
+		Operations: []storage.Operation{{Name: "сборка"}, {Name: "арка"}},
+		Rules: []storage.Rule{
+			{Operation: "сборка", Mode: "additivePlusMultiplied", UnitField: "HasImpostCount",
+				Condition: map[string]interface{}{"HasImpost": true, "PetliStand": map[string]interface{}{"min": 2.0}}},
+			{Operation: "арка", Mode: "multiplied", UnitField: "ArkaCount * 2",
+				Condition: map[string]interface{}{"$expr": "ArkaCount > 0 && profile == 'КП45'"}},
+		},
+	}
+	assert.NoError(t, ValidateTemplate(valid, []string{"ArkaCount"}))
+
+	invalid := valid
+	invalid.Operations = append(invalid.Operations, storage.Operation{Name: "сборка"})
+	invalid.Rules = []storage.Rule{
+		{Operation: "арка", Mode: "multiplied", UnitField: "ArkaCount * 2",
+			Condition: map[string]interface{}{"$expr": "ArkaCount > 0 && Profil == 'КП45'"}},
+		{Operation: "сборка", Mode: "additivePlusMultiplied",
+			Condition: map[string]interface{}{"PetliStand": map[string]interface{}{"from": 2.0}}},
+	}
+
+	err := ValidateTemplate(invalid, nil)
+	var verr *ValidationError
+	if assert.ErrorAs(t, err, &verr) {
+		assert.Equal(t, []FieldError{
+			{Field: "operations[2].name", Message: `операция "сборка" повторяется`},
+			{Field: "rules[0].condition.$expr", Message: `неизвестное поле "ArkaCount"`},
+			{Field: "rules[0].condition.$expr", Message: `неизвестное поле "Profil"`},
+			{Field: "rules[0].unitField", Message: `неизвестное поле "ArkaCount"`},
+			{Field: "rules[1].unitField", Message: "режиму additivePlusMultiplied нужен unitField"},
+			{Field: "rules[1].condition.PetliStand", Message: "ожидалось число или {min, max}"},
+		}, verr.Errors)
+	}
+}
+
+// Тест: вид изделия в условиях — Type; type_izd движку неизвестен, и валидатор его не пропускает
+func TestValidateTemplate_TypeField(t *testing.T) {
+	template := storage.Template{
+		Code:       "56",
+		Name:       "Дверь",
+		Operations: []storage.Operation{{Name: "сборка"}},
+		Rules: []storage.Rule{{Operation: "сборка", Mode: "set", SetValue: 1,
+			Condition: map[string]interface{}{"Type": "door", "$expr": "Type == 'door'"}}},
+	}
+	assert.NoError(t, ValidateTemplate(template, nil))
+
+	template.Rules[0].Condition = map[string]interface{}{"type_izd": "door"}
+	assert.Error(t, ValidateTemplate(template, nil))
+	template.Rules[0].Condition = map[string]interface{}{"$expr": "type_izd == 'door'"}
+	assert.Error(t, ValidateTemplate(template, nil))
+}
+
+// Каждое поле, которое пропускает валидатор, движок действительно считает: нулевой Context
+// удовлетворяет условию {min: 0}/false/"", а неизвестное поле — никогда
+func TestEngineFields_MatchValidator(t *testing.T) {
+	for _, field := range ConditionFields() {
+		var expected interface{} = map[string]interface{}{"min": 0.0}
+		if _, ok := boolFields[field]; ok {
+			expected = false
+		}
+		if _, ok := stringFields[field]; ok {
+			expected = ""
+		}
+		condition := map[string]interface{}{field: expected}
+
+		assert.True(t, MatchesCondition(condition, Context{}), field)
+		assert.NoError(t, ValidateRules([]storage.Rule{{Condition: condition}}), field)
+	}
+	assert.False(t, MatchesCondition(map[string]interface{}{"Unknown": map[string]interface{}{"min": 0.0}}, Context{}))
+
+	for _, field := range UnitFields() {
+		template := storage.Template{Code: "1", Name: "окно", Operations: []storage.Operation{{Name: "сборка"}},
+			Rules: []storage.Rule{{Operation: "сборка", Mode: "multiplied", UnitField: field}}}
+		assert.NoError(t, ValidateTemplate(template, nil), field)
+	}
+
+	for _, mode := range RuleModes() {
+		applied, _ := applyRule(&storage.Operation{}, storage.Rule{Mode: mode}, Context{}, 1)
+		assert.True(t, applied, mode)
+	}
+	applied, _ := applyRule(&storage.Operation{}, storage.Rule{Mode: "replace"}, Context{}, 1)
+	assert.False(t, applied)
+}
+
+func TestRunTemplateCases(t *testing.T) {
+	template := &storage.Template{
+		Operations: []storage.Operation{
+			{Name: "stoyki", Value: 0.1, Minutes: 6, Count: 1},
+			{Name: "upakovka", Value: 0.2, Minutes: 12, Count: 1},
+		},
+		Rules: []storage.Rule{
+			{Operation: "stoyki", Condition: map[string]interface{}{}, Mode: "multiplied", UnitField: "MullionCount", ValuePerUnit: 0.05, MinutesPerUnit: 3},
+		},
+	}
+	materials := []*storage.KlaesMaterials{{NameMat: "Стойка", Count: 3}, {NameMat: "Стеклопакет", Count: 2, Width: 1000, Height: 1000}}
+
+	cases := []storage.TemplateTestCase{
+		{ID: 1, Name: "три стойки", TypeIzd: "vitrage", ItemCount: 2, Materials: materials, Expected: []storage.ExpectedOperation{
+			{Name: "stoyki", Value: 0.15, Minutes: 9},
+			{Name: "upakovka", Value: 0.4, Minutes: 24},
+		}},
+		{ID: 2, Name: "устаревшие ожидания", TypeIzd: "vitrage", ItemCount: 1, Materials: materials, Expected: []storage.ExpectedOperation{
+			{Name: "stoyki", Value: 0.15, Minutes: 10},
+			{Name: "pokraska", Value: 1},
+		}},
+		{ID: 3, Name: "неизвестный тип", TypeIzd: "arka", ItemCount: 1, Materials: materials},
+	}
+
+	report := RunTemplateCases(template, cases, nil)
+
+	assert.Equal(t, 1, report.Passed)
+	assert.Equal(t, 2, report.Failed)
+	assert.True(t, report.Cases[0].Passed)
+	assert.Equal(t, []CaseDiff{
+		{Operation: "stoyki", Field: "minutes", Expected: 10, Actual: 9},
+		{Operation: "pokraska", Field: "missing"},
+	}, report.Cases[1].Diffs)
+	assert.Contains(t, report.Cases[2].Error, "неизвестный тип изделия")
+
+	operations, err := RunCase(template, cases[0], nil)
+	assert.NoError(t, err)
+	assert.Equal(t, cases[0].Expected, ExpectedFromOperations(operations))
+
+	// Атрибуты и тип изделия в примере работают в условиях так же, как при расчёте нормировки
+	template.Rules = append(template.Rules, storage.Rule{Operation: "upakovka", Mode: "set", SetValue: 1, SetMinutes: 60,
+		Condition: map[string]interface{}{"systema": "kbe", "Type": "vitrage"}})
+	withAttrs := cases[0]
+	withAttrs.Systema = "KBE"
+	operations, err = RunCase(template, withAttrs, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 60.0, operations[1].Minutes)
+}
+
+func TestApplyRules_PriorityExclusiveStop(t *testing.T) {
+	operations := []storage.Operation{{Name: "сборка", Value: 10, Minutes: 10, Count: 1}}
+	ctx := Context{HasImpost: true, ImpostCount: 2}
+
+	always := map[string]interface{}{"HasImpost": true}
+	never := map[string]interface{}{"HasImpost": false}
+
+	set := func(v float64) storage.Rule {
+		return storage.Rule{Operation: "сборка", Condition: always, Mode: "set", SetValue: v, SetMinutes: v}
+	}
+	additive := func(v float64) storage.Rule {
+		return storage.Rule{Operation: "сборка", Condition: always, Mode: "additive", ValuePerUnit: v, MinutesPerUnit: v}
+	}
+	minus := func(v float64) storage.Rule {
+		return storage.Rule{Operation: "сборка", Condition: always, Mode: "minus", ValuePerUnit: v, MinutesPerUnit: v}
+	}
+	multiplied := func(v float64) storage.Rule {
+		return storage.Rule{Operation: "сборка", Condition: always, Mode: "multiplied", UnitField: "ImpostCount", ValuePerUnit: v, MinutesPerUnit: v}
+	}
+	with := func(r storage.Rule, f func(r *storage.Rule)) storage.Rule {
+		f(&r)
+		return r
+	}
+
+	tests := []struct {
+		name  string
+		rules []storage.Rule
+		want  float64
+	}{
+		{
+			name:  "без приоритетов — порядок шаблона (set, затем additive)",
+			rules: []storage.Rule{set(5), additive(1)},
+			want:  6,
+		},
+		{
+			name:  "без приоритетов — additive перетирается следующим set",
+			rules: []storage.Rule{additive(1), set(5)},
+			want:  5,
+		},
+		{
+			name:  "приоритет переставляет set перед additive",
+			rules: []storage.Rule{additive(1), with(set(5), func(r *storage.Rule) { r.Priority = 10 })},
+			want:  6,
+		},
+		{
+			name:  "равный приоритет сохраняет порядок шаблона",
+			rules: []storage.Rule{with(minus(2), func(r *storage.Rule) { r.Priority = 1 }), with(set(5), func(r *storage.Rule) { r.Priority = 1 })},
+			want:  5,
+		},
+		{
+			name:  "отрицательный приоритет уходит в конец",
+			rules: []storage.Rule{with(set(5), func(r *storage.Rule) { r.Priority = -1 }), additive(1)},
+			want:  5,
+		},
+		{
+			name:  "stop прерывает остальные правила",
+			rules: []storage.Rule{with(set(5), func(r *storage.Rule) { r.Stop = true }), additive(1), minus(3)},
+			want:  5,
+		},
+		{
+			name:  "stop несовпавшего правила не действует",
+			rules: []storage.Rule{with(set(5), func(r *storage.Rule) { r.Stop = true; r.Condition = never }), additive(1)},
+			want:  11,
+		},
+		{
+			name:  "stop после additive с приоритетом",
+			rules: []storage.Rule{set(5), with(additive(1), func(r *storage.Rule) { r.Priority = 5; r.Stop = true })},
+			want:  11,
+		},
+		{
+			name:  "exclusive применяется в одиночку даже если стоит последним",
+			rules: []storage.Rule{additive(1), minus(3), with(multiplied(4), func(r *storage.Rule) { r.Exclusive = true })},
+			want:  8,
+		},
+		{
+			name:  "несовпавший exclusive не мешает остальным",
+			rules: []storage.Rule{additive(1), with(set(100), func(r *storage.Rule) { r.Exclusive = true; r.Condition = never })},
+			want:  11,
+		},
+		{
+			name: "из двух exclusive выигрывает более приоритетный",
+			rules: []storage.Rule{
+				with(set(20), func(r *storage.Rule) { r.Exclusive = true }),
+				with(set(30), func(r *storage.Rule) { r.Exclusive = true; r.Priority = 1 }),
+			},
+			want: 30,
+		},
+		{
+			name:  "additivePlusMultiplied и minus складываются по порядку",
+			rules: []storage.Rule{with(multiplied(1), func(r *storage.Rule) { r.Mode = "additivePlusMultiplied" }), minus(1)},
+			want:  11,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ApplyRules(operations, tt.rules, ctx, 1)
+			assert.Equal(t, tt.want, result[0].Value)
+			assert.Equal(t, tt.want, result[0].Minutes)
+		})
+	}
+}
+
+func TestApplyRulesExplain_Skipped(t *testing.T) {
+	operations := []storage.Operation{{Name: "сборка", Value: 10, Minutes: 10}}
+	rules := []storage.Rule{
+		{Operation: "сборка", Condition: map[string]interface{}{}, Mode: "additive", ValuePerUnit: 1},
+		{Operation: "сборка", Condition: map[string]interface{}{}, Mode: "set", SetValue: 5, Priority: 2, Stop: true},
+		{Operation: "другая", Condition: map[string]interface{}{}, Mode: "set", SetValue: 1, Exclusive: true},
+	}
+
+	result, trace := ApplyRulesExplain(operations, rules, Context{}, 1)
+
+	assert.Equal(t, 5.0, result[0].Value)
+	assert.Len(t, trace[0].Rules, 2)
+	assert.Equal(t, 1, trace[0].Rules[0].Index, "правило с priority=2 рассматривается первым")
+	assert.True(t, trace[0].Rules[0].Applied)
+	assert.Equal(t, 0, trace[0].Rules[1].Index)
+	assert.Equal(t, "stop", trace[0].Rules[1].Skipped)
+	assert.False(t, trace[0].Rules[1].Applied)
+}
@@ -0,0 +1,271 @@
+package recalculate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"vue-golang/internal/storage"
+)
+
+// FieldError — ошибка в конкретном поле шаблона, Field — путь вида rules[2].condition.$or[0].HasImpost
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError — все найденные ошибки шаблона
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		parts = append(parts, fe.Field+": "+fe.Message)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// ValidateTemplate проверяет шаблон перед сохранением: обязательные поля, уникальность операций,
+// режимы правил, ссылки правил на операции, ключи условий и unitField — по полям движка
+// и признакам реестра featureKeys. Возвращает *ValidationError со всеми найденными ошибками.
+func ValidateTemplate(template storage.Template, featureKeys []string) error {
+	v := &templateValidator{
+		strict:     true,
+		operations: make(map[string]bool, len(template.Operations)),
+		features:   make(map[string]bool, len(featureKeys)),
+	}
+	for _, key := range featureKeys {
+		v.features[key] = true
+	}
+
+	if strings.TrimSpace(template.Code) == "" {
+		v.add("code", "обязательное поле")
+	}
+	if strings.TrimSpace(template.Name) == "" {
+		v.add("name", "обязательное поле")
+	}
+	if len(template.Operations) == 0 {
+		v.add("operations", "нужна хотя бы одна операция")
+	}
+
+	for i, op := range template.Operations {
+		field := fmt.Sprintf("operations[%d].name", i)
+		switch {
+		case strings.TrimSpace(op.Name) == "":
+			v.add(field, "обязательное поле")
+		case v.operations[op.Name]:
+			v.add(field, fmt.Sprintf("операция %q повторяется", op.Name))
+		}
+		v.operations[op.Name] = true
+	}
+
+	v.rules(template.Rules)
+
+	return v.result()
+}
+
+// ValidateRules проверяет только синтаксис правил: структуру условий $or/$and/$not,
+// выражения $expr и unitField, типы значений строковых атрибутов (systema, profile, Type)
+func ValidateRules(rules []storage.Rule) error {
+	v := &templateValidator{}
+	v.rules(rules)
+	return v.result()
+}
+
+type templateValidator struct {
+	// strict — проверять режимы, операции и ключи по движку, иначе только синтаксис
+	strict     bool
+	operations map[string]bool
+	features   map[string]bool
+	errs       []FieldError
+}
+
+func (v *templateValidator) add(field, message string) {
+	v.errs = append(v.errs, FieldError{Field: field, Message: message})
+}
+
+func (v *templateValidator) result() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: v.errs}
+}
+
+func (v *templateValidator) rules(rules []storage.Rule) {
+	for i, rule := range rules {
+		path := fmt.Sprintf("rules[%d]", i)
+
+		if v.strict {
+			switch {
+			case rule.Operation == "":
+				v.add(path+".operation", "обязательное поле")
+			case !v.operations[rule.Operation]:
+				v.add(path+".operation", fmt.Sprintf("операции %q нет в шаблоне", rule.Operation))
+			}
+
+			switch {
+			case rule.Mode == "":
+				v.add(path+".mode", "обязательное поле")
+			case !knownMode(rule.Mode):
+				v.add(path+".mode", fmt.Sprintf("неизвестный режим %q, допустимы: %s", rule.Mode, strings.Join(RuleModes(), ", ")))
+			case ruleModes[rule.Mode].unit && rule.UnitField == "":
+				v.add(path+".unitField", fmt.Sprintf("режиму %s нужен unitField", rule.Mode))
+			}
+		}
+
+		v.condition(path+".condition", rule.Condition)
+
+		if rule.UnitField != "" {
+			v.unitField(path+".unitField", rule.UnitField)
+		}
+	}
+}
+
+func (v *templateValidator) unitField(path, field string) {
+	if isIdent(field) {
+		if v.strict && !isUnitField(field) && !v.features[field] {
+			v.add(path, fmt.Sprintf("неизвестное поле %q", field))
+		}
+		return
+	}
+
+	v.expr(path, field)
+}
+
+func (v *templateValidator) expr(path, src string) {
+	e, err := ParseExpr(src)
+	if err != nil {
+		v.add(path, err.Error())
+		return
+	}
+
+	if !v.strict {
+		return
+	}
+	for _, name := range ExprIdents(e) {
+		if !isExprField(name) && !v.features[name] {
+			v.add(path, fmt.Sprintf("неизвестное поле %q", name))
+		}
+	}
+}
+
+func (v *templateValidator) condition(path string, condition map[string]interface{}) {
+	keys := make([]string, 0, len(condition))
+	for key := range condition {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		expected := condition[key]
+		field := path + "." + key
+
+		switch key {
+		case condExpr:
+			src, ok := expected.(string)
+			if !ok {
+				v.add(field, "ожидалась строка")
+				continue
+			}
+			v.expr(field, src)
+		case condOr, condAnd:
+			group, ok := expected.([]interface{})
+			if !ok || len(group) == 0 {
+				v.add(field, "ожидался непустой массив условий")
+				continue
+			}
+			for i, item := range group {
+				sub, ok := item.(map[string]interface{})
+				if !ok {
+					v.add(fmt.Sprintf("%s[%d]", field, i), "элемент массива должен быть объектом условия")
+					continue
+				}
+				v.condition(fmt.Sprintf("%s[%d]", field, i), sub)
+			}
+		case condNot:
+			sub, ok := expected.(map[string]interface{})
+			if !ok {
+				v.add(field, "ожидался объект условия")
+				continue
+			}
+			v.condition(field, sub)
+		default:
+			if _, ok := stringFields[key]; ok {
+				v.stringValue(field, expected)
+				continue
+			}
+			if !v.strict {
+				continue
+			}
+			if v.features[key] {
+				if !isBoolValue(expected) && !isNumberValue(expected) {
+					v.add(field, "ожидалось true/false, число или {min, max}")
+				}
+				continue
+			}
+
+			_, wantBool := boolFields[key]
+			_, wantNumber := numberFields[key]
+			switch {
+			case wantBool:
+				if !isBoolValue(expected) {
+					v.add(field, "ожидалось true/false")
+				}
+			case wantNumber:
+				if !isNumberValue(expected) {
+					v.add(field, "ожидалось число или {min, max}")
+				}
+			default:
+				v.add(field, fmt.Sprintf("неизвестное поле %q", key))
+			}
+		}
+	}
+}
+
+func (v *templateValidator) stringValue(field string, expected interface{}) {
+	switch val := expected.(type) {
+	case string:
+	case []interface{}:
+		for _, item := range val {
+			if _, ok := item.(string); !ok {
+				v.add(field, "ожидался список строк")
+				return
+			}
+		}
+	default:
+		v.add(field, "ожидалась строка или список строк")
+	}
+}
+
+func knownMode(mode string) bool {
+	_, ok := ruleModes[mode]
+	return ok
+}
+
+func isBoolValue(val interface{}) bool {
+	_, ok := val.(bool)
+	return ok
+}
+
+// isNumberValue — число или объект {min, max} с числовыми границами, как ждёт compareFloatField
+func isNumberValue(val interface{}) bool {
+	switch v := val.(type) {
+	case float64:
+		return true
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return false
+		}
+		for k, bound := range v {
+			if k != "min" && k != "max" {
+				return false
+			}
+			if _, ok := bound.(float64); !ok {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
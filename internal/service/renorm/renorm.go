@@ -0,0 +1,280 @@
+package renorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/sync/errgroup"
+	"math"
+	"vue-golang/internal/service/recalculate"
+	"vue-golang/internal/storage"
+)
+
+// parallel — сколько изделий пересчитывается одновременно, как в пакетном расчёте заказа
+const parallel = 4
+
+var ErrNoProducts = errors.New("не выбрано ни одного изделия")
+
+// Виды изменения операции
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+type Storage interface {
+	GetRenormCandidates(ctx context.Context, templateCode string) ([]storage.RenormCandidate, error)
+	RenormProduct(ctx context.Context, id int64, update storage.RenormUpdate) (int64, error)
+}
+
+type NormCalculator interface {
+	CalculateNorm(ctx context.Context, orderNum string, pos int, typeIzd string, templateCode string, itemCount int, permisDopMaterial bool, attrs recalculate.OrderAttributes) ([]storage.Operation, recalculate.Context, error)
+}
+
+// OperationDelta — изменение одной операции; Executors — сколько сотрудников на ней назначено сейчас
+type OperationDelta struct {
+	Name       string  `json:"operation_name"`
+	Label      string  `json:"operation_label"`
+	Change     string  `json:"change"`
+	OldValue   float64 `json:"old_value"`
+	NewValue   float64 `json:"new_value"`
+	OldMinutes float64 `json:"old_minutes"`
+	NewMinutes float64 `json:"new_minutes"`
+	Executors  int     `json:"executors,omitempty"`
+}
+
+// ProductPreview — разница между сохранённой нормировкой изделия и пересчётом по действующему шаблону.
+// DroppedExecutors — назначения, которые пропадут вместе с исчезнувшими операциями.
+type ProductPreview struct {
+	ProductID          int64            `json:"product_id"`
+	ParentProductID    *int64           `json:"parent_product_id"`
+	OrderNum           string           `json:"order_num"`
+	Position           int              `json:"position"`
+	Name               string           `json:"name"`
+	Status             string           `json:"status"`
+	OldTemplateVersion *int             `json:"old_template_version"`
+	NewTemplateVersion int              `json:"new_template_version"`
+	OldValue           float64          `json:"old_value"`
+	NewValue           float64          `json:"new_value"`
+	OldMinutes         float64          `json:"old_minutes"`
+	NewMinutes         float64          `json:"new_minutes"`
+	Changed            bool             `json:"changed"`
+	Operations         []OperationDelta `json:"operations"`
+	DroppedExecutors   int              `json:"dropped_executors"`
+	Error              string           `json:"error,omitempty"`
+
+	operations []storage.NormOperation
+}
+
+type Preview struct {
+	TemplateCode string           `json:"template_code"`
+	Products     []ProductPreview `json:"products"`
+	Changed      int              `json:"changed"`
+	Failed       int              `json:"failed"`
+}
+
+// Applied — итог применения: Products — выбранные изделия (с ошибкой, если пересчёт или запись не удались)
+type Applied struct {
+	TemplateCode string           `json:"template_code"`
+	Products     []ProductPreview `json:"products"`
+	Applied      int              `json:"applied"`
+	Failed       int              `json:"failed"`
+}
+
+type Service struct {
+	storage Storage
+	calc    NormCalculator
+}
+
+func NewService(storage Storage, calc NormCalculator) *Service {
+	return &Service{storage: storage, calc: calc}
+}
+
+// Preview пересчитывает все нефинальные изделия шаблона и показывает разницу по каждому, ничего не записывая
+func (s *Service) Preview(ctx context.Context, templateCode string) (*Preview, error) {
+	const op = "service.renorm.Preview"
+
+	candidates, err := s.storage.GetRenormCandidates(ctx, templateCode)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	products := s.recalculate(ctx, templateCode, candidates)
+
+	preview := &Preview{TemplateCode: templateCode, Products: products}
+	for _, p := range products {
+		switch {
+		case p.Error != "":
+			preview.Failed++
+		case p.Changed:
+			preview.Changed++
+		}
+	}
+
+	return preview, nil
+}
+
+// Apply пересчитывает выбранные изделия заново (шаблон мог измениться после предпросмотра) и записывает изменившиеся.
+// Изделие, которое больше не подходит (закрыто, отменено, перенормировано), возвращается с ошибкой.
+func (s *Service) Apply(ctx context.Context, templateCode string, productIDs []int64, actor string) (*Applied, error) {
+	const op = "service.renorm.Apply"
+
+	if len(productIDs) == 0 {
+		return nil, ErrNoProducts
+	}
+
+	candidates, err := s.storage.GetRenormCandidates(ctx, templateCode)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	byID := make(map[int64]storage.RenormCandidate, len(candidates))
+	for _, c := range candidates {
+		byID[c.ID] = c
+	}
+
+	result := &Applied{TemplateCode: templateCode, Products: make([]ProductPreview, 0, len(productIDs))}
+	selected := make([]storage.RenormCandidate, 0, len(productIDs))
+	for _, id := range productIDs {
+		c, ok := byID[id]
+		if !ok {
+			result.Products = append(result.Products, ProductPreview{ProductID: id, Error: "изделие не нормировано по шаблону или уже закрыто"})
+			result.Failed++
+			continue
+		}
+		selected = append(selected, c)
+	}
+
+	for _, p := range s.recalculate(ctx, templateCode, selected) {
+		if p.Error == "" && p.Changed {
+			dropped, err := s.storage.RenormProduct(ctx, p.ProductID, storage.RenormUpdate{
+				Operations:      p.operations,
+				TotalTime:       p.NewValue,
+				TemplateVersion: p.NewTemplateVersion,
+				ChangedBy:       actor,
+			})
+			if err != nil {
+				p.Error = err.Error()
+			} else {
+				p.DroppedExecutors = int(dropped)
+			}
+		}
+
+		if p.Error != "" {
+			result.Failed++
+		} else if p.Changed {
+			result.Applied++
+		}
+		result.Products = append(result.Products, p)
+	}
+
+	return result, nil
+}
+
+// recalculate считает изделия параллельно; ошибка одного изделия попадает в его Error
+func (s *Service) recalculate(ctx context.Context, templateCode string, candidates []storage.RenormCandidate) []ProductPreview {
+	products := make([]ProductPreview, len(candidates))
+
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(parallel)
+
+	for i, c := range candidates {
+		p := &products[i]
+		*p = ProductPreview{
+			ProductID:          c.ID,
+			ParentProductID:    c.ParentProductID,
+			OrderNum:           c.OrderNum,
+			Position:           c.Position,
+			Name:               c.Name,
+			Status:             c.Status,
+			OldTemplateVersion: c.TemplateVersion,
+			Operations:         make([]OperationDelta, 0),
+		}
+
+		g.Go(func() error {
+			itemCount := int(math.Round(c.Count))
+			if itemCount <= 0 {
+				itemCount = 1
+			}
+
+			// Версия шаблона не передаётся — пересчёт идёт по действующей
+			attrs := recalculate.OrderAttributes{Systema: c.Systema, Profile: c.Profile}
+			operations, normCtx, err := s.calc.CalculateNorm(gCtx, c.OrderNum, c.Position, c.Type, templateCode, itemCount, c.Type == "door", attrs)
+			if err != nil {
+				p.Error = err.Error()
+				return nil
+			}
+
+			p.NewTemplateVersion = normCtx.TemplateVersion
+			p.operations = normOperations(operations, c.Operations)
+			compare(p, c, p.operations)
+			return nil
+		})
+	}
+
+	// горутины ошибок не возвращают, Wait нужен только чтобы дождаться всех изделий
+	_ = g.Wait()
+
+	return products
+}
+
+// normOperations — пересчитанные операции в виде для сохранения. Нулевая операция, которой не было в нормировке,
+// не добавляется: при ручной нормировке такие операции не сохраняют.
+func normOperations(operations []storage.Operation, saved []storage.NormOperation) []storage.NormOperation {
+	had := make(map[string]bool, len(saved))
+	for _, o := range saved {
+		had[o.Name] = true
+	}
+
+	result := make([]storage.NormOperation, 0, len(operations))
+	for _, o := range operations {
+		if o.Value == 0 && o.Minutes == 0 && !had[o.Name] {
+			continue
+		}
+		result = append(result, storage.NormOperation{Name: o.Name, Label: o.Label, Count: o.Count, Value: o.Value, Minutes: o.Minutes})
+	}
+
+	return result
+}
+
+// compare заполняет итоги и разницу по операциям между сохранённой нормировкой и пересчётом
+func compare(p *ProductPreview, c storage.RenormCandidate, operations []storage.NormOperation) {
+	fresh := make(map[string]storage.NormOperation, len(operations))
+	for _, o := range operations {
+		fresh[o.Name] = o
+		p.NewValue += o.Value
+		p.NewMinutes += o.Minutes
+	}
+
+	saved := make(map[string]bool, len(c.Operations))
+	for _, o := range c.Operations {
+		saved[o.Name] = true
+		p.OldValue += o.Value
+		p.OldMinutes += o.Minutes
+
+		n, ok := fresh[o.Name]
+		switch {
+		case !ok:
+			p.Operations = append(p.Operations, OperationDelta{Name: o.Name, Label: o.Label, Change: ChangeRemoved,
+				OldValue: o.Value, OldMinutes: o.Minutes, Executors: c.Executors[o.Name]})
+			p.DroppedExecutors += c.Executors[o.Name]
+		case !sameAmount(o.Value, n.Value) || !sameAmount(o.Minutes, n.Minutes):
+			p.Operations = append(p.Operations, OperationDelta{Name: o.Name, Label: n.Label, Change: ChangeChanged,
+				OldValue: o.Value, NewValue: n.Value, OldMinutes: o.Minutes, NewMinutes: n.Minutes, Executors: c.Executors[o.Name]})
+		}
+	}
+
+	for _, o := range operations {
+		if !saved[o.Name] {
+			p.Operations = append(p.Operations, OperationDelta{Name: o.Name, Label: o.Label, Change: ChangeAdded,
+				NewValue: o.Value, NewMinutes: o.Minutes})
+		}
+	}
+
+	p.Changed = len(p.Operations) > 0
+}
+
+// sameAmount — значения из базы хранятся с округлением, поэтому сравниваем с допуском
+func sameAmount(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
@@ -0,0 +1,111 @@
+package renorm
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"vue-golang/internal/service/recalculate"
+	"vue-golang/internal/storage"
+)
+
+type fakeStorage struct {
+	mu         sync.Mutex
+	candidates []storage.RenormCandidate
+	updates    map[int64]storage.RenormUpdate
+}
+
+func (f *fakeStorage) GetRenormCandidates(ctx context.Context, templateCode string) ([]storage.RenormCandidate, error) {
+	return f.candidates, nil
+}
+
+func (f *fakeStorage) RenormProduct(ctx context.Context, id int64, update storage.RenormUpdate) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.updates[id] = update
+	return 1, nil
+}
+
+type fakeCalc struct{}
+
+// позиция 1 — шаблон исправлен: резка дороже, упаковка исчезла, добавилась герметизация; позиция 2 — без изменений
+func (fakeCalc) CalculateNorm(ctx context.Context, orderNum string, pos int, typeIzd string, templateCode string, itemCount int, permisDopMaterial bool, attrs recalculate.OrderAttributes) ([]storage.Operation, recalculate.Context, error) {
+	switch pos {
+	case 1:
+		return []storage.Operation{
+			{Name: "cut", Label: "Резка", Value: 0.6, Minutes: 36},
+			{Name: "seal", Label: "Герметизация", Value: 0.2, Minutes: 12},
+			{Name: "extra", Label: "Доп.", Value: 0, Minutes: 0},
+		}, recalculate.Context{TemplateVersion: 3}, nil
+	case 2:
+		return []storage.Operation{{Name: "cut", Label: "Резка", Value: 0.5, Minutes: 30}}, recalculate.Context{TemplateVersion: 3}, nil
+	}
+	return nil, recalculate.Context{}, errors.New("materials: нет материалов")
+}
+
+func candidates() []storage.RenormCandidate {
+	return []storage.RenormCandidate{
+		{
+			ID: 10, OrderNum: "Q6-1", Position: 1, Count: 1, Type: "window",
+			Operations: []storage.NormOperation{
+				{Name: "cut", Label: "Резка", Value: 0.5, Minutes: 30},
+				{Name: "pack", Label: "Упаковка", Value: 0.1, Minutes: 6},
+			},
+			Executors: map[string]int{"cut": 1, "pack": 2},
+		},
+		{
+			ID: 11, OrderNum: "Q6-1", Position: 2, Count: 1, Type: "window",
+			Operations: []storage.NormOperation{{Name: "cut", Label: "Резка", Value: 0.5, Minutes: 30}},
+			Executors:  map[string]int{},
+		},
+		{ID: 12, OrderNum: "Q6-1", Position: 3, Count: 1, Type: "window", Executors: map[string]int{}},
+	}
+}
+
+// Тест: предпросмотр показывает изменённые, удалённые и новые операции и не трогает хранилище
+func TestPreview(t *testing.T) {
+	st := &fakeStorage{candidates: candidates(), updates: map[int64]storage.RenormUpdate{}}
+
+	preview, err := NewService(st, fakeCalc{}).Preview(context.Background(), "window")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, preview.Changed)
+	assert.Equal(t, 1, preview.Failed)
+	assert.Empty(t, st.updates)
+
+	p := preview.Products[0]
+	assert.True(t, p.Changed)
+	assert.InDelta(t, 0.6, p.OldValue, 1e-9)
+	assert.InDelta(t, 0.8, p.NewValue, 1e-9)
+	assert.Equal(t, 2, p.DroppedExecutors)
+
+	changes := map[string]string{}
+	for _, d := range p.Operations {
+		changes[d.Name] = d.Change
+	}
+	assert.Equal(t, map[string]string{"cut": ChangeChanged, "pack": ChangeRemoved, "seal": ChangeAdded}, changes)
+
+	assert.False(t, preview.Products[1].Changed)
+	assert.NotEmpty(t, preview.Products[2].Error)
+}
+
+// Тест: применяются только выбранные и изменившиеся изделия; нулевые новые операции не сохраняются
+func TestApply(t *testing.T) {
+	st := &fakeStorage{candidates: candidates(), updates: map[int64]storage.RenormUpdate{}}
+
+	applied, err := NewService(st, fakeCalc{}).Apply(context.Background(), "window", []int64{10, 11, 99}, "admin")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, applied.Applied)
+	assert.Equal(t, 1, applied.Failed)
+
+	update, ok := st.updates[10]
+	assert.True(t, ok)
+	assert.NotContains(t, st.updates, int64(11))
+	assert.Equal(t, 3, update.TemplateVersion)
+	assert.InDelta(t, 0.8, update.TotalTime, 1e-9)
+	assert.Equal(t, "admin", update.ChangedBy)
+	assert.Len(t, update.Operations, 2)
+
+	_, err = NewService(st, fakeCalc{}).Apply(context.Background(), "window", nil, "admin")
+	assert.ErrorIs(t, err, ErrNoProducts)
+}
@@ -0,0 +1,132 @@
+package statement
+
+import (
+	"context"
+	"fmt"
+	"github.com/xuri/excelize/v2"
+	"time"
+	"vue-golang/internal/storage"
+)
+
+// Excel — ведомость сотрудника файлом xlsx
+func (s *Service) Excel(ctx context.Context, employeeID int64, from, to time.Time) ([]byte, error) {
+	st, err := s.Statement(ctx, employeeID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return WriteExcel(st)
+}
+
+// WriteExcel раскладывает ведомость на лист: шапка с сотрудником и периодом, строки операций,
+// затем итоги по заказам, по дням и за период
+func WriteExcel(st storage.EmployeeStatement) ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+	sheet := "Ведомость"
+	f.SetSheetName("Sheet1", sheet)
+
+	headerStyle, _ := f.NewStyle(&excelize.Style{
+		Font:   &excelize.Font{Bold: true},
+		Fill:   excelize.Fill{Type: "pattern", Color: []string{"E0E0E0"}, Pattern: 1},
+		Border: []excelize.Border{{Type: "bottom", Color: "000000", Style: 2}},
+	})
+	boldStyle, _ := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+
+	f.SetCellValue(sheet, "A1", fmt.Sprintf("Ведомость выработки: %s", st.Employee.Name))
+	f.SetCellValue(sheet, "A2", fmt.Sprintf("Период: %s — %s", st.From.Format("02.01.2006"), st.To.Format("02.01.2006")))
+	f.SetCellStyle(sheet, "A1", "A1", boldStyle)
+
+	headers := []string{"Дата", "№ Заказа", "Позиция", "Изделие", "Операция", "Н/мин", "Н/час", "Факт мин", "Факт н/час", "Коэф.", "Множитель", "Сумма, руб", "Коэф. действует"}
+	row := 4
+	writeHeader(f, sheet, row, headers, headerStyle)
+
+	for _, l := range st.Lines {
+		row++
+		f.SetCellValue(sheet, cellName(1, row), l.Date.Format("02.01.2006"))
+		f.SetCellValue(sheet, cellName(2, row), l.OrderNum)
+		f.SetCellValue(sheet, cellName(3, row), l.Position)
+		f.SetCellValue(sheet, cellName(4, row), productName(l))
+		f.SetCellValue(sheet, cellName(5, row), l.OperationLabel)
+		f.SetCellValue(sheet, cellName(6, row), l.NormMinutes)
+		f.SetCellValue(sheet, cellName(7, row), l.NormValue)
+		f.SetCellValue(sheet, cellName(8, row), l.ActualMinutes)
+		f.SetCellValue(sheet, cellName(9, row), l.ActualValue)
+		if l.Coefficient != nil {
+			f.SetCellValue(sheet, cellName(10, row), *l.Coefficient)
+			f.SetCellValue(sheet, cellName(11, row), l.CustomerFactor)
+			f.SetCellValue(sheet, cellName(12, row), l.Money)
+		} else {
+			f.SetCellValue(sheet, cellName(10, row), "-")
+		}
+		if l.CoefficientFrom != nil {
+			f.SetCellValue(sheet, cellName(13, row), "с "+l.CoefficientFrom.Format("02.01.2006"))
+		} else if l.CoefficientSource == storage.CoefSourceProduct {
+			f.SetCellValue(sheet, cellName(13, row), "вручную")
+		}
+	}
+
+	row += 2
+	row = writeTotals(f, sheet, row, "№ Заказа", st.Orders, headerStyle)
+	row += 2
+	row = writeTotals(f, sheet, row, "Дата", st.Days, headerStyle)
+
+	row += 2
+	writeHeader(f, sheet, row, []string{"Итого за период", "Операций", "Н/мин", "Факт мин", "Факт н/час", "Сумма, руб"}, headerStyle)
+	row++
+	writeTotalRow(f, sheet, row, st.Total)
+	f.SetCellStyle(sheet, cellName(1, row), cellName(6, row), boldStyle)
+
+	if st.MissingCoefficient > 0 {
+		row += 2
+		f.SetCellValue(sheet, cellName(1, row), fmt.Sprintf("Без коэффициента (сумма не посчитана): %d", st.MissingCoefficient))
+	}
+
+	f.SetColWidth(sheet, "A", "C", 12)
+	f.SetColWidth(sheet, "D", "E", 30)
+	f.SetColWidth(sheet, "F", "L", 12)
+	f.SetColWidth(sheet, "M", "M", 16)
+
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeTotals(f *excelize.File, sheet string, row int, keyHeader string, totals []storage.StatementTotal, style int) int {
+	writeHeader(f, sheet, row, []string{keyHeader, "Операций", "Н/мин", "Факт мин", "Факт н/час", "Сумма, руб"}, style)
+	for _, t := range totals {
+		row++
+		writeTotalRow(f, sheet, row, t)
+	}
+	return row
+}
+
+func writeTotalRow(f *excelize.File, sheet string, row int, t storage.StatementTotal) {
+	f.SetCellValue(sheet, cellName(1, row), t.Key)
+	f.SetCellValue(sheet, cellName(2, row), t.Operations)
+	f.SetCellValue(sheet, cellName(3, row), t.NormMinutes)
+	f.SetCellValue(sheet, cellName(4, row), t.ActualMinutes)
+	f.SetCellValue(sheet, cellName(5, row), t.ActualValue)
+	f.SetCellValue(sheet, cellName(6, row), t.Money)
+}
+
+func writeHeader(f *excelize.File, sheet string, row int, headers []string, style int) {
+	for i, name := range headers {
+		f.SetCellValue(sheet, cellName(i+1, row), name)
+	}
+	f.SetCellStyle(sheet, cellName(1, row), cellName(len(headers), row), style)
+}
+
+func productName(l storage.StatementLine) string {
+	if l.TypeIzd != "" {
+		return l.TypeIzd
+	}
+	return l.Name
+}
+
+func cellName(col, row int) string {
+	name, _ := excelize.CoordinatesToCellName(col, row)
+	return name
+}
@@ -0,0 +1,120 @@
+package statement
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+	"vue-golang/internal/storage"
+)
+
+var ErrEmployeeNotFound = errors.New("сотрудник не найден")
+
+type Storage interface {
+	GetEmployeeStatementLines(ctx context.Context, employeeID int64, from, to time.Time) ([]storage.StatementLine, error)
+	GetAssignmentEmployees(ctx context.Context, ids []int64) (map[int64]storage.AssignmentEmployee, error)
+}
+
+type Service struct {
+	storage Storage
+}
+
+func NewService(storage Storage) *Service {
+	return &Service{storage: storage}
+}
+
+// Statement — ведомость сотрудника за период [from, to] по датам готовности изделий
+func (s *Service) Statement(ctx context.Context, employeeID int64, from, to time.Time) (storage.EmployeeStatement, error) {
+	const op = "service.statement.Statement"
+
+	employees, err := s.storage.GetAssignmentEmployees(ctx, []int64{employeeID})
+	if err != nil {
+		return storage.EmployeeStatement{}, fmt.Errorf("%s: %w", op, err)
+	}
+	employee, ok := employees[employeeID]
+	if !ok {
+		return storage.EmployeeStatement{}, fmt.Errorf("%s: id=%d: %w", op, employeeID, ErrEmployeeNotFound)
+	}
+
+	lines, err := s.storage.GetEmployeeStatementLines(ctx, employeeID, from, to)
+	if err != nil {
+		return storage.EmployeeStatement{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return Build(storage.GetWorkers{ID: employee.ID, Name: employee.Name}, from, to, lines), nil
+}
+
+// Build считает деньги по строкам (storage.ExecutorMoney) и итоги по заказам, дням и за период.
+// Итоги складываются из неокруглённых денег строк и округляются в конце — как в отчёте ПЭО и снимке закрытия периода.
+// Заказы и дни идут в порядке первого появления в строках.
+func Build(employee storage.GetWorkers, from, to time.Time, lines []storage.StatementLine) storage.EmployeeStatement {
+	st := storage.EmployeeStatement{
+		Employee: employee,
+		From:     from,
+		To:       to,
+		Lines:    make([]storage.StatementLine, 0, len(lines)),
+		Orders:   []storage.StatementTotal{},
+		Days:     []storage.StatementTotal{},
+		Total:    storage.StatementTotal{Key: "Итого"},
+	}
+
+	orderIdx := make(map[string]int)
+	dayIdx := make(map[string]int)
+
+	for _, l := range lines {
+		var money float64
+		if l.Coefficient != nil {
+			money = storage.ExecutorMoney(l.ActualValue, *l.Coefficient, l.CustomerFactor)
+			l.Money = round(money)
+		} else {
+			st.MissingCoefficient++
+		}
+		st.Lines = append(st.Lines, l)
+
+		st.Orders = addTotal(st.Orders, orderIdx, l.OrderNum, l, money)
+		st.Days = addTotal(st.Days, dayIdx, l.Date.Format("2006-01-02"), l, money)
+		accumulate(&st.Total, l, money)
+	}
+
+	for i := range st.Orders {
+		roundTotal(&st.Orders[i])
+	}
+	for i := range st.Days {
+		roundTotal(&st.Days[i])
+	}
+	roundTotal(&st.Total)
+
+	return st
+}
+
+func addTotal(totals []storage.StatementTotal, idx map[string]int, key string, l storage.StatementLine, money float64) []storage.StatementTotal {
+	i, ok := idx[key]
+	if !ok {
+		i = len(totals)
+		idx[key] = i
+		totals = append(totals, storage.StatementTotal{Key: key})
+	}
+	accumulate(&totals[i], l, money)
+	return totals
+}
+
+func accumulate(t *storage.StatementTotal, l storage.StatementLine, money float64) {
+	t.Operations++
+	t.NormMinutes += l.NormMinutes
+	t.ActualMinutes += l.ActualMinutes
+	t.ActualValue += l.ActualValue
+	t.Money += money
+}
+
+func roundTotal(t *storage.StatementTotal) {
+	t.NormMinutes = round(t.NormMinutes)
+	t.ActualMinutes = round(t.ActualMinutes)
+	t.ActualValue = math.Round(t.ActualValue*10000) / 10000
+	t.Money = round(t.Money)
+}
+
+// round — до копеек
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,55 @@
+package statement
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+	"vue-golang/internal/storage"
+)
+
+func day(d int) time.Time {
+	return time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC)
+}
+
+// Тест: деньги = факт н/час × коэффициент; итоги по заказам, дням и за период; строки без коэффициента считаются отдельно
+func TestBuild(t *testing.T) {
+	coef := 76.87
+	lines := []storage.StatementLine{
+		{OrderNum: "Q6-1", Date: day(1), NormMinutes: 30, ActualMinutes: 30, ActualValue: 0.5, Coefficient: &coef, CustomerFactor: 1},
+		{OrderNum: "Q6-2", Date: day(1), NormMinutes: 60, ActualMinutes: 45, ActualValue: 0.75, Coefficient: &coef, CustomerFactor: 1},
+		{OrderNum: "Q6-1", Date: day(2), NormMinutes: 12, ActualMinutes: 12, ActualValue: 0.2, Coefficient: &coef, CustomerFactor: 1},
+		{OrderNum: "Q6-3", Date: day(2), NormMinutes: 6, ActualMinutes: 6, ActualValue: 0.1, CustomerFactor: 1},
+	}
+
+	st := Build(storage.GetWorkers{ID: 1, Name: "Иванов"}, day(1), day(31), lines)
+
+	assert.Len(t, st.Lines, 4)
+	assert.Equal(t, 38.44, st.Lines[0].Money)
+	assert.Equal(t, 57.65, st.Lines[1].Money)
+	assert.Equal(t, 0.0, st.Lines[3].Money)
+	assert.Equal(t, 1, st.MissingCoefficient)
+
+	assert.Equal(t, []storage.StatementTotal{
+		{Key: "Q6-1", Operations: 2, NormMinutes: 42, ActualMinutes: 42, ActualValue: 0.7, Money: 53.81},
+		{Key: "Q6-2", Operations: 1, NormMinutes: 60, ActualMinutes: 45, ActualValue: 0.75, Money: 57.65},
+		{Key: "Q6-3", Operations: 1, NormMinutes: 6, ActualMinutes: 6, ActualValue: 0.1, Money: 0},
+	}, st.Orders)
+
+	assert.Equal(t, []string{"2026-10-01", "2026-10-02"}, []string{st.Days[0].Key, st.Days[1].Key})
+	assert.Equal(t, 96.09, st.Days[0].Money)
+	assert.Equal(t, 4, st.Total.Operations)
+	assert.Equal(t, 111.46, st.Total.Money)
+	assert.Equal(t, 93.0, st.Total.ActualMinutes)
+}
+
+// Тест: пустой период — пустые, но не nil списки (в JSON — [])
+func TestBuild_Empty(t *testing.T) {
+	st := Build(storage.GetWorkers{ID: 1}, day(1), day(31), nil)
+	assert.NotNil(t, st.Lines)
+	assert.NotNil(t, st.Orders)
+	assert.NotNil(t, st.Days)
+	assert.Equal(t, 0, st.Total.Operations)
+
+	_, err := WriteExcel(st)
+	assert.NoError(t, err)
+}
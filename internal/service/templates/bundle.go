@@ -0,0 +1,307 @@
+package templates
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"vue-golang/internal/service/recalculate"
+	"vue-golang/internal/storage"
+)
+
+const (
+	// BundleFormat — признак файла выгрузки шаблонов
+	BundleFormat = "dem-templates"
+	// BundleFormatVersion — версия формата выгрузки, увеличивается при несовместимых изменениях
+	BundleFormatVersion = 1
+)
+
+// Действия импорта по каждому шаблону
+const (
+	ActionCreate    = "create"
+	ActionUpdate    = "update"
+	ActionUnchanged = "unchanged"
+	ActionInvalid   = "invalid"
+)
+
+var ErrInvalidBundle = errors.New("выгрузка шаблонов содержит ошибки")
+
+// Bundle — выгрузка одного или нескольких шаблонов (операции + правила + метаданные)
+type Bundle struct {
+	Format        string           `json:"format"`
+	FormatVersion int              `json:"format_version"`
+	ExportedAt    time.Time        `json:"exported_at"`
+	Templates     []BundleTemplate `json:"templates"`
+}
+
+type BundleTemplate struct {
+	Code       string              `json:"code"`
+	Name       string              `json:"name"`
+	Category   string              `json:"category"`
+	Systema    string              `json:"systema"`
+	TypeIzd    string              `json:"type_izd"`
+	Profile    string              `json:"profile"`
+	HeadName   string              `json:"head_name"`
+	IsActive   bool                `json:"is_active"`
+	Version    int                 `json:"version,omitempty"` // версия в базе-источнике, при импорте не используется
+	Operations []storage.Operation `json:"operations"`
+	Rules      []storage.Rule      `json:"rules"`
+}
+
+// ImportItem — результат импорта одного шаблона
+type ImportItem struct {
+	Code   string                   `json:"code"`
+	Action string                   `json:"action"`
+	Diff   *Diff                    `json:"diff,omitempty"`
+	Errors []recalculate.FieldError `json:"errors,omitempty"`
+	Cases  *recalculate.CasesReport `json:"cases,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun    bool         `json:"dry_run"`
+	Created   int          `json:"created"`
+	Updated   int          `json:"updated"`
+	Unchanged int          `json:"unchanged"`
+	Invalid   int          `json:"invalid"`
+	Items     []ImportItem `json:"items"`
+}
+
+type BundleStorage interface {
+	GetTemplatesByCodesAdmin(ctx context.Context, codes []string) ([]*storage.Template, error)
+	ImportTemplatesAdmin(ctx context.Context, templates []storage.TemplateAdmin) error
+	GetAllContextFeaturesAdmin(ctx context.Context) ([]storage.ContextFeature, error)
+	GetTemplateTestCasesAdmin(ctx context.Context, templateID int64) ([]storage.TemplateTestCase, error)
+}
+
+type BundleService struct {
+	storage BundleStorage
+}
+
+func NewBundleService(storage BundleStorage) *BundleService {
+	return &BundleService{storage: storage}
+}
+
+// Export выгружает шаблоны с указанными кодами, пустой список — все шаблоны
+func (s *BundleService) Export(ctx context.Context, codes []string) (*Bundle, error) {
+	found, err := s.storage.GetTemplatesByCodesAdmin(ctx, codes)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(codes) > 0 && len(found) != len(codes) {
+		return nil, fmt.Errorf("шаблоны не найдены: %s", strings.Join(missingCodes(codes, found), ", "))
+	}
+
+	bundle := &Bundle{
+		Format:        BundleFormat,
+		FormatVersion: BundleFormatVersion,
+		ExportedAt:    time.Now(),
+		Templates:     make([]BundleTemplate, 0, len(found)),
+	}
+
+	for _, t := range found {
+		bundle.Templates = append(bundle.Templates, BundleTemplate{
+			Code:       t.Code,
+			Name:       t.Name,
+			Category:   t.Category,
+			Systema:    deref(t.Systema),
+			TypeIzd:    deref(t.TypeIzd),
+			Profile:    deref(t.Profile),
+			HeadName:   deref(t.HeadName),
+			IsActive:   t.IsActive,
+			Version:    t.Version,
+			Operations: t.Operations,
+			Rules:      t.Rules,
+		})
+	}
+
+	return bundle, nil
+}
+
+// Import проверяет выгрузку, сравнивает каждый шаблон с сохранённым по code
+// и, если это не dry-run и ошибок нет, создаёт/обновляет шаблоны одной транзакцией.
+// При ошибках в любом шаблоне ничего не сохраняется, возвращается отчёт и ErrInvalidBundle.
+func (s *BundleService) Import(ctx context.Context, bundle *Bundle, dryRun bool, comment string) (*ImportReport, error) {
+	if bundle.Format != BundleFormat {
+		return nil, fmt.Errorf("%w: неизвестный формат %q", ErrInvalidBundle, bundle.Format)
+	}
+	if bundle.FormatVersion != BundleFormatVersion {
+		return nil, fmt.Errorf("%w: неподдерживаемая версия формата %d", ErrInvalidBundle, bundle.FormatVersion)
+	}
+
+	existing, err := s.storage.GetTemplatesByCodesAdmin(ctx, bundleCodes(bundle))
+	if err != nil {
+		return nil, err
+	}
+
+	features, err := s.storage.GetAllContextFeaturesAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	featureKeys := recalculate.FeatureKeys(features)
+
+	byCode := make(map[string]*storage.Template, len(existing))
+	for _, t := range existing {
+		byCode[t.Code] = t
+	}
+
+	report := &ImportReport{DryRun: dryRun, Items: make([]ImportItem, 0, len(bundle.Templates))}
+	toSave := make([]storage.TemplateAdmin, 0, len(bundle.Templates))
+	seen := make(map[string]bool, len(bundle.Templates))
+
+	for _, bt := range bundle.Templates {
+		item := ImportItem{Code: bt.Code}
+
+		if errs := validateBundleTemplate(bt, featureKeys); len(errs) > 0 || seen[bt.Code] {
+			if seen[bt.Code] {
+				errs = append(errs, recalculate.FieldError{Field: "code", Message: "код шаблона повторяется в выгрузке"})
+			}
+			item.Action = ActionInvalid
+			item.Errors = errs
+			report.Invalid++
+			report.Items = append(report.Items, item)
+			continue
+		}
+		seen[bt.Code] = true
+
+		incoming := bt.toTemplate()
+		if current, ok := byCode[bt.Code]; ok {
+			diff := Compare(current, incoming)
+			if diff.Empty() {
+				item.Action = ActionUnchanged
+				report.Unchanged++
+				report.Items = append(report.Items, item)
+				continue
+			}
+
+			// Обновление, как и правка в админке, не должно ломать примеры шаблона
+			cases, err := s.storage.GetTemplateTestCasesAdmin(ctx, int64(current.ID))
+			if err != nil {
+				return nil, err
+			}
+			if len(cases) > 0 {
+				casesReport := recalculate.RunTemplateCases(incoming, cases, recalculate.ActiveFeatures(features))
+				if casesReport.Failed > 0 {
+					item.Action = ActionInvalid
+					item.Diff = &diff
+					item.Cases = &casesReport
+					report.Invalid++
+					report.Items = append(report.Items, item)
+					continue
+				}
+			}
+
+			item.Action = ActionUpdate
+			item.Diff = &diff
+			report.Updated++
+		} else {
+			diff := Compare(&storage.Template{}, incoming)
+			item.Action = ActionCreate
+			item.Diff = &diff
+			report.Created++
+		}
+
+		admin, err := bt.toAdmin(comment)
+		if err != nil {
+			return nil, err
+		}
+		toSave = append(toSave, admin)
+		report.Items = append(report.Items, item)
+	}
+
+	if report.Invalid > 0 {
+		return report, ErrInvalidBundle
+	}
+
+	if dryRun || len(toSave) == 0 {
+		return report, nil
+	}
+
+	if err := s.storage.ImportTemplatesAdmin(ctx, toSave); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func validateBundleTemplate(bt BundleTemplate, featureKeys []string) []recalculate.FieldError {
+	var verr *recalculate.ValidationError
+	if err := recalculate.ValidateTemplate(*bt.toTemplate(), featureKeys); errors.As(err, &verr) {
+		return verr.Errors
+	}
+	return nil
+}
+
+func (bt BundleTemplate) toTemplate() *storage.Template {
+	return &storage.Template{
+		Code:       bt.Code,
+		Name:       bt.Name,
+		Category:   bt.Category,
+		Systema:    &bt.Systema,
+		TypeIzd:    &bt.TypeIzd,
+		Profile:    &bt.Profile,
+		HeadName:   &bt.HeadName,
+		IsActive:   bt.IsActive,
+		Operations: bt.Operations,
+		Rules:      bt.Rules,
+	}
+}
+
+func (bt BundleTemplate) toAdmin(comment string) (storage.TemplateAdmin, error) {
+	operations, err := json.Marshal(bt.Operations)
+	if err != nil {
+		return storage.TemplateAdmin{}, fmt.Errorf("шаблон %s: ошибка сериализации операций: %w", bt.Code, err)
+	}
+
+	rules := bt.Rules
+	if rules == nil {
+		rules = []storage.Rule{}
+	}
+	rulesJSON, err := json.Marshal(rules)
+	if err != nil {
+		return storage.TemplateAdmin{}, fmt.Errorf("шаблон %s: ошибка сериализации правил: %w", bt.Code, err)
+	}
+
+	rulesStr := string(rulesJSON)
+
+	return storage.TemplateAdmin{
+		Code:      bt.Code,
+		Category:  bt.Category,
+		IsActive:  bt.IsActive,
+		Name:      bt.Name,
+		Profile:   bt.Profile,
+		Systema:   bt.Systema,
+		TypeIzd:   bt.TypeIzd,
+		Operation: string(operations),
+		Rules:     &rulesStr,
+		HeadName:  bt.HeadName,
+		Comment:   comment,
+	}, nil
+}
+
+func bundleCodes(bundle *Bundle) []string {
+	codes := make([]string, 0, len(bundle.Templates))
+	for _, t := range bundle.Templates {
+		if t.Code != "" {
+			codes = append(codes, t.Code)
+		}
+	}
+	return codes
+}
+
+func missingCodes(codes []string, found []*storage.Template) []string {
+	have := make(map[string]bool, len(found))
+	for _, t := range found {
+		have[t.Code] = true
+	}
+
+	var missing []string
+	for _, code := range codes {
+		if !have[code] {
+			missing = append(missing, code)
+		}
+	}
+	return missing
+}
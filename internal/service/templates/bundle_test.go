@@ -0,0 +1,178 @@
+package templates
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"vue-golang/internal/service/recalculate"
+	"vue-golang/internal/storage"
+)
+
+type fakeBundleStorage struct {
+	templates []*storage.Template
+	imported  []storage.TemplateAdmin
+	cases     map[int64][]storage.TemplateTestCase
+}
+
+func (f *fakeBundleStorage) GetTemplatesByCodesAdmin(ctx context.Context, codes []string) ([]*storage.Template, error) {
+	if len(codes) == 0 {
+		return f.templates, nil
+	}
+
+	var res []*storage.Template
+	for _, t := range f.templates {
+		for _, code := range codes {
+			if t.Code == code {
+				res = append(res, t)
+			}
+		}
+	}
+	return res, nil
+}
+
+func (f *fakeBundleStorage) ImportTemplatesAdmin(ctx context.Context, templates []storage.TemplateAdmin) error {
+	f.imported = append(f.imported, templates...)
+	return nil
+}
+
+func (f *fakeBundleStorage) GetAllContextFeaturesAdmin(ctx context.Context) ([]storage.ContextFeature, error) {
+	return []storage.ContextFeature{{Key: "ArkaCount"}}, nil
+}
+
+func (f *fakeBundleStorage) GetTemplateTestCasesAdmin(ctx context.Context, templateID int64) ([]storage.TemplateTestCase, error) {
+	return f.cases[templateID], nil
+}
+
+func newFakeBundleStorage() *fakeBundleStorage {
+	return &fakeBundleStorage{templates: []*storage.Template{
+		{
+			ID: 1, Code: "KP45Door", Name: "Дверь КП45", Category: "door", IsActive: true, Version: 2,
+			Systema: strPtr(""), TypeIzd: strPtr(""), Profile: strPtr("КП45"), HeadName: strPtr(""),
+			Operations: []storage.Operation{{Name: "сборка", Value: 1, Minutes: 60}},
+			Rules: []storage.Rule{
+				{Operation: "сборка", Condition: map[string]interface{}{"HasImpost": true}, Mode: "additive", ValuePerUnit: 0.1},
+			},
+		},
+		{
+			ID: 2, Code: "vitrage", Name: "Витраж", Category: "vitrage", IsActive: true, Version: 1,
+			Systema: strPtr(""), TypeIzd: strPtr(""), Profile: strPtr(""), HeadName: strPtr(""),
+			Operations: []storage.Operation{{Name: "сборка", Value: 2, Minutes: 120}},
+		},
+	}}
+}
+
+func TestBundleExportImportRoundTrip(t *testing.T) {
+	fake := newFakeBundleStorage()
+	service := NewBundleService(fake)
+
+	bundle, err := service.Export(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, BundleFormat, bundle.Format)
+	assert.Equal(t, BundleFormatVersion, bundle.FormatVersion)
+	assert.Len(t, bundle.Templates, 2)
+	assert.Equal(t, "КП45", bundle.Templates[0].Profile)
+
+	report, err := service.Import(context.Background(), bundle, false, "импорт")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.Unchanged)
+	assert.Empty(t, fake.imported)
+}
+
+func TestBundleExport_MissingCode(t *testing.T) {
+	service := NewBundleService(newFakeBundleStorage())
+
+	_, err := service.Export(context.Background(), []string{"KP45Door", "нет"})
+	assert.ErrorContains(t, err, "нет")
+}
+
+func TestBundleImport_DryRunAndApply(t *testing.T) {
+	fake := newFakeBundleStorage()
+	service := NewBundleService(fake)
+
+	bundle, err := service.Export(context.Background(), []string{"KP45Door"})
+	assert.NoError(t, err)
+
+	// новый слайс, чтобы не менять «сохранённый» шаблон в фейковом хранилище
+	bundle.Templates[0].Operations = []storage.Operation{{Name: "сборка", Value: 1, Minutes: 72}}
+	bundle.Templates = append(bundle.Templates, BundleTemplate{
+		Code:       "glyhar",
+		Name:       "Глухарь",
+		Operations: []storage.Operation{{Name: "резка", Value: 0.5}},
+		Rules:      []storage.Rule{{Operation: "резка", Mode: "set", SetValue: 1}},
+	})
+
+	report, err := service.Import(context.Background(), bundle, true, "импорт")
+	assert.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 1, report.Updated)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, ActionUpdate, report.Items[0].Action)
+	assert.Len(t, report.Items[0].Diff.Operations, 1)
+	assert.Equal(t, ActionCreate, report.Items[1].Action)
+	assert.Empty(t, fake.imported, "dry-run не должен сохранять")
+
+	report, err = service.Import(context.Background(), bundle, false, "импорт")
+	assert.NoError(t, err)
+	assert.False(t, report.DryRun)
+	if assert.Len(t, fake.imported, 2) {
+		assert.Equal(t, "KP45Door", fake.imported[0].Code)
+		assert.Contains(t, fake.imported[0].Operation, `"minutes":72`)
+		assert.Equal(t, "glyhar", fake.imported[1].Code)
+		assert.Equal(t, "импорт", fake.imported[1].Comment)
+	}
+}
+
+func TestBundleImport_Invalid(t *testing.T) {
+	fake := newFakeBundleStorage()
+	service := NewBundleService(fake)
+
+	bundle := &Bundle{
+		Format:        BundleFormat,
+		FormatVersion: BundleFormatVersion,
+		Templates: []BundleTemplate{
+			{Code: "ok", Name: "ok", Operations: []storage.Operation{{Name: "сборка"}}},
+			{Code: "bad", Name: "bad", Operations: []storage.Operation{{Name: "сборка"}},
+				Rules: []storage.Rule{
+					{Operation: "покраска", Mode: "set"},
+					{Operation: "сборка", Mode: "set", Condition: map[string]interface{}{"$expr": "ArkaCount >"}},
+				}},
+			{Code: "ok", Name: "дубль", Operations: []storage.Operation{{Name: "сборка"}}},
+		},
+	}
+
+	report, err := service.Import(context.Background(), bundle, false, "импорт")
+	assert.ErrorIs(t, err, ErrInvalidBundle)
+	assert.Equal(t, 2, report.Invalid)
+	if assert.Len(t, report.Items[1].Errors, 2) {
+		assert.Equal(t, "rules[0].operation", report.Items[1].Errors[0].Field)
+		assert.Equal(t, "rules[1].condition.$expr", report.Items[1].Errors[1].Field)
+	}
+	assert.Contains(t, report.Items[2].Errors, recalculate.FieldError{Field: "code", Message: "код шаблона повторяется в выгрузке"})
+	assert.Empty(t, fake.imported, "при ошибках ничего не сохраняется")
+
+	_, err = service.Import(context.Background(), &Bundle{Format: "other", FormatVersion: 1}, true, "")
+	assert.ErrorIs(t, err, ErrInvalidBundle)
+}
+
+func TestBundleImport_CasesFail(t *testing.T) {
+	fake := newFakeBundleStorage()
+	fake.cases = map[int64][]storage.TemplateTestCase{
+		2: {{ID: 1, Name: "две стойки", TypeIzd: "vitrage", ItemCount: 1,
+			Materials: []*storage.KlaesMaterials{{NameMat: "Стойка", Count: 2}},
+			Expected:  []storage.ExpectedOperation{{Name: "сборка", Value: 2, Minutes: 120}}}},
+	}
+	service := NewBundleService(fake)
+
+	bundle, err := service.Export(context.Background(), []string{"vitrage"})
+	assert.NoError(t, err)
+
+	bundle.Templates[0].Operations = []storage.Operation{{Name: "сборка", Value: 2, Minutes: 90}}
+
+	report, err := service.Import(context.Background(), bundle, true, "импорт")
+	assert.ErrorIs(t, err, ErrInvalidBundle)
+	assert.Equal(t, ActionInvalid, report.Items[0].Action)
+	if assert.NotNil(t, report.Items[0].Cases) {
+		assert.Equal(t, 1, report.Items[0].Cases.Failed)
+	}
+	assert.Empty(t, fake.imported)
+}
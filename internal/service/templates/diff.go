@@ -0,0 +1,146 @@
+package templates
+
+import (
+	"reflect"
+	"strconv"
+	"vue-golang/internal/storage"
+)
+
+// Статусы изменений между версиями шаблона
+const (
+	StatusAdded   = "added"
+	StatusRemoved = "removed"
+	StatusChanged = "changed"
+)
+
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// OperationChange — изменение операции, операции сопоставляются по name
+type OperationChange struct {
+	Name   string             `json:"name"`
+	Status string             `json:"status"`
+	Before *storage.Operation `json:"before,omitempty"`
+	After  *storage.Operation `json:"after,omitempty"`
+}
+
+// RuleChange — изменение правила; у правил нет ключа, поэтому они сравниваются по позиции
+type RuleChange struct {
+	Index  int           `json:"index"`
+	Status string        `json:"status"`
+	Before *storage.Rule `json:"before,omitempty"`
+	After  *storage.Rule `json:"after,omitempty"`
+}
+
+type Diff struct {
+	FromVersion int               `json:"from_version"`
+	ToVersion   int               `json:"to_version"`
+	Fields      []FieldChange     `json:"fields"`
+	Operations  []OperationChange `json:"operations"`
+	Rules       []RuleChange      `json:"rules"`
+}
+
+// Empty — версии совпадают по содержимому
+func (d Diff) Empty() bool {
+	return len(d.Fields) == 0 && len(d.Operations) == 0 && len(d.Rules) == 0
+}
+
+// Compare сравнивает две версии шаблона: поля, операции и правила
+func Compare(from, to *storage.Template) Diff {
+	diff := Diff{
+		FromVersion: from.Version,
+		ToVersion:   to.Version,
+		Fields:      []FieldChange{},
+		Operations:  []OperationChange{},
+		Rules:       []RuleChange{},
+	}
+
+	addField := func(field, before, after string) {
+		if before != after {
+			diff.Fields = append(diff.Fields, FieldChange{Field: field, Before: before, After: after})
+		}
+	}
+	addField("code", from.Code, to.Code)
+	addField("name", from.Name, to.Name)
+	addField("category", from.Category, to.Category)
+	addField("systema", deref(from.Systema), deref(to.Systema))
+	addField("type_izd", deref(from.TypeIzd), deref(to.TypeIzd))
+	addField("profile", deref(from.Profile), deref(to.Profile))
+	addField("head_name", deref(from.HeadName), deref(to.HeadName))
+	addField("is_active", strconv.FormatBool(from.IsActive), strconv.FormatBool(to.IsActive))
+
+	diff.Operations = compareOperations(from.Operations, to.Operations)
+	diff.Rules = compareRules(from.Rules, to.Rules)
+
+	return diff
+}
+
+func compareOperations(from, to []storage.Operation) []OperationChange {
+	changes := []OperationChange{}
+
+	toByName := make(map[string]int, len(to))
+	for i, o := range to {
+		toByName[o.Name] = i
+	}
+	fromByName := make(map[string]bool, len(from))
+
+	for i := range from {
+		before := from[i]
+		fromByName[before.Name] = true
+
+		j, ok := toByName[before.Name]
+		if !ok {
+			changes = append(changes, OperationChange{Name: before.Name, Status: StatusRemoved, Before: &before})
+			continue
+		}
+
+		after := to[j]
+		if before != after {
+			changes = append(changes, OperationChange{Name: before.Name, Status: StatusChanged, Before: &before, After: &after})
+		}
+	}
+
+	for i := range to {
+		after := to[i]
+		if !fromByName[after.Name] {
+			changes = append(changes, OperationChange{Name: after.Name, Status: StatusAdded, After: &after})
+		}
+	}
+
+	return changes
+}
+
+func compareRules(from, to []storage.Rule) []RuleChange {
+	changes := []RuleChange{}
+
+	n := len(from)
+	if len(to) > n {
+		n = len(to)
+	}
+
+	for i := 0; i < n; i++ {
+		switch {
+		case i >= len(to):
+			before := from[i]
+			changes = append(changes, RuleChange{Index: i, Status: StatusRemoved, Before: &before})
+		case i >= len(from):
+			after := to[i]
+			changes = append(changes, RuleChange{Index: i, Status: StatusAdded, After: &after})
+		case !reflect.DeepEqual(from[i], to[i]):
+			before, after := from[i], to[i]
+			changes = append(changes, RuleChange{Index: i, Status: StatusChanged, Before: &before, After: &after})
+		}
+	}
+
+	return changes
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,67 @@
+package templates
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"vue-golang/internal/storage"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestCompare(t *testing.T) {
+	from := &storage.Template{
+		Version: 1,
+		Code:    "56",
+		Name:    "Дверь",
+		Profile: strPtr("КП45"),
+		Operations: []storage.Operation{
+			{Name: "сборка", Value: 1, Minutes: 60},
+			{Name: "упаковка", Value: 0.1, Minutes: 6},
+			{Name: "покраска", Value: 0.5, Minutes: 30},
+		},
+		Rules: []storage.Rule{
+			{Operation: "сборка", Condition: map[string]interface{}{"HasImpost": true}, Mode: "additive", ValuePerUnit: 0.1},
+			{Operation: "упаковка", Condition: map[string]interface{}{}, Mode: "set", SetValue: 1},
+		},
+	}
+	to := &storage.Template{
+		Version: 3,
+		Code:    "56",
+		Name:    "Дверь усиленная",
+		Profile: strPtr("КП45"),
+		Operations: []storage.Operation{
+			{Name: "сборка", Value: 1.2, Minutes: 72},
+			{Name: "упаковка", Value: 0.1, Minutes: 6},
+			{Name: "фрезеровка", Value: 0.2, Minutes: 12},
+		},
+		Rules: []storage.Rule{
+			{Operation: "сборка", Condition: map[string]interface{}{"HasImpost": true}, Mode: "additive", ValuePerUnit: 0.1},
+		},
+	}
+
+	diff := Compare(from, to)
+
+	assert.Equal(t, 1, diff.FromVersion)
+	assert.Equal(t, 3, diff.ToVersion)
+	assert.False(t, diff.Empty())
+
+	assert.Equal(t, []FieldChange{{Field: "name", Before: "Дверь", After: "Дверь усиленная"}}, diff.Fields)
+
+	assert.Len(t, diff.Operations, 3)
+	assert.Equal(t, "сборка", diff.Operations[0].Name)
+	assert.Equal(t, StatusChanged, diff.Operations[0].Status)
+	assert.Equal(t, 72.0, diff.Operations[0].After.Minutes)
+	assert.Equal(t, "покраска", diff.Operations[1].Name)
+	assert.Equal(t, StatusRemoved, diff.Operations[1].Status)
+	assert.Nil(t, diff.Operations[1].After)
+	assert.Equal(t, "фрезеровка", diff.Operations[2].Name)
+	assert.Equal(t, StatusAdded, diff.Operations[2].Status)
+
+	assert.Len(t, diff.Rules, 1)
+	assert.Equal(t, 1, diff.Rules[0].Index)
+	assert.Equal(t, StatusRemoved, diff.Rules[0].Status)
+
+	assert.True(t, Compare(from, from).Empty())
+}
@@ -0,0 +1,231 @@
+package templates
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"vue-golang/internal/constants"
+	"vue-golang/internal/storage"
+)
+
+// Веса признаков подбора. Тип изделия и профиль различают шаблоны сильнее, чем система (х/т)
+const (
+	weightSystema      = 2
+	weightTypeIzd      = 3
+	weightProfile      = 3
+	weightNameCategory = 4
+	weightNameWord     = 1
+	weightMaterials    = 3
+
+	// DefaultMatchLimit — сколько подсказок отдаётся по умолчанию
+	DefaultMatchLimit = 5
+)
+
+// Attributes — атрибуты позиции, по которым подбирается шаблон (как колонки systema/izd/profile шаблона)
+type Attributes struct {
+	Systema string `json:"systema"`
+	TypeIzd string `json:"type_izd"`
+	Profile string `json:"profile"`
+}
+
+// MatchInput — всё, что известно о позиции заказа: атрибуты, name_position из dem_types и материалы из dem_klaes_materials.
+// Features — активные признаки реестра: материалы признака с типом изделия тоже указывают на категорию.
+type MatchInput struct {
+	Attributes
+	NamePosition string                    `json:"name_position"`
+	Materials    []*storage.KlaesMaterials `json:"-"`
+	Features     []storage.ContextFeature  `json:"-"`
+}
+
+// Suggestion — шаблон-кандидат с баллами и объяснением, за что они начислены или сняты
+type Suggestion struct {
+	TemplateID int      `json:"template_id"`
+	Code       string   `json:"code"`
+	Name       string   `json:"name"`
+	Category   string   `json:"category"`
+	Score      int      `json:"score"`
+	Reasons    []string `json:"reasons"`
+}
+
+// nameCategories — основы слов в name_position, по которым угадывается категория шаблона
+var nameCategories = map[string][]string{
+	"window":  {"окн", "фрамуг"},
+	"door":    {"двер"},
+	"vitrage": {"витраж"},
+	"loggia":  {"лодж", "раздвиж", "балкон"},
+	"glyhar":  {"глух"},
+}
+
+// materialCategories — встроенные списки материалов, которые встречаются только в изделиях своей категории (см. BuildContext*)
+var materialCategories = map[string][]map[string]bool{
+	"door": {
+		constants.MnogozapZamok, constants.StandZamok, constants.StublinaCount, constants.PritvorKP40,
+		constants.PetliStand, constants.PetliRolik, constants.Petli3Section, constants.PetliFural, constants.PetliRDRH,
+	},
+	"window":  {constants.StvWindow, constants.TagCountWin},
+	"vitrage": {constants.VitrageMullion, constants.VitrageTransom},
+	"loggia":  {constants.LoggiaFrame, constants.LoggiaStv},
+}
+
+// Match оценивает шаблоны для позиции и возвращает подходящие по убыванию баллов, при равенстве — по ID.
+// Заполненный атрибут шаблона (systema/izd/profile), который не совпадает с позицией, исключает шаблон:
+// по нему считать нельзя. Название позиции и материалы только добавляют или снимают баллы.
+func Match(templates []*storage.Template, input MatchInput) []Suggestion {
+	nameCategory := categoryByName(input.NamePosition)
+	materialHits := categoriesByMaterials(input.Materials, input.Features)
+	nameWords := make(map[string]bool)
+	for _, w := range words(input.NamePosition) {
+		nameWords[w] = true
+	}
+
+	var suggestions []Suggestion
+	for _, t := range templates {
+		s := Suggestion{TemplateID: t.ID, Code: t.Code, Name: t.Name, Category: t.Category}
+
+		if !matchAttributes(t, input.Attributes, &s) {
+			continue
+		}
+
+		if nameCategory != "" {
+			if nameCategory == t.Category {
+				s.Score += weightNameCategory
+				s.Reasons = append(s.Reasons, fmt.Sprintf("позиция «%s» — категория %s", input.NamePosition, t.Category))
+			} else {
+				s.Score -= weightNameCategory
+				s.Reasons = append(s.Reasons, fmt.Sprintf("позиция «%s» не похожа на категорию %s", input.NamePosition, t.Category))
+			}
+		}
+
+		for _, w := range words(t.Name) {
+			if nameWords[w] {
+				s.Score += weightNameWord
+				s.Reasons = append(s.Reasons, fmt.Sprintf("в названии шаблона есть «%s»", w))
+			}
+		}
+
+		if material, ok := materialHits[t.Category]; ok {
+			s.Score += weightMaterials
+			s.Reasons = append(s.Reasons, fmt.Sprintf("в материалах есть «%s»", material))
+		} else if len(materialHits) > 0 {
+			s.Score -= weightMaterials
+			s.Reasons = append(s.Reasons, fmt.Sprintf("в материалах нет признаков категории %s", t.Category))
+		}
+
+		if s.Score <= 0 {
+			continue
+		}
+		suggestions = append(suggestions, s)
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].TemplateID < suggestions[j].TemplateID
+	})
+
+	return suggestions
+}
+
+// matchAttributes начисляет баллы за совпавшие атрибуты; false — атрибут шаблона противоречит позиции
+func matchAttributes(t *storage.Template, attrs Attributes, s *Suggestion) bool {
+	for _, a := range []struct {
+		field  string
+		want   string
+		have   string
+		weight int
+	}{
+		{"systema", deref(t.Systema), attrs.Systema, weightSystema},
+		{"type_izd", deref(t.TypeIzd), attrs.TypeIzd, weightTypeIzd},
+		{"profile", deref(t.Profile), attrs.Profile, weightProfile},
+	} {
+		want, have := normalizeAttr(a.want), normalizeAttr(a.have)
+		if want == "" || have == "" {
+			continue
+		}
+		if want != have {
+			return false
+		}
+		s.Score += a.weight
+		s.Reasons = append(s.Reasons, fmt.Sprintf("%s совпадает: %s", a.field, a.want))
+	}
+	return true
+}
+
+func categoryByName(name string) string {
+	name = normalizeAttr(name)
+	if name == "" {
+		return ""
+	}
+
+	// обходим категории в фиксированном порядке, чтобы результат не зависел от порядка map
+	categories := make([]string, 0, len(nameCategories))
+	for category := range nameCategories {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	for _, category := range categories {
+		for _, stem := range nameCategories[category] {
+			if strings.Contains(name, stem) {
+				return category
+			}
+		}
+	}
+	return ""
+}
+
+// categoriesByMaterials — категории, признаки которых нашлись в материалах, с первым найденным материалом.
+// К встроенным спискам добавляются наименования признаков реестра, привязанных к типу изделия.
+func categoriesByMaterials(materials []*storage.KlaesMaterials, features []storage.ContextFeature) map[string]string {
+	names := make(map[string]string)
+	for category, sets := range materialCategories {
+		for _, set := range sets {
+			for name := range set {
+				names[normalizeAttr(name)] = category
+			}
+		}
+	}
+	for _, f := range features {
+		if f.ProductType == "" || !f.IsActive {
+			continue
+		}
+		for _, name := range f.MatchNames {
+			if _, builtin := names[normalizeAttr(name)]; !builtin {
+				names[normalizeAttr(name)] = f.ProductType
+			}
+		}
+	}
+
+	hits := make(map[string]string)
+	for _, m := range materials {
+		name := strings.TrimSpace(m.NameMat)
+		category, ok := names[normalizeAttr(name)]
+		if !ok {
+			continue
+		}
+		if _, seen := hits[category]; !seen {
+			hits[category] = name
+		}
+	}
+	return hits
+}
+
+// words — значимые слова названия (от 3 символов) без учёта регистра и без повторов
+func words(s string) []string {
+	seen := make(map[string]bool)
+	var result []string
+	for _, w := range strings.FieldsFunc(normalizeAttr(s), func(r rune) bool {
+		return r == ' ' || r == ',' || r == '.' || r == '(' || r == ')' || r == '/' || r == '-'
+	}) {
+		if len([]rune(w)) >= 3 && !seen[w] {
+			seen[w] = true
+			result = append(result, w)
+		}
+	}
+	return result
+}
+
+func normalizeAttr(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
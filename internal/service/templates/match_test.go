@@ -0,0 +1,85 @@
+package templates
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"vue-golang/internal/storage"
+)
+
+func matchTemplates() []*storage.Template {
+	return []*storage.Template{
+		{ID: 40, Code: "40", Name: "Двери 1П шх", Category: "door", Systema: strPtr("х"), TypeIzd: strPtr("1П"), Profile: strPtr("шх")},
+		{ID: 56, Code: "56", Name: "Двери 1П КП45", Category: "door", Systema: strPtr("х"), TypeIzd: strPtr("1П"), Profile: strPtr("сх")},
+		{ID: 61, Code: "61", Name: "Окна КП45", Category: "window", Systema: strPtr("х"), TypeIzd: nil, Profile: strPtr("сх")},
+		{ID: 7, Code: "7", Name: "Окна пов.-отк.", Category: "window", Systema: strPtr("х"), TypeIzd: strPtr("окно пов.-отк."), Profile: nil},
+		{ID: 70, Code: "vitrage", Name: "витраж базовый", Category: "vitrage"},
+	}
+}
+
+func codes(suggestions []Suggestion) []string {
+	var result []string
+	for _, s := range suggestions {
+		result = append(result, s.Code)
+	}
+	return result
+}
+
+// Тест: конфликт заполненного атрибута исключает шаблон, совпадения ранжируются
+func TestMatch_Attributes(t *testing.T) {
+	suggestions := Match(matchTemplates(), MatchInput{Attributes: Attributes{Systema: "Х", TypeIzd: " 1П ", Profile: "сх"}})
+
+	assert.Equal(t, []string{"56", "61"}, codes(suggestions))
+	assert.Equal(t, 8, suggestions[0].Score)
+	assert.Equal(t, []string{"systema совпадает: х", "type_izd совпадает: 1П", "profile совпадает: сх"}, suggestions[0].Reasons)
+
+	assert.Empty(t, Match(matchTemplates(), MatchInput{Attributes: Attributes{TypeIzd: "2П", Profile: "ат"}}))
+}
+
+// Тест: название позиции и материалы перевешивают шаблон другой категории
+func TestMatch_NameAndMaterials(t *testing.T) {
+	suggestions := Match(matchTemplates(), MatchInput{
+		Attributes:   Attributes{Systema: "х", Profile: "сх"},
+		NamePosition: "Дверь",
+		Materials: []*storage.KlaesMaterials{
+			{NameMat: "Импост"},
+			{NameMat: " Петля роликовая для КП45 "},
+		},
+	})
+
+	assert.Equal(t, []string{"56"}, codes(suggestions), "окна КП45 ушли в минус: позиция — дверь, в материалах петли")
+	assert.Contains(t, suggestions[0].Reasons, "позиция «Дверь» — категория door")
+	assert.Contains(t, suggestions[0].Reasons, "в материалах есть «Петля роликовая для КП45»")
+
+	suggestions = Match(matchTemplates(), MatchInput{
+		NamePosition: "Витраж",
+		Materials:    []*storage.KlaesMaterials{{NameMat: "Стойка"}},
+	})
+	if assert.Len(t, suggestions, 1) {
+		assert.Equal(t, "vitrage", suggestions[0].Code)
+		assert.Equal(t, []string{
+			"позиция «Витраж» — категория vitrage",
+			"в названии шаблона есть «витраж»",
+			"в материалах есть «Стойка»",
+		}, suggestions[0].Reasons)
+	}
+}
+
+// Тест: материалы лоджий берутся из списков движка, признаки реестра с типом изделия тоже указывают на категорию
+func TestMatch_MaterialsFromEngineAndRegistry(t *testing.T) {
+	all := []*storage.Template{
+		{ID: 80, Code: "loggia", Name: "лоджия", Category: "loggia"},
+		{ID: 81, Code: "arka", Name: "арка", Category: "arka"},
+	}
+
+	suggestions := Match(all, MatchInput{Materials: []*storage.KlaesMaterials{{NameMat: "Рама нижняя"}}})
+	assert.Equal(t, []string{"loggia"}, codes(suggestions))
+
+	suggestions = Match(all, MatchInput{
+		Materials: []*storage.KlaesMaterials{{NameMat: "Профиль арочный"}},
+		Features: []storage.ContextFeature{
+			{Key: "ArkaCount", ProductType: "arka", MatchNames: []string{"профиль арочный"}, IsActive: true},
+			{Key: "Old", ProductType: "loggia", MatchNames: []string{"Профиль арочный"}, IsActive: false},
+		},
+	})
+	assert.Equal(t, []string{"arka"}, codes(suggestions))
+}
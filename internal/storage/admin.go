@@ -1,10 +1,36 @@
 package storage
 
+import (
+	"errors"
+	"time"
+)
+
 type CoefficientPEOAdmin struct {
 	ID          int64   `json:"id"`
 	Type        string  `json:"type"`
 	Coefficient float64 `json:"coefficient"`
 	IsActive    bool    `json:"is_active"`
+	// С какой даты действует новый коэффициент (2006-01-02); пусто — с сегодняшнего дня
+	EffectiveFrom string `json:"effective_from,omitempty"`
+}
+
+// Откуда взят коэффициент изделия в отчётах
+const (
+	CoefSourceProduct = "product" // задан у изделия вручную
+	CoefSourceHistory = "history" // действовал по типу на дату готовности
+	CoefSourceType    = "type"    // текущий по типу (истории по типу нет)
+)
+
+var ErrCoefficientNotFound = errors.New("коэффициент не найден")
+
+// CoefficientHistory — значение коэффициента типа, действующее с EffectiveFrom до следующей записи
+type CoefficientHistory struct {
+	ID            int64     `json:"id"`
+	Type          string    `json:"type"`
+	Coefficient   float64   `json:"coefficient"`
+	EffectiveFrom time.Time `json:"effective_from"`
+	CreatedBy     string    `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
 }
 
 type EmployeesAdmin struct {
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrDuplicateNorm — позиция заказа уже нормирована по этому шаблону; повторно — только через перенормировку
+var ErrDuplicateNorm = errors.New("позиция заказа уже нормирована по этому шаблону")
+
+// ExistingNorm — действующая нормировка позиции Dem-заказа (корень, не отменён и не заменён)
+type ExistingNorm struct {
+	ID           int64     `json:"id"`
+	OrderNum     string    `json:"order_num"`
+	Position     int       `json:"position"`
+	TemplateCode string    `json:"template_code"`
+	Name         string    `json:"name"`
+	Status       string    `json:"status"`
+	TotalTime    float64   `json:"total_time"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// DuplicateNormError — действующие нормировки, мешающие сохранить новую
+type DuplicateNormError struct {
+	Existing []ExistingNorm
+}
+
+func (e *DuplicateNormError) Error() string {
+	ids := make([]int64, 0, len(e.Existing))
+	for _, n := range e.Existing {
+		ids = append(ids, n.ID)
+	}
+	return fmt.Sprintf("%s: id=%v", ErrDuplicateNorm, ids)
+}
+
+func (e *DuplicateNormError) Unwrap() error {
+	return ErrDuplicateNorm
+}
+
+// ErrSupersedeLocked — перенормировка не заменяет назначенные и финальные наряды: их назначения пропали бы молча
+var ErrSupersedeLocked = errors.New("наряд уже назначен или закрыт: сначала отмените или переоткройте его")
+
+// SupersedeLockedError — действующие нормировки позиции, которые перенормировка заменить не может
+type SupersedeLockedError struct {
+	Locked []ExistingNorm
+}
+
+func (e *SupersedeLockedError) Error() string {
+	ids := make([]int64, 0, len(e.Locked))
+	for _, n := range e.Locked {
+		ids = append(ids, n.ID)
+	}
+	return fmt.Sprintf("%s: id=%v", ErrSupersedeLocked, ids)
+}
+
+func (e *SupersedeLockedError) Unwrap() error {
+	return ErrSupersedeLocked
+}
+
+// DuplicateNormGroup — несколько действующих нормировок одной позиции по одному шаблону, от старых к новым
+type DuplicateNormGroup struct {
+	OrderNum     string         `json:"order_num"`
+	Position     int            `json:"position"`
+	TemplateCode string         `json:"template_code"`
+	Norms        []ExistingNorm `json:"norms"`
+}
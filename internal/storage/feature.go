@@ -0,0 +1,28 @@
+package storage
+
+import "errors"
+
+var ErrFeatureNotFound = errors.New("признак не найден")
+
+// ContextFeature — признак контекста нормирования из реестра dem_context_features_al.
+// Значение признака считается по материалам позиции и доступно правилам шаблона
+// по ключу Key (в unitField и condition) наравне со встроенными полями Context.
+type ContextFeature struct {
+	ID          int64    `json:"id"`
+	Key         string   `json:"feature_key"`
+	ProductType string   `json:"product_type"` // пусто — признак для всех типов изделий
+	MatchNames  []string `json:"match_names"`
+	MinWidth    *float64 `json:"min_width"`
+	MaxWidth    *float64 `json:"max_width"`
+	MinHeight   *float64 `json:"min_height"`
+	MaxHeight   *float64 `json:"max_height"`
+	Aggregation string   `json:"aggregation"` // "sum", "count", "divide"
+	Divisor     float64  `json:"divisor"`
+	IsActive    bool     `json:"is_active"`
+}
+
+// ContextFeatureUpdate — правка признака в админке: не переданный is_active остаётся прежним
+type ContextFeatureUpdate struct {
+	ContextFeature
+	IsActive *bool `json:"is_active"`
+}
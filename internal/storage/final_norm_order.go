@@ -51,11 +51,18 @@ type PEOProduct struct {
 	Profile         string     `json:"profile"`
 	Count           int        `json:"count"`
 	Sqr             float64    `json:"sqr"`
+	SqrStv          *float64   `json:"sqr_stv"` // площадь створки (лоджии, витражи), nil — не задана
 	Brigade         string     `json:"brigade"`
 	NormMoney       float64    `json:"norm_money"`
 	Position        float64    `json:"position"`
 	ReadyDate       *time.Time `json:"ready_date"`
 	Coefficient     *float64   `json:"coefficient"`
+	// Откуда взят коэффициент (storage.CoefSource*) и с какой даты он действует, если взят из истории
+	CoefficientSource string     `json:"coefficient_source"`
+	CoefficientFrom   *time.Time `json:"coefficient_from"`
+	// Множитель Н/руб направления заказчика (нет записи — 1)
+	CustomerFactor float64 `json:"customer_factor"`
+	RowVersion     int     `json:"row_version"`
 
 	// Мапа: employee_id → суммарные минуты
 	EmployeeMinutes map[int64]float64 `json:"employee_minutes"`
@@ -2,15 +2,22 @@ package mysql
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/go-sql-driver/mysql"
+	"math"
+	"time"
 	"vue-golang/internal/storage"
 )
 
 func (s *Storage) GetAllCoefficientAdmin(ctx context.Context) ([]*storage.CoefficientPEOAdmin, error) {
 	const op = "storage.mysql.sql.GetAllCoefficientAdmin"
 
-	stmt := `SELECT id, type, coefficient, is_active FROM dem_coefficient_al`
+	// Показываем коэффициент, действующий сегодня: запись истории с будущей датой ещё не в силе
+	stmt := `SELECT dc.id, dc.type, COALESCE((SELECT ch.coefficient FROM dem_coefficient_history_al ch
+		WHERE ch.type = dc.type AND ch.effective_from <= CURDATE() ORDER BY ch.effective_from DESC LIMIT 1), dc.coefficient),
+		dc.is_active FROM dem_coefficient_al dc`
 
 	rows, err := s.db.QueryContext(ctx, stmt)
 	if err != nil {
@@ -38,7 +45,11 @@ func (s *Storage) GetAllCoefficientAdmin(ctx context.Context) ([]*storage.Coeffi
 	return coefs, nil
 }
 
-func (s *Storage) UpdateCoefficientPEOAdmin(ctx context.Context, coeffs []storage.CoefficientPEOAdmin) error {
+// UpdateCoefficientPEOAdmin записывает новые коэффициенты в историю с датой начала действия (по умолчанию сегодня).
+// Прежние значения остаются в истории и применяются к изделиям, готовым до этой даты. Если значение не изменилось
+// и дата не указана — меняется только активность. Н/руб изделий типа без своего коэффициента пересчитывается,
+// кроме изделий в закрытых расчётных периодах.
+func (s *Storage) UpdateCoefficientPEOAdmin(ctx context.Context, coeffs []storage.CoefficientPEOAdmin, changedBy string) error {
 	const op = "storage.mysql.sql.UpdateCoefficientPEOAdmin"
 
 	tx, err := s.db.BeginTx(ctx, nil)
@@ -48,20 +59,49 @@ func (s *Storage) UpdateCoefficientPEOAdmin(ctx context.Context, coeffs []storag
 
 	defer tx.Rollback()
 
-	stmt, err := tx.PrepareContext(ctx, `
-		UPDATE dem_coefficient_al 
-		SET coefficient = ?, is_active = ? 
-		WHERE id = ? AND type = ?
-	`)
-	if err != nil {
-		return fmt.Errorf("%s: не удалось подготовить запрос для обновления коэффициентов: %w", op, err)
-	}
+	stmtCurrent := `SELECT COALESCE((SELECT ch.coefficient FROM dem_coefficient_history_al ch
+		WHERE ch.type = dc.type AND ch.effective_from <= CURDATE() ORDER BY ch.effective_from DESC LIMIT 1), dc.coefficient)
+		FROM dem_coefficient_al dc WHERE dc.id = ? AND dc.type = ? FOR UPDATE`
+	stmtHistory := `INSERT INTO dem_coefficient_history_al (type, coefficient, effective_from, created_by) VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE coefficient = VALUES(coefficient), created_by = VALUES(created_by), created_at = CURRENT_TIMESTAMP`
+	// В dem_coefficient_al держим значение, действующее сегодня
+	stmtUpdate := `UPDATE dem_coefficient_al SET is_active = ?, coefficient = COALESCE((SELECT ch.coefficient
+		FROM dem_coefficient_history_al ch WHERE ch.type = ? AND ch.effective_from <= CURDATE()
+		ORDER BY ch.effective_from DESC LIMIT 1), ?) WHERE id = ? AND type = ?`
+
+	today := time.Now().Format("2006-01-02")
 
 	for _, coef := range coeffs {
-		_, err := stmt.ExecContext(ctx, coef.Coefficient, coef.IsActive, coef.ID, coef.Type)
+		var current sql.NullFloat64
+		err := tx.QueryRowContext(ctx, stmtCurrent, coef.ID, coef.Type).Scan(&current)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
 		if err != nil {
+			return fmt.Errorf("%s: ошибка получения коэффициента id=%d: %w", op, coef.ID, err)
+		}
+
+		unchanged := current.Valid && math.Abs(current.Float64-coef.Coefficient) < 1e-9
+		if !unchanged || coef.EffectiveFrom != "" {
+			effective := coef.EffectiveFrom
+			if effective == "" {
+				effective = today
+			}
+			if _, err := tx.ExecContext(ctx, stmtHistory, coef.Type, coef.Coefficient, effective, changedBy); err != nil {
+				return fmt.Errorf("%s: ошибка записи истории коэффициента id=%d: %w", op, coef.ID, err)
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, stmtUpdate, coef.IsActive, coef.Type, coef.Coefficient, coef.ID, coef.Type); err != nil {
 			return fmt.Errorf("%s: ошибка обновления коэффициента id=%d: %w", op, coef.ID, err)
 		}
+
+		// Н/руб изделий типа, готовых после даты начала действия, считается по новому значению
+		if !unchanged || coef.EffectiveFrom != "" {
+			if err := recomputeTypeNormMoneyTx(ctx, tx, coef.Type); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
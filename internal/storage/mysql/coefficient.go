@@ -0,0 +1,79 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"vue-golang/internal/storage"
+)
+
+// coefficientAtReadySQL — значение из истории коэффициентов, действовавшее для типа изделия p на его дату готовности
+// (без даты — на сегодня); %s — столбец истории
+const coefficientAtReadySQL = `(SELECT ch.%s FROM dem_coefficient_history_al ch
+	WHERE ch.type = p.type AND ch.effective_from <= COALESCE(p.ready_date, CURDATE())
+	ORDER BY ch.effective_from DESC LIMIT 1)`
+
+// Коэффициент изделия p для отчётов: заданный у изделия, иначе по истории на дату готовности, иначе текущий по типу.
+// В запросе должен быть LEFT JOIN dem_coefficient_al dc ON dc.type = p.type.
+var (
+	effectiveCoefficientSQL = fmt.Sprintf(`COALESCE(p.coefficient, %s, dc.coefficient)`,
+		fmt.Sprintf(coefficientAtReadySQL, "coefficient"))
+
+	coefficientSourceSQL = fmt.Sprintf(`CASE WHEN p.coefficient IS NOT NULL THEN '%s' WHEN %s IS NOT NULL THEN '%s'
+		WHEN dc.coefficient IS NOT NULL THEN '%s' ELSE '' END`,
+		storage.CoefSourceProduct, fmt.Sprintf(coefficientAtReadySQL, "id"), storage.CoefSourceHistory, storage.CoefSourceType)
+
+	coefficientFromSQL = fmt.Sprintf(`CASE WHEN p.coefficient IS NULL THEN %s END`,
+		fmt.Sprintf(coefficientAtReadySQL, "effective_from"))
+)
+
+// GetCoefficientHistory — история коэффициентов типа (пустой тип — всех), от новых к старым
+func (s *Storage) GetCoefficientHistory(ctx context.Context, productType string) ([]storage.CoefficientHistory, error) {
+	const op = "storage.mysql.GetCoefficientHistory"
+
+	query := `SELECT id, type, coefficient, effective_from, created_by, created_at FROM dem_coefficient_history_al`
+	var args []interface{}
+	if productType != "" {
+		query += ` WHERE type = ?`
+		args = append(args, productType)
+	}
+	query += ` ORDER BY type, effective_from DESC`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	history := []storage.CoefficientHistory{}
+	for rows.Next() {
+		var h storage.CoefficientHistory
+		if err := rows.Scan(&h.ID, &h.Type, &h.Coefficient, &h.EffectiveFrom, &h.CreatedBy, &h.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: ошибка сканирования истории коэффициентов: %w", op, err)
+		}
+		history = append(history, h)
+	}
+
+	return history, rows.Err()
+}
+
+// ResolveCoefficient — коэффициент типа, действующий на дату
+func (s *Storage) ResolveCoefficient(ctx context.Context, productType string, date time.Time) (storage.CoefficientHistory, error) {
+	const op = "storage.mysql.ResolveCoefficient"
+
+	var h storage.CoefficientHistory
+	err := s.db.QueryRowContext(ctx, `SELECT id, type, coefficient, effective_from, created_by, created_at
+		FROM dem_coefficient_history_al WHERE type = ? AND effective_from <= ?
+		ORDER BY effective_from DESC LIMIT 1`, productType, date.Format("2006-01-02")).
+		Scan(&h.ID, &h.Type, &h.Coefficient, &h.EffectiveFrom, &h.CreatedBy, &h.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return h, fmt.Errorf("%s: тип %q на %s: %w", op, productType, date.Format("2006-01-02"), storage.ErrCoefficientNotFound)
+	}
+	if err != nil {
+		return h, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return h, nil
+}
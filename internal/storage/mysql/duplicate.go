@@ -0,0 +1,130 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"vue-golang/internal/storage"
+)
+
+// activeNormCondition — действующая нормировка: корень, не отменён и не заменён перенормировкой
+const activeNormCondition = `parent_product_id IS NULL AND superseded_by IS NULL AND COALESCE(status, '') <> 'cancel'`
+
+const selectExistingNorm = `SELECT id, order_num, position, COALESCE(template_code, ''), name, COALESCE(status, ''), total_time, created_at
+		FROM dem_product_instances_al`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanExistingNorm(row rowScanner) (storage.ExistingNorm, error) {
+	var n storage.ExistingNorm
+	err := row.Scan(&n.ID, &n.OrderNum, &n.Position, &n.TemplateCode, &n.Name, &n.Status, &n.TotalTime, &n.CreatedAt)
+	return n, err
+}
+
+// GetExistingNorms — действующие нормировки позиции заказа; пустой templateCode — по всем шаблонам
+func (s *Storage) GetExistingNorms(ctx context.Context, orderNum string, position int, templateCode string) ([]storage.ExistingNorm, error) {
+	const op = "storage.mysql.GetExistingNorms"
+
+	rows, err := s.db.QueryContext(ctx, selectExistingNorm+`
+		WHERE order_num = ? AND position = ? AND (? = '' OR template_code = ?) AND `+activeNormCondition+`
+		ORDER BY created_at, id`, orderNum, position, templateCode, templateCode)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	norms := make([]storage.ExistingNorm, 0)
+	for rows.Next() {
+		n, err := scanExistingNorm(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: ошибка сканирования строки: %w", op, err)
+		}
+		norms = append(norms, n)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: ошибка при итерации по строкам: %w", op, err)
+	}
+
+	return norms, nil
+}
+
+// GetDuplicateNorms — отчёт для чистки: позиции, у которых больше одной действующей нормировки по одному шаблону
+func (s *Storage) GetDuplicateNorms(ctx context.Context) ([]storage.DuplicateNormGroup, error) {
+	const op = "storage.mysql.GetDuplicateNorms"
+
+	rows, err := s.db.QueryContext(ctx, selectExistingNorm+` p
+		WHERE `+activeNormCondition+` AND EXISTS (
+			SELECT 1 FROM dem_product_instances_al d
+			WHERE d.order_num = p.order_num AND d.position = p.position AND COALESCE(d.template_code, '') = COALESCE(p.template_code, '')
+			  AND d.id <> p.id AND d.parent_product_id IS NULL AND d.superseded_by IS NULL AND COALESCE(d.status, '') <> 'cancel')
+		ORDER BY order_num, position, template_code, created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	groups := make([]storage.DuplicateNormGroup, 0)
+	for rows.Next() {
+		n, err := scanExistingNorm(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: ошибка сканирования строки: %w", op, err)
+		}
+
+		last := len(groups) - 1
+		if last < 0 || groups[last].OrderNum != n.OrderNum || groups[last].Position != n.Position || groups[last].TemplateCode != n.TemplateCode {
+			groups = append(groups, storage.DuplicateNormGroup{OrderNum: n.OrderNum, Position: n.Position, TemplateCode: n.TemplateCode})
+			last++
+		}
+		groups[last].Norms = append(groups[last].Norms, n)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: ошибка при итерации по строкам: %w", op, err)
+	}
+
+	return groups, nil
+}
+
+// lockExistingNormsTx блокирует действующие нормировки позиции (и промежуток индекса под новую вставку)
+func lockExistingNormsTx(ctx context.Context, tx *sql.Tx, order storage.OrderNormDetails) ([]storage.ExistingNorm, error) {
+	rows, err := tx.QueryContext(ctx, selectExistingNorm+`
+		WHERE order_num = ? AND position = ? AND template_code = ? AND `+activeNormCondition+`
+		ORDER BY created_at, id
+		FOR UPDATE`, order.OrderNum, order.Position, order.TemplateCode)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var norms []storage.ExistingNorm
+	for rows.Next() {
+		n, err := scanExistingNorm(rows)
+		if err != nil {
+			return nil, err
+		}
+		norms = append(norms, n)
+	}
+
+	return norms, rows.Err()
+}
+
+// supersedeNormTx отменяет прежнюю нормировку со всеми sub-изделиями и ссылается на новую. Назначенные и финальные
+// наряды сюда не попадают (SaveNormOrder отвечает storage.SupersedeLockedError), переход остаётся в истории.
+func (s *Storage) supersedeNormTx(ctx context.Context, tx *sql.Tx, old storage.ExistingNorm, newID int64, actor string) error {
+	change := storage.StatusChange{
+		ProductID: old.ID,
+		From:      old.Status,
+		To:        "cancel",
+		ChangedBy: actor,
+		Reason:    fmt.Sprintf("перенормировка: заменена нормировкой id=%d", newID),
+	}
+	if err := s.ChangeStatusTx(ctx, tx, change); err != nil {
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx, `UPDATE dem_product_instances_al SET superseded_by = ? WHERE id = ? OR parent_product_id = ?`, newID, old.ID, old.ID)
+	return err
+}
@@ -0,0 +1,182 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"vue-golang/internal/storage"
+)
+
+const featureColumns = `id, feature_key, product_type, match_names, min_width, max_width, min_height, max_height, aggregation, divisor, is_active`
+
+// GetContextFeatures возвращает активные признаки реестра для типа изделия (и общие для всех типов)
+func (s *Storage) GetContextFeatures(ctx context.Context, typeIzd string) ([]storage.ContextFeature, error) {
+	const op = "storage.mysql.GetContextFeatures"
+
+	stmt := `SELECT ` + featureColumns + ` FROM dem_context_features_al
+				WHERE is_active = TRUE AND (product_type = ? OR product_type = '') ORDER BY id`
+
+	features, err := s.queryContextFeatures(ctx, stmt, typeIzd)
+	if err != nil {
+		return nil, fmt.Errorf("%s: ошибка получения признаков реестра для типа %s: %w", op, typeIzd, err)
+	}
+
+	return features, nil
+}
+
+func (s *Storage) GetAllContextFeaturesAdmin(ctx context.Context) ([]storage.ContextFeature, error) {
+	const op = "storage.mysql.GetAllContextFeaturesAdmin"
+
+	stmt := `SELECT ` + featureColumns + ` FROM dem_context_features_al ORDER BY product_type, feature_key`
+
+	features, err := s.queryContextFeatures(ctx, stmt)
+	if err != nil {
+		return nil, fmt.Errorf("%s: ошибка получения всех признаков реестра: %w", op, err)
+	}
+
+	return features, nil
+}
+
+func (s *Storage) CreateContextFeatureAdmin(ctx context.Context, feature storage.ContextFeature) (int64, error) {
+	const op = "storage.mysql.CreateContextFeatureAdmin"
+
+	namesJSON, err := json.Marshal(feature.MatchNames)
+	if err != nil {
+		return 0, fmt.Errorf("%s: ошибка сериализации наименований материалов: %w", op, err)
+	}
+
+	// Пустая агрегация — значение столбца по умолчанию (sum)
+	stmt := `INSERT INTO dem_context_features_al (feature_key, product_type, match_names, min_width, max_width,
+            min_height, max_height, aggregation, divisor, is_active)
+            VALUES (?, ?, ?, ?, ?, ?, ?, COALESCE(NULLIF(?, ''), DEFAULT(aggregation)), ?, ?)`
+
+	res, err := s.db.ExecContext(ctx, stmt, feature.Key, feature.ProductType, string(namesJSON), feature.MinWidth,
+		feature.MaxWidth, feature.MinHeight, feature.MaxHeight, feature.Aggregation, feature.Divisor, feature.IsActive)
+	if err != nil {
+		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1062 {
+			return 0, fmt.Errorf("%s: признак '%s' для типа '%s' уже существует: %w", op, feature.Key, feature.ProductType, err)
+		}
+		return 0, fmt.Errorf("%s: ошибка сохранения признака в базу: %w", op, err)
+	}
+
+	return res.LastInsertId()
+}
+
+// UpdateContextFeatureAdmin перезаписывает признак; нет такого id — storage.ErrFeatureNotFound
+func (s *Storage) UpdateContextFeatureAdmin(ctx context.Context, id int64, update storage.ContextFeatureUpdate) error {
+	const op = "storage.mysql.UpdateContextFeatureAdmin"
+
+	feature := update.ContextFeature
+	namesJSON, err := json.Marshal(feature.MatchNames)
+	if err != nil {
+		return fmt.Errorf("%s: ошибка сериализации наименований материалов: %w", op, err)
+	}
+
+	stmt := `UPDATE dem_context_features_al SET feature_key = ?, product_type = ?, match_names = ?, min_width = ?,
+            max_width = ?, min_height = ?, max_height = ?, aggregation = COALESCE(NULLIF(?, ''), DEFAULT(aggregation)),
+            divisor = ?, is_active = COALESCE(?, is_active) WHERE id = ?`
+
+	res, err := s.db.ExecContext(ctx, stmt, feature.Key, feature.ProductType, string(namesJSON), feature.MinWidth,
+		feature.MaxWidth, feature.MinHeight, feature.MaxHeight, feature.Aggregation, feature.Divisor, update.IsActive, id)
+	if err != nil {
+		return fmt.Errorf("%s: ошибка обновления признака id=%d: %w", op, id, err)
+	}
+
+	// MySQL не считает строку затронутой, если значения не изменились, поэтому при нуле проверяем, есть ли она
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		var exists bool
+		if err := s.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM dem_context_features_al WHERE id = ?)`, id).Scan(&exists); err != nil {
+			return fmt.Errorf("%s: ошибка проверки признака id=%d: %w", op, id, err)
+		}
+		if !exists {
+			return fmt.Errorf("%s: id=%d: %w", op, id, storage.ErrFeatureNotFound)
+		}
+	}
+
+	return nil
+}
+
+// featureMaterialNames — наименования материалов из активных признаков реестра,
+// чтобы GetOrderMaterials выбирал их вместе со встроенным списком
+func (s *Storage) featureMaterialNames(ctx context.Context) ([]string, error) {
+	stmt := `SELECT match_names FROM dem_context_features_al WHERE is_active = TRUE`
+
+	rows, err := s.db.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var namesJSON string
+		if err := rows.Scan(&namesJSON); err != nil {
+			return nil, err
+		}
+
+		var featureNames []string
+		if err := json.Unmarshal([]byte(namesJSON), &featureNames); err != nil {
+			return nil, err
+		}
+		names = append(names, featureNames...)
+	}
+
+	return names, rows.Err()
+}
+
+func (s *Storage) queryContextFeatures(ctx context.Context, stmt string, args ...interface{}) ([]storage.ContextFeature, error) {
+	rows, err := s.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var features []storage.ContextFeature
+	for rows.Next() {
+		var (
+			f         storage.ContextFeature
+			namesJSON string
+			minWidth  sql.NullFloat64
+			maxWidth  sql.NullFloat64
+			minHeight sql.NullFloat64
+			maxHeight sql.NullFloat64
+		)
+
+		err := rows.Scan(&f.ID, &f.Key, &f.ProductType, &namesJSON, &minWidth, &maxWidth, &minHeight, &maxHeight,
+			&f.Aggregation, &f.Divisor, &f.IsActive)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования признака: %w", err)
+		}
+
+		if err := json.Unmarshal([]byte(namesJSON), &f.MatchNames); err != nil {
+			return nil, fmt.Errorf("ошибка парсинга JSON наименований признака %s: %w", f.Key, err)
+		}
+
+		f.MinWidth = nullFloatPtr(minWidth)
+		f.MaxWidth = nullFloatPtr(maxWidth)
+		f.MinHeight = nullFloatPtr(minHeight)
+		f.MaxHeight = nullFloatPtr(maxHeight)
+
+		features = append(features, f)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при итерации по строкам: %w", err)
+	}
+
+	return features, nil
+}
+
+func nullFloatPtr(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	f := v.Float64
+	return &f
+}
@@ -628,6 +628,9 @@ const (
 	StatusFinal    = "final"
 )
 
+// ReportStatuses — статусы изделий, попадающих в отчёт ПЭО
+var ReportStatuses = []string{StatusAssigned, StatusFinal}
+
 // --- Вспомогательные структуры и конструкторы запросов ---
 
 // buildProductFilters формирует SQL условия и аргументы
@@ -636,8 +639,10 @@ func buildProductFilters(f ProductFilter) (string, []interface{}) {
 	var args []interface{}
 
 	// Стандартные условия (всегда присутствуют)
-	conditions = append(conditions, "p.status IN (?, ?)")
-	args = append(args, StatusAssigned, StatusFinal)
+	conditions = append(conditions, fmt.Sprintf("p.status IN (%s)", placeholders(len(ReportStatuses))))
+	for _, status := range ReportStatuses {
+		args = append(args, status)
+	}
 
 	if !f.From.IsZero() {
 		conditions = append(conditions, "p.ready_date >= ?")
@@ -726,14 +731,14 @@ func (s *Storage) fetchProducts(ctx context.Context, f ProductFilter) (map[int64
 			p.id, p.order_num, p.customer, p.total_time, p.created_at, p.status,
 			p.part_type, p.type, p.parent_product_id, p.parent_assembly,
 			COALESCE(c.short_name_customer, p.customer_type) AS customer_type,
-			p.systema, p.type_izd, p.profile, p.count, p.sqr, p.brigade, 
+			p.systema, p.type_izd, p.profile, p.count, p.sqr, p.sqr_stv, p.brigade, 
 			p.norm_money, p.position, p.ready_date,
-			COALESCE(p.coefficient, dc.coefficient) AS coefficient
+			%s AS coefficient, %s AS coefficient_source, %s AS coefficient_from, %s AS customer_factor, p.row_version
 		FROM dem_product_instances_al p
 		LEFT JOIN dem_customer_al c ON p.customer = c.name
 		LEFT JOIN dem_coefficient_al dc ON dc.type = p.type
 		%s
-		ORDER BY p.ready_date DESC, p.order_num`, whereClause)
+		ORDER BY p.ready_date DESC, p.order_num`, effectiveCoefficientSQL, coefficientSourceSQL, coefficientFromSQL, customerFactorSQL, whereClause)
 
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -747,15 +752,15 @@ func (s *Storage) fetchProducts(ctx context.Context, f ProductFilter) (map[int64
 	for rows.Next() {
 		var p storage.PEOProduct
 		var parentID sql.NullInt64
-		var readyDate sql.NullTime
-		var coef sql.NullFloat64
+		var readyDate, coefFrom sql.NullTime
+		var coef, sqrStv sql.NullFloat64
 
 		err := rows.Scan(
 			&p.ID, &p.OrderNum, &p.Customer, &p.TotalTime, &p.CreatedAt, &p.Status,
 			&p.PartType, &p.Type, &parentID, &p.ParentAssembly,
 			&p.CustomerType, &p.Systema, &p.TypeIzd, &p.Profile,
-			&p.Count, &p.Sqr, &p.Brigade, &p.NormMoney, &p.Position,
-			&readyDate, &coef,
+			&p.Count, &p.Sqr, &sqrStv, &p.Brigade, &p.NormMoney, &p.Position,
+			&readyDate, &coef, &p.CoefficientSource, &coefFrom, &p.CustomerFactor, &p.RowVersion,
 		)
 		if err != nil {
 			return nil, nil, err
@@ -773,6 +778,14 @@ func (s *Storage) fetchProducts(ctx context.Context, f ProductFilter) (map[int64
 			v := coef.Float64
 			p.Coefficient = &v
 		}
+		if coefFrom.Valid {
+			t := coefFrom.Time
+			p.CoefficientFrom = &t
+		}
+		if sqrStv.Valid {
+			v := sqrStv.Float64
+			p.SqrStv = &v
+		}
 
 		p.EmployeeMinutes = make(map[int64]float64)
 		p.EmployeeValue = make(map[int64]float64)
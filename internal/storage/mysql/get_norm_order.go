@@ -11,13 +11,13 @@ import (
 func (s *Storage) GetNormOrder(ctx context.Context, id int64) (*storage.GetOrderDetails, error) {
 	const op = "storage.mysql.GetNormOrder"
 
-	stmtOrder := "SELECT order_num, name, count, total_time, created_at, updated_at, type FROM dem_product_instances_al WHERE id = ?"
+	stmtOrder := "SELECT order_num, name, count, total_time, created_at, updated_at, type, status, row_version FROM dem_product_instances_al WHERE id = ?"
 
 	stmtOperation := "SELECT operation_name, operation_label, count, value, minutes FROM dem_operation_values_al WHERE product_id = ? ORDER BY sort_operation ASC"
 
-	var res storage.GetOrderDetails
+	res := storage.GetOrderDetails{ID: id}
 
-	err := s.db.QueryRowContext(ctx, stmtOrder, id).Scan(&res.OrderNum, &res.Name, &res.Count, &res.TotalTime, &res.CreatedAT, &res.UpdatedAT, &res.Type)
+	err := s.db.QueryRowContext(ctx, stmtOrder, id).Scan(&res.OrderNum, &res.Name, &res.Count, &res.TotalTime, &res.CreatedAT, &res.UpdatedAT, &res.Type, &res.Status, &res.RowVersion)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("%s: нормировка не найдена: %w", op, err)
@@ -184,7 +184,8 @@ func (s *Storage) GetNormOrderIdSub(ctx context.Context, id int64) ([]*storage.G
 	stmt := `
 		SELECT 
 			pi.id, pi.name, pi.count, pi.total_time, pi.created_at, pi.updated_at, pi.type, pi.part_type, pi.parent_assembly, 
-			pi.parent_product_id, pi.order_num, pi.template_code, t.head_name, pi.type_izd, pi.status, pi.ready_date, pi.position
+			pi.parent_product_id, pi.order_num, pi.template_code, t.head_name, pi.type_izd, pi.status, pi.ready_date, pi.position,
+			pi.template_version, pi.row_version
 		FROM dem_product_instances_al pi
 		LEFT JOIN dem_templates_al t ON pi.template_code = t.code
 		WHERE pi.id = ? OR pi.parent_product_id = ?
@@ -226,6 +227,8 @@ func (s *Storage) GetNormOrderIdSub(ctx context.Context, id int64) ([]*storage.G
 			&detail.Status,
 			&detail.ReadyDate,
 			&detail.Position,
+			&detail.TemplateVersion,
+			&detail.RowVersion,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("%s: ошибка сканирования: %w", op, err)
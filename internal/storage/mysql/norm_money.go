@@ -0,0 +1,141 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"vue-golang/internal/storage"
+)
+
+// customerFactorSQL — множитель Н/руб направления заказчика изделия p (нет записи — 1)
+const customerFactorSQL = `COALESCE((SELECT cf.factor FROM dem_customer_type_factors_al cf WHERE cf.customer_type = p.customer_type), 1)`
+
+// computedNormMoneySQL — Н/руб изделия p: Н/час × коэффициент × множитель направления, до тысячных, как считал фронтенд.
+// Кол-во отдельно не умножается: правила шаблона уже умножили операции на ItemCount, и total_time — на всю позицию.
+// Без коэффициента — NULL. В запросе должен быть LEFT JOIN dem_coefficient_al dc ON dc.type = p.type.
+var computedNormMoneySQL = fmt.Sprintf(`ROUND(p.total_time * %s * %s, 3)`, effectiveCoefficientSQL, customerFactorSQL)
+
+// executorMoneySQL — заработок по строке исполнителя oe изделия p, как storage.ExecutorMoney; без коэффициента — 0.
+// Округлять до копеек нужно итог (ROUND(SUM(...), 2)), а не строки. В запросе должен быть LEFT JOIN dem_coefficient_al dc ON dc.type = p.type.
+var executorMoneySQL = fmt.Sprintf(`COALESCE(oe.actual_value, 0) * COALESCE(%s, 0) * %s`, effectiveCoefficientSQL, customerFactorSQL)
+
+// recomputeNormMoneyTx пересчитывает Н/руб изделий; если коэффициента нет, прежнее значение остаётся
+func recomputeNormMoneyTx(ctx context.Context, tx *sql.Tx, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE dem_product_instances_al p
+		LEFT JOIN dem_coefficient_al dc ON dc.type = p.type
+		SET p.norm_money = COALESCE(%s, p.norm_money)
+		WHERE p.id IN (%s)`, computedNormMoneySQL, placeholders(len(ids))), toInterfaceSlice(ids)...)
+	if err != nil {
+		return fmt.Errorf("ошибка пересчёта Н/руб: %w", err)
+	}
+
+	return nil
+}
+
+// recomputeTypeNormMoneyTx — пересчёт после изменения истории коэффициентов типа: изделия без своего коэффициента,
+// кроме тех, что в закрытых расчётных периодах
+func recomputeTypeNormMoneyTx(ctx context.Context, tx *sql.Tx, productType string) error {
+	args := []interface{}{productType}
+	frozen := ""
+	if team, ok := storage.ProductTeams[productType]; ok {
+		frozen = `AND NOT EXISTS (SELECT 1 FROM dem_payroll_periods_al pp JOIN dem_teams_al t ON t.id = pp.team_id
+			WHERE t.slug = ? AND pp.status = ? AND pp.period_start = DATE_FORMAT(p.ready_date, '%Y-%m-01'))`
+		args = append(args, team, storage.PeriodClosed)
+	}
+
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE dem_product_instances_al p
+		LEFT JOIN dem_coefficient_al dc ON dc.type = p.type
+		SET p.norm_money = COALESCE(%s, p.norm_money)
+		WHERE p.type = ? AND p.coefficient IS NULL %s`, computedNormMoneySQL, frozen), args...)
+	if err != nil {
+		return fmt.Errorf("ошибка пересчёта Н/руб типа %s: %w", productType, err)
+	}
+
+	return nil
+}
+
+// GetNormMoneyMismatches — отчёт для админки: изделия, где сохранённая Н/руб расходится с посчитанной больше чем на tolerance
+func (s *Storage) GetNormMoneyMismatches(ctx context.Context, tolerance float64) ([]storage.NormMoneyMismatch, error) {
+	const op = "storage.mysql.GetNormMoneyMismatches"
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT id, order_num, position, type, status, customer_type, ready_date, total_time,
+			coefficient, coefficient_source, factor, stored, computed
+		FROM (
+			SELECT p.id, p.order_num, COALESCE(p.position, 0) AS position, COALESCE(p.type, '') AS type,
+				COALESCE(p.status, '') AS status, COALESCE(p.customer_type, '') AS customer_type, p.ready_date,
+				p.total_time, %s AS coefficient, %s AS coefficient_source, %s AS factor,
+				COALESCE(p.norm_money, 0) AS stored, %s AS computed
+			FROM dem_product_instances_al p
+			LEFT JOIN dem_coefficient_al dc ON dc.type = p.type
+			WHERE COALESCE(p.status, '') <> 'cancel' AND p.superseded_by IS NULL
+		) AS t
+		WHERE computed IS NOT NULL AND ABS(stored - computed) > ?
+		ORDER BY ABS(stored - computed) DESC, id`,
+		effectiveCoefficientSQL, coefficientSourceSQL, customerFactorSQL, computedNormMoneySQL), tolerance)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	mismatches := []storage.NormMoneyMismatch{}
+	for rows.Next() {
+		var (
+			m     storage.NormMoneyMismatch
+			ready sql.NullTime
+		)
+		err := rows.Scan(&m.ID, &m.OrderNum, &m.Position, &m.Type, &m.Status, &m.CustomerType, &ready, &m.TotalTime,
+			&m.Coefficient, &m.CoefficientSource, &m.Factor, &m.Stored, &m.Computed)
+		if err != nil {
+			return nil, fmt.Errorf("%s: ошибка сканирования изделия: %w", op, err)
+		}
+		if ready.Valid {
+			t := ready.Time
+			m.ReadyDate = &t
+		}
+		m.Diff = m.Stored - m.Computed
+		mismatches = append(mismatches, m)
+	}
+
+	return mismatches, rows.Err()
+}
+
+// RecomputeNormMoney — исправление по отчёту: пересчитывает Н/руб выбранных изделий, кроме замороженных закрытым периодом.
+// Возвращает ID пересчитанных изделий.
+func (s *Storage) RecomputeNormMoney(ctx context.Context, ids []int64) ([]int64, error) {
+	const op = "storage.mysql.RecomputeNormMoney"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: старт транзакции: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var open []int64
+	for _, id := range ids {
+		err := checkPeriodsOpenTx(ctx, tx, []int64{id}, "")
+		if err == nil {
+			open = append(open, id)
+			continue
+		}
+		var closed *storage.PeriodClosedError
+		if !errors.As(err, &closed) {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := recomputeNormMoneyTx(ctx, tx, open); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: ошибка завершения транзакции: %w", op, err)
+	}
+
+	return open, nil
+}
@@ -192,3 +192,37 @@ func (s *Storage) GetOrderDetails(ctx context.Context, orderNum string) ([]*stor
 
 	return details, nil
 }
+
+// GetOrderPositionAttributes — systema/type_izd/profile позиций заказа по последней действующей нормировке изделия.
+// Позиции, которые ещё не нормировали, в результат не попадают.
+func (s *Storage) GetOrderPositionAttributes(ctx context.Context, orderNum string) (map[int]storage.PositionAttributes, error) {
+	const op = "storage.mysql.GetOrderPositionAttributes"
+
+	stmt := `SELECT position, COALESCE(systema, ''), COALESCE(type_izd, ''), COALESCE(profile, '')
+			 FROM dem_product_instances_al
+			 WHERE order_num = ? AND position IS NOT NULL AND ` + activeNormCondition + `
+			 ORDER BY id DESC`
+
+	rows, err := s.db.QueryContext(ctx, stmt, orderNum)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	attrs := make(map[int]storage.PositionAttributes)
+	for rows.Next() {
+		var (
+			pos int
+			a   storage.PositionAttributes
+		)
+		if err := rows.Scan(&pos, &a.Systema, &a.TypeIzd, &a.Profile); err != nil {
+			return nil, fmt.Errorf("%s: ошибка сканирования атрибутов позиции: %w", op, err)
+		}
+		// строки идут от новых к старым — оставляем последнюю нормировку позиции
+		if _, ok := attrs[pos]; !ok {
+			attrs[pos] = a
+		}
+	}
+
+	return attrs, rows.Err()
+}
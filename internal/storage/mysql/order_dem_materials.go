@@ -3,6 +3,7 @@ package mysql
 import (
 	"context"
 	"fmt"
+	"vue-golang/internal/constants"
 	"vue-golang/internal/storage"
 )
 
@@ -20,17 +21,22 @@ func (s *Storage) GetOrderMaterials(ctx context.Context, orderNum string, pos in
 		return nil, fmt.Errorf("%s: ошибка выполнения запроса для получения id который нужен для материалов %w", op, err)
 	}
 
-	stmt := `SELECT idorders, articul_mat, name_mat, width, height, count, pole, position FROM dem_klaes_materials 
-            	WHERE idorders=? AND position=? AND TRIM(name_mat) IN ('импост', 'стойка-импост', 'профиль импостный',
-            	'импост в дверь', 'Накладка на цилиндр Stublina', 'Створка Т-образная', 'Створка-коробка', 'Створка Т - образ.',
-            	'Петля роликовая RDRH', 'Многозапорный замок Stublina с управлением от ручки', 'Петля роликовая для КП45',
-            	'Петля Фурал дверная 2-част. с подшипником', 'Петля дверная трехсекционная с удлиненной базой', 'Притвор КП40',
-            	'Петля двухсекционная 67мм', 'Накладка на цилиндр Stublina (под покраску)', 'Замок Elementis 1155 (D30) (для бугельных ручек)',
-            	'Замок Elementis 1153 (D30) (под нажимной гарнитур)', 'Штульп', 'Створка оконная', 'Створка оконная усиленная прямоугольная',
-            	'Фурнитурная тяга', 'Многозапорный замок KFV AS4350 с управлением от ручки', 'Многозапорный замок KFV AS2750 с управлением от ключа',
-            	'Рама нижняя', 'Створка оконная усиленная', 'Створка верх/низ')`
-
-	rows, err := s.db.QueryContext(ctx, stmt, id, pos)
+	// Материалы встроенных признаков Context + наименования из реестра признаков
+	names, err := s.featureMaterialNames(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: ошибка получения наименований материалов из реестра признаков %w", op, err)
+	}
+	names = append(names, constants.BuiltinMaterialNames()...)
+
+	stmt := fmt.Sprintf(`SELECT idorders, articul_mat, name_mat, width, height, count, pole, position FROM dem_klaes_materials 
+            	WHERE idorders=? AND position=? AND TRIM(name_mat) IN (%s)`, placeholders(len(names)))
+
+	args := []interface{}{id, pos}
+	for _, name := range names {
+		args = append(args, name)
+	}
+
+	rows, err := s.db.QueryContext(ctx, stmt, args...)
 	if err != nil {
 		return nil, fmt.Errorf("%s: ошибка выполнения запроса для получения материалов %w", op, err)
 	}
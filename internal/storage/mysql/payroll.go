@@ -0,0 +1,353 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"vue-golang/internal/storage"
+)
+
+// ClosePayrollPeriod закрывает месяц бригады и сохраняет снимок итогов по сотрудникам.
+// Правки, уже начатые по изделиям периода, держат его строку (или промежуток индекса) и закрытие их дожидается.
+func (s *Storage) ClosePayrollPeriod(ctx context.Context, team string, month time.Time, actor string) (storage.PayrollPeriod, error) {
+	const op = "storage.mysql.ClosePayrollPeriod"
+
+	start := monthStart(month)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return storage.PayrollPeriod{}, fmt.Errorf("%s: старт транзакции: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	teamID, err := teamIDTx(ctx, tx, team)
+	if err != nil {
+		return storage.PayrollPeriod{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT IGNORE INTO dem_payroll_periods_al (team_id, period_start, status) VALUES (?, ?, ?)`,
+		teamID, start.Format("2006-01-02"), storage.PeriodOpen); err != nil {
+		return storage.PayrollPeriod{}, fmt.Errorf("%s: ошибка создания периода: %w", op, err)
+	}
+
+	periodID, status, err := lockPeriodTx(ctx, tx, teamID, start)
+	if err != nil {
+		return storage.PayrollPeriod{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if status == storage.PeriodClosed {
+		return storage.PayrollPeriod{}, fmt.Errorf("%s: период %s бригады %s уже закрыт: %w", op, start.Format("2006-01"), team, storage.ErrPeriodState)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE dem_payroll_periods_al SET status = ? WHERE id = ?`, storage.PeriodClosed, periodID); err != nil {
+		return storage.PayrollPeriod{}, fmt.Errorf("%s: ошибка закрытия периода: %w", op, err)
+	}
+
+	eventID, err := insertPeriodEventTx(ctx, tx, periodID, storage.PeriodActionClose, actor, "")
+	if err != nil {
+		return storage.PayrollPeriod{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := insertPayrollSnapshotTx(ctx, tx, eventID, team, start); err != nil {
+		return storage.PayrollPeriod{}, fmt.Errorf("%s: ошибка записи итогов: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return storage.PayrollPeriod{}, fmt.Errorf("%s: ошибка завершения транзакции: %w", op, err)
+	}
+
+	return s.GetPayrollPeriod(ctx, periodID)
+}
+
+// ReopenPayrollPeriod снова разрешает правки изделий периода; причина пишется в историю периода.
+// Снимок прошлого закрытия остаётся, при следующем закрытии пишется новый.
+func (s *Storage) ReopenPayrollPeriod(ctx context.Context, team string, month time.Time, actor, reason string) (storage.PayrollPeriod, error) {
+	const op = "storage.mysql.ReopenPayrollPeriod"
+
+	start := monthStart(month)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return storage.PayrollPeriod{}, fmt.Errorf("%s: старт транзакции: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	teamID, err := teamIDTx(ctx, tx, team)
+	if err != nil {
+		return storage.PayrollPeriod{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	periodID, status, err := lockPeriodTx(ctx, tx, teamID, start)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.PayrollPeriod{}, fmt.Errorf("%s: период %s бригады %s: %w", op, start.Format("2006-01"), team, storage.ErrPeriodMissing)
+	}
+	if err != nil {
+		return storage.PayrollPeriod{}, fmt.Errorf("%s: %w", op, err)
+	}
+	if status != storage.PeriodClosed {
+		return storage.PayrollPeriod{}, fmt.Errorf("%s: период %s бригады %s не закрыт: %w", op, start.Format("2006-01"), team, storage.ErrPeriodState)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE dem_payroll_periods_al SET status = ? WHERE id = ?`, storage.PeriodOpen, periodID); err != nil {
+		return storage.PayrollPeriod{}, fmt.Errorf("%s: ошибка переоткрытия периода: %w", op, err)
+	}
+
+	if _, err := insertPeriodEventTx(ctx, tx, periodID, storage.PeriodActionReopen, actor, reason); err != nil {
+		return storage.PayrollPeriod{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return storage.PayrollPeriod{}, fmt.Errorf("%s: ошибка завершения транзакции: %w", op, err)
+	}
+
+	return s.GetPayrollPeriod(ctx, periodID)
+}
+
+// GetPayrollPeriods — все периоды, которые хоть раз закрывали, от новых к старым
+func (s *Storage) GetPayrollPeriods(ctx context.Context) ([]storage.PayrollPeriod, error) {
+	const op = "storage.mysql.GetPayrollPeriods"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT pp.id, pp.team_id, t.slug, t.name, pp.period_start, pp.status, pp.updated_at
+		FROM dem_payroll_periods_al pp
+		JOIN dem_teams_al t ON t.id = pp.team_id
+		ORDER BY pp.period_start DESC, t.slug`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	periods := []storage.PayrollPeriod{}
+	for rows.Next() {
+		p, err := scanPeriod(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: ошибка сканирования периода: %w", op, err)
+		}
+		periods = append(periods, p)
+	}
+
+	return periods, rows.Err()
+}
+
+// GetPayrollPeriod — период с историей закрытий и итогами последнего закрытия
+func (s *Storage) GetPayrollPeriod(ctx context.Context, id int64) (storage.PayrollPeriod, error) {
+	const op = "storage.mysql.GetPayrollPeriod"
+
+	p, err := scanPeriod(s.db.QueryRowContext(ctx, `
+		SELECT pp.id, pp.team_id, t.slug, t.name, pp.period_start, pp.status, pp.updated_at
+		FROM dem_payroll_periods_al pp
+		JOIN dem_teams_al t ON t.id = pp.team_id
+		WHERE pp.id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return p, fmt.Errorf("%s: id=%d: %w", op, id, storage.ErrPeriodMissing)
+	}
+	if err != nil {
+		return p, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT id, action, actor, reason, created_at
+		FROM dem_payroll_period_events_al WHERE period_id = ? ORDER BY id`, id)
+	if err != nil {
+		return p, fmt.Errorf("%s: ошибка получения истории периода: %w", op, err)
+	}
+	defer rows.Close()
+
+	var lastClose int64
+	for rows.Next() {
+		var e storage.PeriodEvent
+		if err := rows.Scan(&e.ID, &e.Action, &e.Actor, &e.Reason, &e.CreatedAt); err != nil {
+			return p, fmt.Errorf("%s: ошибка сканирования истории периода: %w", op, err)
+		}
+		if e.Action == storage.PeriodActionClose {
+			lastClose = e.ID
+		}
+		p.Events = append(p.Events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return p, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if lastClose == 0 {
+		return p, nil
+	}
+
+	totals, err := s.db.QueryContext(ctx, `SELECT employee_id, employee_name, operations, actual_minutes, actual_value, money
+		FROM dem_payroll_snapshots_al WHERE event_id = ? ORDER BY employee_name`, lastClose)
+	if err != nil {
+		return p, fmt.Errorf("%s: ошибка получения итогов периода: %w", op, err)
+	}
+	defer totals.Close()
+
+	for totals.Next() {
+		var t storage.PayrollTotal
+		if err := totals.Scan(&t.EmployeeID, &t.EmployeeName, &t.Operations, &t.ActualMinutes, &t.ActualValue, &t.Money); err != nil {
+			return p, fmt.Errorf("%s: ошибка сканирования итогов периода: %w", op, err)
+		}
+		p.Totals = append(p.Totals, t)
+	}
+
+	return p, totals.Err()
+}
+
+// checkPeriodsOpenTx не даёт править изделия из закрытых расчётных периодов. Период изделия — месяц его ready_date
+// у бригады его типа; readyDate, если задана, — новая дата готовности и тоже не должна попадать в закрытый период.
+// Строка периода читается с разделяемой блокировкой, чтобы закрытие дождалось этой правки.
+func checkPeriodsOpenTx(ctx context.Context, tx *sql.Tx, ids []int64, readyDate string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var newDate *time.Time
+	if readyDate != "" {
+		d, err := time.Parse("2006-01-02", readyDate[:min(len(readyDate), 10)])
+		if err != nil {
+			return fmt.Errorf("неверная дата готовности %q: %w", readyDate, err)
+		}
+		newDate = &d
+	}
+
+	type periodKey struct {
+		team  string
+		start time.Time
+	}
+	type check struct {
+		key       periodKey
+		productID int64
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, ready_date, COALESCE(type, '') FROM dem_product_instances_al
+		WHERE id IN (`+placeholders(len(ids))+`) ORDER BY id`, toInterfaceSlice(ids)...)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[periodKey]bool)
+	var checks []check
+	for rows.Next() {
+		var (
+			id          int64
+			ready       sql.NullTime
+			productType string
+		)
+		if err := rows.Scan(&id, &ready, &productType); err != nil {
+			rows.Close()
+			return err
+		}
+
+		team, ok := storage.ProductTeams[productType]
+		if !ok {
+			continue
+		}
+
+		var dates []time.Time
+		if ready.Valid {
+			dates = append(dates, ready.Time)
+		}
+		if newDate != nil {
+			dates = append(dates, *newDate)
+		}
+		for _, d := range dates {
+			key := periodKey{team, monthStart(d)}
+			if !seen[key] {
+				seen[key] = true
+				checks = append(checks, check{key, id})
+			}
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, c := range checks {
+		var status string
+		err := tx.QueryRowContext(ctx, `SELECT pp.status FROM dem_payroll_periods_al pp
+			WHERE pp.team_id = (SELECT id FROM dem_teams_al WHERE slug = ?) AND pp.period_start = ? FOR SHARE`,
+			c.key.team, c.key.start.Format("2006-01-02")).Scan(&status)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("период %s бригады %s: %w", c.key.start.Format("2006-01"), c.key.team, err)
+		}
+		if status == storage.PeriodClosed {
+			return &storage.PeriodClosedError{ProductID: c.productID, Team: c.key.team, Period: c.key.start.Format("2006-01")}
+		}
+	}
+
+	return nil
+}
+
+// insertPayrollSnapshotTx сохраняет итоги сотрудников по изделиям бригады с ready_date в месяце — так же, как считает ведомость
+func insertPayrollSnapshotTx(ctx context.Context, tx *sql.Tx, eventID int64, team string, start time.Time) error {
+	types := storage.TeamProductTypes(team)
+	if len(types) == 0 {
+		return nil
+	}
+
+	args := []interface{}{eventID}
+	for _, t := range types {
+		args = append(args, t)
+	}
+	args = append(args, StatusAssigned, StatusFinal, start.Format("2006-01-02"), start.AddDate(0, 1, 0).Format("2006-01-02"))
+
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO dem_payroll_snapshots_al (event_id, employee_id, employee_name, operations, actual_minutes, actual_value, money)
+		SELECT ?, oe.employee_id, e.name, COUNT(*), SUM(oe.actual_minutes), SUM(COALESCE(oe.actual_value, 0)),
+			ROUND(SUM(%s), 2)
+		FROM dem_operation_executors_al oe
+		JOIN dem_product_instances_al p ON p.id = oe.product_id
+		JOIN dem_employees_al e ON e.id = oe.employee_id
+		LEFT JOIN dem_coefficient_al dc ON dc.type = p.type
+		WHERE p.type IN (%s)
+		  AND p.status IN (?, ?)
+		  AND p.ready_date >= ? AND p.ready_date < ?
+		GROUP BY oe.employee_id, e.name`, executorMoneySQL, placeholders(len(types))), args...)
+
+	return err
+}
+
+func teamIDTx(ctx context.Context, tx *sql.Tx, slug string) (int64, error) {
+	var id int64
+	err := tx.QueryRowContext(ctx, `SELECT id FROM dem_teams_al WHERE slug = ?`, slug).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("бригада %q: %w", slug, storage.ErrTeamNotFound)
+	}
+	return id, err
+}
+
+func lockPeriodTx(ctx context.Context, tx *sql.Tx, teamID int64, start time.Time) (int64, string, error) {
+	var (
+		id     int64
+		status string
+	)
+	err := tx.QueryRowContext(ctx, `SELECT id, status FROM dem_payroll_periods_al WHERE team_id = ? AND period_start = ? FOR UPDATE`,
+		teamID, start.Format("2006-01-02")).Scan(&id, &status)
+	return id, status, err
+}
+
+func insertPeriodEventTx(ctx context.Context, tx *sql.Tx, periodID int64, action, actor, reason string) (int64, error) {
+	res, err := tx.ExecContext(ctx, `INSERT INTO dem_payroll_period_events_al (period_id, action, actor, reason) VALUES (?, ?, ?, ?)`,
+		periodID, action, actor, reason)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка записи истории периода: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+func scanPeriod(row rowScanner) (storage.PayrollPeriod, error) {
+	var (
+		p     storage.PayrollPeriod
+		start time.Time
+	)
+	if err := row.Scan(&p.ID, &p.TeamID, &p.Team, &p.TeamName, &start, &p.Status, &p.UpdatedAt); err != nil {
+		return p, err
+	}
+	p.Period = start.Format("2006-01")
+	return p, nil
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
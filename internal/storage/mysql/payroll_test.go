@@ -0,0 +1,75 @@
+package mysql
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"vue-golang/internal/service/statement"
+	"vue-golang/internal/storage"
+)
+
+func cleanupPayrollPeriod(t *testing.T, team string, start time.Time) {
+	_, err := testDB.Exec(`DELETE pp FROM dem_payroll_periods_al pp JOIN dem_teams_al t ON t.id = pp.team_id
+		WHERE t.slug = ? AND pp.period_start = ?`, team, start.Format("2006-01-02"))
+	require.NoError(t, err)
+}
+
+// Тест: снимок закрытия периода и ведомость сотрудника дают одну сумму (коэффициент × множитель направления,
+// до копеек только итог), хотя при округлении каждой строки вышло бы 26.82
+func TestStorage_ClosePayrollPeriod_MatchesStatement(t *testing.T) {
+	const (
+		orderNum     = "Q6-PAYROLL"
+		customerType = "q6"
+		team         = "windows"
+		employeeID   = int64(1)
+	)
+	start := time.Date(2031, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	cleanup := func() {
+		cleanupPayrollPeriod(t, team, start)
+		cleanupNormOrder(t, orderNum)
+		_, err := testDB.Exec(`DELETE FROM dem_customer_type_factors_al WHERE customer_type = ?`, customerType)
+		require.NoError(t, err)
+	}
+	cleanup()
+	defer cleanup()
+
+	ctx := context.Background()
+	s := &Storage{db: testDB}
+
+	_, err := testDB.Exec(`INSERT INTO dem_customer_type_factors_al (customer_type, factor) VALUES (?, 1.15)`, customerType)
+	require.NoError(t, err)
+
+	for position := 1; position <= 3; position++ {
+		saved, err := s.SaveNormOrder(ctx, storage.OrderNormDetails{OrderNum: orderNum, Position: position, TemplateCode: "window",
+			Name: "Окно", Count: 1, Type: "window", PartType: "main", Status: "draft"})
+		require.NoError(t, err)
+
+		_, err = testDB.Exec(`UPDATE dem_product_instances_al
+			SET status = ?, ready_date = ?, customer_type = ?, coefficient = 77.77 WHERE id = ?`,
+			StatusFinal, start.AddDate(0, 0, position).Format("2006-01-02"), customerType, saved.OrderID)
+		require.NoError(t, err)
+		_, err = testDB.Exec(`INSERT INTO dem_operation_executors_al (product_id, operation_name, employee_id, actual_minutes, actual_value)
+			VALUES (?, 'cut', ?, 6, 0.1)`, saved.OrderID, employeeID)
+		require.NoError(t, err)
+	}
+
+	lines, err := s.GetEmployeeStatementLines(ctx, employeeID, start, start.AddDate(0, 1, -1))
+	require.NoError(t, err)
+	require.Len(t, lines, 3)
+	assert.Equal(t, 1.15, lines[0].CustomerFactor)
+	st := statement.Build(storage.GetWorkers{ID: employeeID}, start, start.AddDate(0, 1, -1), lines)
+
+	period, err := s.ClosePayrollPeriod(ctx, team, start, "test")
+	require.NoError(t, err)
+
+	var money float64
+	require.NoError(t, testDB.QueryRow(`SELECT ps.money FROM dem_payroll_snapshots_al ps
+		JOIN dem_payroll_period_events_al pe ON pe.id = ps.event_id
+		WHERE pe.period_id = ? AND ps.employee_id = ?`, period.ID, employeeID).Scan(&money))
+
+	assert.Equal(t, 26.83, st.Total.Money)
+	assert.Equal(t, st.Total.Money, money)
+}
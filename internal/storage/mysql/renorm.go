@@ -0,0 +1,179 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"vue-golang/internal/storage"
+)
+
+// GetRenormCandidates — нефинальные, неотменённые и незаменённые изделия, сохранённые по шаблону, с текущими операциями
+func (s *Storage) GetRenormCandidates(ctx context.Context, templateCode string) ([]storage.RenormCandidate, error) {
+	const op = "storage.mysql.GetRenormCandidates"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, parent_product_id, order_num, position, name, COALESCE(type, ''), COALESCE(type_izd, ''),
+			COALESCE(systema, ''), COALESCE(profile, ''), COALESCE(status, ''), count, total_time, template_version
+		FROM dem_product_instances_al
+		WHERE template_code = ? AND superseded_by IS NULL AND COALESCE(status, '') NOT IN ('final', 'cancel')
+		ORDER BY order_num, position, id`, templateCode)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	candidates := make([]storage.RenormCandidate, 0)
+	byID := make(map[int64]int)
+	for rows.Next() {
+		var c storage.RenormCandidate
+		err := rows.Scan(&c.ID, &c.ParentProductID, &c.OrderNum, &c.Position, &c.Name, &c.Type, &c.TypeIzd,
+			&c.Systema, &c.Profile, &c.Status, &c.Count, &c.TotalTime, &c.TemplateVersion)
+		if err != nil {
+			return nil, fmt.Errorf("%s: ошибка сканирования строки: %w", op, err)
+		}
+		c.Operations = make([]storage.NormOperation, 0)
+		c.Executors = make(map[string]int)
+		byID[c.ID] = len(candidates)
+		candidates = append(candidates, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: ошибка при итерации по строкам: %w", op, err)
+	}
+	rows.Close()
+
+	if len(candidates) == 0 {
+		return candidates, nil
+	}
+
+	ids := make([]int64, 0, len(candidates))
+	for _, c := range candidates {
+		ids = append(ids, c.ID)
+	}
+	in := placeholders(len(ids))
+
+	opRows, err := s.db.QueryContext(ctx, `SELECT product_id, operation_name, operation_label, count, value, minutes
+		FROM dem_operation_values_al WHERE product_id IN (`+in+`) ORDER BY product_id, sort_operation, id`, toInterfaceSlice(ids)...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: операции: %w", op, err)
+	}
+	defer opRows.Close()
+
+	for opRows.Next() {
+		var (
+			productID int64
+			o         storage.NormOperation
+		)
+		if err := opRows.Scan(&productID, &o.Name, &o.Label, &o.Count, &o.Value, &o.Minutes); err != nil {
+			return nil, fmt.Errorf("%s: операции: %w", op, err)
+		}
+		c := &candidates[byID[productID]]
+		c.Operations = append(c.Operations, o)
+	}
+	if err = opRows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: операции: %w", op, err)
+	}
+
+	execRows, err := s.db.QueryContext(ctx, `SELECT product_id, operation_name, COUNT(*)
+		FROM dem_operation_executors_al WHERE product_id IN (`+in+`) GROUP BY product_id, operation_name`, toInterfaceSlice(ids)...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: назначения: %w", op, err)
+	}
+	defer execRows.Close()
+
+	for execRows.Next() {
+		var (
+			productID int64
+			name      string
+			count     int
+		)
+		if err := execRows.Scan(&productID, &name, &count); err != nil {
+			return nil, fmt.Errorf("%s: назначения: %w", op, err)
+		}
+		candidates[byID[productID]].Executors[name] = count
+	}
+	if err = execRows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: назначения: %w", op, err)
+	}
+
+	return candidates, nil
+}
+
+// RenormProduct перезаписывает операции изделия пересчитанными. Назначения сотрудников остаются у операций,
+// которые есть в новой нормировке; назначения исчезнувших операций удаляются — их число возвращается.
+// Если изделие успели закрыть или отменить — storage.ErrStatusConflict.
+func (s *Storage) RenormProduct(ctx context.Context, id int64, update storage.RenormUpdate) (int64, error) {
+	const op = "storage.mysql.RenormProduct"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: старт транзакции: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRowContext(ctx, `SELECT COALESCE(status, '') FROM dem_product_instances_al WHERE id = ? FOR UPDATE`, id).Scan(&status)
+	if err != nil {
+		return 0, fmt.Errorf("%s: изделие id=%d: %w", op, id, err)
+	}
+	if status == "final" || status == "cancel" {
+		return 0, fmt.Errorf("%s: изделие id=%d в статусе %q: %w", op, id, status, storage.ErrStatusConflict)
+	}
+	if err := checkPeriodsOpenTx(ctx, tx, []int64{id}, ""); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := ensureBaselineTx(ctx, tx, id); err != nil {
+		return 0, fmt.Errorf("%s: ошибка записи исходной ревизии: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE dem_product_instances_al SET total_time = ?, template_version = ?, row_version = row_version + 1 WHERE id = ?`,
+		update.TotalTime, update.TemplateVersion, id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: ошибка обновления изделия: %w", op, err)
+	}
+	if err := recomputeNormMoneyTx(ctx, tx, []int64{id}); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM dem_operation_values_al WHERE product_id = ?`, id); err != nil {
+		return 0, fmt.Errorf("%s: ошибка удаления старых операций: %w", op, err)
+	}
+
+	prepareInsert, err := tx.PrepareContext(ctx, `INSERT INTO dem_operation_values_al
+		(product_id, operation_name, operation_label, count, value, minutes, sort_operation) VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: ошибка при подготовке вставки операций: %w", op, err)
+	}
+	defer prepareInsert.Close()
+
+	names := make([]interface{}, 0, len(update.Operations)+1)
+	names = append(names, id)
+	for i, o := range update.Operations {
+		if _, err := prepareInsert.ExecContext(ctx, id, o.Name, o.Label, o.Count, o.Value, o.Minutes, i); err != nil {
+			return 0, fmt.Errorf("%s: ошибка вставки операции %s: %w", op, o.Name, err)
+		}
+		names = append(names, o.Name)
+	}
+
+	stmtDropExecutors := `DELETE FROM dem_operation_executors_al WHERE product_id = ?`
+	if len(update.Operations) > 0 {
+		stmtDropExecutors += ` AND operation_name NOT IN (` + placeholders(len(update.Operations)) + `)`
+	}
+	res, err := tx.ExecContext(ctx, stmtDropExecutors, names...)
+	if err != nil {
+		return 0, fmt.Errorf("%s: ошибка удаления назначений исчезнувших операций: %w", op, err)
+	}
+	dropped, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := recordRevisionTx(ctx, tx, id, storage.RevisionRenorm, update.ChangedBy); err != nil {
+		return 0, fmt.Errorf("%s: ошибка записи ревизии: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: ошибка завершения транзакции: %w", op, err)
+	}
+
+	return dropped, nil
+}
@@ -0,0 +1,238 @@
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"vue-golang/internal/storage"
+)
+
+// GetProductRevisions — все ревизии изделия по возрастанию номера
+func (s *Storage) GetProductRevisions(ctx context.Context, productID int64) ([]storage.ProductRevision, error) {
+	const op = "storage.mysql.GetProductRevisions"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, product_id, revision, kind, changed_by, snapshot, created_at
+		FROM dem_product_revisions_al
+		WHERE product_id = ?
+		ORDER BY revision`, productID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	revisions := make([]storage.ProductRevision, 0)
+	for rows.Next() {
+		var (
+			r            storage.ProductRevision
+			snapshotJSON []byte
+		)
+		if err := rows.Scan(&r.ID, &r.ProductID, &r.Revision, &r.Kind, &r.ChangedBy, &snapshotJSON, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: ошибка сканирования строки: %w", op, err)
+		}
+		if err := json.Unmarshal(snapshotJSON, &r.Snapshot); err != nil {
+			return nil, fmt.Errorf("%s: ревизия %d: ошибка разбора снимка: %w", op, r.Revision, err)
+		}
+		revisions = append(revisions, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: ошибка при итерации по строкам: %w", op, err)
+	}
+
+	return revisions, nil
+}
+
+// productIDsTx — корень и его sub-изделия
+func productIDsTx(ctx context.Context, tx *sql.Tx, rootProductID int64) ([]int64, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM dem_product_instances_al WHERE id = ? OR parent_product_id = ? ORDER BY id`, rootProductID, rootProductID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// productSnapshotTx читает текущее состояние изделия: итоги, операции по порядку и назначенных сотрудников
+func productSnapshotTx(ctx context.Context, tx *sql.Tx, productID int64) (storage.ProductSnapshot, error) {
+	var snapshot storage.ProductSnapshot
+
+	err := tx.QueryRowContext(ctx, `SELECT total_time, COALESCE(type, ''), COALESCE(status, ''), template_version,
+			COALESCE(customer_type, ''), COALESCE(systema, ''), COALESCE(profile, ''), COALESCE(type_izd, ''), COALESCE(brigade, ''),
+			COALESCE(sqr, 0), coefficient, COALESCE(norm_money, 0)
+		FROM dem_product_instances_al WHERE id = ?`, productID).
+		Scan(&snapshot.TotalTime, &snapshot.Type, &snapshot.Status, &snapshot.TemplateVersion,
+			&snapshot.CustomerType, &snapshot.Systema, &snapshot.Profile, &snapshot.TypeIzd, &snapshot.Brigade,
+			&snapshot.Sqr, &snapshot.Coefficient, &snapshot.NormMoney)
+	if err != nil {
+		return snapshot, fmt.Errorf("изделие id=%d: %w", productID, err)
+	}
+
+	executors := make(map[string][]storage.SnapshotExecutor)
+	rows, err := tx.QueryContext(ctx, `
+		SELECT e.operation_name, e.employee_id, COALESCE(w.name, ''), e.actual_minutes, COALESCE(e.actual_value, 0), COALESCE(e.notes, '')
+		FROM dem_operation_executors_al e
+		LEFT JOIN dem_employees_al w ON w.id = e.employee_id
+		WHERE e.product_id = ?
+		ORDER BY e.operation_name, e.employee_id`, productID)
+	if err != nil {
+		return snapshot, fmt.Errorf("назначения изделия id=%d: %w", productID, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			name string
+			e    storage.SnapshotExecutor
+		)
+		if err := rows.Scan(&name, &e.EmployeeID, &e.EmployeeName, &e.ActualMinutes, &e.ActualValue, &e.Notes); err != nil {
+			return snapshot, fmt.Errorf("назначения изделия id=%d: %w", productID, err)
+		}
+		executors[name] = append(executors[name], e)
+	}
+	if err := rows.Err(); err != nil {
+		return snapshot, fmt.Errorf("назначения изделия id=%d: %w", productID, err)
+	}
+	// в транзакции следующий запрос нельзя выполнить, пока не закрыт предыдущий результат
+	rows.Close()
+
+	opRows, err := tx.QueryContext(ctx, `
+		SELECT operation_name, operation_label, count, value, minutes
+		FROM dem_operation_values_al
+		WHERE product_id = ?
+		ORDER BY sort_operation, id`, productID)
+	if err != nil {
+		return snapshot, fmt.Errorf("операции изделия id=%d: %w", productID, err)
+	}
+	defer opRows.Close()
+
+	snapshot.Operations = make([]storage.SnapshotOperation, 0)
+	for opRows.Next() {
+		var o storage.SnapshotOperation
+		if err := opRows.Scan(&o.Name, &o.Label, &o.Count, &o.Value, &o.Minutes); err != nil {
+			return snapshot, fmt.Errorf("операции изделия id=%d: %w", productID, err)
+		}
+		o.Executors = executors[o.Name]
+		snapshot.Operations = append(snapshot.Operations, o)
+	}
+
+	return snapshot, opRows.Err()
+}
+
+// ensureBaselineTx записывает состояние до первой правки, если у изделия ещё нет ревизий.
+// Строка изделия блокируется первой, поэтому параллельные первые правки идут по очереди,
+// а ON DUPLICATE KEY по UNIQUE(product_id, revision) делает вставку первой ревизии идемпотентной.
+func ensureBaselineTx(ctx context.Context, tx *sql.Tx, productID int64) error {
+	if err := lockProductTx(ctx, tx, productID); err != nil {
+		return err
+	}
+
+	snapshot, err := productSnapshotTx(ctx, tx, productID)
+	if err != nil {
+		return err
+	}
+	snapshotJSON, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO dem_product_revisions_al (product_id, revision, kind, changed_by, snapshot)
+		VALUES (?, 1, ?, '', ?) ON DUPLICATE KEY UPDATE id = id`, productID, storage.RevisionBaseline, string(snapshotJSON))
+	if err != nil {
+		return fmt.Errorf("исходная ревизия изделия id=%d: %w", productID, err)
+	}
+
+	return nil
+}
+
+// lockProductTx блокирует строку изделия до конца транзакции: ревизии одного изделия пишутся по очереди
+func lockProductTx(ctx context.Context, tx *sql.Tx, productID int64) error {
+	var id int64
+	if err := tx.QueryRowContext(ctx, `SELECT id FROM dem_product_instances_al WHERE id = ? FOR UPDATE`, productID).Scan(&id); err != nil {
+		return fmt.Errorf("изделие id=%d: %w", productID, err)
+	}
+
+	return nil
+}
+
+// recordRevisionTx добавляет ревизию с текущим состоянием изделия. Если ничего не изменилось — ревизия не пишется.
+func recordRevisionTx(ctx context.Context, tx *sql.Tx, productID int64, kind, changedBy string) error {
+	if err := lockProductTx(ctx, tx, productID); err != nil {
+		return err
+	}
+
+	snapshot, err := productSnapshotTx(ctx, tx, productID)
+	if err != nil {
+		return err
+	}
+
+	var (
+		last         int
+		lastSnapshot []byte
+	)
+	err = tx.QueryRowContext(ctx, `SELECT revision, snapshot FROM dem_product_revisions_al
+		WHERE product_id = ? ORDER BY revision DESC LIMIT 1 FOR UPDATE`, productID).Scan(&last, &lastSnapshot)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	if last > 0 {
+		same, err := sameSnapshot(lastSnapshot, snapshot)
+		if err != nil {
+			return err
+		}
+		if same {
+			return nil
+		}
+	}
+
+	return insertRevisionTx(ctx, tx, productID, last+1, kind, changedBy, snapshot)
+}
+
+func insertRevisionTx(ctx context.Context, tx *sql.Tx, productID int64, revision int, kind, changedBy string, snapshot storage.ProductSnapshot) error {
+	snapshotJSON, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO dem_product_revisions_al (product_id, revision, kind, changed_by, snapshot) VALUES (?, ?, ?, ?, ?)`,
+		productID, revision, kind, changedBy, string(snapshotJSON))
+	if err != nil {
+		return fmt.Errorf("ревизия %d изделия id=%d: %w", revision, productID, err)
+	}
+
+	return nil
+}
+
+// sameSnapshot сравнивает сохранённый снимок с текущим; MySQL хранит JSON в своём порядке ключей,
+// поэтому сохранённый снимок сначала разбирается и сериализуется заново
+func sameSnapshot(stored []byte, current storage.ProductSnapshot) (bool, error) {
+	var previous storage.ProductSnapshot
+	if err := json.Unmarshal(stored, &previous); err != nil {
+		return false, err
+	}
+
+	a, err := json.Marshal(previous)
+	if err != nil {
+		return false, err
+	}
+	b, err := json.Marshal(current)
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(a, b), nil
+}
@@ -2,69 +2,229 @@ package mysql
 
 import (
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
-	"github.com/go-sql-driver/mysql"
 	"math/rand"
 	"vue-golang/internal/storage"
 )
 
-func (s *Storage) SaveNormOrder(ctx context.Context, result storage.OrderNormDetails) (int64, error) {
-	const op = "storage.mysql.sql.SaveNormOrder"
-	stmt := `INSERT INTO dem_product_instances_al (order_num, template_code, name, count, total_time, type, part_type, 
-            parent_assembly, parent_product_id, customer, position, status, systema, type_izd, profile, sqr) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?,?,?,?)`
+// customerMaxLen — длина колонки customer в dem_product_instances_al
+const customerMaxLen = 30
 
-	strCustm := result.Customer
-	strCustmRune := []rune(strCustm)
-	trimStrCustm := string(strCustmRune[0:30])
+// SaveNormOrder сохраняет изделие, его sub-изделия (parent_product_id = id корня) и все их операции в одной транзакции.
+// Повтор запроса с тем же IdempotencyKey ничего не пишет и возвращает уже сохранённые id (Replayed = true);
+// тот же ключ с другим содержимым — storage.ErrIdempotencyKeyReused.
+// Если позиция заказа уже нормирована по тому же шаблону — *storage.DuplicateNormError; с Renorm прежние нормировки
+// отменяются и ссылаются на новую, кроме назначенных и финальных — тогда *storage.SupersedeLockedError.
+// Для sub-изделия (ParentProductID задан) дубли не ищутся.
+func (s *Storage) SaveNormOrder(ctx context.Context, order storage.OrderNormDetails) (storage.SavedNormOrder, error) {
+	const op = "storage.mysql.SaveNormOrder"
 
-	exec, err := s.db.ExecContext(ctx, stmt, result.OrderNum, result.TemplateCode, result.Name, result.Count, result.TotalTime,
-		result.Type, result.PartType, result.ParentAssembly, result.ParentProductID, trimStrCustm, result.Position,
-		result.Status, result.Systema, result.TypeIzd, result.Profile, result.Sqr)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return storage.SavedNormOrder{}, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if order.IdempotencyKey != "" {
+		hash, err := requestHash(order)
+		if err != nil {
+			return storage.SavedNormOrder{}, fmt.Errorf("%s: ключ идемпотентности %q: %w", op, order.IdempotencyKey, err)
+		}
+		saved, replayed, err := claimIdempotencyKeyTx(ctx, tx, order.IdempotencyKey, hash)
+		if err != nil {
+			return storage.SavedNormOrder{}, fmt.Errorf("%s: ключ идемпотентности %q: %w", op, order.IdempotencyKey, err)
+		}
+		if replayed {
+			return saved, nil
+		}
+	}
+
+	// Sub-изделие, присланное отдельно, совпадает с корнем по заказу, позиции и шаблону: это не дубль,
+	// и заменять его корень оно не должно
+	var existing []storage.ExistingNorm
+	if order.ParentProductID == nil {
+		existing, err = lockExistingNormsTx(ctx, tx, order)
+		if err != nil {
+			return storage.SavedNormOrder{}, fmt.Errorf("%s: ошибка проверки дублей: %w", op, err)
+		}
+		if len(existing) > 0 && !order.Renorm {
+			return storage.SavedNormOrder{}, fmt.Errorf("%s: %w", op, &storage.DuplicateNormError{Existing: existing})
+		}
+		if locked := lockedNorms(existing); len(locked) > 0 {
+			return storage.SavedNormOrder{}, fmt.Errorf("%s: %w", op, &storage.SupersedeLockedError{Locked: locked})
+		}
+	}
+
+	rootID, err := insertNormProductTx(ctx, tx, order)
 	if err != nil {
-		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1452 {
-			return 0, fmt.Errorf("%s: Ошибка сохранения нормировки в базу='%s'", op, err)
+		return storage.SavedNormOrder{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	saved := storage.SavedNormOrder{OrderID: rootID, SubIDs: make([]int64, 0, len(order.SubProducts))}
+
+	for _, old := range existing {
+		if err := s.supersedeNormTx(ctx, tx, old, rootID, order.ChangedBy); err != nil {
+			return storage.SavedNormOrder{}, fmt.Errorf("%s: ошибка замены нормировки id=%d: %w", op, old.ID, err)
+		}
+		saved.Superseded = append(saved.Superseded, old.ID)
+	}
+
+	for i, sub := range order.SubProducts {
+		sub.ParentProductID = &rootID
+		if sub.Status == "" {
+			sub.Status = order.Status
+		}
+
+		subID, err := insertNormProductTx(ctx, tx, sub)
+		if err != nil {
+			return storage.SavedNormOrder{}, fmt.Errorf("%s: sub-изделие %d: %w", op, i, err)
 		}
-		return 0, fmt.Errorf("%s: Ошибка сохранения нормировки в базу='%s'", op, err)
+		saved.SubIDs = append(saved.SubIDs, subID)
 	}
 
-	return exec.LastInsertId()
+	if order.IdempotencyKey != "" {
+		_, err := tx.ExecContext(ctx, `UPDATE dem_norm_idempotency_al SET product_id = ? WHERE idempotency_key = ?`, rootID, order.IdempotencyKey)
+		if err != nil {
+			return storage.SavedNormOrder{}, fmt.Errorf("%s: ошибка сохранения ключа идемпотентности: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return storage.SavedNormOrder{}, fmt.Errorf("%s: commit transaction: %w", op, err)
+	}
+
+	return saved, nil
 }
 
-func (s *Storage) SaveNormOperation(ctx context.Context, OrderID int64, operations []storage.NormOperation) error {
-	const op = "storage.mysql.sql.SaveNormOperation"
+// lockedNorms — нормировки, которые перенормировка не заменяет: у назначенных и финальных нарядов
+// отмена удалила бы назначения сотрудников, а финальный наряд жизненный цикл не выпускает вовсе
+func lockedNorms(norms []storage.ExistingNorm) []storage.ExistingNorm {
+	var locked []storage.ExistingNorm
+	for _, n := range norms {
+		if n.Status == StatusAssigned || n.Status == StatusFinal {
+			locked = append(locked, n)
+		}
+	}
+	return locked
+}
 
-	tx, err := s.db.BeginTx(ctx, nil)
+// requestHash — SHA-256 содержимого запроса без самого ключа; ChangedBy в JSON не попадает
+func requestHash(order storage.OrderNormDetails) (string, error) {
+	order.IdempotencyKey = ""
+	body, err := json.Marshal(order)
 	if err != nil {
-		return fmt.Errorf("%s: begin transaction: %w", op, err)
+		return "", err
 	}
 
-	defer tx.Rollback()
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// claimIdempotencyKeyTx занимает ключ вместе с хэшем запроса. Если его уже занял другой запрос — ждёт его коммита
+// (блокировка уникального ключа) и возвращает то, что тот сохранил; если хэши различаются — storage.ErrIdempotencyKeyReused.
+func claimIdempotencyKeyTx(ctx context.Context, tx *sql.Tx, key, hash string) (storage.SavedNormOrder, bool, error) {
+	res, err := tx.ExecContext(ctx, `INSERT IGNORE INTO dem_norm_idempotency_al (idempotency_key, request_hash) VALUES (?, ?)`, key, hash)
+	if err != nil {
+		return storage.SavedNormOrder{}, false, err
+	}
+
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return storage.SavedNormOrder{}, false, err
+	}
+	if inserted > 0 {
+		return storage.SavedNormOrder{}, false, nil
+	}
+
+	var (
+		rootID     sql.NullInt64
+		storedHash sql.NullString
+	)
+	err = tx.QueryRowContext(ctx, `SELECT product_id, request_hash FROM dem_norm_idempotency_al WHERE idempotency_key = ? FOR UPDATE`, key).
+		Scan(&rootID, &storedHash)
+	if err != nil {
+		return storage.SavedNormOrder{}, false, err
+	}
+	// У ключей, занятых до появления хэша, сверять не с чем
+	if storedHash.Valid && storedHash.String != hash {
+		return storage.SavedNormOrder{}, false, storage.ErrIdempotencyKeyReused
+	}
+	if !rootID.Valid {
+		return storage.SavedNormOrder{}, false, fmt.Errorf("ключ занят незавершённым сохранением")
+	}
+
+	ids, err := productIDsTx(ctx, tx, rootID.Int64)
+	if err != nil {
+		return storage.SavedNormOrder{}, false, err
+	}
 
-	stmt, err := tx.PrepareContext(ctx, `
-		INSERT INTO dem_operation_values_al 
+	saved := storage.SavedNormOrder{OrderID: rootID.Int64, SubIDs: make([]int64, 0, len(ids)), Replayed: true}
+	for _, id := range ids {
+		if id != rootID.Int64 {
+			saved.SubIDs = append(saved.SubIDs, id)
+		}
+	}
+
+	return saved, true, nil
+}
+
+// insertNormProductTx вставляет одно изделие с операциями и возвращает его id
+func insertNormProductTx(ctx context.Context, tx *sql.Tx, product storage.OrderNormDetails) (int64, error) {
+	stmtProduct := `INSERT INTO dem_product_instances_al (order_num, template_code, name, count, total_time, type, part_type,
+            parent_assembly, parent_product_id, customer, position, status, systema, type_izd, profile, sqr, template_version)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?,?,?,?, COALESCE(NULLIF(?, 0), ` + currentTemplateVersion + `))`
+
+	stmtOperation := `INSERT INTO dem_operation_values_al
 			(product_id, operation_name, operation_label, count, value, minutes, sort_operation)
 		VALUES (?, ?, ?, ?, ?, ?, ?)
 		ON DUPLICATE KEY UPDATE
 		    operation_name = VALUES(operation_name),
 			count = VALUES(count),
 			value = VALUES(value),
-			sort_operation = VALUES(sort_operation)
-	`)
+			sort_operation = VALUES(sort_operation)`
+
+	customer := []rune(product.Customer)
+	if len(customer) > customerMaxLen {
+		customer = customer[:customerMaxLen]
+	}
+
+	exec, err := tx.ExecContext(ctx, stmtProduct, product.OrderNum, product.TemplateCode, product.Name, product.Count, product.TotalTime,
+		product.Type, product.PartType, product.ParentAssembly, product.ParentProductID, string(customer), product.Position,
+		product.Status, product.Systema, product.TypeIzd, product.Profile, product.Sqr, product.TemplateVersion, product.TemplateCode)
+	if err != nil {
+		return 0, fmt.Errorf("Ошибка сохранения нормировки в базу: %w", err)
+	}
+
+	productID, err := exec.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("Ошибка получения id нормировки: %w", err)
+	}
+
+	if err := recomputeNormMoneyTx(ctx, tx, []int64{productID}); err != nil {
+		return 0, err
+	}
+
+	prepareInsert, err := tx.PrepareContext(ctx, stmtOperation)
 	if err != nil {
-		return fmt.Errorf("%s: prepare statement: %w", op, err)
+		return 0, fmt.Errorf("prepare statement: %w", err)
 	}
+	defer prepareInsert.Close()
 
-	for i, opr := range operations {
+	for i, opr := range product.Operations {
 		opName := opr.Name
 		if opName == "" {
 			opName = fmt.Sprintf("manual_%d", rand.Intn(10000))
 		}
 
-		_, err := stmt.ExecContext(ctx, OrderID, opName, opr.Label, opr.Count, opr.Value, opr.Minutes, i)
+		_, err := prepareInsert.ExecContext(ctx, productID, opName, opr.Label, opr.Count, opr.Value, opr.Minutes, i)
 		if err != nil {
-			return fmt.Errorf("%s: Ошибка сохранения нормированных операции в базу='%s'", op, err)
+			return 0, fmt.Errorf("Ошибка сохранения нормированной операции %s в базу: %w", opName, err)
 		}
 	}
 
-	return tx.Commit()
+	return productID, nil
 }
@@ -0,0 +1,90 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"vue-golang/internal/storage"
+)
+
+func cleanupNormOrder(t *testing.T, orderNum string) {
+	_, err := testDB.Exec(`DELETE FROM dem_product_instances_al WHERE order_num = ?`, orderNum)
+	require.NoError(t, err)
+}
+
+// Тест: sub-изделия, присланные отдельными POST с заказом, позицией и шаблоном корня,
+// не считаются дублем и не заменяют корень (в том числе с renorm)
+func TestStorage_SaveNormOrder_SeparateSub(t *testing.T) {
+	const orderNum = "Q6-SUB"
+	cleanupNormOrder(t, orderNum)
+	defer cleanupNormOrder(t, orderNum)
+
+	ctx := context.Background()
+	s := &Storage{db: testDB}
+
+	root := storage.OrderNormDetails{OrderNum: orderNum, Position: 1, TemplateCode: "window", Name: "Окно", Count: 1,
+		Type: "window", PartType: "main", Status: "draft"}
+	saved, err := s.SaveNormOrder(ctx, root)
+	require.NoError(t, err)
+
+	sub := root
+	sub.Name = "Створка"
+	sub.PartType = "sub"
+	sub.ParentProductID = &saved.OrderID
+	savedSub, err := s.SaveNormOrder(ctx, sub)
+	require.NoError(t, err)
+	assert.Empty(t, savedSub.Superseded)
+
+	sub.Name = "Фрамуга"
+	sub.Renorm = true
+	savedRenorm, err := s.SaveNormOrder(ctx, sub)
+	require.NoError(t, err)
+	assert.Empty(t, savedRenorm.Superseded)
+
+	norms, err := s.GetExistingNorms(ctx, orderNum, 1, "window")
+	require.NoError(t, err)
+	require.Len(t, norms, 1)
+	assert.Equal(t, saved.OrderID, norms[0].ID)
+	assert.Equal(t, "draft", norms[0].Status)
+
+	for _, id := range []int64{savedSub.OrderID, savedRenorm.OrderID} {
+		var parent sql.NullInt64
+		require.NoError(t, testDB.QueryRow(`SELECT parent_product_id FROM dem_product_instances_al WHERE id = ?`, id).Scan(&parent))
+		assert.Equal(t, saved.OrderID, parent.Int64)
+	}
+
+	// Повтор корня по-прежнему дубль
+	_, err = s.SaveNormOrder(ctx, root)
+	var duplicate *storage.DuplicateNormError
+	assert.ErrorAs(t, err, &duplicate)
+}
+
+// Тест: перенормировка не отменяет финальный наряд и не удаляет его назначения
+func TestStorage_SaveNormOrder_RenormLocked(t *testing.T) {
+	const orderNum = "Q6-LOCKED"
+	cleanupNormOrder(t, orderNum)
+	defer cleanupNormOrder(t, orderNum)
+
+	ctx := context.Background()
+	s := &Storage{db: testDB}
+
+	root := storage.OrderNormDetails{OrderNum: orderNum, Position: 1, TemplateCode: "window", Name: "Окно", Count: 1,
+		Type: "window", PartType: "main", Status: "draft"}
+	saved, err := s.SaveNormOrder(ctx, root)
+	require.NoError(t, err)
+	_, err = testDB.Exec(`UPDATE dem_product_instances_al SET status = ? WHERE id = ?`, StatusFinal, saved.OrderID)
+	require.NoError(t, err)
+
+	root.Renorm = true
+	_, err = s.SaveNormOrder(ctx, root)
+	var locked *storage.SupersedeLockedError
+	if assert.ErrorAs(t, err, &locked) {
+		assert.Equal(t, saved.OrderID, locked.Locked[0].ID)
+	}
+
+	status, err := s.GetProductStatus(ctx, saved.OrderID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusFinal, status)
+}
@@ -0,0 +1,65 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+	"vue-golang/internal/storage"
+)
+
+// GetEmployeeStatementLines — операции, выполненные сотрудником в изделиях с датой готовности в [from, to].
+// Как и отчёт ПЭО, учитываются только назначенные и готовые изделия.
+func (s *Storage) GetEmployeeStatementLines(ctx context.Context, employeeID int64, from, to time.Time) ([]storage.StatementLine, error) {
+	const op = "storage.mysql.GetEmployeeStatementLines"
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT
+			p.id, p.order_num, COALESCE(p.position, 0), COALESCE(p.name, ''), COALESCE(p.type, ''),
+			COALESCE(p.type_izd, ''), p.ready_date,
+			oe.operation_name, COALESCE(ov.operation_label, oe.operation_name),
+			COALESCE(ov.minutes, 0), COALESCE(ov.value, 0),
+			oe.actual_minutes, COALESCE(oe.actual_value, 0),
+			%s, %s, %s, %s
+		FROM dem_operation_executors_al oe
+		JOIN dem_product_instances_al p ON p.id = oe.product_id
+		LEFT JOIN dem_operation_values_al ov ON ov.product_id = oe.product_id AND ov.operation_name = oe.operation_name
+		LEFT JOIN dem_coefficient_al dc ON dc.type = p.type
+		WHERE oe.employee_id = ?
+		  AND p.status IN (?, ?)
+		  AND p.ready_date >= ? AND p.ready_date < ?
+		ORDER BY p.ready_date, p.order_num, p.position, ov.sort_operation, oe.operation_name`,
+		effectiveCoefficientSQL, coefficientSourceSQL, coefficientFromSQL, customerFactorSQL),
+		employeeID, StatusAssigned, StatusFinal,
+		from.Format("2006-01-02"), to.AddDate(0, 0, 1).Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var lines []storage.StatementLine
+	for rows.Next() {
+		var (
+			l        storage.StatementLine
+			coef     sql.NullFloat64
+			coefFrom sql.NullTime
+		)
+		err := rows.Scan(&l.ProductID, &l.OrderNum, &l.Position, &l.Name, &l.Type, &l.TypeIzd, &l.Date,
+			&l.OperationName, &l.OperationLabel, &l.NormMinutes, &l.NormValue,
+			&l.ActualMinutes, &l.ActualValue, &coef, &l.CoefficientSource, &coefFrom, &l.CustomerFactor)
+		if err != nil {
+			return nil, fmt.Errorf("%s: ошибка сканирования строки ведомости: %w", op, err)
+		}
+		if coef.Valid {
+			v := coef.Float64
+			l.Coefficient = &v
+		}
+		if coefFrom.Valid {
+			t := coefFrom.Time
+			l.CoefficientFrom = &t
+		}
+		lines = append(lines, l)
+	}
+
+	return lines, rows.Err()
+}
@@ -0,0 +1,136 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"vue-golang/internal/storage"
+)
+
+// GetProductStatus — текущий статус изделия; у старых записей без статуса — пустая строка
+func (s *Storage) GetProductStatus(ctx context.Context, id int64) (string, error) {
+	const op = "storage.mysql.GetProductStatus"
+
+	var status string
+	err := s.db.QueryRowContext(ctx, `SELECT COALESCE(status, '') FROM dem_product_instances_al WHERE id = ?`, id).Scan(&status)
+	if err != nil {
+		return "", fmt.Errorf("%s: изделие id=%d: %w", op, id, err)
+	}
+
+	return status, nil
+}
+
+// ChangeStatus переводит изделие и его sub-изделия в новый статус и пишет историю
+func (s *Storage) ChangeStatus(ctx context.Context, change storage.StatusChange) error {
+	const op = "storage.mysql.ChangeStatus"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: старт транзакции: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := s.ChangeStatusTx(ctx, tx, change); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: ошибка завершения транзакции: %w", op, err)
+	}
+
+	return nil
+}
+
+// ChangeStatusTx — то же внутри чужой транзакции. Статус изделия блокируется и сверяется с change.From:
+// если его успели поменять, возвращается storage.ErrStatusConflict. Переход в тот же статус ничего не пишет.
+// При отмене назначенные на операции сотрудники удаляются, а до и после этого каждому изделию сборки
+// пишется ревизия (storage.RevisionCancel), чтобы снятые назначения остались в истории. Сборку из закрытого расчётного периода не трогает
+// (storage.PeriodClosedError).
+func (s *Storage) ChangeStatusTx(ctx context.Context, tx *sql.Tx, change storage.StatusChange) error {
+	const op = "storage.mysql.ChangeStatusTx"
+
+	stmtLock := `SELECT COALESCE(status, '') FROM dem_product_instances_al WHERE id = ? FOR UPDATE`
+	stmtUpdateStatus := `UPDATE dem_product_instances_al SET status = ?, row_version = row_version + 1 WHERE id = ? OR parent_product_id = ?`
+	stmtDeleteExecutors := `DELETE FROM dem_operation_executors_al WHERE product_id IN (SELECT * FROM (
+		SELECT id FROM dem_product_instances_al WHERE id = ? OR parent_product_id = ?) AS tmp)`
+	stmtHistory := `INSERT INTO dem_product_status_history_al (product_id, from_status, to_status, changed_by, reason) VALUES (?, ?, ?, ?, ?)`
+
+	var current string
+	if err := tx.QueryRowContext(ctx, stmtLock, change.ProductID).Scan(&current); err != nil {
+		return fmt.Errorf("%s: изделие id=%d: %w", op, change.ProductID, err)
+	}
+	if current != change.From {
+		return fmt.Errorf("%s: изделие id=%d в статусе %q, ожидался %q: %w", op, change.ProductID, current, change.From, storage.ErrStatusConflict)
+	}
+	if change.From == change.To {
+		return nil
+	}
+
+	ids, err := productIDsTx(ctx, tx, change.ProductID)
+	if err != nil {
+		return fmt.Errorf("%s: ошибка получения изделий сборки id=%d: %w", op, change.ProductID, err)
+	}
+	if err := checkPeriodsOpenTx(ctx, tx, ids, ""); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	cancel := change.To == "cancel"
+	if cancel {
+		for _, id := range ids {
+			if err := ensureBaselineTx(ctx, tx, id); err != nil {
+				return fmt.Errorf("%s: ошибка записи исходной ревизии id=%d: %w", op, id, err)
+			}
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, stmtUpdateStatus, change.To, change.ProductID, change.ProductID); err != nil {
+		return fmt.Errorf("%s: ошибка обновления статуса root ID %d: %w", op, change.ProductID, err)
+	}
+
+	if cancel {
+		if _, err := tx.ExecContext(ctx, stmtDeleteExecutors, change.ProductID, change.ProductID); err != nil {
+			return fmt.Errorf("%s: ошибка удаления назначенных сотрудников заказа с ID %d: %w", op, change.ProductID, err)
+		}
+		for _, id := range ids {
+			if err := recordRevisionTx(ctx, tx, id, storage.RevisionCancel, change.ChangedBy); err != nil {
+				return fmt.Errorf("%s: ошибка записи ревизии id=%d: %w", op, id, err)
+			}
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, stmtHistory, change.ProductID, change.From, change.To, change.ChangedBy, change.Reason); err != nil {
+		return fmt.Errorf("%s: ошибка записи истории статусов ID %d: %w", op, change.ProductID, err)
+	}
+
+	return nil
+}
+
+// GetStatusHistory — переходы статусов изделия, от старых к новым
+func (s *Storage) GetStatusHistory(ctx context.Context, productID int64) ([]storage.StatusChange, error) {
+	const op = "storage.mysql.GetStatusHistory"
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, product_id, from_status, to_status, changed_by, reason, changed_at
+		FROM dem_product_status_history_al
+		WHERE product_id = ?
+		ORDER BY changed_at, id`, productID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	history := make([]storage.StatusChange, 0)
+	for rows.Next() {
+		var c storage.StatusChange
+		if err := rows.Scan(&c.ID, &c.ProductID, &c.From, &c.To, &c.ChangedBy, &c.Reason, &c.ChangedAt); err != nil {
+			return nil, fmt.Errorf("%s: ошибка сканирования строки: %w", op, err)
+		}
+		history = append(history, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: ошибка при итерации по строкам: %w", op, err)
+	}
+
+	return history, nil
+}
@@ -10,46 +10,25 @@ import (
 	"vue-golang/internal/storage"
 )
 
+// GetTemplateByCode возвращает версию шаблона, действующую на текущую дату
 func (s *Storage) GetTemplateByCode(ctx context.Context, code string) (*storage.Template, error) {
 	const op = "storage.mysql.sql.GetFormByCode"
 
 	query := `
-		SELECT id, code, name, category, operations, systema, izd, profile, rules
-		FROM dem_templates_al 
-		WHERE code = ? AND is_active = TRUE
+		SELECT ` + templateVersionColumns + `
+		FROM dem_template_versions_al v
+		JOIN dem_templates_al t ON t.id = v.template_id
+		WHERE t.code = ? AND t.is_active = TRUE AND v.effective_from <= NOW()
+		ORDER BY v.effective_from DESC, v.version DESC
+		LIMIT 1
 	`
 
-	template := &storage.Template{}
-
-	// Сканируем JSON как строку
-	var operationsJSON string
-	var rulesJSON string
-	err := s.db.QueryRowContext(ctx, query, code).Scan(
-		&template.ID,
-		&template.Code,
-		&template.Name,
-		&template.Category,
-		&operationsJSON,
-		&template.Systema,
-		&template.TypeIzd,
-		&template.Profile,
-		&rulesJSON,
-	)
+	template, err := scanTemplateVersion(s.db.QueryRowContext(ctx, query, code))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("%s: шаблон с code='%s' не найден: %w", op, code, err)
 		}
-		return nil, fmt.Errorf("%s: выполнение запроса завершилось ошибкой: %w", op, err)
-	}
-
-	// Парсим JSON операций
-	if err := json.Unmarshal([]byte(operationsJSON), &template.Operations); err != nil {
-		return nil, fmt.Errorf("%s: ошибка парсинга JSON операций: %w", op, err)
-	}
-
-	// парсим json правила
-	if err := json.Unmarshal([]byte(rulesJSON), &template.Rules); err != nil {
-		return nil, fmt.Errorf("%s: ошибка парсинга JSON правил: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
 	return template, nil
@@ -97,9 +76,9 @@ func (s *Storage) GetTemplateByCodeAdmin(ctx context.Context, id int64) (*storag
 
 	template := &storage.Template{}
 
-	// Сканируем JSON как строку
+	// Сканируем JSON как строку; rules может быть NULL — у шаблона нет правил
 	var operationsJSON string
-	var rulesJSON string
+	var rulesJSON sql.NullString
 	err := s.db.QueryRowContext(ctx, query, id).Scan(
 		&template.ID,
 		&template.Code,
@@ -126,8 +105,10 @@ func (s *Storage) GetTemplateByCodeAdmin(ctx context.Context, id int64) (*storag
 	}
 
 	// парсим json правила
-	if err := json.Unmarshal([]byte(rulesJSON), &template.Rules); err != nil {
-		return nil, fmt.Errorf("%s: ошибка парсинга JSON правил: %w", op, err)
+	if rulesJSON.Valid {
+		if err := json.Unmarshal([]byte(rulesJSON.String), &template.Rules); err != nil {
+			return nil, fmt.Errorf("%s: ошибка парсинга JSON правил: %w", op, err)
+		}
 	}
 
 	return template, nil
@@ -164,27 +145,59 @@ func (s *Storage) GetAllTemplatesAdmin(ctx context.Context) ([]*storage.Template
 	return templates, nil
 }
 
+// UpdateTemplateAdmin обновляет шаблон и сохраняет его новое состояние отдельной версией;
+// нет такого id — storage.ErrTemplateNotFound
 func (s *Storage) UpdateTemplateAdmin(ctx context.Context, id int, update storage.TemplateAdmin) error {
 	const op = "storage.mysql.TemplateAdmin"
 
-	stmt := `UPDATE dem_templates_al SET code=?, category=?, is_active=?, name=?, profile=?, systema=?, izd=?, operations=?, head_name=? WHERE code=?`
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	// UPDATE без изменений тоже даёт 0 затронутых строк, поэтому наличие шаблона проверяем отдельно
+	var exists int
+	err = tx.QueryRowContext(ctx, `SELECT id FROM dem_templates_al WHERE id = ? FOR UPDATE`, id).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%s: id=%d: %w", op, id, storage.ErrTemplateNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: ошибка получения шаблона: %w", op, err)
+	}
+
+	// Шаблон ищем по id: он в маршруте /template/update/{id}, и по нему же ведётся история версий,
+	// а code меняется этим же запросом. Rules == nil — правила не передавали, оставляем сохранённые.
+	stmt := `UPDATE dem_templates_al SET code=?, category=?, is_active=?, name=?, profile=?, systema=?, izd=?, operations=?, head_name=?,
+            rules=IF(?, ?, rules) WHERE id=?`
 
-	_, err := s.db.ExecContext(ctx, stmt, update.Code, update.Category, update.IsActive, update.Name, update.Profile,
-		update.Systema, update.TypeIzd, update.Operation, update.HeadName, id)
+	_, err = tx.ExecContext(ctx, stmt, update.Code, update.Category, update.IsActive, update.Name, update.Profile,
+		update.Systema, update.TypeIzd, update.Operation, update.HeadName, update.Rules != nil, update.Rules, id)
 	if err != nil {
 		return fmt.Errorf("%s: ошибка обновления шаблона нормирования: %w", op, err)
 	}
 
-	return nil
+	if _, err := insertTemplateVersion(ctx, tx, int64(id), update.EffectiveFrom, update.Comment); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tx.Commit()
 }
 
+// CreateTemplateAdmin создаёт шаблон вместе с его первой версией
 func (s *Storage) CreateTemplateAdmin(ctx context.Context, res storage.TemplateAdmin) error {
 	const op = "storage.mysql.CreateTemplateAdmin"
 
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
 	stmt := `INSERT INTO dem_templates_al (code, name, category, operations, is_active, systema, 
             izd, profile, head_name, rules) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
-	_, err := s.db.ExecContext(ctx, stmt, res.Code, res.Name, res.Category, res.Operation,
+	exec, err := tx.ExecContext(ctx, stmt, res.Code, res.Name, res.Category, res.Operation,
 		res.IsActive, res.Systema, res.TypeIzd, res.Profile, res.HeadName, res.Rules)
 	if err != nil {
 		if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == 1452 {
@@ -193,5 +206,14 @@ func (s *Storage) CreateTemplateAdmin(ctx context.Context, res storage.TemplateA
 		return fmt.Errorf("%s: Ошибка сохранения шаблона в базу='%s'", op, err)
 	}
 
-	return nil
+	id, err := exec.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("%s: ошибка получения id шаблона: %w", op, err)
+	}
+
+	if _, err := insertTemplateVersion(ctx, tx, id, res.EffectiveFrom, res.Comment); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tx.Commit()
 }
@@ -0,0 +1,147 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"vue-golang/internal/storage"
+)
+
+const templateCaseColumns = `id, template_id, name, type_izd, item_count, systema, profile, materials, dop_info, expected, created_at`
+
+// GetTemplateTestCasesAdmin возвращает все регрессионные примеры шаблона
+func (s *Storage) GetTemplateTestCasesAdmin(ctx context.Context, templateID int64) ([]storage.TemplateTestCase, error) {
+	const op = "storage.mysql.GetTemplateTestCasesAdmin"
+
+	stmt := `SELECT ` + templateCaseColumns + ` FROM dem_template_test_cases_al WHERE template_id = ? ORDER BY id`
+
+	rows, err := s.db.QueryContext(ctx, stmt, templateID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: ошибка получения примеров шаблона %d: %w", op, templateID, err)
+	}
+	defer rows.Close()
+
+	cases := []storage.TemplateTestCase{}
+	for rows.Next() {
+		var (
+			tc            storage.TemplateTestCase
+			materialsJSON string
+			dopInfoJSON   sql.NullString
+			expectedJSON  string
+		)
+
+		err := rows.Scan(&tc.ID, &tc.TemplateID, &tc.Name, &tc.TypeIzd, &tc.ItemCount, &tc.Systema, &tc.Profile,
+			&materialsJSON, &dopInfoJSON, &expectedJSON, &tc.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: ошибка сканирования примера: %w", op, err)
+		}
+
+		if err := json.Unmarshal([]byte(materialsJSON), &tc.Materials); err != nil {
+			return nil, fmt.Errorf("%s: ошибка парсинга материалов примера %d: %w", op, tc.ID, err)
+		}
+		if dopInfoJSON.Valid {
+			if err := json.Unmarshal([]byte(dopInfoJSON.String), &tc.DopInfo); err != nil {
+				return nil, fmt.Errorf("%s: ошибка парсинга доп. информации примера %d: %w", op, tc.ID, err)
+			}
+		}
+		if err := json.Unmarshal([]byte(expectedJSON), &tc.Expected); err != nil {
+			return nil, fmt.Errorf("%s: ошибка парсинга ожидаемых значений примера %d: %w", op, tc.ID, err)
+		}
+
+		cases = append(cases, tc)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: ошибка при итерации по строкам: %w", op, err)
+	}
+
+	return cases, nil
+}
+
+func (s *Storage) CreateTemplateTestCaseAdmin(ctx context.Context, tc storage.TemplateTestCase) (int64, error) {
+	const op = "storage.mysql.CreateTemplateTestCaseAdmin"
+
+	materials, dopInfo, expected, err := marshalTemplateCase(tc)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	stmt := `INSERT INTO dem_template_test_cases_al (template_id, name, type_izd, item_count, systema, profile,
+            materials, dop_info, expected) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	res, err := s.db.ExecContext(ctx, stmt, tc.TemplateID, tc.Name, tc.TypeIzd, tc.ItemCount, tc.Systema, tc.Profile,
+		materials, dopInfo, expected)
+	if err != nil {
+		return 0, fmt.Errorf("%s: ошибка сохранения примера шаблона: %w", op, err)
+	}
+
+	return res.LastInsertId()
+}
+
+func (s *Storage) UpdateTemplateTestCaseAdmin(ctx context.Context, templateID, id int64, tc storage.TemplateTestCase) error {
+	const op = "storage.mysql.UpdateTemplateTestCaseAdmin"
+
+	materials, dopInfo, expected, err := marshalTemplateCase(tc)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	stmt := `UPDATE dem_template_test_cases_al SET name = ?, type_izd = ?, item_count = ?, systema = ?, profile = ?,
+            materials = ?, dop_info = ?, expected = ? WHERE id = ? AND template_id = ?`
+
+	res, err := s.db.ExecContext(ctx, stmt, tc.Name, tc.TypeIzd, tc.ItemCount, tc.Systema, tc.Profile,
+		materials, dopInfo, expected, id, templateID)
+	if err != nil {
+		return fmt.Errorf("%s: ошибка обновления примера id=%d: %w", op, id, err)
+	}
+
+	return checkAffected(res, op, id)
+}
+
+func (s *Storage) DeleteTemplateTestCaseAdmin(ctx context.Context, templateID, id int64) error {
+	const op = "storage.mysql.DeleteTemplateTestCaseAdmin"
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM dem_template_test_cases_al WHERE id = ? AND template_id = ?`, id, templateID)
+	if err != nil {
+		return fmt.Errorf("%s: ошибка удаления примера id=%d: %w", op, id, err)
+	}
+
+	return checkAffected(res, op, id)
+}
+
+func marshalTemplateCase(tc storage.TemplateTestCase) (materials, dopInfo, expected string, err error) {
+	if tc.Materials == nil {
+		tc.Materials = []*storage.KlaesMaterials{}
+	}
+	if tc.DopInfo == nil {
+		tc.DopInfo = []*storage.DopInfoDemPrice{}
+	}
+
+	m, err := json.Marshal(tc.Materials)
+	if err != nil {
+		return "", "", "", fmt.Errorf("ошибка сериализации материалов: %w", err)
+	}
+	d, err := json.Marshal(tc.DopInfo)
+	if err != nil {
+		return "", "", "", fmt.Errorf("ошибка сериализации доп. информации: %w", err)
+	}
+	e, err := json.Marshal(tc.Expected)
+	if err != nil {
+		return "", "", "", fmt.Errorf("ошибка сериализации ожидаемых значений: %w", err)
+	}
+
+	return string(m), string(d), string(e), nil
+}
+
+// checkAffected возвращает sql.ErrNoRows, если запрос не затронул ни одной строки
+func checkAffected(res sql.Result, op string, id int64) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s: запись id=%d не найдена: %w", op, id, sql.ErrNoRows)
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"vue-golang/internal/storage"
+)
+
+const templateVersionColumns = `t.id, v.code, v.name, v.category, v.operations, v.systema, v.izd, v.profile, v.rules,
+		t.is_active, v.head_name, v.version, v.effective_from`
+
+// currentTemplateVersion — подзапрос номера действующей версии шаблона по коду (параметр — code)
+const currentTemplateVersion = `(SELECT v.version FROM dem_template_versions_al v JOIN dem_templates_al t ON t.id = v.template_id
+			WHERE t.code = ? AND v.effective_from <= NOW() ORDER BY v.effective_from DESC, v.version DESC LIMIT 1)`
+
+// GetTemplateVersion возвращает конкретную версию шаблона — для пересчёта и открытия старых нормировок
+func (s *Storage) GetTemplateVersion(ctx context.Context, code string, version int) (*storage.Template, error) {
+	const op = "storage.mysql.GetTemplateVersion"
+
+	query := `
+		SELECT ` + templateVersionColumns + `
+		FROM dem_template_versions_al v
+		JOIN dem_templates_al t ON t.id = v.template_id
+		WHERE t.code = ? AND v.version = ?
+	`
+
+	template, err := scanTemplateVersion(s.db.QueryRowContext(ctx, query, code, version))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: версия %d шаблона code='%s' не найдена: %w", op, version, code, err)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return template, nil
+}
+
+func (s *Storage) GetTemplateVersionAdmin(ctx context.Context, templateID int64, version int) (*storage.Template, error) {
+	const op = "storage.mysql.GetTemplateVersionAdmin"
+
+	query := `
+		SELECT ` + templateVersionColumns + `
+		FROM dem_template_versions_al v
+		JOIN dem_templates_al t ON t.id = v.template_id
+		WHERE v.template_id = ? AND v.version = ?
+	`
+
+	template, err := scanTemplateVersion(s.db.QueryRowContext(ctx, query, templateID, version))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: версия %d шаблона id=%d не найдена: %w", op, version, templateID, err)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return template, nil
+}
+
+func (s *Storage) GetTemplateVersionsAdmin(ctx context.Context, templateID int64) ([]storage.TemplateVersion, error) {
+	const op = "storage.mysql.GetTemplateVersionsAdmin"
+
+	stmt := `SELECT id, template_id, version, effective_from, code, name, comment, created_at
+			FROM dem_template_versions_al WHERE template_id = ? ORDER BY version DESC`
+
+	rows, err := s.db.QueryContext(ctx, stmt, templateID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: ошибка получения версий шаблона id=%d: %w", op, templateID, err)
+	}
+	defer rows.Close()
+
+	var versions []storage.TemplateVersion
+	for rows.Next() {
+		var v storage.TemplateVersion
+		if err := rows.Scan(&v.ID, &v.TemplateID, &v.Version, &v.EffectiveFrom, &v.Code, &v.Name, &v.Comment, &v.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: ошибка сканирования версии: %w", op, err)
+		}
+		versions = append(versions, v)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: ошибка при итерации по строкам: %w", op, err)
+	}
+
+	return versions, nil
+}
+
+// RollbackTemplateAdmin возвращает шаблон к состоянию версии version.
+// История не переписывается: содержимое старой версии сохраняется как новая версия.
+func (s *Storage) RollbackTemplateAdmin(ctx context.Context, templateID int64, version int, effectiveFrom *time.Time) (int, error) {
+	const op = "storage.mysql.RollbackTemplateAdmin"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM dem_template_versions_al WHERE template_id = ? AND version = ?)`,
+		templateID, version).Scan(&exists)
+	if err != nil {
+		return 0, fmt.Errorf("%s: ошибка проверки версии: %w", op, err)
+	}
+	if !exists {
+		return 0, fmt.Errorf("%s: версия %d шаблона id=%d не найдена: %w", op, version, templateID, sql.ErrNoRows)
+	}
+
+	stmt := `UPDATE dem_templates_al t
+			JOIN dem_template_versions_al v ON v.template_id = t.id AND v.version = ?
+			SET t.code = v.code, t.name = v.name, t.category = v.category, t.operations = v.operations, t.rules = v.rules,
+			    t.systema = v.systema, t.izd = v.izd, t.profile = v.profile, t.head_name = v.head_name
+			WHERE t.id = ?`
+
+	if _, err := tx.ExecContext(ctx, stmt, version, templateID); err != nil {
+		return 0, fmt.Errorf("%s: ошибка отката шаблона id=%d к версии %d: %w", op, templateID, version, err)
+	}
+
+	newVersion, err := insertTemplateVersion(ctx, tx, templateID, effectiveFrom, fmt.Sprintf("откат к версии %d", version))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: commit: %w", op, err)
+	}
+
+	return newVersion, nil
+}
+
+// insertTemplateVersion сохраняет текущее состояние dem_templates_al как следующую версию шаблона
+func insertTemplateVersion(ctx context.Context, tx *sql.Tx, templateID int64, effectiveFrom *time.Time, comment string) (int, error) {
+	stmt := `INSERT INTO dem_template_versions_al (template_id, version, effective_from, code, name, category, operations,
+                rules, systema, izd, profile, head_name, comment)
+			SELECT t.id, COALESCE((SELECT MAX(v.version) FROM dem_template_versions_al v WHERE v.template_id = t.id), 0) + 1,
+			       COALESCE(?, NOW()), t.code, t.name, t.category, t.operations, t.rules, t.systema, t.izd, t.profile,
+			       t.head_name, ?
+			FROM dem_templates_al t WHERE t.id = ?`
+
+	if _, err := tx.ExecContext(ctx, stmt, effectiveFrom, comment, templateID); err != nil {
+		return 0, fmt.Errorf("ошибка сохранения версии шаблона id=%d: %w", templateID, err)
+	}
+
+	// Версий нет — шаблона с таким id нет, и INSERT ... SELECT ничего не вставил
+	var version sql.NullInt64
+	err := tx.QueryRowContext(ctx, `SELECT MAX(version) FROM dem_template_versions_al WHERE template_id = ?`, templateID).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка получения номера версии шаблона id=%d: %w", templateID, err)
+	}
+	if !version.Valid {
+		return 0, fmt.Errorf("шаблон id=%d: %w", templateID, storage.ErrTemplateNotFound)
+	}
+
+	return int(version.Int64), nil
+}
+
+func scanTemplateVersion(row *sql.Row) (*storage.Template, error) {
+	template := &storage.Template{}
+
+	var (
+		operationsJSON string
+		rulesJSON      sql.NullString
+		effectiveFrom  time.Time
+	)
+
+	err := row.Scan(
+		&template.ID,
+		&template.Code,
+		&template.Name,
+		&template.Category,
+		&operationsJSON,
+		&template.Systema,
+		&template.TypeIzd,
+		&template.Profile,
+		&rulesJSON,
+		&template.IsActive,
+		&template.HeadName,
+		&template.Version,
+		&effectiveFrom,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("выполнение запроса завершилось ошибкой: %w", err)
+	}
+	template.EffectiveFrom = &effectiveFrom
+
+	// Парсим JSON операций
+	if err := json.Unmarshal([]byte(operationsJSON), &template.Operations); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга JSON операций: %w", err)
+	}
+
+	// парсим json правила
+	if rulesJSON.Valid {
+		if err := json.Unmarshal([]byte(rulesJSON.String), &template.Rules); err != nil {
+			return nil, fmt.Errorf("ошибка парсинга JSON правил: %w", err)
+		}
+	}
+
+	return template, nil
+}
+
+// GetTemplatesByCodesAdmin возвращает шаблоны целиком (с операциями и правилами) для выгрузки.
+// Пустой список кодов — все шаблоны.
+func (s *Storage) GetTemplatesByCodesAdmin(ctx context.Context, codes []string) ([]*storage.Template, error) {
+	const op = "storage.mysql.GetTemplatesByCodesAdmin"
+
+	stmt := `SELECT t.id, t.code, t.name, t.category, t.operations, t.systema, t.izd, t.profile, t.rules, t.is_active, t.head_name,
+				COALESCE((SELECT MAX(v.version) FROM dem_template_versions_al v WHERE v.template_id = t.id), 0)
+			FROM dem_templates_al t`
+
+	var args []interface{}
+	if len(codes) > 0 {
+		stmt += fmt.Sprintf(` WHERE t.code IN (%s)`, placeholders(len(codes)))
+		for _, code := range codes {
+			args = append(args, code)
+		}
+	}
+	stmt += ` ORDER BY t.code`
+
+	rows, err := s.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: ошибка получения шаблонов: %w", op, err)
+	}
+	defer rows.Close()
+
+	var templates []*storage.Template
+	for rows.Next() {
+		template := &storage.Template{}
+
+		var (
+			operationsJSON string
+			rulesJSON      sql.NullString
+		)
+
+		err := rows.Scan(&template.ID, &template.Code, &template.Name, &template.Category, &operationsJSON, &template.Systema,
+			&template.TypeIzd, &template.Profile, &rulesJSON, &template.IsActive, &template.HeadName, &template.Version)
+		if err != nil {
+			return nil, fmt.Errorf("%s: ошибка сканирования шаблона: %w", op, err)
+		}
+
+		if err := json.Unmarshal([]byte(operationsJSON), &template.Operations); err != nil {
+			return nil, fmt.Errorf("%s: ошибка парсинга JSON операций шаблона %s: %w", op, template.Code, err)
+		}
+		if rulesJSON.Valid {
+			if err := json.Unmarshal([]byte(rulesJSON.String), &template.Rules); err != nil {
+				return nil, fmt.Errorf("%s: ошибка парсинга JSON правил шаблона %s: %w", op, template.Code, err)
+			}
+		}
+
+		templates = append(templates, template)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: ошибка при итерации по строкам: %w", op, err)
+	}
+
+	return templates, nil
+}
+
+// ImportTemplatesAdmin создаёт или обновляет шаблоны по code в одной транзакции.
+// Для каждого шаблона сохраняется новая версия.
+func (s *Storage) ImportTemplatesAdmin(ctx context.Context, templates []storage.TemplateAdmin) error {
+	const op = "storage.mysql.ImportTemplatesAdmin"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: begin transaction: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	stmt := `INSERT INTO dem_templates_al (code, name, category, operations, is_active, systema, izd, profile, head_name, rules)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE name = VALUES(name), category = VALUES(category), operations = VALUES(operations),
+				is_active = VALUES(is_active), systema = VALUES(systema), izd = VALUES(izd), profile = VALUES(profile),
+				head_name = VALUES(head_name), rules = VALUES(rules)`
+
+	for _, t := range templates {
+		_, err := tx.ExecContext(ctx, stmt, t.Code, t.Name, t.Category, t.Operation, t.IsActive, t.Systema, t.TypeIzd,
+			t.Profile, t.HeadName, t.Rules)
+		if err != nil {
+			return fmt.Errorf("%s: ошибка сохранения шаблона %s: %w", op, t.Code, err)
+		}
+
+		var id int64
+		if err := tx.QueryRowContext(ctx, `SELECT id FROM dem_templates_al WHERE code = ?`, t.Code).Scan(&id); err != nil {
+			return fmt.Errorf("%s: ошибка получения id шаблона %s: %w", op, t.Code, err)
+		}
+
+		if _, err := insertTemplateVersion(ctx, tx, id, t.EffectiveFrom, t.Comment); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return tx.Commit()
+}
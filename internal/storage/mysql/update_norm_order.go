@@ -2,41 +2,63 @@ package mysql
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"math/rand"
 	"vue-golang/internal/storage"
 )
 
-func (s *Storage) UpdateNormOrder(ctx context.Context, ID int64, update storage.UpdateOrderDetails) error {
+// UpdateNormOrder перезаписывает нормировку изделия и возвращает новую версию строки.
+// Если update.RowVersion не совпадает с текущей версией — storage.ErrVersionConflict, ничего не меняется.
+func (s *Storage) UpdateNormOrder(ctx context.Context, ID int64, update storage.UpdateOrderDetails) (int, error) {
 	const op = "storage.mysql.UpdateNormOrder"
 
-	stmtUpdate := `UPDATE dem_product_instances_al SET total_time = ?, type = ?, status = ? WHERE id = ?`
+	stmtUpdate := `UPDATE dem_product_instances_al SET total_time = ?, type = ?, 
+            template_version = COALESCE(?, template_version) WHERE id = ?`
 	stmtDelete := `DELETE FROM dem_operation_values_al WHERE product_id = ?`
 	stmtInsert := `INSERT INTO dem_operation_values_al (product_id, operation_name, operation_label, count, value, minutes, sort_operation) VALUES (?, ?, ?, ?, ?, ?, ?)`
 
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("%s: старт транзакции: %w", op, err)
+		return 0, fmt.Errorf("%s: старт транзакции: %w", op, err)
 	}
 	defer tx.Rollback()
 
+	if err := checkVersionTx(ctx, tx, ID, update.RowVersion); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := checkPeriodsOpenTx(ctx, tx, []int64{ID}, ""); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Прежние операции удаляются, поэтому до первой правки фиксируем исходное состояние
+	if err := ensureBaselineTx(ctx, tx, ID); err != nil {
+		return 0, fmt.Errorf("%s: ошибка записи исходной ревизии: %w", op, err)
+	}
+
+	// Статус меняется только через проверенный переход
+	if update.StatusChange != nil {
+		if err := s.ChangeStatusTx(ctx, tx, *update.StatusChange); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
 	//Обновляем основное изделие
-	_, err = tx.ExecContext(ctx, stmtUpdate, update.TotalTime, update.Type, update.Status, ID)
+	_, err = tx.ExecContext(ctx, stmtUpdate, update.TotalTime, update.Type, update.TemplateVersion, ID)
 	if err != nil {
-		return fmt.Errorf("%s: ошибка обновление основной информации об изделии: %w", op, err)
+		return 0, fmt.Errorf("%s: ошибка обновление основной информации об изделии: %w", op, err)
 	}
 
 	// Удаляем старые операции
 	_, err = tx.ExecContext(ctx, stmtDelete, ID)
 	if err != nil {
-		return fmt.Errorf("%s: ошибка удаления старых операции: %w", op, err)
+		return 0, fmt.Errorf("%s: ошибка удаления старых операции: %w", op, err)
 	}
 
 	// Вставляем новые операции
 	prepareInsert, err := tx.PrepareContext(ctx, stmtInsert)
 	if err != nil {
-		return fmt.Errorf("%s: ошибка при подготовке вставки новых операции: %w", op, err)
+		return 0, fmt.Errorf("%s: ошибка при подготовке вставки новых операции: %w", op, err)
 	}
 	defer prepareInsert.Close()
 
@@ -48,61 +70,88 @@ func (s *Storage) UpdateNormOrder(ctx context.Context, ID int64, update storage.
 
 		_, err := prepareInsert.ExecContext(ctx, ID, opName, operation.Label, operation.Count, operation.Value, operation.Minutes, i)
 		if err != nil {
-			return fmt.Errorf("%s: ошибка вставки новых операции %s: %w", op, opName, err)
+			return 0, fmt.Errorf("%s: ошибка вставки новых операции %s: %w", op, opName, err)
 		}
 	}
 
+	// Н/час изменилось — Н/руб пересчитывается вместе с ним
+	if err := recomputeNormMoneyTx(ctx, tx, []int64{ID}); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := recordRevisionTx(ctx, tx, ID, storage.RevisionNorm, update.ChangedBy); err != nil {
+		return 0, fmt.Errorf("%s: ошибка записи ревизии: %w", op, err)
+	}
+
+	version, err := bumpVersionTx(ctx, tx, ID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: ошибка обновления версии: %w", op, err)
+	}
+
 	// Коммит
 	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("%s: ошибка завершения транзакции: %w", op, err)
+		return 0, fmt.Errorf("%s: ошибка завершения транзакции: %w", op, err)
 	}
 
-	return nil
+	return version, nil
 }
 
-func (s *Storage) UpdateFinalOrder(ctx context.Context, ID int64, update storage.UpdateFinalOrderDetails) error {
+// UpdateFinalOrder сохраняет финальные данные изделия с той же проверкой версии, что и UpdateNormOrder.
+// Н/руб пересчитывается на сервере, присланное значение игнорируется; коэффициент изделия меняется только
+// при явном update.CoefficientOverride, иначе действует коэффициент из истории на дату готовности.
+func (s *Storage) UpdateFinalOrder(ctx context.Context, ID int64, update storage.UpdateFinalOrderDetails) (int, error) {
 	const op = "storage.mysql.UpdateFinalOrder"
 
-	stmt := `UPDATE dem_product_instances_al SET customer_type = ?, norm_money = ?, profile = ?, sqr = ?, systema = ?, 
-            parent_assembly = ?, brigade = ?, type_izd = ?, status = 'final', coefficient = ? WHERE id = ?`
+	stmt := `UPDATE dem_product_instances_al SET customer_type = ?, profile = ?, sqr = ?, sqr_stv = COALESCE(?, sqr_stv), systema = ?, 
+            parent_assembly = ?, brigade = ?, type_izd = ?, coefficient = IF(?, ?, coefficient) WHERE id = ?`
 
-	_, err := s.db.ExecContext(ctx, stmt, update.CustomerType, update.NormMoney, update.Profile, update.Sqr, update.Systema, update.ParentAssembly,
-		update.Brigade, update.TypeIzd, update.Coefficient, ID)
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("%s: ошибка обновления  %w", op, err)
+		return 0, fmt.Errorf("%s: старт транзакции: %w", op, err)
 	}
+	defer tx.Rollback()
 
-	return nil
-}
+	if err := checkVersionTx(ctx, tx, ID, update.RowVersion); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
 
-func (s *Storage) UpdateStatus(ctx context.Context, rootProductID int64, status string) error {
-	const op = "storage.mysql.UpdateStatus"
+	if err := checkPeriodsOpenTx(ctx, tx, []int64{ID}, ""); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
 
-	stmtUpdateStatus := `UPDATE dem_product_instances_al SET status = ? WHERE id = ? OR parent_product_id = ?`
-	stmtDeleteExecutors := `DELETE FROM dem_operation_executors_al WHERE product_id IN (SELECT id FROM dem_product_instances_al WHERE id = ? OR parent_product_id = ?)`
+	if err := ensureBaselineTx(ctx, tx, ID); err != nil {
+		return 0, fmt.Errorf("%s: ошибка записи исходной ревизии: %w", op, err)
+	}
 
-	_, err := s.db.ExecContext(ctx, stmtUpdateStatus, status, rootProductID, rootProductID)
-	if err != nil {
-		return fmt.Errorf("%s: ошибка обновления статуса root ID %d: %w", op, rootProductID, err)
+	if update.StatusChange != nil {
+		if err := s.ChangeStatusTx(ctx, tx, *update.StatusChange); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
 	}
 
-	_, err = s.db.ExecContext(ctx, stmtDeleteExecutors, rootProductID, rootProductID)
+	_, err = tx.ExecContext(ctx, stmt, update.CustomerType, update.Profile, update.Sqr, update.SqrStv, update.Systema, update.ParentAssembly,
+		update.Brigade, update.TypeIzd, update.CoefficientOverride, update.Coefficient, ID)
 	if err != nil {
-		return fmt.Errorf("%s: ошибка удаления назначенных сотрудников заказа с ID %d: %w", op, rootProductID, err)
+		return 0, fmt.Errorf("%s: ошибка обновления  %w", op, err)
 	}
 
-	return nil
-}
-
-func (s *Storage) UpdateStatusTx(ctx context.Context, tx *sql.Tx, rootProductID int64, status string) error {
-	const op = "storage.mysql.UpdateStatusTx"
+	// Н/руб не берётся от клиента: считается по Н/час, коэффициенту и направлению заказчика
+	if err := recomputeNormMoneyTx(ctx, tx, []int64{ID}); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
 
-	stmtUpdateStatus := `UPDATE dem_product_instances_al SET status = ? WHERE id = ? OR parent_product_id = ?`
+	if err := recordRevisionTx(ctx, tx, ID, storage.RevisionFinal, update.ChangedBy); err != nil {
+		return 0, fmt.Errorf("%s: ошибка записи ревизии: %w", op, err)
+	}
 
-	_, err := tx.ExecContext(ctx, stmtUpdateStatus, status, rootProductID, rootProductID)
+	version, err := bumpVersionTx(ctx, tx, ID)
 	if err != nil {
-		return fmt.Errorf("%s: failed to update status in tx for root ID %d: %w", op, rootProductID, err)
+		return 0, fmt.Errorf("%s: ошибка обновления версии: %w", op, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: ошибка завершения транзакции: %w", op, err)
 	}
 
-	return nil
+	return version, nil
 }
@@ -0,0 +1,48 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"vue-golang/internal/storage"
+)
+
+// GetProductVersion — текущая версия строки изделия для ETag
+func (s *Storage) GetProductVersion(ctx context.Context, id int64) (int, error) {
+	const op = "storage.mysql.GetProductVersion"
+
+	var version int
+	if err := s.db.QueryRowContext(ctx, `SELECT row_version FROM dem_product_instances_al WHERE id = ?`, id).Scan(&version); err != nil {
+		return 0, fmt.Errorf("%s: изделие id=%d: %w", op, id, err)
+	}
+
+	return version, nil
+}
+
+// checkVersionTx блокирует строку изделия и сверяет версию с ожидаемой клиентом; nil — клиент версию не передал
+func checkVersionTx(ctx context.Context, tx *sql.Tx, id int64, expected *int) error {
+	var current int
+	if err := tx.QueryRowContext(ctx, `SELECT row_version FROM dem_product_instances_al WHERE id = ? FOR UPDATE`, id).Scan(&current); err != nil {
+		return fmt.Errorf("изделие id=%d: %w", id, err)
+	}
+
+	if expected != nil && *expected != current {
+		return &storage.VersionConflictError{ProductID: id, Expected: *expected, Current: current}
+	}
+
+	return nil
+}
+
+// bumpVersionTx увеличивает версию изделия и возвращает новую
+func bumpVersionTx(ctx context.Context, tx *sql.Tx, id int64) (int, error) {
+	if _, err := tx.ExecContext(ctx, `UPDATE dem_product_instances_al SET row_version = row_version + 1 WHERE id = ?`, id); err != nil {
+		return 0, fmt.Errorf("изделие id=%d: %w", id, err)
+	}
+
+	var version int
+	if err := tx.QueryRowContext(ctx, `SELECT row_version FROM dem_product_instances_al WHERE id = ?`, id).Scan(&version); err != nil {
+		return 0, fmt.Errorf("изделие id=%d: %w", id, err)
+	}
+
+	return version, nil
+}
@@ -11,22 +11,13 @@ import (
 func (s *Storage) GetAllWorkers(ctx context.Context, typeIzd string) ([]storage.GetWorkers, error) {
 	const op = "storage.mysql.GetWorkers"
 
-	productToTeam := map[string]string{
-		"window": "windows",
-		"door":   "windows",
-		"glyhar": "windows",
-
-		"vitrage": "vitrages",
-		"loggia":  "vitrages",
-	}
-
 	baseQuery := `SELECT DISTINCT e.id, e.name FROM dem_employees_al e`
 	var query string
 	var args []interface{}
 
 	if typeIzd != "" {
 		// Проверяем, есть ли тип в мапе
-		if teamSlug, ok := productToTeam[typeIzd]; ok {
+		if teamSlug, ok := storage.ProductTeams[typeIzd]; ok {
 			query = baseQuery + `
                 JOIN dem_employee_teams_al et ON e.id = et.employee_id
                 JOIN dem_teams_al t ON et.team_id = t.id
@@ -117,15 +108,45 @@ func (s *Storage) GetWorkersForReport(ctx context.Context, productIDs []int64) (
 	return workers, rows.Err()
 }
 
-func (s *Storage) SaveOperationWorkers(ctx context.Context, req storage.SaveWorkers) error {
+// SaveOperationWorkers перезаписывает назначения сборки и возвращает новую версию корня.
+// Если req.RowVersion не совпадает с версией корня — storage.ErrVersionConflict, ничего не меняется.
+func (s *Storage) SaveOperationWorkers(ctx context.Context, req storage.SaveWorkers) (int, error) {
 	const op = "storage.mysql.SaveOperationWorkers"
 
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("%s: begin transaction: %w", op, err)
+		return 0, fmt.Errorf("%s: begin transaction: %w", op, err)
 	}
 	defer tx.Rollback()
 
+	// Без корня сборки версию сверять не с чем (старые клиенты)
+	if req.RootProductID != 0 {
+		if err := checkVersionTx(ctx, tx, req.RootProductID, req.RowVersion); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	productIDs, err := productIDsTx(ctx, tx, req.RootProductID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: ошибка получения изделий сборки id=%d: %w", op, req.RootProductID, err)
+	}
+
+	// Изделия сборки и те, на которые назначают (без корня — только они), не должны быть в закрытом периоде
+	frozenCheck := append([]int64{}, productIDs...)
+	for _, a := range req.Assignments {
+		frozenCheck = append(frozenCheck, a.ProductID)
+	}
+	if err := checkPeriodsOpenTx(ctx, tx, frozenCheck, req.ReadyDate); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Старые назначения удаляются целиком, поэтому до первой правки фиксируем исходное состояние
+	for _, id := range productIDs {
+		if err := ensureBaselineTx(ctx, tx, id); err != nil {
+			return 0, fmt.Errorf("%s: ошибка записи исходной ревизии id=%d: %w", op, id, err)
+		}
+	}
+
 	// удаляем корень + дети
 	_, err = tx.ExecContext(ctx, `
 		DELETE FROM dem_operation_executors_al
@@ -137,7 +158,7 @@ func (s *Storage) SaveOperationWorkers(ctx context.Context, req storage.SaveWork
 		   )
 	`, req.RootProductID, req.RootProductID)
 	if err != nil {
-		return fmt.Errorf("%s: ошибка удаления старых назначении с id=%d %w", op, req.RootProductID, err)
+		return 0, fmt.Errorf("%s: ошибка удаления старых назначении с id=%d %w", op, req.RootProductID, err)
 	}
 
 	stmt, err := tx.PrepareContext(ctx, `
@@ -151,7 +172,7 @@ func (s *Storage) SaveOperationWorkers(ctx context.Context, req storage.SaveWork
             updated_at = CURRENT_TIMESTAMP
     `)
 	if err != nil {
-		return fmt.Errorf("%s: ошибка подготовки запроса: %w", op, err)
+		return 0, fmt.Errorf("%s: ошибка подготовки запроса: %w", op, err)
 	}
 	defer stmt.Close()
 
@@ -165,30 +186,48 @@ func (s *Storage) SaveOperationWorkers(ctx context.Context, req storage.SaveWork
 			a.ActualValue,
 		)
 		if err != nil {
-			return fmt.Errorf("%s: ошибка вставки новых назначенных сотрудников для нормировки с id=%d , op=%s: %w", op, a.ProductID, a.OperationName, err)
+			return 0, fmt.Errorf("%s: ошибка вставки новых назначенных сотрудников для нормировки с id=%d , op=%s: %w", op, a.ProductID, a.OperationName, err)
 		}
 	}
 
 	//Если указано — обновляем статус всей сборки
-	if req.UpdateStatus != "" && req.RootProductID != 0 {
+	if req.StatusChange != nil {
 		// Обновляем main + все его sub
-		if err := s.UpdateStatusTx(ctx, tx, req.RootProductID, req.UpdateStatus); err != nil {
-			return fmt.Errorf("%s: ошибка обновления статуса для родительского заказа id= %d: %w", op, req.RootProductID, err)
+		if err := s.ChangeStatusTx(ctx, tx, *req.StatusChange); err != nil {
+			return 0, fmt.Errorf("%s: ошибка обновления статуса для родительского заказа id= %d: %w", op, req.RootProductID, err)
 		}
 
 	}
 
 	if req.ReadyDate != "" {
 		if err := s.SaveReadyDate(ctx, tx, req.RootProductID, req.ReadyDate); err != nil {
-			return fmt.Errorf("%s: ошибка обновления даты готовности для родительского заказа id= %d: %w", op, req.RootProductID, err)
+			return 0, fmt.Errorf("%s: ошибка обновления даты готовности для родительского заказа id= %d: %w", op, req.RootProductID, err)
+		}
+		// Новая дата готовности может попасть в другой период действия коэффициента
+		if err := recomputeNormMoneyTx(ctx, tx, productIDs); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	for _, id := range productIDs {
+		if err := recordRevisionTx(ctx, tx, id, storage.RevisionExecutors, req.ChangedBy); err != nil {
+			return 0, fmt.Errorf("%s: ошибка записи ревизии id=%d: %w", op, id, err)
+		}
+	}
+
+	var version int
+	if req.RootProductID != 0 {
+		version, err = bumpVersionTx(ctx, tx, req.RootProductID)
+		if err != nil {
+			return 0, fmt.Errorf("%s: ошибка обновления версии id=%d: %w", op, req.RootProductID, err)
 		}
 	}
 
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("%s: commit transaction: %w", op, err)
+		return 0, fmt.Errorf("%s: commit transaction: %w", op, err)
 	}
 
-	return nil
+	return version, nil
 }
 
 func (s *Storage) SaveReadyDate(ctx context.Context, tx *sql.Tx, rootProductID int64, readyDate string) error {
@@ -204,3 +243,96 @@ func (s *Storage) SaveReadyDate(ctx context.Context, tx *sql.Tx, rootProductID i
 
 	return nil
 }
+
+// GetAssignmentProducts — изделия с типом, корнем сборки и нормированными операциями для проверки назначений
+func (s *Storage) GetAssignmentProducts(ctx context.Context, ids []int64) (map[int64]storage.AssignmentProduct, error) {
+	const op = "storage.mysql.GetAssignmentProducts"
+
+	products := make(map[int64]storage.AssignmentProduct, len(ids))
+	if len(ids) == 0 {
+		return products, nil
+	}
+	in := placeholders(len(ids))
+
+	rows, err := s.db.QueryContext(ctx, `SELECT id, COALESCE(parent_product_id, id), COALESCE(type, '')
+		FROM dem_product_instances_al WHERE id IN (`+in+`)`, toInterfaceSlice(ids)...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p := storage.AssignmentProduct{Operations: make(map[string]storage.NormOperation)}
+		if err := rows.Scan(&p.ID, &p.RootID, &p.Type); err != nil {
+			return nil, fmt.Errorf("%s: ошибка сканирования изделия: %w", op, err)
+		}
+		products[p.ID] = p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	rows.Close()
+
+	opRows, err := s.db.QueryContext(ctx, `SELECT product_id, operation_name, operation_label, count, value, minutes
+		FROM dem_operation_values_al WHERE product_id IN (`+in+`)`, toInterfaceSlice(ids)...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: операции: %w", op, err)
+	}
+	defer opRows.Close()
+
+	for opRows.Next() {
+		var (
+			productID int64
+			o         storage.NormOperation
+		)
+		if err := opRows.Scan(&productID, &o.Name, &o.Label, &o.Count, &o.Value, &o.Minutes); err != nil {
+			return nil, fmt.Errorf("%s: операции: %w", op, err)
+		}
+		if p, ok := products[productID]; ok {
+			p.Operations[o.Name] = o
+		}
+	}
+
+	return products, opRows.Err()
+}
+
+// GetAssignmentEmployees — сотрудники (включая неактивных) с бригадами
+func (s *Storage) GetAssignmentEmployees(ctx context.Context, ids []int64) (map[int64]storage.AssignmentEmployee, error) {
+	const op = "storage.mysql.GetAssignmentEmployees"
+
+	employees := make(map[int64]storage.AssignmentEmployee, len(ids))
+	if len(ids) == 0 {
+		return employees, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT e.id, e.name, e.is_active, COALESCE(t.slug, '')
+		FROM dem_employees_al e
+		LEFT JOIN dem_employee_teams_al et ON et.employee_id = e.id
+		LEFT JOIN dem_teams_al t ON t.id = et.team_id AND t.is_active = TRUE
+		WHERE e.id IN (`+placeholders(len(ids))+`)`, toInterfaceSlice(ids)...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			e    storage.AssignmentEmployee
+			slug string
+		)
+		if err := rows.Scan(&e.ID, &e.Name, &e.Active, &slug); err != nil {
+			return nil, fmt.Errorf("%s: ошибка сканирования сотрудника: %w", op, err)
+		}
+
+		if existing, ok := employees[e.ID]; ok {
+			e.Teams = existing.Teams
+		}
+		if slug != "" {
+			e.Teams = append(e.Teams, slug)
+		}
+		employees[e.ID] = e
+	}
+
+	return employees, rows.Err()
+}
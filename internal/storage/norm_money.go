@@ -0,0 +1,25 @@
+package storage
+
+import "time"
+
+// NormMoneyTolerance — расхождение Н/руб в пределах округления (руб.), которое не считается ошибкой
+const NormMoneyTolerance = 0.01
+
+// NormMoneyMismatch — изделие, у которого сохранённая Н/руб не совпадает с посчитанной
+// по Н/час, коэффициенту на дату готовности и множителю направления заказчика
+type NormMoneyMismatch struct {
+	ID                int64      `json:"id"`
+	OrderNum          string     `json:"order_num"`
+	Position          int        `json:"position"`
+	Type              string     `json:"type"`
+	Status            string     `json:"status"`
+	CustomerType      string     `json:"customer_type"`
+	ReadyDate         *time.Time `json:"ready_date"`
+	TotalTime         float64    `json:"total_time"`
+	Coefficient       float64    `json:"coefficient"`
+	CoefficientSource string     `json:"coefficient_source"`
+	Factor            float64    `json:"factor"`
+	Stored            float64    `json:"stored"`
+	Computed          float64    `json:"computed"`
+	Diff              float64    `json:"diff"`
+}
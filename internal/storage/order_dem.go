@@ -36,3 +36,10 @@ type ResultOrderDetails struct {
 	Color        *string `json:"color"`
 	Customer     *string `json:"customer"`
 }
+
+// PositionAttributes — атрибуты позиции из последней действующей нормировки: в заказах Dem их нет
+type PositionAttributes struct {
+	Systema string `json:"systema"`
+	TypeIzd string `json:"type_izd"`
+	Profile string `json:"profile"`
+}
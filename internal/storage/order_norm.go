@@ -1,6 +1,12 @@
 package storage
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+// ErrIdempotencyKeyReused — ключ идемпотентности уже занят запросом с другим содержимым
+var ErrIdempotencyKeyReused = errors.New("ключ идемпотентности уже использован для другого запроса")
 
 type OrderNormDetails struct {
 	OrderNum        string          `json:"order_num"`
@@ -20,6 +26,25 @@ type OrderNormDetails struct {
 	TypeIzd         string          `json:"type_izd"`
 	Profile         string          `json:"profile"`
 	Sqr             float64         `json:"sqr"`
+	// Версия шаблона, по которой посчитана нормировка; 0 — действующая на момент сохранения
+	TemplateVersion int `json:"template_version"`
+
+	// Sub-изделия сохраняются вместе с корнем; parent_product_id проставляется при сохранении
+	SubProducts []OrderNormDetails `json:"sub_products,omitempty"`
+	// Ключ идемпотентности (заголовок Idempotency-Key или тело): повтор с тем же ключом не создаёт дубль
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	// Перенормировка: действующие нормировки той же позиции и шаблона отменяются и помечаются заменёнными
+	Renorm bool `json:"renorm,omitempty"`
+	// Кто сохраняет; пишется в историю статусов заменённых нормировок
+	ChangedBy string `json:"-"`
+}
+
+// SavedNormOrder — id сохранённого корня и его sub-изделий; Replayed — ответ на повтор запроса с тем же ключом
+type SavedNormOrder struct {
+	OrderID    int64   `json:"order_id"`
+	SubIDs     []int64 `json:"sub_ids"`
+	Replayed   bool    `json:"replayed"`
+	Superseded []int64 `json:"superseded,omitempty"`
 }
 
 type NormOperation struct {
@@ -56,5 +81,7 @@ type GetOrderDetails struct {
 	TypeIzd         string          `json:"type_izd"`
 	ReadyDate       *string         `json:"ready_date"`
 	Position        int             `json:"position"`
+	TemplateVersion *int            `json:"template_version"`
+	RowVersion      int             `json:"row_version"`
 	//AssignWorkers   []AssignedWorkers `json:"assign_workers"`
 }
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Статусы расчётного периода и действия с ним
+const (
+	PeriodOpen   = "open"
+	PeriodClosed = "closed"
+
+	PeriodActionClose  = "close"
+	PeriodActionReopen = "reopen"
+)
+
+var (
+	// ErrPeriodClosed — изделие относится к закрытому расчётному периоду, правки запрещены
+	ErrPeriodClosed = errors.New("расчётный период закрыт")
+	// ErrPeriodState — период уже закрыт (при закрытии) или не закрыт (при переоткрытии)
+	ErrPeriodState   = errors.New("недопустимое действие для текущего состояния периода")
+	ErrPeriodMissing = errors.New("расчётный период не найден")
+	ErrTeamNotFound  = errors.New("бригада не найдена")
+)
+
+// PeriodClosedError — какое изделие и какой закрытый период (бригада и месяц 2006-01) помешали правке
+type PeriodClosedError struct {
+	ProductID int64
+	Team      string
+	Period    string
+}
+
+func (e *PeriodClosedError) Error() string {
+	return fmt.Sprintf("%s: изделие id=%d, бригада %s, период %s", ErrPeriodClosed, e.ProductID, e.Team, e.Period)
+}
+
+func (e *PeriodClosedError) Unwrap() error {
+	return ErrPeriodClosed
+}
+
+// PayrollPeriod — месяц по бригаде; Totals — снимок последнего закрытия
+type PayrollPeriod struct {
+	ID        int64          `json:"id"`
+	TeamID    int64          `json:"team_id"`
+	Team      string         `json:"team"`
+	TeamName  string         `json:"team_name"`
+	Period    string         `json:"period"`
+	Status    string         `json:"status"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	Events    []PeriodEvent  `json:"events,omitempty"`
+	Totals    []PayrollTotal `json:"totals,omitempty"`
+}
+
+type PeriodEvent struct {
+	ID        int64     `json:"id"`
+	Action    string    `json:"action"`
+	Actor     string    `json:"actor"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PayrollTotal — итог сотрудника за закрытый период
+type PayrollTotal struct {
+	EmployeeID    int64   `json:"employee_id"`
+	EmployeeName  string  `json:"employee_name"`
+	Operations    int     `json:"operations"`
+	ActualMinutes float64 `json:"actual_minutes"`
+	ActualValue   float64 `json:"actual_value"`
+	Money         float64 `json:"money"`
+}
+
+// TeamProductTypes — типы изделий, которые делает бригада (обратное к ProductTeams), по алфавиту
+func TeamProductTypes(team string) []string {
+	var types []string
+	for productType, slug := range ProductTeams {
+		if slug == team {
+			types = append(types, productType)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
@@ -0,0 +1,29 @@
+package storage
+
+// RenormCandidate — сохранённая нормировка по шаблону, которую ещё можно перенормировать (не финальная и не отменённая)
+type RenormCandidate struct {
+	ID              int64           `json:"id"`
+	ParentProductID *int64          `json:"parent_product_id"`
+	OrderNum        string          `json:"order_num"`
+	Position        int             `json:"position"`
+	Name            string          `json:"name"`
+	Type            string          `json:"type"`
+	TypeIzd         string          `json:"type_izd"`
+	Systema         string          `json:"systema"`
+	Profile         string          `json:"profile"`
+	Status          string          `json:"status"`
+	Count           float64         `json:"count"`
+	TotalTime       float64         `json:"total_time"`
+	TemplateVersion *int            `json:"template_version"`
+	Operations      []NormOperation `json:"operations"`
+	// Число назначенных сотрудников по операциям
+	Executors map[string]int `json:"executors"`
+}
+
+// RenormUpdate — пересчитанные операции изделия. Назначения сохраняются у операций, которые остались в нормировке.
+type RenormUpdate struct {
+	Operations      []NormOperation
+	TotalTime       float64
+	TemplateVersion int
+	ChangedBy       string
+}
@@ -0,0 +1,59 @@
+package storage
+
+import "time"
+
+// Виды ревизий нормировки
+const (
+	RevisionBaseline  = "baseline"  // состояние до первой правки, записывается вместе с ней
+	RevisionNorm      = "norm"      // UpdateNormOrder: итоги и операции
+	RevisionExecutors = "executors" // SaveOperationWorkers: назначенные сотрудники
+	RevisionRenorm    = "renorm"    // RenormProduct: пересчёт по исправленному шаблону
+	RevisionCancel    = "cancel"    // ChangeStatus в cancel: назначенные сотрудники сняты
+	RevisionFinal     = "final"     // UpdateFinalOrder: финальные данные изделия
+)
+
+// ProductRevision — неизменяемый снимок изделия после правки
+type ProductRevision struct {
+	ID        int64           `json:"id"`
+	ProductID int64           `json:"product_id"`
+	Revision  int             `json:"revision"`
+	Kind      string          `json:"kind"`
+	ChangedBy string          `json:"changed_by"`
+	Snapshot  ProductSnapshot `json:"snapshot"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type ProductSnapshot struct {
+	TotalTime       float64             `json:"total_time"`
+	Type            string              `json:"type"`
+	Status          string              `json:"status"`
+	TemplateVersion *int                `json:"template_version"`
+	Operations      []SnapshotOperation `json:"operations"`
+
+	// Финальные данные (UpdateFinalOrder)
+	CustomerType string   `json:"customer_type"`
+	Systema      string   `json:"systema"`
+	Profile      string   `json:"profile"`
+	TypeIzd      string   `json:"type_izd"`
+	Brigade      string   `json:"brigade"`
+	Sqr          float64  `json:"sqr"`
+	Coefficient  *float64 `json:"coefficient"`
+	NormMoney    float64  `json:"norm_money"`
+}
+
+type SnapshotOperation struct {
+	Name      string             `json:"operation_name"`
+	Label     string             `json:"operation_label"`
+	Count     float64            `json:"count"`
+	Value     float64            `json:"value"`
+	Minutes   float64            `json:"minutes"`
+	Executors []SnapshotExecutor `json:"executors,omitempty"`
+}
+
+type SnapshotExecutor struct {
+	EmployeeID    int64   `json:"employee_id"`
+	EmployeeName  string  `json:"employee_name"`
+	ActualMinutes float64 `json:"actual_minutes"`
+	ActualValue   float64 `json:"actual_value"`
+	Notes         string  `json:"notes,omitempty"`
+}
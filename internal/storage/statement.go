@@ -0,0 +1,57 @@
+package storage
+
+import "time"
+
+// StatementLine — одна выполненная сотрудником операция изделия. Date — дата готовности изделия,
+// Coefficient — руб. за нормо-час (изделия или по типу на дату готовности), nil — коэффициент не задан.
+type StatementLine struct {
+	ProductID      int64     `json:"product_id"`
+	OrderNum       string    `json:"order_num"`
+	Position       int       `json:"position"`
+	Name           string    `json:"name"`
+	Type           string    `json:"type"`
+	TypeIzd        string    `json:"type_izd"`
+	Date           time.Time `json:"date"`
+	OperationName  string    `json:"operation_name"`
+	OperationLabel string    `json:"operation_label"`
+	NormMinutes    float64   `json:"norm_minutes"`
+	NormValue      float64   `json:"norm_value"`
+	ActualMinutes  float64   `json:"actual_minutes"`
+	ActualValue    float64   `json:"actual_value"`
+	Coefficient    *float64  `json:"coefficient"`
+	// Откуда взят коэффициент (CoefSource*) и с какой даты он действует, если взят из истории
+	CoefficientSource string     `json:"coefficient_source"`
+	CoefficientFrom   *time.Time `json:"coefficient_from"`
+	// Множитель направления заказчика изделия (нет записи — 1)
+	CustomerFactor float64 `json:"customer_factor"`
+	Money          float64 `json:"money"`
+}
+
+// StatementTotal — итог по заказу, дню или за весь период; Key — номер заказа или дата (2006-01-02)
+type StatementTotal struct {
+	Key           string  `json:"key"`
+	Operations    int     `json:"operations"`
+	NormMinutes   float64 `json:"norm_minutes"`
+	ActualMinutes float64 `json:"actual_minutes"`
+	ActualValue   float64 `json:"actual_value"`
+	Money         float64 `json:"money"`
+}
+
+// EmployeeStatement — выработка и заработок сотрудника за период
+type EmployeeStatement struct {
+	Employee GetWorkers       `json:"employee"`
+	From     time.Time        `json:"from"`
+	To       time.Time        `json:"to"`
+	Lines    []StatementLine  `json:"lines"`
+	Orders   []StatementTotal `json:"orders"`
+	Days     []StatementTotal `json:"days"`
+	Total    StatementTotal   `json:"total"`
+	// Строки без коэффициента: деньги по ним не посчитаны
+	MissingCoefficient int `json:"missing_coefficient"`
+}
+
+// ExecutorMoney — заработок за операцию: факт н/час × коэффициент × множитель направления заказчика.
+// Не округляется: ведомость, отчёт ПЭО и снимок закрытия периода округляют до копеек только итоги, поэтому суммы сходятся.
+func ExecutorMoney(actualValue, coefficient, customerFactor float64) float64 {
+	return actualValue * coefficient * customerFactor
+}
@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrStatusConflict — статус изделия изменился между проверкой перехода и записью
+var ErrStatusConflict = errors.New("статус нормировки уже изменён")
+
+// StatusChange — переход нормировки из одного статуса в другой; From пустой у старых записей без статуса
+type StatusChange struct {
+	ID        int64     `json:"id"`
+	ProductID int64     `json:"product_id"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	ChangedBy string    `json:"changed_by"`
+	Reason    string    `json:"reason"`
+	ChangedAt time.Time `json:"changed_at"`
+}
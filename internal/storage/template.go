@@ -1,5 +1,12 @@
 package storage
 
+import (
+	"errors"
+	"time"
+)
+
+var ErrTemplateNotFound = errors.New("шаблон не найден")
+
 type Template struct {
 	ID         int         `json:"ID"`
 	Code       string      `json:"code"`
@@ -12,6 +19,10 @@ type Template struct {
 	Rules      []Rule      `json:"rules"`
 	IsActive   bool        `json:"is_active"`
 	HeadName   *string     `json:"head_name"`
+
+	// Версия из dem_template_versions_al, по которой идёт расчёт
+	Version       int        `json:"version,omitempty"`
+	EffectiveFrom *time.Time `json:"effective_from,omitempty"`
 }
 
 type Operation struct {
@@ -37,6 +48,13 @@ type Rule struct {
 
 	UnitField string `json:"unitField,omitempty"`
 	// Set — можно удалить, если не используется
+
+	// Порядок применения: сначала больший priority, при равном — порядок в шаблоне
+	Priority int `json:"priority,omitempty"`
+	// Exclusive — если правило совпало, к операции применяется только оно
+	Exclusive bool `json:"exclusive,omitempty"`
+	// Stop — после применения правила остальные правила операции не рассматриваются
+	Stop bool `json:"stop,omitempty"`
 }
 
 type TemplateAdmin struct {
@@ -48,6 +66,25 @@ type TemplateAdmin struct {
 	Systema   string `json:"systema"`
 	TypeIzd   string `json:"type_izd"`
 	Operation string `json:"operation"`
-	Rules     string `json:"rules"`
 	HeadName  string `json:"head_name"`
+
+	// JSON правил; при обновлении nil — оставить сохранённые, "[]" — очистить.
+	// NULL в dem_templates_al.rules означает то же, что пустой список: правил нет.
+	Rules *string `json:"rules"`
+
+	// Дата начала действия новой версии, nil — сразу
+	EffectiveFrom *time.Time `json:"effective_from"`
+	Comment       string     `json:"comment"`
+}
+
+// TemplateVersion — строка истории версий шаблона (без операций и правил)
+type TemplateVersion struct {
+	ID            int64     `json:"id"`
+	TemplateID    int       `json:"template_id"`
+	Version       int       `json:"version"`
+	EffectiveFrom time.Time `json:"effective_from"`
+	Code          string    `json:"code"`
+	Name          string    `json:"name"`
+	Comment       string    `json:"comment"`
+	CreatedAt     time.Time `json:"created_at"`
 }
@@ -0,0 +1,25 @@
+package storage
+
+import "time"
+
+// TemplateTestCase — регрессионный пример шаблона: материалы позиции заморожены,
+// Expected — какие value/minutes должны получиться по операциям
+type TemplateTestCase struct {
+	ID         int64               `json:"id"`
+	TemplateID int64               `json:"template_id"`
+	Name       string              `json:"name"`
+	TypeIzd    string              `json:"type_izd"`
+	ItemCount  int                 `json:"item_count"`
+	Systema    string              `json:"systema"`
+	Profile    string              `json:"profile"`
+	Materials  []*KlaesMaterials   `json:"materials"`
+	DopInfo    []*DopInfoDemPrice  `json:"dop_info"`
+	Expected   []ExpectedOperation `json:"expected"`
+	CreatedAt  time.Time           `json:"created_at"`
+}
+
+type ExpectedOperation struct {
+	Name    string  `json:"name"`
+	Value   float64 `json:"value"`
+	Minutes float64 `json:"minutes"`
+}
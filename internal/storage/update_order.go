@@ -16,17 +16,38 @@ type UpdateOrderDetails struct {
 	ParentAssembly  string          `json:"parent_assembly"`
 	ParentProductID *int64          `json:"parent_product_id"`
 	Status          *string         `json:"status"`
+	// Версия шаблона при перенормировании; nil — не меняется
+	TemplateVersion *int `json:"template_version"`
+	// Версия строки, которую клиент прочитал (If-Match или тело); без неё обработчик отвечает 428
+	RowVersion *int `json:"row_version"`
+
+	// Проверенный переход статуса; заполняется сервисом lifecycle, а не клиентом
+	StatusChange *StatusChange `json:"-"`
+	// Кто правит — для ревизий
+	ChangedBy string `json:"-"`
 }
 
 type UpdateFinalOrderDetails struct {
-	Brigade        *string  `json:"brigade"`
+	Brigade *string `json:"brigade"`
+	// Не используется: Н/руб считается на сервере (оставлено для совместимости со старым фронтендом)
 	NormMoney      *float64 `json:"norm_money"`
 	ParentAssembly *string  `json:"parent_assembly"`
 	Profile        *string  `json:"profile"`
 	Sqr            *float64 `json:"sqr"`
-	Systema        *string  `json:"systema"`
-	TypeIzd        *string  `json:"type_izd"`
-	CustomerType   *string  `json:"customer_type"`
-	Coefficient    *float64 `json:"coefficient"`
-	ID             int64    `json:"id"`
+	// Площадь створки; не передана — прежнее значение остаётся
+	SqrStv       *float64 `json:"sqr_stv"`
+	Systema      *string  `json:"systema"`
+	TypeIzd      *string  `json:"type_izd"`
+	CustomerType *string  `json:"customer_type"`
+	// Коэффициент изделия сохраняется только с CoefficientOverride: иначе клиент прислал бы обратно
+	// показанный ему коэффициент из истории и закрепил его. Override с пустым coefficient снимает ручное значение.
+	Coefficient         *float64 `json:"coefficient"`
+	CoefficientOverride bool     `json:"coefficient_override"`
+	ID                  int64    `json:"id"`
+	RowVersion          *int     `json:"row_version"`
+
+	// Переход в final; заполняется сервисом lifecycle
+	StatusChange *StatusChange `json:"-"`
+	// Кто правит — для ревизий
+	ChangedBy string `json:"-"`
 }
@@ -0,0 +1,24 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrVersionConflict — изделие изменили после того, как клиент его прочитал
+var ErrVersionConflict = errors.New("нормировка изменена другим пользователем")
+
+// VersionConflictError — версия, которую ожидал клиент, и текущая версия в базе
+type VersionConflictError struct {
+	ProductID int64
+	Expected  int
+	Current   int
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("%s: изделие id=%d, ожидалась версия %d, текущая %d", ErrVersionConflict, e.ProductID, e.Expected, e.Current)
+}
+
+func (e *VersionConflictError) Unwrap() error {
+	return ErrVersionConflict
+}
@@ -1,10 +1,29 @@
 package storage
 
+// ProductTeams — какая бригада делает изделие данного типа
+var ProductTeams = map[string]string{
+	"window": "windows",
+	"door":   "windows",
+	"glyhar": "windows",
+
+	"vitrage": "vitrages",
+	"loggia":  "vitrages",
+}
+
 type SaveWorkers struct {
-	Assignments   []OperationWorkers `json:"assignments"`
-	UpdateStatus  string             `json:"update_status"`
-	ReadyDate     string             `json:"ready_date"`
-	RootProductID int64              `json:"root_product_id"`
+	Assignments []OperationWorkers `json:"assignments"`
+	// Автоматическое деление нормы операции; заменяет ручные назначения той же операции
+	Splits        []OperationSplit `json:"splits,omitempty"`
+	UpdateStatus  string           `json:"update_status"`
+	ReadyDate     string           `json:"ready_date"`
+	RootProductID int64            `json:"root_product_id"`
+	// Версия корня, которую клиент прочитал (If-Match или тело); без неё обработчик отвечает 428
+	RowVersion *int `json:"row_version"`
+
+	// Проверенный переход в UpdateStatus; заполняется сервисом lifecycle
+	StatusChange *StatusChange `json:"-"`
+	// Кто назначает — для ревизий
+	ChangedBy string `json:"-"`
 }
 
 type OperationWorkers struct {
@@ -16,7 +35,45 @@ type OperationWorkers struct {
 	ActualValue   float64 `json:"actual_value"`
 }
 
+// Стратегии деления нормы операции между исполнителями
+const (
+	SplitEqual   = "equal"   // поровну
+	SplitPercent = "percent" // по процентам, в сумме 100
+	SplitHours   = "hours"   // пропорционально отработанным часам
+)
+
+// OperationSplit — как поделить норму операции изделия между сотрудниками
+type OperationSplit struct {
+	ProductID     int64        `json:"product_id"`
+	OperationName string       `json:"operation_name"`
+	Strategy      string       `json:"strategy"`
+	Shares        []SplitShare `json:"shares"`
+}
+
+type SplitShare struct {
+	EmployeeID int64   `json:"employee_id"`
+	Percent    float64 `json:"percent,omitempty"`
+	Hours      float64 `json:"hours,omitempty"`
+	Notes      string  `json:"notes,omitempty"`
+}
+
 type GetWorkers struct {
 	ID   int64  `json:"id"`
 	Name string `json:"name"`
 }
+
+// AssignmentProduct — изделие, на которое назначают сотрудников: корень сборки, тип и нормированные операции
+type AssignmentProduct struct {
+	ID         int64
+	RootID     int64
+	Type       string
+	Operations map[string]NormOperation
+}
+
+// AssignmentEmployee — сотрудник с активностью и бригадами (slug)
+type AssignmentEmployee struct {
+	ID     int64
+	Name   string
+	Active bool
+	Teams  []string
+}
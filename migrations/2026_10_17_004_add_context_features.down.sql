@@ -0,0 +1 @@
+DROP TABLE IF EXISTS `dem_context_features_al`;
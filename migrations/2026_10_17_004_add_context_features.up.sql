@@ -0,0 +1,21 @@
+-- Реестр признаков контекста для движка правил нормирования.
+-- Каждая строка описывает признак (feature_key), который считается по материалам
+-- из dem_klaes_materials и становится доступен в unitField/condition правил шаблона.
+CREATE TABLE IF NOT EXISTS `dem_context_features_al` (
+    `id` bigint NOT NULL AUTO_INCREMENT,
+    `feature_key` varchar(100) NOT NULL,
+    `product_type` varchar(25) NOT NULL DEFAULT '' COMMENT 'glyhar/window/door/loggia, пусто — для всех',
+    `match_names` json NOT NULL COMMENT 'точные наименования материалов (name_mat)',
+    `min_width` double DEFAULT NULL,
+    `max_width` double DEFAULT NULL,
+    `min_height` double DEFAULT NULL,
+    `max_height` double DEFAULT NULL,
+    `aggregation` varchar(20) NOT NULL DEFAULT 'sum' COMMENT 'sum, count, divide',
+    `divisor` double NOT NULL DEFAULT '1',
+    `is_active` tinyint(1) DEFAULT '1',
+    `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `unique_feature_type` (`feature_key`, `product_type`),
+    KEY `idx_product_type` (`product_type`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
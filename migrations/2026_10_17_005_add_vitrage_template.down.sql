@@ -0,0 +1 @@
+DELETE FROM `dem_templates_al` WHERE `code` = 'vitrage';
@@ -0,0 +1,19 @@
+-- Базовый шаблон нормирования витража.
+-- Операции на одно изделие, правила пересчитывают их по признакам BuildContextVitrage:
+-- MullionCount (стойки), TransomCount (ригели), VitragePanelCount (поля заполнения), GlassArea (м² заполнения).
+INSERT IGNORE INTO `dem_templates_al` (`code`, `name`, `category`, `operations`, `is_active`, `systema`, `izd`, `profile`, `head_name`, `rules`) VALUES
+    ('vitrage', 'витраж базовый', 'vitrage',
+     '[{"name": "napil_stoek", "type": "number", "count": 1, "label": "Напиловка стоек", "value": 0.1, "minutes": 6, "required": true},
+       {"name": "napil_rigel", "type": "number", "count": 1, "label": "Напиловка ригелей", "value": 0.083, "minutes": 5, "required": true},
+       {"name": "obr_stoek_rigel", "type": "number", "count": 1, "label": "Обработка стоек и ригелей", "value": 0.167, "minutes": 10, "required": true},
+       {"name": "sborka_karkas", "type": "number", "count": 1, "label": "Сборка каркаса", "value": 0.5, "minutes": 30, "required": true},
+       {"name": "ust_zapoln", "type": "number", "count": 1, "label": "Установка заполнения", "value": 0.25, "minutes": 15, "required": true},
+       {"name": "ust_stv", "type": "number", "count": 0, "label": "Установка створок", "value": 0, "minutes": 0, "required": false},
+       {"name": "upakovka", "type": "number", "count": 1, "label": "Упаковка", "value": 0.083, "minutes": 5, "required": true, "group": "ign"}]',
+     1, 'х', 'витраж', NULL, 'изготовления витражей',
+     '[{"operation": "napil_stoek", "condition": {"MullionCount": {"min": 1}}, "mode": "multiplied", "unitField": "MullionCount", "value_per_unit": 0.05, "minutes_per_unit": 3},
+       {"operation": "napil_rigel", "condition": {"TransomCount": {"min": 1}}, "mode": "multiplied", "unitField": "TransomCount", "value_per_unit": 0.042, "minutes_per_unit": 2.5},
+       {"operation": "obr_stoek_rigel", "condition": {"$expr": "MullionCount + TransomCount > 0"}, "mode": "multiplied", "unitField": "MullionCount + TransomCount", "value_per_unit": 0.083, "minutes_per_unit": 5},
+       {"operation": "ust_zapoln", "condition": {"VitragePanelCount": {"min": 1}}, "mode": "multiplied", "unitField": "VitragePanelCount", "value_per_unit": 0.1, "minutes_per_unit": 6},
+       {"operation": "ust_zapoln", "condition": {"GlassArea": {"min": 3}}, "mode": "additivePlusMultiplied", "unitField": "GlassArea", "value_per_unit": 0.017, "minutes_per_unit": 1},
+       {"operation": "ust_stv", "condition": {"StvWindowCount": {"min": 1}}, "mode": "multiplied", "unitField": "StvWindowCount", "value_per_unit": 0.25, "minutes_per_unit": 15}]');
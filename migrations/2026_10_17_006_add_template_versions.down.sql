@@ -0,0 +1,3 @@
+ALTER TABLE `dem_product_instances_al` DROP COLUMN `template_version`;
+
+DROP TABLE IF EXISTS `dem_template_versions_al`;
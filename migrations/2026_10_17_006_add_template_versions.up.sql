@@ -0,0 +1,35 @@
+-- Неизменяемые версии шаблонов нормирования.
+-- dem_templates_al остаётся «головой» шаблона (последняя правка), а расчёт берёт
+-- версию, действующую на текущую дату (effective_from <= NOW()).
+CREATE TABLE IF NOT EXISTS `dem_template_versions_al` (
+    `id` bigint NOT NULL AUTO_INCREMENT,
+    `template_id` int NOT NULL,
+    `version` int NOT NULL,
+    `effective_from` datetime NOT NULL,
+    `code` varchar(100) NOT NULL,
+    `name` varchar(255) NOT NULL,
+    `category` varchar(50) DEFAULT NULL,
+    `operations` json NOT NULL,
+    `rules` json DEFAULT NULL,
+    `systema` varchar(5) DEFAULT NULL,
+    `izd` varchar(50) DEFAULT NULL,
+    `profile` varchar(50) DEFAULT NULL,
+    `head_name` varchar(255) DEFAULT NULL,
+    `comment` varchar(255) NOT NULL DEFAULT '',
+    `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `unique_template_version` (`template_id`, `version`),
+    KEY `idx_code_effective` (`code`, `effective_from`),
+    CONSTRAINT `fk_tv_template` FOREIGN KEY (`template_id`) REFERENCES `dem_templates_al` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- Текущее состояние всех шаблонов становится версией 1
+INSERT INTO `dem_template_versions_al` (`template_id`, `version`, `effective_from`, `code`, `name`, `category`,
+                                        `operations`, `rules`, `systema`, `izd`, `profile`, `head_name`, `comment`)
+SELECT `id`, 1, COALESCE(`created_at`, NOW()), `code`, `name`, `category`, `operations`, `rules`, `systema`, `izd`,
+       `profile`, `head_name`, 'начальная версия'
+FROM `dem_templates_al`;
+
+-- Версия шаблона, по которой посчитана нормировка
+ALTER TABLE `dem_product_instances_al`
+    ADD COLUMN `template_version` int DEFAULT NULL COMMENT 'dem_template_versions_al.version';
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS `dem_template_test_cases_al`;
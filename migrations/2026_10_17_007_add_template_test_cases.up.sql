@@ -0,0 +1,19 @@
+-- Регрессионные примеры шаблона: замороженный список материалов, количество изделий
+-- и ожидаемые value/minutes по операциям. Прогоняются перед каждым обновлением шаблона.
+CREATE TABLE IF NOT EXISTS `dem_template_test_cases_al` (
+    `id` bigint NOT NULL AUTO_INCREMENT,
+    `template_id` int NOT NULL,
+    `name` varchar(255) NOT NULL,
+    `type_izd` varchar(50) NOT NULL,
+    `item_count` int NOT NULL DEFAULT 1,
+    `systema` varchar(50) NOT NULL DEFAULT '',
+    `profile` varchar(50) NOT NULL DEFAULT '',
+    `materials` json NOT NULL,
+    `dop_info` json DEFAULT NULL,
+    `expected` json NOT NULL,
+    `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    KEY `idx_template` (`template_id`),
+    CONSTRAINT `fk_ttc_template` FOREIGN KEY (`template_id`) REFERENCES `dem_templates_al` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
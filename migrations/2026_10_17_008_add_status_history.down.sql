@@ -0,0 +1 @@
+DROP TABLE IF EXISTS `dem_product_status_history_al`;
@@ -0,0 +1,13 @@
+-- История смены статусов нормировки: кто и когда перевёл изделие из одного статуса в другой.
+-- Статус корня и его sub-изделий меняется вместе, поэтому запись делается на корень.
+CREATE TABLE IF NOT EXISTS `dem_product_status_history_al` (
+    `id` bigint NOT NULL AUTO_INCREMENT,
+    `product_id` bigint NOT NULL,
+    `from_status` varchar(20) NOT NULL DEFAULT '',
+    `to_status` varchar(20) NOT NULL,
+    `changed_by` varchar(100) NOT NULL DEFAULT '',
+    `reason` varchar(255) NOT NULL DEFAULT '',
+    `changed_at` datetime DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    KEY `idx_product_changed` (`product_id`, `changed_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS `dem_product_revisions_al`;
@@ -0,0 +1,14 @@
+-- Ревизии нормировки: снимок изделия (итоги, операции и назначенные сотрудники) после каждой правки.
+-- Таблица только пополняется: UpdateNormOrder и SaveOperationWorkers перезаписывают операции и
+-- назначения, а прежние значения остаются здесь. Ревизия baseline — состояние до первой правки.
+CREATE TABLE IF NOT EXISTS `dem_product_revisions_al` (
+    `id` bigint NOT NULL AUTO_INCREMENT,
+    `product_id` bigint NOT NULL,
+    `revision` int NOT NULL,
+    `kind` varchar(20) NOT NULL,
+    `changed_by` varchar(100) NOT NULL DEFAULT '',
+    `snapshot` json NOT NULL,
+    `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `unique_product_revision` (`product_id`, `revision`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
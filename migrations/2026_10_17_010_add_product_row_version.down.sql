@@ -0,0 +1,2 @@
+ALTER TABLE `dem_product_instances_al`
+    DROP COLUMN `row_version`;
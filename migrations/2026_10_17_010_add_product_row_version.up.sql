@@ -0,0 +1,4 @@
+-- Версия строки изделия для оптимистической блокировки: растёт при каждой правке нормировки,
+-- назначений, финальных данных и статуса. Клиент передаёт её в If-Match, ответ на чтение отдаёт в ETag.
+ALTER TABLE `dem_product_instances_al`
+    ADD COLUMN `row_version` int NOT NULL DEFAULT 1;
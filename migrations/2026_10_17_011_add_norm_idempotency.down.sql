@@ -0,0 +1 @@
+DROP TABLE IF EXISTS `dem_norm_idempotency_al`;
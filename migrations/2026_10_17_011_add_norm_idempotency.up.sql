@@ -0,0 +1,8 @@
+-- Ключи идемпотентности сохранения нормировки: повторный POST с тем же ключом возвращает уже сохранённый корень,
+-- а не создаёт дубль. product_id пуст, пока транзакция, занявшая ключ, не завершилась.
+CREATE TABLE IF NOT EXISTS `dem_norm_idempotency_al` (
+    `idempotency_key` varchar(100) NOT NULL,
+    `product_id` bigint DEFAULT NULL,
+    `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`idempotency_key`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,3 @@
+ALTER TABLE `dem_product_instances_al`
+    DROP KEY `idx_order_position_template`,
+    DROP COLUMN `superseded_by`;
@@ -0,0 +1,5 @@
+-- Перенормировка позиции: прежняя нормировка отменяется и ссылается на ту, что её заменила.
+-- Индекс по позиции заказа и шаблону — для проверки дублей при сохранении (блокирует параллельную вставку той же позиции).
+ALTER TABLE `dem_product_instances_al`
+    ADD COLUMN `superseded_by` bigint DEFAULT NULL,
+    ADD KEY `idx_order_position_template` (`order_num`, `position`, `template_code`);
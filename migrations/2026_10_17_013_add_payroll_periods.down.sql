@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `dem_payroll_snapshots_al`;
+DROP TABLE IF EXISTS `dem_payroll_period_events_al`;
+DROP TABLE IF EXISTS `dem_payroll_periods_al`;
@@ -0,0 +1,39 @@
+-- Расчётные периоды: месяц по бригаде. Закрытый период замораживает изделия бригады с ready_date в этом месяце.
+CREATE TABLE IF NOT EXISTS `dem_payroll_periods_al` (
+    `id` bigint NOT NULL AUTO_INCREMENT,
+    `team_id` int NOT NULL,
+    `period_start` date NOT NULL,
+    `status` varchar(20) NOT NULL DEFAULT 'open',
+    `updated_at` datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `unique_team_period` (`team_id`, `period_start`),
+    CONSTRAINT `fk_pp_team` FOREIGN KEY (`team_id`) REFERENCES `dem_teams_al` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- Закрытия и переоткрытия периода: кто, когда и почему (причина обязательна при переоткрытии)
+CREATE TABLE IF NOT EXISTS `dem_payroll_period_events_al` (
+    `id` bigint NOT NULL AUTO_INCREMENT,
+    `period_id` bigint NOT NULL,
+    `action` varchar(20) NOT NULL,
+    `actor` varchar(100) NOT NULL DEFAULT '',
+    `reason` varchar(255) NOT NULL DEFAULT '',
+    `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    KEY `idx_period_created` (`period_id`, `created_at`),
+    CONSTRAINT `fk_ppe_period` FOREIGN KEY (`period_id`) REFERENCES `dem_payroll_periods_al` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- Итоги по сотрудникам на момент закрытия; у каждого закрытия свой снимок
+CREATE TABLE IF NOT EXISTS `dem_payroll_snapshots_al` (
+    `id` bigint NOT NULL AUTO_INCREMENT,
+    `event_id` bigint NOT NULL,
+    `employee_id` bigint NOT NULL,
+    `employee_name` varchar(255) NOT NULL DEFAULT '',
+    `operations` int NOT NULL DEFAULT 0,
+    `actual_minutes` double NOT NULL DEFAULT 0,
+    `actual_value` double NOT NULL DEFAULT 0,
+    `money` double NOT NULL DEFAULT 0,
+    PRIMARY KEY (`id`),
+    KEY `idx_event` (`event_id`),
+    CONSTRAINT `fk_ps_event` FOREIGN KEY (`event_id`) REFERENCES `dem_payroll_period_events_al` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
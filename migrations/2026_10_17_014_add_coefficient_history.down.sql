@@ -0,0 +1 @@
+DROP TABLE IF EXISTS `dem_coefficient_history_al`;
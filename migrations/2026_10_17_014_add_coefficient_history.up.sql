@@ -0,0 +1,18 @@
+-- История коэффициентов ПЭО по типу изделия: коэффициент действует с effective_from до следующей записи.
+-- Изделию без своего коэффициента берётся тот, что действовал на его ready_date.
+CREATE TABLE IF NOT EXISTS `dem_coefficient_history_al` (
+    `id` bigint NOT NULL AUTO_INCREMENT,
+    `type` varchar(50) NOT NULL,
+    `coefficient` decimal(10,3) NOT NULL,
+    `effective_from` date NOT NULL,
+    `created_by` varchar(100) NOT NULL DEFAULT '',
+    `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `unique_type_effective` (`type`, `effective_from`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- Текущие коэффициенты считаются действующими всегда, пока не появится более поздняя запись
+INSERT IGNORE INTO `dem_coefficient_history_al` (`type`, `coefficient`, `effective_from`, `created_by`)
+SELECT `type`, `coefficient`, '2000-01-01', 'migration'
+FROM `dem_coefficient_al`
+WHERE `type` IS NOT NULL AND `coefficient` IS NOT NULL;
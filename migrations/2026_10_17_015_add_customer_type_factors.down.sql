@@ -0,0 +1 @@
+DROP TABLE IF EXISTS `dem_customer_type_factors_al`;
@@ -0,0 +1,13 @@
+-- Множитель Н/руб по направлению заказчика (customer_type изделия): Н/руб = Н/час × коэффициент × множитель.
+-- Направление без записи считается с множителем 1.
+CREATE TABLE IF NOT EXISTS `dem_customer_type_factors_al` (
+    `customer_type` varchar(30) NOT NULL,
+    `factor` decimal(10,4) NOT NULL DEFAULT '1.0000',
+    `note` varchar(100) NOT NULL DEFAULT '',
+    PRIMARY KEY (`customer_type`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+INSERT IGNORE INTO `dem_customer_type_factors_al` (`customer_type`, `factor`, `note`) VALUES
+    ('к', 1.0000, 'корпоративный'),
+    ('ч', 1.0000, 'частный'),
+    ('д', 1.0000, 'дилер');
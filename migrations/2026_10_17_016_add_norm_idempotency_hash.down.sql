@@ -0,0 +1 @@
+ALTER TABLE `dem_norm_idempotency_al` DROP COLUMN `request_hash`;
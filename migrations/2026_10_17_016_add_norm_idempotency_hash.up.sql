@@ -0,0 +1,3 @@
+-- Хэш запроса, занявшего ключ идемпотентности: повтор ключа с другим телом отклоняется.
+-- У ключей, занятых до этой миграции, хэша нет — их повтор сверяется только по ключу.
+ALTER TABLE `dem_norm_idempotency_al` ADD COLUMN `request_hash` char(64) DEFAULT NULL AFTER `product_id`;